@@ -7,6 +7,9 @@ import (
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+	"github.com/y3gi/zero-trust-aws/tools/timing"
 )
 
 // deployedModules tracks which modules have been successfully deployed
@@ -28,15 +31,15 @@ func destroyAllDeployedModules(t *testing.T) {
 		module := deployedModules[i]
 		t.Logf("Destroying module: %s", module)
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/" + module,
 			TerraformBinary: "terraform",
 		})
 
-		// Use RunTerraformCommand to avoid failing the test if destroy has issues
-		_, err := terraform.RunTerraformCommandE(t, terraformOptions, terraform.FormatArgs(terraformOptions, "destroy", "-auto-approve")...)
+		// Keep going on failure so the remaining modules are still destroyed
+		err := destroyAndVerifyE(t, terraformOptions)
 		if err != nil {
-			t.Logf("Warning: Failed to destroy module %s: %v", module, err)
+			t.Errorf("Failed to destroy module %s: %v", module, err)
 		} else {
 			t.Logf("Successfully destroyed module: %s", module)
 		}
@@ -88,183 +91,205 @@ func TestE2EStackDeployment(t *testing.T) {
 	// 8. Certificates, RBAC, Secrets, VPC Endpoints...
 
 	t.Run("01_Bootstrap", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/bootstrap",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("bootstrap") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify bootstrap outputs
-		tfStateBucket := terraform.Output(t, terraformOptions, "terraform_state_bucket_name")
+		tfStateBucket := requireOutput(t, outputs, "terraform_state_bucket_name")
 		assert.NotEmpty(t, tfStateBucket, "Terraform state bucket should be created")
 
-		cloudTrailBucket := terraform.Output(t, terraformOptions, "cloudtrail_bucket_name")
+		cloudTrailBucket := requireOutput(t, outputs, "cloudtrail_bucket_name")
 		assert.NotEmpty(t, cloudTrailBucket, "CloudTrail bucket should be created")
 
-		kmsKeyID := terraform.Output(t, terraformOptions, "kms_key_id")
+		kmsKeyID := requireOutput(t, outputs, "kms_key_id")
 		assert.NotEmpty(t, kmsKeyID, "KMS key should be created")
 	})
 
 	t.Run("02_Security", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/security",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("security") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify security outputs
-		appRoleName := terraform.Output(t, terraformOptions, "app_instance_role_name")
+		appRoleName := requireOutput(t, outputs, "app_instance_role_name")
 		assert.NotEmpty(t, appRoleName, "App instance role should be created")
 
-		kmsKeyID := terraform.Output(t, terraformOptions, "kms_key_id")
+		kmsKeyID := requireOutput(t, outputs, "kms_key_id")
 		assert.NotEmpty(t, kmsKeyID, "KMS key should be created in security module")
 
-		instanceProfile := terraform.Output(t, terraformOptions, "app_instance_profile_name")
+		instanceProfile := requireOutput(t, outputs, "app_instance_profile_name")
 		assert.NotEmpty(t, instanceProfile, "Instance profile should be created")
 	})
 
 	t.Run("03_VPC", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/vpc",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("vpc") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify VPC outputs
-		vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+		vpcID := requireOutput(t, outputs, "vpc_id")
 		assert.NotEmpty(t, vpcID, "VPC should be created")
 
-		vpcCIDR := terraform.Output(t, terraformOptions, "vpc_cidr")
+		vpcCIDR := requireOutput(t, outputs, "vpc_cidr")
 		assert.Equal(t, "10.0.0.0/16", vpcCIDR, "VPC CIDR should match configuration")
 
-		publicSubnets := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
+		publicSubnets := requireOutputList(t, outputs, "public_subnet_ids")
 		assert.Greater(t, len(publicSubnets), 0, "Public subnets should be created")
 
-		privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
+		privateSubnets := requireOutputList(t, outputs, "private_subnet_ids")
 		assert.Greater(t, len(privateSubnets), 0, "Private subnets should be created")
 
-		igwID := terraform.Output(t, terraformOptions, "igw_id")
+		igwID := requireOutput(t, outputs, "igw_id")
 		assert.NotEmpty(t, igwID, "Internet Gateway should be created")
 	})
 
 	t.Run("04_DataStore", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/data_store",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("data_store") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify data store outputs
-		tfLocksTable := terraform.Output(t, terraformOptions, "terraform_locks_table_name")
+		tfLocksTable := requireOutput(t, outputs, "terraform_locks_table_name")
 		assert.NotEmpty(t, tfLocksTable, "Terraform locks table should be created")
 	})
 
 	t.Run("05_Firewall", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/firewall",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("firewall") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify firewall outputs
-		firewallID := terraform.Output(t, terraformOptions, "firewall_id")
+		firewallID := requireOutput(t, outputs, "firewall_id")
 		assert.NotEmpty(t, firewallID, "Firewall should be created")
 	})
 
 	t.Run("06_Compute", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/compute",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("compute") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify compute outputs
-		bastionID := terraform.Output(t, terraformOptions, "bastion_instance_id")
+		bastionID := requireOutput(t, outputs, "bastion_instance_id")
 		assert.NotEmpty(t, bastionID, "Bastion instance should be created")
 
-		bastionSG := terraform.Output(t, terraformOptions, "bastion_security_group_id")
+		bastionSG := requireOutput(t, outputs, "bastion_security_group_id")
 		assert.NotEmpty(t, bastionSG, "Bastion security group should be created")
 	})
 
 	t.Run("07_Monitoring", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/monitoring",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("monitoring") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify monitoring outputs
-		cloudWatchLogGroup := terraform.Output(t, terraformOptions, "cloudwatch_log_group_name")
+		cloudWatchLogGroup := requireOutput(t, outputs, "cloudwatch_log_group_name")
 		assert.NotEmpty(t, cloudWatchLogGroup, "CloudWatch log group should be created")
 	})
 
 	t.Run("08_Certificates", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/certificates",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("certificates") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify certificates outputs
-		rootCAARN := terraform.Output(t, terraformOptions, "root_ca_arn")
+		rootCAARN := requireOutput(t, outputs, "root_ca_arn")
 		assert.NotEmpty(t, rootCAARN, "Root CA ARN should be created")
 	})
 
 	t.Run("09_RBAC", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/rbac-authorization",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("rbac-authorization") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify RBAC outputs
-		bastionPolicyARN := terraform.Output(t, terraformOptions, "bastion_policy_arn")
+		bastionPolicyARN := requireOutput(t, outputs, "bastion_policy_arn")
 		assert.NotEmpty(t, bastionPolicyARN, "Bastion policy should be created")
 	})
 
 	t.Run("10_Secrets", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/secrets",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("secrets") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify secrets outputs
-		dbSecretARN := terraform.Output(t, terraformOptions, "db_credentials_secret_arn")
+		dbSecretARN := requireOutput(t, outputs, "db_credentials_secret_arn")
 		assert.NotEmpty(t, dbSecretARN, "DB credentials secret should be created")
 	})
 
 	t.Run("11_VPCEndpoints", func(t *testing.T) {
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/vpc-endpoints",
 			TerraformBinary: "terraform",
 		})
 		// No destroy - keep resources up for dependent modules
-		terraform.InitAndApply(t, terraformOptions)
+		initAndApply(t, terraformOptions)
 		markModuleDeployed("vpc-endpoints") // Track for failsafe cleanup
 
+		outputs := readOutputs(t, terraformOptions)
+
 		// Verify VPC endpoints outputs
-		s3EndpointID := terraform.Output(t, terraformOptions, "s3_vpc_endpoint_id")
+		s3EndpointID := requireOutput(t, outputs, "s3_vpc_endpoint_id")
 		assert.NotEmpty(t, s3EndpointID, "S3 VPC endpoint should be created")
 	})
 
@@ -274,6 +299,7 @@ func TestE2EStackDeployment(t *testing.T) {
 }
 
 // TestE2EStackCleanup destroys all resources in reverse dependency order
+// and fails if a module's state still tracks resources afterwards
 // Run this test AFTER TestE2EStackDeployment to clean up resources
 func TestE2EStackCleanup(t *testing.T) {
 	// Do NOT run in parallel - modules must destroy in reverse order
@@ -296,11 +322,11 @@ func TestE2EStackCleanup(t *testing.T) {
 
 	for i, module := range modules {
 		t.Run(fmt.Sprintf("%02d_Destroy_%s", i+1, module), func(t *testing.T) {
-			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			terraformOptions := withRetryableErrors(t, &terraform.Options{
 				TerraformDir:    "../../envs/test/e2e/" + module,
 				TerraformBinary: "terraform",
 			})
-			terraform.Destroy(t, terraformOptions)
+			require.NoError(t, destroyAndVerifyE(t, terraformOptions))
 		})
 	}
 }
@@ -323,17 +349,17 @@ func destroyCriticalPathModules(t *testing.T) {
 		module := criticalPathModules[i]
 		t.Logf("Destroying module: %s", module)
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/" + module,
 			TerraformBinary: "terraform",
 			// Disable color output to avoid GitHub Actions ::debug:: interference
 			NoColor: true,
 		})
 
-		// Use RunTerraformCommandE to avoid failing the test if destroy has issues
-		_, err := terraform.RunTerraformCommandE(t, terraformOptions, terraform.FormatArgs(terraformOptions, "destroy", "-auto-approve")...)
+		// Keep going on failure so the remaining modules are still destroyed
+		err := destroyAndVerifyE(t, terraformOptions)
 		if err != nil {
-			t.Logf("Warning: Failed to destroy module %s: %v", module, err)
+			t.Errorf("Failed to destroy module %s: %v", module, err)
 		} else {
 			t.Logf("Successfully destroyed module: %s", module)
 		}
@@ -349,36 +375,32 @@ func markCriticalPathModuleDeployed(module string) {
 	criticalPathModules = append(criticalPathModules, module)
 }
 
-// safeOutputAll retrieves all outputs with error handling for CI environments
-// GitHub Actions can inject ::debug:: output that corrupts JSON parsing
-func safeOutputAll(t *testing.T, terraformOptions *terraform.Options) map[string]interface{} {
-	outputs, err := terraform.OutputAllE(t, terraformOptions)
-	if err != nil {
-		t.Logf("Warning: Failed to parse terraform outputs (this may be a CI environment issue): %v", err)
-		// Return empty map instead of failing - the deployment succeeded
-		return make(map[string]interface{})
-	}
+// readOutputs runs `terraform output -json` for a module and parses it strictly
+// GitHub Actions can inject ::debug:: lines into stdout; tfoutput strips them and
+// fails with the raw text attached instead of silently returning empty values
+func readOutputs(t *testing.T, terraformOptions *terraform.Options) tfoutput.Outputs {
+	var outputs tfoutput.Outputs
+	err := timings.Time(moduleName(terraformOptions), timing.PhaseOutput, func() error {
+		var err error
+		outputs, err = tfoutput.Read(terraformOptions.TerraformBinary, terraformOptions.TerraformDir, terraformOptions.EnvVars)
+		return err
+	})
+	require.NoError(t, err, "Failed to read terraform outputs for %s", terraformOptions.TerraformDir)
 	return outputs
 }
 
-// safeOutput retrieves a single output with error handling
-func safeOutput(t *testing.T, terraformOptions *terraform.Options, key string) string {
-	output, err := terraform.OutputE(t, terraformOptions, key)
-	if err != nil {
-		t.Logf("Warning: Failed to get output '%s': %v", key, err)
-		return ""
-	}
-	return output
+// requireOutput returns a string output, failing the test if it is missing or not a string
+func requireOutput(t *testing.T, outputs tfoutput.Outputs, key string) string {
+	value, err := outputs.String(key)
+	require.NoError(t, err)
+	return value
 }
 
-// safeOutputList retrieves a list output with error handling
-func safeOutputList(t *testing.T, terraformOptions *terraform.Options, key string) []string {
-	output, err := terraform.OutputListE(t, terraformOptions, key)
-	if err != nil {
-		t.Logf("Warning: Failed to get output list '%s': %v", key, err)
-		return []string{}
-	}
-	return output
+// requireOutputList returns a list output, failing the test if it is missing or not a list
+func requireOutputList(t *testing.T, outputs tfoutput.Outputs, key string) []string {
+	value, err := outputs.List(key)
+	require.NoError(t, err)
+	return value
 }
 
 // TestE2ECriticalPath tests only the critical path: Bootstrap → Security → VPC
@@ -404,9 +426,9 @@ func TestE2ECriticalPath(t *testing.T) {
 		t.Log("SKIP_E2E_CLEANUP is set - failsafe cleanup disabled")
 	}
 
-	var bootstrapOutputs map[string]interface{}
-	var securityOutputs map[string]interface{}
-	var vpcOutputs map[string]interface{}
+	var bootstrapOutputs tfoutput.Outputs
+	var securityOutputs tfoutput.Outputs
+	var vpcOutputs tfoutput.Outputs
 
 	// Track if any deploy step failed to skip subsequent steps
 	var deployFailed bool
@@ -417,7 +439,7 @@ func TestE2ECriticalPath(t *testing.T) {
 			t.Skip("Skipping due to previous deployment failure")
 		}
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/bootstrap",
 			TerraformBinary: "terraform",
 			// Disable color output to avoid GitHub Actions ::debug:: interference
@@ -425,20 +447,20 @@ func TestE2ECriticalPath(t *testing.T) {
 		})
 
 		// Deploy first, then mark as deployed for cleanup
-		if _, err := terraform.InitAndApplyE(t, terraformOptions); err != nil {
+		if err := initAndApplyE(t, terraformOptions); err != nil {
 			deployFailed = true
 			t.Fatalf("Bootstrap deployment failed: %v", err)
 		}
 		markCriticalPathModuleDeployed("bootstrap") // Track for failsafe cleanup
 
-		// Use safe output functions to avoid JSON parsing issues in CI
-		bootstrapOutputs = safeOutputAll(t, terraformOptions)
+		// Read all outputs once; parsing is strict so CI log noise fails loudly
+		bootstrapOutputs = readOutputs(t, terraformOptions)
 
-		// Verify critical outputs exist (use safe functions)
-		tfStateBucket := safeOutput(t, terraformOptions, "terraform_state_bucket_name")
+		// Verify critical outputs exist
+		tfStateBucket := requireOutput(t, bootstrapOutputs, "terraform_state_bucket_name")
 		assert.NotEmpty(t, tfStateBucket, "Terraform state bucket must exist")
 
-		cloudtrailBucket := safeOutput(t, terraformOptions, "cloudtrail_bucket_name")
+		cloudtrailBucket := requireOutput(t, bootstrapOutputs, "cloudtrail_bucket_name")
 		assert.NotEmpty(t, cloudtrailBucket, "CloudTrail bucket must exist for audit logging")
 	})
 
@@ -448,28 +470,28 @@ func TestE2ECriticalPath(t *testing.T) {
 			t.Skip("Skipping due to previous deployment failure")
 		}
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/security",
 			TerraformBinary: "terraform",
 			NoColor:         true,
 		})
 
-		if _, err := terraform.InitAndApplyE(t, terraformOptions); err != nil {
+		if err := initAndApplyE(t, terraformOptions); err != nil {
 			deployFailed = true
 			t.Fatalf("Security deployment failed: %v", err)
 		}
 		markCriticalPathModuleDeployed("security") // Track for failsafe cleanup
 
-		securityOutputs = safeOutputAll(t, terraformOptions)
+		securityOutputs = readOutputs(t, terraformOptions)
 
 		// Verify critical outputs exist
-		appRole := safeOutput(t, terraformOptions, "app_instance_role_name")
+		appRole := requireOutput(t, securityOutputs, "app_instance_role_name")
 		assert.NotEmpty(t, appRole, "App instance role must exist for compute")
 
-		instanceProfile := safeOutput(t, terraformOptions, "app_instance_profile_name")
+		instanceProfile := requireOutput(t, securityOutputs, "app_instance_profile_name")
 		assert.NotEmpty(t, instanceProfile, "Instance profile must exist for compute")
 
-		kmsKeyID := safeOutput(t, terraformOptions, "kms_key_id")
+		kmsKeyID := requireOutput(t, securityOutputs, "kms_key_id")
 		assert.NotEmpty(t, kmsKeyID, "KMS key must exist for downstream modules")
 	})
 
@@ -479,25 +501,25 @@ func TestE2ECriticalPath(t *testing.T) {
 			t.Skip("Skipping due to previous deployment failure")
 		}
 
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		terraformOptions := withRetryableErrors(t, &terraform.Options{
 			TerraformDir:    "../../envs/test/e2e/vpc",
 			TerraformBinary: "terraform",
 			NoColor:         true,
 		})
 
-		if _, err := terraform.InitAndApplyE(t, terraformOptions); err != nil {
+		if err := initAndApplyE(t, terraformOptions); err != nil {
 			deployFailed = true
 			t.Fatalf("VPC deployment failed: %v", err)
 		}
 		markCriticalPathModuleDeployed("vpc") // Track for failsafe cleanup
 
-		vpcOutputs = safeOutputAll(t, terraformOptions)
+		vpcOutputs = readOutputs(t, terraformOptions)
 
 		// Verify critical outputs exist
-		vpcID := safeOutput(t, terraformOptions, "vpc_id")
+		vpcID := requireOutput(t, vpcOutputs, "vpc_id")
 		assert.NotEmpty(t, vpcID, "VPC ID must exist")
 
-		publicSubnets := safeOutputList(t, terraformOptions, "public_subnet_ids")
+		publicSubnets := requireOutputList(t, vpcOutputs, "public_subnet_ids")
 		assert.Greater(t, len(publicSubnets), 0, "Public subnets must exist for compute")
 	})
 
@@ -507,11 +529,10 @@ func TestE2ECriticalPath(t *testing.T) {
 			t.Skip("Skipping due to previous deployment failure")
 		}
 
-		// All critical paths should have completed
-		// Note: These may be empty if output parsing failed, but deployment still succeeded
-		t.Logf("Bootstrap outputs: %v", bootstrapOutputs)
-		t.Logf("Security outputs: %v", securityOutputs)
-		t.Logf("VPC outputs: %v", vpcOutputs)
+		// All critical paths should have completed with parsed outputs
+		t.Logf("Bootstrap outputs: %v", bootstrapOutputs.Names())
+		t.Logf("Security outputs: %v", securityOutputs.Names())
+		t.Logf("VPC outputs: %v", vpcOutputs.Names())
 
 		// Verify modules were tracked for cleanup
 		assert.Equal(t, 3, len(criticalPathModules), "All 3 critical path modules should be tracked")
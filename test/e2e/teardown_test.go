@@ -0,0 +1,32 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/y3gi/zero-trust-aws/tools/teardown"
+)
+
+// destroyAndVerifyE destroys a module, retrying ordering failures such as a
+// subnet that still has endpoint ENIs with bounded backoff, then reads the
+// module's state and returns an error listing anything still tracked
+func destroyAndVerifyE(t *testing.T, terraformOptions *terraform.Options) error {
+	module := moduleName(terraformOptions)
+
+	err := teardown.Retry(teardown.DefaultBackoff, time.Sleep,
+		func(attempt int, delay time.Duration, err error) {
+			t.Logf("Destroy of %s blocked by dependent resources (attempt %d), retrying in %s: %v", module, attempt, delay, err)
+		},
+		func() error { return destroyE(t, terraformOptions) },
+	)
+	if err != nil {
+		return err
+	}
+
+	state, err := terraform.ShowE(t, terraformOptions)
+	if err != nil {
+		return err
+	}
+	return teardown.Verify(module, []byte(state))
+}
@@ -0,0 +1,79 @@
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/timing"
+)
+
+// timings records init/plan/apply/output/destroy durations for every module
+// the E2E suite touches. TestMain writes the report once all tests finish.
+var timings = timing.NewRecorder("e2e")
+
+// TestMain runs the suite, then writes reports/<name>.json and .md and fails
+// the run if a module exceeded its budget in timing-budgets.json or regressed
+// against timing-baselines/<name>.json
+// Set TIMING_REPORT_NAME per `go test -run` invocation to keep reports apart
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	violations, err := timing.Finish(timings, timing.SettingsFromEnv(timing.Settings{
+		Name:        "e2e",
+		ReportDir:   "reports",
+		ConfigPath:  "timing-budgets.json",
+		BaselineDir: "timing-baselines",
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write timing report: %v\n", err)
+		code = 1
+	}
+	for _, violation := range violations {
+		fmt.Fprintf(os.Stderr, "TIMING VIOLATION: %s\n", violation)
+	}
+	if len(violations) > 0 && code == 0 {
+		code = 1
+	}
+
+	os.Exit(code)
+}
+
+// moduleName returns the module a set of options points at, e.g. "firewall"
+func moduleName(terraformOptions *terraform.Options) string {
+	return filepath.Base(terraformOptions.TerraformDir)
+}
+
+// initAndApplyE runs init and apply as separately timed phases
+func initAndApplyE(t *testing.T, terraformOptions *terraform.Options) error {
+	module := moduleName(terraformOptions)
+
+	err := timings.Time(module, timing.PhaseInit, func() error {
+		_, err := terraform.InitE(t, terraformOptions)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return timings.Time(module, timing.PhaseApply, func() error {
+		_, err := terraform.ApplyE(t, terraformOptions)
+		return err
+	})
+}
+
+// initAndApply is initAndApplyE that fails the test on error
+func initAndApply(t *testing.T, terraformOptions *terraform.Options) {
+	require.NoError(t, initAndApplyE(t, terraformOptions))
+}
+
+// destroyE runs a timed terraform destroy
+func destroyE(t *testing.T, terraformOptions *terraform.Options) error {
+	return timings.Time(moduleName(terraformOptions), timing.PhaseDestroy, func() error {
+		_, err := terraform.DestroyE(t, terraformOptions)
+		return err
+	})
+}
@@ -10,23 +10,23 @@ import (
 func TestBootstrapModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/bootstrap",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify Terraform state bucket created
-	tfStateBucket := terraform.Output(t, terraformOptions, "terraform_state_bucket_name")
+	tfStateBucket := output(t, terraformOptions, "terraform_state_bucket_name")
 	assert.NotEmpty(t, tfStateBucket, "Terraform state bucket name should not be empty")
 
 	// Verify CloudTrail bucket created
-	cloudTrailBucket := terraform.Output(t, terraformOptions, "cloudtrail_bucket_name")
+	cloudTrailBucket := output(t, terraformOptions, "cloudtrail_bucket_name")
 	assert.NotEmpty(t, cloudTrailBucket, "CloudTrail bucket name should not be empty")
 
 	// Verify KMS key created
-	kmsKeyID := terraform.Output(t, terraformOptions, "kms_key_id")
+	kmsKeyID := output(t, terraformOptions, "kms_key_id")
 	assert.NotEmpty(t, kmsKeyID, "KMS key ID should not be empty")
 }
@@ -10,19 +10,19 @@ import (
 func TestCertificatesModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/certificates",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify root CA created
-	rootCAARN := terraform.Output(t, terraformOptions, "root_ca_arn")
+	rootCAARN := output(t, terraformOptions, "root_ca_arn")
 	assert.NotEmpty(t, rootCAARN, "Root CA ARN should not be empty")
 
 	// Verify root CA domain
-	rootCADomain := terraform.Output(t, terraformOptions, "root_ca_domain")
+	rootCADomain := output(t, terraformOptions, "root_ca_domain")
 	assert.NotEmpty(t, rootCADomain, "Root CA domain should not be empty")
 }
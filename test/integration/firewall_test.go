@@ -10,26 +10,26 @@ import (
 func TestFirewallModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/firewall",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify firewall resources created
-	firewallID := terraform.Output(t, terraformOptions, "firewall_id")
+	firewallID := output(t, terraformOptions, "firewall_id")
 	assert.NotEmpty(t, firewallID, "Firewall ID should not be empty")
 
-	firewallPolicyID := terraform.Output(t, terraformOptions, "firewall_policy_id")
+	firewallPolicyID := output(t, terraformOptions, "firewall_policy_id")
 	assert.NotEmpty(t, firewallPolicyID, "Firewall policy ID should not be empty")
 }
 
 func TestFirewallNACLs(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/firewall",
 		TerraformBinary: "terraform",
 		Vars: map[string]interface{}{
@@ -40,8 +40,8 @@ func TestFirewallNACLs(t *testing.T) {
 		},
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify firewall rule group in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_networkfirewall_rule_group.stateful", "Firewall rule group should be in plan")
@@ -50,7 +50,7 @@ func TestFirewallNACLs(t *testing.T) {
 func TestFirewallSecurityGroupRules(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/firewall",
 		TerraformBinary: "terraform",
 		Vars: map[string]interface{}{
@@ -61,8 +61,8 @@ func TestFirewallSecurityGroupRules(t *testing.T) {
 		},
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify firewall status endpoint in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_networkfirewall_firewall_policy.main", "Firewall policy should be in plan")
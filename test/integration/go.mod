@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/gruntwork-io/terratest v0.46.8
 	github.com/stretchr/testify v1.8.4
+	github.com/y3gi/zero-trust-aws/tools v0.0.0
 )
 
 require (
@@ -68,3 +69,5 @@ require (
 	google.golang.org/protobuf v1.32.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/y3gi/zero-trust-aws/tools => ../../tools
@@ -10,33 +10,33 @@ import (
 func TestMonitoringModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/monitoring",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify CloudWatch log group created
-	logGroupName := terraform.Output(t, terraformOptions, "cloudwatch_log_group_name")
+	logGroupName := output(t, terraformOptions, "cloudwatch_log_group_name")
 	assert.NotEmpty(t, logGroupName, "CloudWatch log group name should not be empty")
 
 	// Verify budget created
-	budgetID := terraform.Output(t, terraformOptions, "budget_id")
+	budgetID := output(t, terraformOptions, "budget_id")
 	assert.NotEmpty(t, budgetID, "Budget ID should not be empty")
 }
 
 func TestMonitoringCloudTrail(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/monitoring",
 		TerraformBinary: "terraform",
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify CloudTrail in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_cloudtrail.main", "CloudTrail should be in plan")
@@ -45,13 +45,13 @@ func TestMonitoringCloudTrail(t *testing.T) {
 func TestMonitoringAlarms(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/monitoring",
 		TerraformBinary: "terraform",
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify alarms in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_cloudwatch_metric_alarm.authorized_api_calls", "CloudWatch alarm should be in plan")
@@ -60,13 +60,13 @@ func TestMonitoringAlarms(t *testing.T) {
 func TestMonitoringBudget(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/monitoring",
 		TerraformBinary: "terraform",
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify budget in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_budgets_budget.monthly", "Budget should be in plan")
@@ -10,32 +10,32 @@ import (
 func TestRBACModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/rbac-authorization",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify IAM policies created
-	bastionPolicyARN := terraform.Output(t, terraformOptions, "bastion_policy_arn")
+	bastionPolicyARN := output(t, terraformOptions, "bastion_policy_arn")
 	assert.NotEmpty(t, bastionPolicyARN, "Bastion policy ARN should not be empty")
 
-	appServerPolicyARN := terraform.Output(t, terraformOptions, "app_server_policy_arn")
+	appServerPolicyARN := output(t, terraformOptions, "app_server_policy_arn")
 	assert.NotEmpty(t, appServerPolicyARN, "App server policy ARN should not be empty")
 }
 
 func TestRBACPolicies(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/rbac-authorization",
 		TerraformBinary: "terraform",
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify IAM policies in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_iam_policy.bastion_policy", "Bastion policy should be in plan")
@@ -0,0 +1,23 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/y3gi/zero-trust-aws/tools/retryable"
+)
+
+// withRetryableErrors is terraform.WithDefaultRetryableErrors plus the
+// project's catalog of errors that clear on their own (firewall updates in
+// progress, PCA state transitions, secrets pending deletion, IAM propagation)
+func withRetryableErrors(t *testing.T, originalOptions *terraform.Options) *terraform.Options {
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, originalOptions)
+	terraformOptions.RetryableTerraformErrors = retryable.Merge(terraformOptions.RetryableTerraformErrors)
+	if terraformOptions.MaxRetries < retryable.MaxRetries {
+		terraformOptions.MaxRetries = retryable.MaxRetries
+	}
+	if terraformOptions.TimeBetweenRetries < retryable.TimeBetweenRetries {
+		terraformOptions.TimeBetweenRetries = retryable.TimeBetweenRetries
+	}
+	return terraformOptions
+}
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/timing"
+)
+
+// timings records init/plan/apply/output/destroy durations per module. Tests
+// run in parallel and several tests share a module, so durations are summed.
+var timings = timing.NewRecorder("integration")
+
+// TestMain runs the suite, then writes reports/<name>.json and .md and fails
+// the run if a module exceeded its budget in timing-budgets.json or regressed
+// against timing-baselines/<name>.json
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	violations, err := timing.Finish(timings, timing.SettingsFromEnv(timing.Settings{
+		Name:        "integration",
+		ReportDir:   "reports",
+		ConfigPath:  "timing-budgets.json",
+		BaselineDir: "timing-baselines",
+	}))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write timing report: %v\n", err)
+		code = 1
+	}
+	for _, violation := range violations {
+		fmt.Fprintf(os.Stderr, "TIMING VIOLATION: %s\n", violation)
+	}
+	if len(violations) > 0 && code == 0 {
+		code = 1
+	}
+
+	os.Exit(code)
+}
+
+// moduleName returns the module a set of options points at, e.g. "firewall"
+func moduleName(terraformOptions *terraform.Options) string {
+	return filepath.Base(terraformOptions.TerraformDir)
+}
+
+// timed runs a terraform step as the given phase and fails the test on error
+func timed(t *testing.T, terraformOptions *terraform.Options, phase timing.Phase, step func() error) {
+	require.NoError(t, timings.Time(moduleName(terraformOptions), phase, step))
+}
+
+// initAndApply is terraform.InitAndApply with init and apply timed separately
+func initAndApply(t *testing.T, terraformOptions *terraform.Options) {
+	timed(t, terraformOptions, timing.PhaseInit, func() error {
+		_, err := terraform.InitE(t, terraformOptions)
+		return err
+	})
+	timed(t, terraformOptions, timing.PhaseApply, func() error {
+		_, err := terraform.ApplyE(t, terraformOptions)
+		return err
+	})
+}
+
+// initAndPlan is terraform.InitAndPlan with init and plan timed separately
+func initAndPlan(t *testing.T, terraformOptions *terraform.Options) {
+	timed(t, terraformOptions, timing.PhaseInit, func() error {
+		_, err := terraform.InitE(t, terraformOptions)
+		return err
+	})
+	timed(t, terraformOptions, timing.PhasePlan, func() error {
+		_, err := terraform.PlanE(t, terraformOptions)
+		return err
+	})
+}
+
+// initAndPlanAndShowWithStruct is terraform.InitAndPlanAndShowWithStruct with
+// init and plan (including show) timed separately
+func initAndPlanAndShowWithStruct(t *testing.T, terraformOptions *terraform.Options) *terraform.PlanStruct {
+	require.NotEmpty(t, terraformOptions.PlanFilePath, terraform.PlanFilePathRequired.Error())
+
+	var plan *terraform.PlanStruct
+	timed(t, terraformOptions, timing.PhaseInit, func() error {
+		_, err := terraform.InitE(t, terraformOptions)
+		return err
+	})
+	timed(t, terraformOptions, timing.PhasePlan, func() error {
+		if _, err := terraform.PlanE(t, terraformOptions); err != nil {
+			return err
+		}
+		var err error
+		plan, err = terraform.ShowWithStructE(t, terraformOptions)
+		return err
+	})
+	return plan
+}
+
+// output is terraform.Output, timed
+func output(t *testing.T, terraformOptions *terraform.Options, key string) string {
+	var value string
+	timed(t, terraformOptions, timing.PhaseOutput, func() error {
+		var err error
+		value, err = terraform.OutputE(t, terraformOptions, key)
+		return err
+	})
+	return value
+}
+
+// outputList is terraform.OutputList, timed
+func outputList(t *testing.T, terraformOptions *terraform.Options, key string) []string {
+	var value []string
+	timed(t, terraformOptions, timing.PhaseOutput, func() error {
+		var err error
+		value, err = terraform.OutputListE(t, terraformOptions, key)
+		return err
+	})
+	return value
+}
+
+// destroy is terraform.Destroy, timed
+func destroy(t *testing.T, terraformOptions *terraform.Options) {
+	timed(t, terraformOptions, timing.PhaseDestroy, func() error {
+		_, err := terraform.DestroyE(t, terraformOptions)
+		return err
+	})
+}
@@ -10,33 +10,33 @@ import (
 func TestVPCEndpointsModule(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/vpc-endpoints",
 		TerraformBinary: "terraform",
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroy(t, terraformOptions)
+	initAndApply(t, terraformOptions)
 
 	// Verify S3 VPC endpoint created
-	s3EndpointID := terraform.Output(t, terraformOptions, "s3_vpc_endpoint_id")
+	s3EndpointID := output(t, terraformOptions, "s3_vpc_endpoint_id")
 	assert.NotEmpty(t, s3EndpointID, "S3 VPC endpoint ID should not be empty")
 
 	// Verify Secrets Manager VPC endpoint created
-	secretsEndpointID := terraform.Output(t, terraformOptions, "secretsmanager_vpc_endpoint_id")
+	secretsEndpointID := output(t, terraformOptions, "secretsmanager_vpc_endpoint_id")
 	assert.NotEmpty(t, secretsEndpointID, "Secrets Manager VPC endpoint ID should not be empty")
 }
 
 func TestVPCEndpointsOutputs(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := withRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../envs/test/integration/vpc-endpoints",
 		TerraformBinary: "terraform",
 	})
 
-	terraform.InitAndPlan(t, terraformOptions)
-	plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+	initAndPlan(t, terraformOptions)
+	plan := initAndPlanAndShowWithStruct(t, terraformOptions)
 
 	// Verify VPC endpoint resources in plan
 	assert.Contains(t, plan.ResourceChangesMap, "aws_vpc_endpoint.s3", "S3 VPC endpoint should be in plan")
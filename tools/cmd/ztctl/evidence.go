@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/evidence"
+)
+
+// evidenceOptions are the flags of the evidence command
+type evidenceOptions struct {
+	plansDir string
+	unitJSON string
+	out      string
+	strict   bool
+}
+
+// buildEvidence runs the unit rules (or reads their `go test -json` output),
+// evaluates the plans when given, and writes the evidence archive
+func buildEvidence(ctx context.Context, config deploy.Config, opts evidenceOptions, stdout io.Writer, logger *slog.Logger) int {
+	in := evidence.Input{Root: config.Root, Env: config.Env, Now: time.Now()}
+
+	commit, dirty, err := gitCommit(ctx, config.Root)
+	if err != nil {
+		logger.Error("cannot read the git commit", "error", err)
+		return exitPreflight
+	}
+	in.GitCommit, in.GitDirty = commit, dirty
+
+	unitJSON, err := unitTestOutput(ctx, config.Root, opts.unitJSON, logger)
+	if err != nil {
+		logger.Error("cannot run the unit rules", "error", err)
+		return exitFailed
+	}
+	if in.Unit, err = evidence.ParseTestJSON(bytes.NewReader(unitJSON)); err != nil {
+		logger.Error("cannot read the unit rule results", "error", err)
+		return exitFailed
+	}
+
+	if opts.plansDir != "" {
+		plans, err := readPlans(config, opts.plansDir, logger)
+		if err != nil {
+			logger.Error("cannot read plans", "error", err)
+			return exitUsage
+		}
+		in.Plans = map[string][]byte{}
+		for _, plan := range plans {
+			in.Plans[plan.Module] = plan.JSON
+		}
+	} else {
+		logger.Warn("no --plans given, plan-level rules are not run")
+	}
+
+	bundle, err := evidence.Build(in)
+	if err != nil {
+		logger.Error("cannot build the evidence bundle", "error", err)
+		return exitFailed
+	}
+
+	out := opts.out
+	if out == "" {
+		out = filepath.Join("reports", fmt.Sprintf("evidence-%s-%.12s.tar.gz", config.Env, commit))
+	}
+	if err := writeArchive(bundle, out); err != nil {
+		logger.Error("cannot write the evidence archive", "error", err)
+		return exitFailed
+	}
+
+	if err := writeSummary(string(bundle.Files["summary.md"]), stdout); err != nil {
+		logger.Warn("cannot write the step summary", "error", err)
+	}
+	logger.Info("evidence written", "path", out)
+
+	if failed := bundle.Failed(); len(failed) > 0 {
+		logger.Warn("controls with failing rules", "controls", failed)
+		if opts.strict {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+func writeArchive(bundle *evidence.Bundle, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bundle.WriteArchive(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// gitCommit returns HEAD and whether the working tree has uncommitted changes
+func gitCommit(ctx context.Context, root string) (string, bool, error) {
+	head, err := git(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+	status, err := git(ctx, root, "status", "--porcelain")
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(head), strings.TrimSpace(status) != "", nil
+}
+
+// unitTestOutput reads `go test -json` output from path, or runs test/unit
+// when path is empty. Failing tests are results, not errors.
+func unitTestOutput(ctx context.Context, root, path string, logger *slog.Logger) ([]byte, error) {
+	if path != "" {
+		return os.ReadFile(path)
+	}
+
+	logger.Info("running unit rules", "dir", "test/unit")
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", "test", "-json", "-count=1", "./...")
+	cmd.Dir = filepath.Join(root, "test", "unit")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		return nil, fmt.Errorf("go test: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/impact"
+)
+
+// impactOptions are the flags of the impact command
+type impactOptions struct {
+	base string
+	diff string
+	run  string
+	json string
+}
+
+// selectTests maps the changed files to the integration tests and E2E
+// profiles to run
+func selectTests(ctx context.Context, config deploy.Config, opts impactOptions, stdin io.Reader, stdout io.Writer, logger *slog.Logger) int {
+	if opts.run != "" && !knownSuite(opts.run) {
+		logger.Error("unknown suite", "run", opts.run)
+		return exitUsage
+	}
+
+	files, err := changedFiles(ctx, config.Root, opts, stdin)
+	if err != nil {
+		logger.Error("cannot read the changed files", "error", err)
+		return exitFailed
+	}
+
+	result, err := impact.Analyze(config.Root, config.Env, files)
+	if err != nil {
+		logger.Error("cannot analyze the change", "error", err)
+		return exitPreflight
+	}
+	if len(result.Ignored) > 0 {
+		logger.Info("files that affect no test", "files", result.Ignored)
+	}
+
+	if opts.json != "" {
+		data, err := result.JSON()
+		if err == nil {
+			err = os.WriteFile(opts.json, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			logger.Error("failed to write impact report", "error", err)
+			return exitFailed
+		}
+	}
+
+	if opts.run != "" {
+		fmt.Fprintln(stdout, result.Suite(opts.run).Run())
+		return exitOK
+	}
+	result.Text(stdout)
+	return exitOK
+}
+
+func knownSuite(name string) bool {
+	for _, suite := range impact.Suites {
+		if suite.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles reads the diff from --diff, or asks git for the files changed
+// since the merge base with --base, including uncommitted changes
+func changedFiles(ctx context.Context, root string, opts impactOptions, stdin io.Reader) ([]string, error) {
+	switch opts.diff {
+	case "":
+	case "-":
+		return impact.ParseDiff(stdin)
+	default:
+		f, err := os.Open(opts.diff)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return impact.ParseDiff(f)
+	}
+
+	mergeBase, err := git(ctx, root, "merge-base", opts.base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	names, err := git(ctx, root, "diff", "--name-only", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, err
+	}
+	return impact.ParseDiff(strings.NewReader(names))
+}
+
+func git(ctx context.Context, root string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
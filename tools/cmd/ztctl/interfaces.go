@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/interfaces"
+)
+
+// snapshotInterfaces writes the snapshot of the module interfaces to out,
+// by default the committed one, and prints how it differs from the snapshot
+// it replaces
+func snapshotInterfaces(config deploy.Config, out string, stdout io.Writer, logger *slog.Logger) int {
+	if out == "" {
+		out = filepath.Join(config.Root, filepath.FromSlash(interfaces.SnapshotPath))
+	}
+	current, err := interfaces.Take(config.Root)
+	if err != nil {
+		logger.Error("cannot read modules", "error", err)
+		return exitFailed
+	}
+	uses, err := interfaces.LoadUses(config.Root)
+	if err != nil {
+		logger.Error("cannot read envs", "error", err)
+		return exitFailed
+	}
+
+	old, err := interfaces.Read(out)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		old = &interfaces.Snapshot{Modules: map[string]*interfaces.Interface{}}
+	case err != nil:
+		logger.Error("cannot read the previous snapshot", "error", err)
+		return exitFailed
+	}
+	changes := interfaces.Compare(old, current, uses)
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+
+	var buf bytes.Buffer
+	if err := current.Write(&buf); err != nil {
+		logger.Error("cannot encode the snapshot", "error", err)
+		return exitFailed
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		logger.Error("cannot write the snapshot", "error", err)
+		return exitFailed
+	}
+	logger.Info("snapshot written", "path", out, "changes", len(changes), "breaking", len(interfaces.Breaking(changes)))
+	return exitOK
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/endpoints"
+	"github.com/y3gi/zero-trust-aws/tools/escalation"
+	"github.com/y3gi/zero-trust-aws/tools/firewall"
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+	"github.com/y3gi/zero-trust-aws/tools/policies"
+	"github.com/y3gi/zero-trust-aws/tools/providers"
+	"github.com/y3gi/zero-trust-aws/tools/routes"
+	"github.com/y3gi/zero-trust-aws/tools/variables"
+)
+
+// analyzers are the static checks run by the lint command
+var analyzers = []struct {
+	name string
+	run  func(root string) ([]lint.Finding, error)
+}{
+	{"firewall", func(root string) ([]lint.Finding, error) {
+		config, err := firewall.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return firewall.Lint(config), nil
+	}},
+	{"routes", func(root string) ([]lint.Finding, error) {
+		network, err := routes.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return routes.Lint(network), nil
+	}},
+	{"endpoints", func(root string) ([]lint.Finding, error) {
+		config, err := endpoints.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return endpoints.Lint(config), nil
+	}},
+	{"policies", func(root string) ([]lint.Finding, error) {
+		documents, err := iam.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		catalog, err := iam.LoadCatalog()
+		if err != nil {
+			return nil, err
+		}
+		return policies.Lint(documents, catalog), nil
+	}},
+	{"escalation", func(root string) ([]lint.Finding, error) {
+		graph, err := escalation.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return escalation.Lint(graph), nil
+	}},
+	{"variables", func(root string) ([]lint.Finding, error) {
+		config, err := variables.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return variables.Lint(config), nil
+	}},
+	{"providers", func(root string) ([]lint.Finding, error) {
+		config, err := providers.Load(root)
+		if err != nil {
+			return nil, err
+		}
+		return providers.Lint(config), nil
+	}},
+}
+
+// lintConfig runs every analyzer on the modules under config.Root and prints
+// one finding per line
+func lintConfig(config deploy.Config, jsonPath string, stdout io.Writer, logger *slog.Logger) int {
+	var findings []lint.Finding
+	for _, analyzer := range analyzers {
+		found, err := analyzer.run(config.Root)
+		if err != nil {
+			logger.Error("analyzer failed", "analyzer", analyzer.name, "error", err)
+			return exitFailed
+		}
+		findings = append(findings, found...)
+	}
+	lint.Sort(findings)
+
+	for _, f := range findings {
+		fmt.Fprintln(stdout, f)
+	}
+	if jsonPath != "" {
+		data, err := json.MarshalIndent(map[string]interface{}{"findings": findings}, "", "  ")
+		if err == nil {
+			err = os.WriteFile(jsonPath, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			logger.Error("failed to write lint report", "error", err)
+			return exitFailed
+		}
+	}
+
+	errors, warnings := lint.Count(findings, lint.SeverityError), lint.Count(findings, lint.SeverityWarning)
+	if errors > 0 {
+		logger.Error("lint found errors", "errors", errors, "warnings", warnings)
+		return exitFindings
+	}
+	logger.Info("lint passed", "warnings", warnings)
+	return exitOK
+}
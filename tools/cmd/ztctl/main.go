@@ -0,0 +1,536 @@
+// Command ztctl deploys, plans, checks for drift, estimates the cost of and
+// destroys an environment's root modules in dependency order, collects
+// compliance evidence for them, lints their configuration, selects the tests
+// affected by a change, minimizes IAM policies from CloudTrail logs and
+// snapshots the interfaces of the modules.
+//
+// Usage:
+//
+//	ztctl deploy  [--env=dev] [--module=NAME] [--dry-run] [--auto-approve]
+//	ztctl plan    [--env=dev] [--module=NAME]
+//	ztctl drift   [--env=dev] [--module=NAME] [--json=PATH]
+//	ztctl cost    [--env=dev] [--plans=DIR] [--json=PATH]
+//	ztctl evidence [--env=dev] [--plans=DIR] [--unit-json=PATH] [--out=PATH] [--strict]
+//	ztctl impact  [--env=dev] [--base=origin/main | --diff=FILE|-] [--run=SUITE] [--json=PATH]
+//	ztctl lint    [--json=PATH]
+//	ztctl minimize --logs=DIR --role=NAME --policy=ADDRESS [--days=90] [--until=YYYY-MM-DD]
+//	ztctl interfaces [--out=PATH]
+//	ztctl destroy [--env=dev] [--module=NAME] [--dry-run] [--auto-approve | --force]
+//
+// Logs are written to stderr, as JSON when --log-format=json or when running
+// in GitHub Actions. Plans printed by --dry-run and plan, and the drift and
+// cost reports, go to stdout. In GitHub Actions the reports are also added to
+// the step summary.
+//
+// cost plans every module, or reads <module>.json plans (`terraform show
+// -json` output) from --plans, and prices them with tools/cost.
+//
+// evidence runs test/unit (or reads its `go test -json` output from
+// --unit-json), evaluates the plans in --plans and writes the evidence
+// archive built by tools/evidence. It needs neither terraform nor AWS.
+//
+// impact maps the files changed since --base, or listed by the diff in
+// --diff, to the integration tests and E2E profiles to run (tools/impact).
+// With --run=integration or --run=e2e it prints only the -run regular
+// expression of that suite:
+//
+//	go test -run "$(go run ./cmd/ztctl impact --run=integration)" ./...
+//
+// lint runs the static analyzers on modules/ and envs/ (tools/firewall,
+// tools/routes, tools/endpoints, tools/policies, tools/escalation,
+// tools/variables, tools/providers) and prints one "file:line: severity:
+// address: message [check]" line per finding.
+//
+// minimize reads the CloudTrail log files under --logs (the objects of the
+// trail bucket, downloaded) for the calls --role made in the --days before
+// --until, prints the actions and resources of --policy that no call used
+// and a diff of the .tf file with the minimized policy (tools/minimize).
+//
+// interfaces writes the inputs and outputs of every module under modules/
+// to --out, by default the snapshot tools/interfaces/snapshot.json that the
+// unit tests compare with the code, and prints the breaking and non-breaking
+// changes to the snapshot it replaces, with the envs/ consumers of the
+// breaking ones (tools/interfaces).
+//
+// Exit codes:
+//
+//	0  success
+//	1  terraform failed for a module
+//	2  invalid usage
+//	3  preflight failed (terraform missing, no AWS credentials, invalid envs/)
+//	4  cancelled at the confirmation prompt
+//	5  critical drift detected (takes precedence over 1)
+//	6  estimated monthly cost exceeds the budget
+//	7  lint found errors
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/cost"
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/drift"
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+const (
+	exitOK        = 0
+	exitFailed    = 1
+	exitUsage     = 2
+	exitPreflight = 3
+	exitCancelled = 4
+	exitDrift     = 5
+	exitBudget    = 6
+	exitFindings  = 7
+)
+
+const usage = `Usage: ztctl <%s> [flags]
+
+Deploys, plans, checks for drift, estimates the monthly cost of, collects
+compliance evidence for or destroys the root modules under envs/<env>,
+selects the tests affected by a change, lints modules/, minimizes its IAM
+policies from CloudTrail logs or snapshots the interfaces of its modules.
+
+Flags:
+`
+
+// clients creates the terraform and AWS clients; tests replace it with fakes
+type clients func(terraformBinary, region string) (deploy.Terraform, deploy.Cloud, error)
+
+func awsClients(terraformBinary, region string) (deploy.Terraform, deploy.Cloud, error) {
+	if _, err := exec.LookPath(terraformBinary); err != nil {
+		return nil, nil, fmt.Errorf("terraform is not installed: %w", err)
+	}
+	cloud, err := deploy.NewAWSCloud(region)
+	if err != nil {
+		return nil, nil, err
+	}
+	return deploy.ExecTerraform{Binary: terraformBinary}, cloud, nil
+}
+
+// invocation is a parsed command line and what the command writes to
+type invocation struct {
+	config          deploy.Config
+	terraformBinary string
+	force           bool
+	jsonReport      string
+	plansDir        string
+	snapshotOut     string
+	evidence        evidenceOptions
+	impact          impactOptions
+	minimize        minimizeOptions
+
+	stdin      io.Reader
+	stdout     io.Writer
+	logger     *slog.Logger
+	newClients clients
+}
+
+// command is a subcommand of ztctl: the flags it takes besides --root and
+// --log-format, and what it runs once they are parsed
+type command struct {
+	name  string
+	flags func(flags *flag.FlagSet, inv *invocation)
+	run   func(ctx context.Context, inv *invocation) int
+}
+
+var commands = []command{
+	{"deploy", func(flags *flag.FlagSet, inv *invocation) {
+		terraformFlags(flags, inv)
+		changeFlags(flags, inv)
+	}, func(ctx context.Context, inv *invocation) int {
+		return withRunner(inv, func(runner *deploy.Runner) int {
+			return exitCode(inv.logger, runner.Deploy(ctx))
+		})
+	}},
+	{"plan", terraformFlags, func(ctx context.Context, inv *invocation) int {
+		return withRunner(inv, func(runner *deploy.Runner) int {
+			return exitCode(inv.logger, runner.Plan(ctx))
+		})
+	}},
+	{"drift", func(flags *flag.FlagSet, inv *invocation) {
+		terraformFlags(flags, inv)
+		jsonFlag(flags, inv, "also write the report as JSON to this file")
+	}, checkDrift},
+	{"cost", func(flags *flag.FlagSet, inv *invocation) {
+		terraformFlags(flags, inv)
+		jsonFlag(flags, inv, "also write the report as JSON to this file")
+		flags.StringVar(&inv.plansDir, "plans", "", "read <module>.json plans from this directory instead of running terraform")
+	}, priceCost},
+	{"evidence", func(flags *flag.FlagSet, inv *invocation) {
+		envFlag(flags, inv)
+		flags.StringVar(&inv.evidence.plansDir, "plans", "", "evaluate plan-level rules on the <module>.json plans in this directory")
+		flags.StringVar(&inv.evidence.unitJSON, "unit-json", "", "read test/unit results from this `go test -json` output instead of running them")
+		flags.StringVar(&inv.evidence.out, "out", "", "archive to write (default: reports/evidence-<env>-<commit>.tar.gz)")
+		flags.BoolVar(&inv.evidence.strict, "strict", false, "exit 1 when a control has a failing rule")
+	}, func(ctx context.Context, inv *invocation) int {
+		return buildEvidence(ctx, inv.config, inv.evidence, inv.stdout, inv.logger)
+	}},
+	{"impact", func(flags *flag.FlagSet, inv *invocation) {
+		envFlag(flags, inv)
+		flags.StringVar(&inv.impact.base, "base", "origin/main", "compare against the merge base with this git revision")
+		flags.StringVar(&inv.impact.diff, "diff", "", "read the change from this `git diff` or `git diff --name-only` output instead, - for stdin")
+		flags.StringVar(&inv.impact.run, "run", "", "only print the -run regular expression of this suite: integration or e2e")
+		flags.StringVar(&inv.impact.json, "json", "", "also write the selection as JSON to this file")
+	}, func(ctx context.Context, inv *invocation) int {
+		return selectTests(ctx, inv.config, inv.impact, inv.stdin, inv.stdout, inv.logger)
+	}},
+	{"lint", func(flags *flag.FlagSet, inv *invocation) {
+		jsonFlag(flags, inv, "also write the findings as JSON to this file")
+	}, func(_ context.Context, inv *invocation) int {
+		return lintConfig(inv.config, inv.jsonReport, inv.stdout, inv.logger)
+	}},
+	{"minimize", func(flags *flag.FlagSet, inv *invocation) {
+		flags.StringVar(&inv.minimize.logs, "logs", "", "directory of CloudTrail log files (*.json.gz)")
+		flags.StringVar(&inv.minimize.role, "role", "", "name or ARN of the role whose calls to read")
+		flags.StringVar(&inv.minimize.policy, "policy", "", "address of the policy to minimize, e.g. aws_iam_role_policy.app_secrets_policy")
+		flags.IntVar(&inv.minimize.days, "days", 90, "length of the window in days")
+		flags.StringVar(&inv.minimize.until, "until", "", "last day of the window, YYYY-MM-DD (default: today)")
+	}, func(_ context.Context, inv *invocation) int {
+		return minimizePolicy(inv.config, inv.minimize, inv.stdout, inv.logger)
+	}},
+	{"interfaces", func(flags *flag.FlagSet, inv *invocation) {
+		flags.StringVar(&inv.snapshotOut, "out", "", "snapshot to write (default: tools/interfaces/snapshot.json under the root)")
+	}, func(_ context.Context, inv *invocation) int {
+		return snapshotInterfaces(inv.config, inv.snapshotOut, inv.stdout, inv.logger)
+	}},
+	{"destroy", func(flags *flag.FlagSet, inv *invocation) {
+		terraformFlags(flags, inv)
+		changeFlags(flags, inv)
+		flags.BoolVar(&inv.force, "force", false, "alias for --auto-approve")
+	}, func(ctx context.Context, inv *invocation) int {
+		inv.config.AutoApprove = inv.config.AutoApprove || inv.force
+		return withRunner(inv, func(runner *deploy.Runner) int {
+			return exitCode(inv.logger, runner.Destroy(ctx))
+		})
+	}},
+}
+
+// envFlag adds --env to the commands that read an environment's root modules
+func envFlag(flags *flag.FlagSet, inv *invocation) {
+	flags.StringVar(&inv.config.Env, "env", "dev", "environment under envs/")
+}
+
+// terraformFlags adds the flags of the commands that run terraform on the
+// modules of an environment
+func terraformFlags(flags *flag.FlagSet, inv *invocation) {
+	envFlag(flags, inv)
+	flags.StringVar(&inv.config.Region, "region", "eu-north-1", "AWS region of the state bucket")
+	flags.StringVar(&inv.config.Module, "module", "", "only operate on this module")
+	flags.StringVar(&inv.terraformBinary, "terraform", "terraform", "terraform binary")
+}
+
+// changeFlags adds the flags of the commands that change infrastructure
+func changeFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.BoolVar(&inv.config.DryRun, "dry-run", false, "show what would change without changing anything")
+	flags.BoolVar(&inv.config.AutoApprove, "auto-approve", false, "skip the confirmation prompt")
+}
+
+func jsonFlag(flags *flag.FlagSet, inv *invocation, usage string) {
+	flags.StringVar(&inv.jsonReport, "json", "", usage)
+}
+
+func commandNames() string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	return strings.Join(names, "|")
+}
+
+func lookup(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, awsClients))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, newClients clients) int {
+	var cmd *command
+	if len(args) > 0 {
+		cmd = lookup(args[0])
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, usage, commandNames())
+		fmt.Fprintln(stderr, "  run 'ztctl <command> -h' for the flags of a command")
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet("ztctl "+cmd.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, commandNames())
+		flags.PrintDefaults()
+	}
+
+	inv := &invocation{
+		config:     deploy.Config{Stdin: stdin, Stdout: stdout},
+		stdin:      stdin,
+		stdout:     stdout,
+		newClients: newClients,
+	}
+	var logFormat string
+
+	defaultLogFormat := "text"
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		defaultLogFormat = "json"
+	}
+
+	flags.StringVar(&inv.config.Root, "root", "", "repository root containing envs/ (default: nearest parent of the working directory with an envs/ directory)")
+	flags.StringVar(&logFormat, "log-format", defaultLogFormat, "log format: text or json")
+	cmd.flags(flags, inv)
+
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %v\n", flags.Args())
+		return exitUsage
+	}
+
+	var handler slog.Handler
+	switch logFormat {
+	case "json":
+		handler = slog.NewJSONHandler(stderr, nil)
+	case "text":
+		handler = slog.NewTextHandler(stderr, nil)
+	default:
+		fmt.Fprintf(stderr, "invalid --log-format %q: expected text or json\n", logFormat)
+		return exitUsage
+	}
+	inv.logger = slog.New(handler).With("command", cmd.name)
+
+	if inv.config.Root == "" {
+		root, err := findRoot()
+		if err != nil {
+			inv.logger.Error("cannot find repository root", "error", err)
+			return exitPreflight
+		}
+		inv.config.Root = root
+	}
+	return cmd.run(ctx, inv)
+}
+
+// withRunner creates the terraform and AWS clients and runs a command with
+// the runner of the environment
+func withRunner(inv *invocation, run func(runner *deploy.Runner) int) int {
+	terraform, cloud, err := inv.newClients(inv.terraformBinary, inv.config.Region)
+	if err != nil {
+		inv.logger.Error("preflight failed", "error", err)
+		return exitPreflight
+	}
+	runner, err := deploy.New(inv.config, terraform, cloud, inv.logger)
+	if err != nil {
+		return exitCode(inv.logger, err)
+	}
+	return run(runner)
+}
+
+// checkDrift reports the drift of the environment and fails on critical drift
+func checkDrift(ctx context.Context, inv *invocation) int {
+	return withRunner(inv, func(runner *deploy.Runner) int {
+		report, err := runner.Drift(ctx)
+		if writeErr := writeDriftReport(report, inv.jsonReport, inv.stdout); writeErr != nil {
+			inv.logger.Error("failed to write drift report", "error", writeErr)
+			return exitFailed
+		}
+		if report.HasCritical() {
+			exitCode(inv.logger, err)
+			inv.logger.Error("critical drift detected", "critical", report.Count(drift.SeverityCritical))
+			return exitDrift
+		}
+		return exitCode(inv.logger, err)
+	})
+}
+
+// priceCost estimates the cost of the plans in --plans, or of the plans
+// terraform makes when there is no --plans
+func priceCost(ctx context.Context, inv *invocation) int {
+	if inv.plansDir != "" {
+		plans, err := readPlans(inv.config, inv.plansDir, inv.logger)
+		if err != nil {
+			inv.logger.Error("cannot read plans", "error", err)
+			return exitUsage
+		}
+		return estimateCost(inv.config, plans, inv.jsonReport, inv.stdout, inv.logger)
+	}
+	return withRunner(inv, func(runner *deploy.Runner) int {
+		plans, err := runner.PlanJSON(ctx)
+		if err != nil {
+			return exitCode(inv.logger, err)
+		}
+		return estimateCost(inv.config, plans, inv.jsonReport, inv.stdout, inv.logger)
+	})
+}
+
+// writeDriftReport prints the Markdown report to stdout, appends it to the
+// GitHub Actions step summary and writes the JSON report when requested
+func writeDriftReport(report drift.Report, jsonPath string, stdout io.Writer) error {
+	if err := writeSummary(report.Markdown(), stdout); err != nil {
+		return err
+	}
+	if jsonPath != "" {
+		return report.WriteJSON(jsonPath)
+	}
+	return nil
+}
+
+// readPlans reads <module>.json for every module of the environment, in
+// deployment order; modules without a file are skipped with a warning
+func readPlans(config deploy.Config, dir string, logger *slog.Logger) ([]deploy.ModulePlan, error) {
+	s, err := stack.Load(filepath.Join(config.Root, "envs", config.Env))
+	if err != nil {
+		return nil, err
+	}
+	modules, err := s.Order()
+	if err != nil {
+		return nil, err
+	}
+	if config.Module != "" {
+		if _, ok := s.Modules[config.Module]; !ok {
+			return nil, fmt.Errorf("unknown module %q", config.Module)
+		}
+		modules = []string{config.Module}
+	}
+
+	var plans []deploy.ModulePlan
+	for _, module := range modules {
+		data, err := os.ReadFile(filepath.Join(dir, module+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Warn("no plan for module, not included in the estimate", "module", module)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, deploy.ModulePlan{Module: module, JSON: data})
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("no <module>.json plans in %s", dir)
+	}
+	return plans, nil
+}
+
+// estimateCost prices the plans, prints the estimate and fails when it
+// exceeds the limit of aws_budgets_budget.monthly
+func estimateCost(config deploy.Config, plans []deploy.ModulePlan, jsonPath string, stdout io.Writer, logger *slog.Logger) int {
+	prices, err := cost.LoadPrices(config.Region)
+	if err != nil {
+		logger.Error("cannot load prices", "error", err)
+		return exitUsage
+	}
+	budget, err := cost.BudgetLimit(config.Root, config.Env)
+	if err != nil {
+		logger.Error("cannot read the budget limit", "error", err)
+		return exitFailed
+	}
+
+	estimate := cost.Estimate{Env: config.Env, Region: prices.Region, Currency: prices.Currency, PriceVersion: prices.Version, Budget: budget}
+	for _, plan := range plans {
+		module, err := cost.EstimatePlan(plan.Module, plan.JSON, prices)
+		if err != nil {
+			logger.Error("cannot estimate module", "module", plan.Module, "error", err)
+			return exitFailed
+		}
+		estimate.Add(module)
+	}
+
+	if err := writeSummary(estimate.Markdown(), stdout); err != nil {
+		logger.Error("failed to write cost report", "error", err)
+		return exitFailed
+	}
+	if jsonPath != "" {
+		if err := estimate.WriteJSON(jsonPath); err != nil {
+			logger.Error("failed to write cost report", "error", err)
+			return exitFailed
+		}
+	}
+
+	logger.Info("cost estimate complete", "total", estimate.Total, "budget", budget, "price_version", prices.Version)
+	if estimate.OverBudget() {
+		logger.Error("estimated monthly cost exceeds the budget", "total", estimate.Total, "budget", budget)
+		return exitBudget
+	}
+	return exitOK
+}
+
+// writeSummary prints a Markdown report to stdout and appends it to the
+// GitHub Actions step summary
+func writeSummary(summary string, stdout io.Writer) error {
+	fmt.Fprint(stdout, summary)
+
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := fmt.Fprintln(f, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exitCode logs err and maps it to the documented exit codes
+func exitCode(logger *slog.Logger, err error) int {
+	var usageErr *deploy.UsageError
+	var moduleErr *deploy.ModuleError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, deploy.ErrCancelled):
+		logger.Warn("cancelled")
+		return exitCancelled
+	case errors.As(err, &usageErr):
+		logger.Error("invalid usage", "error", err)
+		return exitUsage
+	case errors.As(err, &moduleErr):
+		logger.Error("failed", "module", moduleErr.Module, "op", moduleErr.Op, "error", moduleErr.Err)
+		return exitFailed
+	default:
+		logger.Error("preflight failed", "error", err)
+		return exitPreflight
+	}
+}
+
+// findRoot walks up from the working directory to the first directory that
+// contains envs/, so ztctl works from the repository root and from scripts/
+func findRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "envs")); err == nil && info.IsDir() {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no envs/ directory in the working directory or its parents")
+		}
+		dir = parent
+	}
+}
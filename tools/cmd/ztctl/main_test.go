@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/deploy/deploytest"
+)
+
+// newRoot writes a minimal envs/dev so tests never touch the real one
+func newRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, backend := range map[string]string{"bootstrap": "local", "vpc": "s3"} {
+		dir := filepath.Join(root, "envs", "dev", name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		content := "terraform {\n    backend \"" + backend + "\" {}\n}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0o644))
+	}
+	return root
+}
+
+func fakeClients(terraform *deploytest.Terraform, cloud *deploytest.Cloud) clients {
+	return func(string, string) (deploy.Terraform, deploy.Cloud, error) {
+		return terraform, cloud, nil
+	}
+}
+
+func runZtctl(t *testing.T, newClients clients, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr, newClients)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsageErrors(t *testing.T) {
+	t.Parallel()
+
+	clients := fakeClients(&deploytest.Terraform{}, deploytest.NewCloud("123456789012"))
+
+	code, _, stderr := runZtctl(t, clients)
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "Usage: ztctl <deploy|plan|drift|cost|evidence|impact|lint|minimize|interfaces|destroy>")
+
+	code, _, _ = runZtctl(t, clients, "apply")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runZtctl(t, clients, "plan", "--dry-run")
+	assert.Equal(t, exitUsage, code, "plan never changes anything, so it has no --dry-run")
+
+	for _, args := range [][]string{{"lint", "--env=prod"}, {"minimize", "--module=vpc"}, {"interfaces", "--region=us-east-1"}} {
+		code, _, stderr = runZtctl(t, clients, args...)
+		assert.Equal(t, exitUsage, code, "%s reads no environment", args[0])
+		assert.Contains(t, stderr, "flag provided but not defined")
+	}
+
+	code, _, stderr = runZtctl(t, clients, "deploy", "--root="+newRoot(t), "--module=network", "--log-format=json")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `unknown module \"network\"`)
+}
+
+func TestPreflightFailures(t *testing.T) {
+	t.Parallel()
+
+	failing := func(string, string) (deploy.Terraform, deploy.Cloud, error) {
+		return nil, nil, errors.New("terraform is not installed")
+	}
+	code, _, _ := runZtctl(t, failing, "deploy", "--root="+newRoot(t))
+	assert.Equal(t, exitPreflight, code)
+
+	code, _, stderr := runZtctl(t, fakeClients(&deploytest.Terraform{}, deploytest.NewCloud("")), "plan", "--root="+newRoot(t))
+	assert.Equal(t, exitPreflight, code)
+	assert.Contains(t, stderr, "AWS credentials not configured")
+}
+
+func TestDryRunEmitsJSONLogs(t *testing.T) {
+	t.Parallel()
+
+	terraform := &deploytest.Terraform{}
+	code, stdout, stderr := runZtctl(t, fakeClients(terraform, deploytest.NewCloud("123456789012")),
+		"deploy", "--root="+newRoot(t), "--module=vpc", "--dry-run", "--log-format=json")
+
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "Plan for vpc\n", stdout)
+	assert.Empty(t, terraform.Modules("apply"))
+
+	for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		assert.Equal(t, "deploy", record["command"])
+	}
+	assert.Contains(t, stderr, `"module":"vpc"`)
+}
+
+func TestModuleFailureExitCode(t *testing.T) {
+	t.Parallel()
+
+	terraform := &deploytest.Terraform{Fail: map[string]error{"vpc:plan": errors.New("Error: No valid credential sources found")}}
+	code, _, stderr := runZtctl(t, fakeClients(terraform, deploytest.NewCloud("123456789012")),
+		"destroy", "--root="+newRoot(t), "--module=vpc", "--dry-run")
+
+	assert.Equal(t, exitOK, code, "vpc has no state in S3, so nothing is planned: %s", stderr)
+
+	code, _, _ = runZtctl(t, fakeClients(terraform, deploytest.NewCloud("123456789012")),
+		"deploy", "--root="+newRoot(t), "--module=vpc", "--dry-run")
+	assert.Equal(t, exitFailed, code)
+}
+
+func TestDriftExitCodes(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+
+	cloud := deploytest.NewCloud("123456789012")
+	cloud.CreateBucket("dev-terraform-state-a1b2c3")
+	cloud.Objects["dev-terraform-state-a1b2c3"]["dev/vpc/terraform.tfstate"] = []byte(`{"version":4,"resources":[{"type":"aws_vpc"}]}`)
+
+	code, stdout, stderr := runZtctl(t, fakeClients(&deploytest.Terraform{}, cloud), "drift", "--root="+newRoot(t))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "No drift detected.")
+
+	terraform := &deploytest.Terraform{RefreshPlans: map[string]string{"vpc": `{"resource_drift":[{"address":"aws_route_table.private_rt",
+		"mode":"managed","type":"aws_route_table","change":{"actions":["update"],
+		"before":{"route":[{"nat_gateway_id":"nat-1"}]},"after":{"route":[{"gateway_id":"igw-1"}]}}}]}`}}
+	report := filepath.Join(t.TempDir(), "drift.json")
+	code, stdout, _ = runZtctl(t, fakeClients(terraform, cloud), "drift", "--root="+newRoot(t), "--json="+report)
+	assert.Equal(t, exitDrift, code)
+	assert.Contains(t, stdout, "**2 critical**")
+	assert.FileExists(t, report)
+
+	written, err := os.ReadFile(summary)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "## Drift report: dev")
+}
+
+func TestCostAgainstBudget(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	root := newRoot(t)
+	monitoring := filepath.Join(root, "modules", "monitoring")
+	require.NoError(t, os.MkdirAll(monitoring, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(monitoring, "budget.tf"),
+		[]byte("resource \"aws_budgets_budget\" \"monthly\" {\n  limit_amount = \"50\"\n}\n"), 0o644))
+
+	plans := t.TempDir()
+	nat := `{"resource_changes":[{"address":"aws_nat_gateway.nat_gtw","mode":"managed","type":"aws_nat_gateway","change":{"actions":["create"],"after":{}}}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(plans, "vpc.json"), []byte(nat), 0o644))
+
+	failing := func(string, string) (deploy.Terraform, deploy.Cloud, error) {
+		return nil, nil, errors.New("reading plans from a directory needs neither terraform nor AWS")
+	}
+	code, stdout, stderr := runZtctl(t, failing, "cost", "--root="+root, "--plans="+plans)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "| vpc | $33.58 |")
+	assert.Contains(t, stderr, "no plan for module", "bootstrap has no plan file")
+
+	require.NoError(t, os.WriteFile(filepath.Join(plans, "bootstrap.json"), []byte(strings.ReplaceAll(nat, "nat_gtw", "second")), 0o644))
+	report := filepath.Join(t.TempDir(), "cost.json")
+	code, stdout, _ = runZtctl(t, failing, "cost", "--root="+root, "--plans="+plans, "--json="+report)
+	assert.Equal(t, exitBudget, code)
+	assert.Contains(t, stdout, "Over budget: $67.16")
+	assert.FileExists(t, report)
+
+	code, _, _ = runZtctl(t, fakeClients(&deploytest.Terraform{Plans: map[string]string{"vpc": nat}}, deploytest.NewCloud("123456789012")),
+		"cost", "--root="+root)
+	assert.Equal(t, exitOK, code, "plans run through terraform are priced the same way")
+}
+
+func TestEvidenceArchive(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	root := newRoot(t)
+	security := filepath.Join(root, "modules", "security")
+	require.NoError(t, os.MkdirAll(security, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(security, "kms.tf"),
+		[]byte("resource \"aws_kms_key\" \"main\" {\n  enable_key_rotation = true\n}\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=ztctl", "-c", "user.email=ztctl@example.com", "commit", "--quiet", "-m", "fixture"},
+	} {
+		require.NoError(t, exec.Command("git", append([]string{"-C", root}, args...)...).Run(), args)
+	}
+
+	unit := filepath.Join(t.TempDir(), "unit.json")
+	require.NoError(t, os.WriteFile(unit, []byte(`{"Action":"pass","Test":"TestKMSKeyRotation"}
+{"Action":"fail","Test":"TestNoWildcardActions"}
+`), 0o644))
+
+	failing := func(string, string) (deploy.Terraform, deploy.Cloud, error) {
+		return nil, nil, errors.New("collecting evidence needs neither terraform nor AWS")
+	}
+	out := filepath.Join(t.TempDir(), "evidence.tar.gz")
+	code, stdout, stderr := runZtctl(t, failing, "evidence", "--root="+root, "--unit-json="+unit, "--out="+out)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "## encryption-at-rest: Encryption at rest with customer managed KMS keys\n\nStatus: **pass**")
+	assert.Contains(t, stderr, "evidence written")
+	assert.Contains(t, stderr, "no --plans given")
+	assert.Contains(t, stderr, "least-privilege-iam", "failing controls are reported")
+	assert.FileExists(t, out)
+
+	code, _, _ = runZtctl(t, failing, "evidence", "--root="+root, "--unit-json="+unit, "--out="+out, "--strict")
+	assert.Equal(t, exitFailed, code)
+}
+
+func TestImpactSelectsTests(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"impact", "--root=../../..", "--run=integration", "--diff=-"},
+		strings.NewReader("modules/certificates/main.tf\nREADME.md\n"), &stdout, &stderr, nil)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "^(TestCertificatesModule)$\n", stdout.String())
+	assert.Contains(t, stderr.String(), "README.md", "ignored files are logged")
+
+	diff := filepath.Join(t.TempDir(), "change.diff")
+	require.NoError(t, os.WriteFile(diff, []byte("diff --git a/envs/dev/vpc/main.tf b/envs/dev/vpc/main.tf\n--- a/envs/dev/vpc/main.tf\n+++ b/envs/dev/vpc/main.tf\n"), 0o644))
+	report := filepath.Join(t.TempDir(), "impact.json")
+	code, out, _ := runZtctl(t, nil, "impact", "--root=../../..", "--diff="+diff, "--json="+report)
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, out, "Affected modules: compute, firewall, monitoring, vpc, vpc-endpoints")
+	assert.Contains(t, out, "TestE2ECriticalPath")
+	assert.FileExists(t, report)
+
+	code, _, _ = runZtctl(t, nil, "impact", "--root=../../..", "--diff="+diff, "--run=unit")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestMinimizeExitCodes(t *testing.T) {
+	t.Parallel()
+
+	logs := t.TempDir()
+	code, _, stderr := runZtctl(t, nil, "minimize", "--root=../../..", "--logs="+logs, "--role=dev-ZT-App-Role")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "--policy")
+
+	code, _, _ = runZtctl(t, nil, "minimize", "--root=../../..", "--logs="+logs, "--role=dev-ZT-App-Role", "--policy=aws_iam_role_policy.missing")
+	assert.Equal(t, exitUsage, code)
+
+	code, stdout, stderr := runZtctl(t, nil, "minimize", "--root=../../..", "--logs="+logs, "--role=dev-ZT-App-Role", "--policy=aws_iam_role_policy.app_secrets_policy")
+	assert.Equal(t, exitFailed, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "refusing to propose an empty policy")
+}
+
+func TestLintExitCodes(t *testing.T) {
+	t.Parallel()
+
+	root := newRoot(t)
+	code, stdout, stderr := runZtctl(t, nil, "lint", "--root="+root)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, stdout)
+
+	firewall := filepath.Join(root, "modules", "firewall")
+	require.NoError(t, os.MkdirAll(firewall, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(firewall, "main.tf"), []byte(`resource "aws_networkfirewall_firewall_policy" "main" {
+  firewall_policy {
+    stateless_default_actions = ["aws:pass"]
+  }
+}
+`), 0o644))
+	report := filepath.Join(t.TempDir(), "lint.json")
+	code, stdout, _ = runZtctl(t, nil, "lint", "--root="+root, "--json="+report)
+	assert.Equal(t, exitFindings, code)
+	assert.Contains(t, stdout, "modules/firewall/main.tf:1: error: aws_networkfirewall_firewall_policy.main: stateless_default_actions contain aws:pass")
+	assert.FileExists(t, report)
+}
+
+func TestInterfacesSnapshot(t *testing.T) {
+	t.Parallel()
+
+	out := filepath.Join(t.TempDir(), "snapshot.json")
+	code, stdout, stderr := runZtctl(t, nil, "interfaces", "--root=../../..", "--out="+out)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "non-breaking: modules/security added\n")
+	assert.FileExists(t, out)
+
+	code, stdout, _ = runZtctl(t, nil, "interfaces", "--root=../../..", "--out="+out)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, stdout)
+
+	committed, err := os.ReadFile("../../interfaces/snapshot.json")
+	require.NoError(t, err)
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(written))
+}
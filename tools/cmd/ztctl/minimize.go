@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/minimize"
+)
+
+// minimizeOptions are the flags of the minimize command
+type minimizeOptions struct {
+	logs   string
+	role   string
+	policy string
+	days   int
+	until  string
+}
+
+// minimizePolicy proposes a least-privilege version of a policy from the
+// calls a role made over a window of CloudTrail logs
+func minimizePolicy(config deploy.Config, opts minimizeOptions, stdout io.Writer, logger *slog.Logger) int {
+	if opts.logs == "" || opts.role == "" || opts.policy == "" {
+		logger.Error("--logs, --role and --policy are required")
+		return exitUsage
+	}
+	until := time.Now().UTC()
+	if opts.until != "" {
+		t, err := time.Parse(time.DateOnly, opts.until)
+		if err != nil {
+			logger.Error("invalid --until, expected YYYY-MM-DD", "error", err)
+			return exitUsage
+		}
+		until = t.AddDate(0, 0, 1)
+	}
+	since := until.AddDate(0, 0, -opts.days)
+
+	policies, err := iam.Load(config.Root)
+	if err != nil {
+		logger.Error("cannot load policies", "error", err)
+		return exitFailed
+	}
+	var policy *iam.Policy
+	for _, p := range policies {
+		if p.Address == opts.policy && p.Kind == iam.KindIdentity {
+			policy = p
+		}
+	}
+	if policy == nil {
+		logger.Error("no identity policy with this address under modules/", "policy", opts.policy)
+		return exitUsage
+	}
+
+	events, err := minimize.ReadLogs(opts.logs, opts.role, since, until)
+	if err != nil {
+		logger.Error("cannot read CloudTrail logs", "error", err)
+		return exitFailed
+	}
+	if len(events) == 0 {
+		logger.Error("no calls by the role in the window, refusing to propose an empty policy",
+			"role", opts.role, "since", since.Format(time.DateOnly), "until", until.Format(time.DateOnly))
+		return exitFailed
+	}
+
+	result := minimize.Minimize(policy, events)
+	diff, err := result.Diff(config.Root)
+	if err != nil {
+		logger.Error("cannot propose a minimized policy", "error", err)
+		return exitFailed
+	}
+	result.Text(stdout)
+	if diff != "" {
+		fmt.Fprintf(stdout, "\n%s", diff)
+	}
+	logger.Info("minimize complete", "role", opts.role, "calls", len(events), "unused_statements", len(result.Unused))
+	return exitOK
+}
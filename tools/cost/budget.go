@@ -0,0 +1,74 @@
+package cost
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// BudgetLimit returns the monthly limit of aws_budgets_budget.monthly in
+// modules/monitoring. When limit_amount is a variable, the value passed by
+// envs/<env>/monitoring wins over the variable's default.
+func BudgetLimit(root, env string) (float64, error) {
+	monitoring, err := tfconfig.LoadDir(filepath.Join(root, "modules", "monitoring"))
+	if err != nil {
+		return 0, err
+	}
+
+	var budget *tfconfig.Block
+	for _, b := range monitoring.BlocksOfType("resource", "aws_budgets_budget") {
+		if b.Name() == "monthly" {
+			budget = b
+		}
+	}
+	if budget == nil {
+		return 0, fmt.Errorf("modules/monitoring has no aws_budgets_budget.monthly")
+	}
+
+	value, _ := budget.Attr("limit_amount")
+	if !tfconfig.IsExpression(value) {
+		return amount(value, budget.Position())
+	}
+
+	variable := strings.TrimSuffix(strings.TrimPrefix(value.(string), "${var."), "}")
+	if variable == value.(string) {
+		return 0, fmt.Errorf("%s: cannot resolve limit_amount = %s", budget.Position(), value)
+	}
+
+	caller, err := tfconfig.LoadDir(filepath.Join(root, "envs", env, "monitoring"))
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range caller.BlocksOfType("module") {
+		if filepath.Base(m.String("source")) != "monitoring" || !m.Has(variable) {
+			continue
+		}
+		v, _ := m.Attr(variable)
+		return amount(v, m.Position())
+	}
+
+	for _, v := range monitoring.BlocksOfType("variable", variable) {
+		if v.Has("default") {
+			d, _ := v.Attr("default")
+			return amount(d, v.Position())
+		}
+	}
+	return 0, fmt.Errorf("%s: var.%s has no value in envs/%s/monitoring and no default", budget.Position(), variable, env)
+}
+
+// amount converts a literal limit, which may be a number or a string such as
+// "100.0", to a float
+func amount(value interface{}, position string) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: limit_amount %v is not a number", position, value)
+}
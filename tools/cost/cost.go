@@ -0,0 +1,307 @@
+// Package cost estimates the monthly cost of an environment from the plans
+// of its root modules and checks it against the monthly budget.
+//
+// Prices come from a checked-in table per region (prices/<region>.json)
+// whose version is part of every estimate. Only fixed charges are priced:
+// hourly resources (NAT gateway, Network Firewall and interface endpoints,
+// instances, public IPv4 addresses) and monthly ones (ACM private CA, KMS
+// keys, secrets, alarms). Usage-based resources such as S3 buckets and log
+// groups are listed but count as zero.
+package cost
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+)
+
+//go:embed prices/*.json
+var priceFiles embed.FS
+
+// Prices is a versioned price table for one region
+type Prices struct {
+	Version       string             `json:"version"`
+	Region        string             `json:"region"`
+	Currency      string             `json:"currency"`
+	HoursPerMonth float64            `json:"hours_per_month"`
+	Source        string             `json:"source"`
+	Hourly        map[string]float64 `json:"hourly"`
+	Monthly       map[string]float64 `json:"monthly"`
+	// InstanceHourly is the on-demand Linux price per instance type
+	InstanceHourly map[string]float64 `json:"instance_hourly"`
+	// EBSGBMonthly is the price per GB-month per volume type
+	EBSGBMonthly map[string]float64 `json:"ebs_gb_monthly"`
+}
+
+// LoadPrices returns the checked-in price table for region
+func LoadPrices(region string) (*Prices, error) {
+	data, err := priceFiles.ReadFile("prices/" + region + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no price table for region %s: %w", region, err)
+	}
+	return ParsePrices(data)
+}
+
+// ParsePrices parses a price table
+func ParsePrices(data []byte) (*Prices, error) {
+	var p Prices
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid price table: %w", err)
+	}
+	if p.Version == "" || p.HoursPerMonth <= 0 {
+		return nil, fmt.Errorf("invalid price table: version and hours_per_month are required")
+	}
+	return &p, nil
+}
+
+func (p *Prices) hourly(key string) (float64, error) {
+	price, ok := p.Hourly[key]
+	if !ok {
+		return 0, fmt.Errorf("price table %s has no hourly price %q", p.Version, key)
+	}
+	return price * p.HoursPerMonth, nil
+}
+
+func (p *Prices) monthly(key string) (float64, error) {
+	price, ok := p.Monthly[key]
+	if !ok {
+		return 0, fmt.Errorf("price table %s has no monthly price %q", p.Version, key)
+	}
+	return price, nil
+}
+
+// Line is the estimated monthly cost of one resource
+type Line struct {
+	Address string  `json:"address"`
+	Type    string  `json:"type"`
+	Monthly float64 `json:"monthly"`
+	Detail  string  `json:"detail"`
+	// UsageBased resources are billed by usage, which a plan cannot tell
+	UsageBased bool `json:"usage_based,omitempty"`
+}
+
+// ModuleEstimate is the estimated monthly cost of one root module
+type ModuleEstimate struct {
+	Module  string  `json:"module"`
+	Monthly float64 `json:"monthly"`
+	Lines   []Line  `json:"lines"`
+}
+
+// Estimate is the estimated monthly cost of an environment
+type Estimate struct {
+	Env          string           `json:"env"`
+	Region       string           `json:"region"`
+	Currency     string           `json:"currency"`
+	PriceVersion string           `json:"price_version"`
+	Modules      []ModuleEstimate `json:"modules"`
+	Total        float64          `json:"total"`
+	// Budget is the monthly limit of aws_budgets_budget.monthly, 0 if unknown
+	Budget float64 `json:"budget"`
+}
+
+// OverBudget reports whether the estimate exceeds a known budget
+func (e Estimate) OverBudget() bool {
+	return e.Budget > 0 && e.Total > e.Budget
+}
+
+// Add appends a module estimate and updates the total
+func (e *Estimate) Add(m ModuleEstimate) {
+	e.Modules = append(e.Modules, m)
+	e.Total = round(e.Total + m.Monthly)
+}
+
+// plan is the subset of `terraform show -json` output that is priced
+type plan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Mode    string `json:"mode"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string               `json:"actions"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// EstimatePlan prices every managed resource that exists once the plan is
+// applied, i.e. everything in the plan except deletions
+func EstimatePlan(module string, planJSON []byte, prices *Prices) (ModuleEstimate, error) {
+	estimate := ModuleEstimate{Module: module, Lines: []Line{}}
+
+	var p plan
+	cleaned := bytes.TrimSpace(tfoutput.Clean(planJSON))
+	if err := json.Unmarshal(cleaned, &p); err != nil {
+		return estimate, &tfoutput.ParseError{Raw: string(planJSON), Err: err}
+	}
+
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode == "data" || rc.Change.After == nil {
+			continue
+		}
+		pricer, ok := pricers[rc.Type]
+		if !ok {
+			continue
+		}
+		monthly, detail, err := pricer(rc.Change.After, prices)
+		if err != nil {
+			return estimate, fmt.Errorf("%s: %w", rc.Address, err)
+		}
+		line := Line{Address: rc.Address, Type: rc.Type, Monthly: round(monthly), Detail: detail}
+		if detail == usageBased {
+			line.UsageBased = true
+		}
+		estimate.Lines = append(estimate.Lines, line)
+		estimate.Monthly = round(estimate.Monthly + line.Monthly)
+	}
+
+	sort.SliceStable(estimate.Lines, func(i, j int) bool { return estimate.Lines[i].Monthly > estimate.Lines[j].Monthly })
+	return estimate, nil
+}
+
+const usageBased = "usage-based, not estimated"
+
+// pricer returns the monthly cost of a resource from its planned values
+type pricer func(after map[string]interface{}, p *Prices) (float64, string, error)
+
+var pricers = map[string]pricer{
+	"aws_nat_gateway": func(_ map[string]interface{}, p *Prices) (float64, string, error) {
+		cost, err := p.hourly("nat_gateway")
+		return cost, "NAT gateway hours, data processing not included", err
+	},
+	"aws_eip": func(_ map[string]interface{}, p *Prices) (float64, string, error) {
+		cost, err := p.hourly("public_ipv4_address")
+		return cost, "public IPv4 address", err
+	},
+	"aws_instance":                     priceInstance,
+	"aws_vpc_endpoint":                 priceVPCEndpoint,
+	"aws_networkfirewall_firewall":     priceFirewall,
+	"aws_acmpca_certificate_authority": pricePrivateCA,
+	"aws_dynamodb_table":               priceDynamoDB,
+	"aws_kms_key":                      fixedMonthly("kms_key", "customer managed key"),
+	"aws_secretsmanager_secret":        fixedMonthly("secretsmanager_secret", "secret, API calls not included"),
+	"aws_cloudwatch_metric_alarm":      fixedMonthly("cloudwatch_alarm", "standard resolution alarm"),
+	"aws_s3_bucket":                    usage,
+	"aws_cloudwatch_log_group":         usage,
+	"aws_flow_log":                     usage,
+	"aws_cloudtrail":                   usage,
+}
+
+func fixedMonthly(key, detail string) pricer {
+	return func(_ map[string]interface{}, p *Prices) (float64, string, error) {
+		cost, err := p.monthly(key)
+		return cost, detail, err
+	}
+}
+
+func usage(map[string]interface{}, *Prices) (float64, string, error) {
+	return 0, usageBased, nil
+}
+
+func priceInstance(after map[string]interface{}, p *Prices) (float64, string, error) {
+	instanceType, _ := after["instance_type"].(string)
+	hourly, ok := p.InstanceHourly[instanceType]
+	if !ok {
+		return 0, "", fmt.Errorf("price table %s has no price for instance type %q", p.Version, instanceType)
+	}
+	cost := hourly * p.HoursPerMonth
+	detail := instanceType
+
+	size, volumeType := 8.0, "gp3"
+	if volumes, ok := after["root_block_device"].([]interface{}); ok && len(volumes) > 0 {
+		if volume, ok := volumes[0].(map[string]interface{}); ok {
+			if v, ok := volume["volume_size"].(float64); ok && v > 0 {
+				size = v
+			}
+			if v, ok := volume["volume_type"].(string); ok && v != "" {
+				volumeType = v
+			}
+		}
+	}
+	gbMonth, ok := p.EBSGBMonthly[volumeType]
+	if !ok {
+		return 0, "", fmt.Errorf("price table %s has no price for EBS volume type %q", p.Version, volumeType)
+	}
+	cost += size * gbMonth
+	detail += fmt.Sprintf(", %.0f GB %s root volume", size, volumeType)
+
+	if public, _ := after["associate_public_ip_address"].(bool); public {
+		ip, err := p.hourly("public_ipv4_address")
+		if err != nil {
+			return 0, "", err
+		}
+		cost += ip
+		detail += ", public IPv4 address"
+	}
+	return cost, detail, nil
+}
+
+func priceVPCEndpoint(after map[string]interface{}, p *Prices) (float64, string, error) {
+	endpointType, _ := after["vpc_endpoint_type"].(string)
+	if endpointType != "Interface" {
+		return 0, strings.ToLower(endpointType) + " endpoint, free", nil
+	}
+	azs, known := count(after, "subnet_ids")
+	perAZ, err := p.hourly("interface_endpoint_per_az")
+	detail := fmt.Sprintf("interface endpoint in %d AZ(s), data processing not included", azs)
+	if !known {
+		detail = "interface endpoint, subnets unknown until apply, assumed 1 AZ"
+	}
+	return float64(azs) * perAZ, detail, err
+}
+
+func priceFirewall(after map[string]interface{}, p *Prices) (float64, string, error) {
+	endpoints, known := count(after, "subnet_mapping")
+	perEndpoint, err := p.hourly("network_firewall_endpoint")
+	detail := fmt.Sprintf("%d firewall endpoint(s), traffic processing not included", endpoints)
+	if !known {
+		detail = "firewall endpoint, subnets unknown until apply, assumed 1"
+	}
+	return float64(endpoints) * perEndpoint, detail, err
+}
+
+func pricePrivateCA(after map[string]interface{}, p *Prices) (float64, string, error) {
+	mode, _ := after["usage_mode"].(string)
+	if mode == "SHORT_LIVED_CERTIFICATE" {
+		cost, err := p.monthly("acm_pca_short_lived_certificate")
+		return cost, "private CA, short-lived certificate mode", err
+	}
+	cost, err := p.monthly("acm_pca_general_purpose")
+	return cost, "private CA, general-purpose mode", err
+}
+
+func priceDynamoDB(after map[string]interface{}, p *Prices) (float64, string, error) {
+	if mode, _ := after["billing_mode"].(string); mode != "PROVISIONED" {
+		return 0, usageBased, nil
+	}
+	read, _ := after["read_capacity"].(float64)
+	write, _ := after["write_capacity"].(float64)
+	rcu, err := p.hourly("dynamodb_read_capacity_unit")
+	if err != nil {
+		return 0, "", err
+	}
+	wcu, err := p.hourly("dynamodb_write_capacity_unit")
+	if err != nil {
+		return 0, "", err
+	}
+	return read*rcu + write*wcu, fmt.Sprintf("provisioned %.0f RCU / %.0f WCU", read, write), nil
+}
+
+// count returns the length of a list attribute; unknown values (absent from
+// "after" until apply) count as 1
+func count(after map[string]interface{}, attribute string) (int, bool) {
+	list, ok := after[attribute].([]interface{})
+	if !ok {
+		return 1, false
+	}
+	return len(list), true
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,153 @@
+package cost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func estimateFixture(t *testing.T, module string) ModuleEstimate {
+	t.Helper()
+	prices, err := LoadPrices("eu-north-1")
+	require.NoError(t, err)
+
+	planJSON, err := os.ReadFile(filepath.Join("testdata", module+".json"))
+	require.NoError(t, err)
+	estimate, err := EstimatePlan(module, planJSON, prices)
+	require.NoError(t, err)
+	return estimate
+}
+
+func line(t *testing.T, m ModuleEstimate, address string) Line {
+	t.Helper()
+	for _, l := range m.Lines {
+		if l.Address == address {
+			return l
+		}
+	}
+	t.Fatalf("no line for %s in %+v", address, m.Lines)
+	return Line{}
+}
+
+func TestLoadPrices(t *testing.T) {
+	t.Parallel()
+
+	prices, err := LoadPrices("eu-north-1")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-north-1", prices.Region)
+	assert.NotEmpty(t, prices.Version)
+
+	_, err = LoadPrices("us-east-1")
+	assert.Error(t, err, "only checked-in regions are priced")
+
+	_, err = ParsePrices([]byte(`{"region":"eu-north-1"}`))
+	assert.Error(t, err, "a table without a version is rejected")
+}
+
+func TestEstimateHourlyResources(t *testing.T) {
+	t.Parallel()
+
+	vpc := estimateFixture(t, "vpc")
+	assert.Equal(t, 33.58, line(t, vpc, "module.vpc.aws_nat_gateway.nat_gtw").Monthly)
+	assert.Equal(t, 3.65, line(t, vpc, "module.vpc.aws_eip.nat").Monthly)
+	assert.Equal(t, 37.23, vpc.Monthly, "the deleted internet gateway and the free VPC add nothing")
+	assert.Len(t, vpc.Lines, 2)
+
+	firewall := estimateFixture(t, "firewall")
+	assert.Equal(t, 288.35, firewall.Monthly)
+	assert.Equal(t, "1 firewall endpoint(s), traffic processing not included", firewall.Lines[0].Detail)
+}
+
+func TestEstimateInterfaceEndpointsPerAZ(t *testing.T) {
+	t.Parallel()
+
+	endpoints := estimateFixture(t, "vpc-endpoints")
+	assert.Equal(t, 16.06, line(t, endpoints, "module.vpc_endpoints.aws_vpc_endpoint.secretsmanager").Monthly)
+	assert.Equal(t, 8.03, line(t, endpoints, "module.vpc_endpoints.aws_vpc_endpoint.ssm").Monthly, "unknown subnets count as one AZ")
+	assert.Equal(t, 0.0, line(t, endpoints, "module.vpc_endpoints.aws_vpc_endpoint.s3").Monthly)
+	assert.Equal(t, 24.09, endpoints.Monthly)
+}
+
+func TestEstimateInstancesWithVolumes(t *testing.T) {
+	t.Parallel()
+
+	compute := estimateFixture(t, "compute")
+	bastion := line(t, compute, "module.compute.aws_instance.bastion[0]")
+	assert.Equal(t, 12.2, bastion.Monthly)
+	assert.Equal(t, "t3.micro, 8 GB gp3 root volume, public IPv4 address", bastion.Detail)
+	assert.Equal(t, 17.44, line(t, compute, "module.compute.aws_instance.app_server[0]").Monthly)
+	assert.Equal(t, "module.compute.aws_instance.app_server[0]", compute.Lines[0].Address, "lines are sorted by cost")
+}
+
+func TestEstimateRejectsUnpricedInstanceType(t *testing.T) {
+	t.Parallel()
+
+	prices, err := LoadPrices("eu-north-1")
+	require.NoError(t, err)
+	_, err = EstimatePlan("compute", []byte(`{"resource_changes":[{"address":"aws_instance.gpu","mode":"managed",
+		"type":"aws_instance","change":{"actions":["create"],"after":{"instance_type":"p4d.24xlarge"}}}]}`), prices)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no price for instance type "p4d.24xlarge"`)
+}
+
+func TestEstimateAgainstBudget(t *testing.T) {
+	t.Parallel()
+
+	prices, err := LoadPrices("eu-north-1")
+	require.NoError(t, err)
+
+	estimate := Estimate{Env: "dev", Region: prices.Region, Currency: prices.Currency, PriceVersion: prices.Version, Budget: 100}
+	for _, module := range []string{"vpc", "firewall", "vpc-endpoints", "certificates", "compute"} {
+		estimate.Add(estimateFixture(t, module))
+	}
+
+	assert.Equal(t, 779.31, estimate.Total)
+	assert.True(t, estimate.OverBudget())
+
+	summary := estimate.Markdown()
+	assert.Contains(t, summary, "| certificates | $400.00 |")
+	assert.Contains(t, summary, "| **Total** | **$779.31** |")
+	assert.Contains(t, summary, "❌ Over budget: $779.31 exceeds the monthly limit of $100.00 by $679.31.")
+	assert.Contains(t, summary, "version "+prices.Version)
+
+	path := filepath.Join(t.TempDir(), "cost.json")
+	require.NoError(t, estimate.WriteJSON(path))
+	assert.FileExists(t, path)
+
+	estimate.Budget = 1000
+	assert.False(t, estimate.OverBudget())
+	assert.Contains(t, estimate.Markdown(), "✅ Within the monthly limit of $1000.00 (78% used).")
+}
+
+func TestBudgetLimitOfRepository(t *testing.T) {
+	t.Parallel()
+
+	limit, err := BudgetLimit("../..", "dev")
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, limit)
+}
+
+func TestBudgetLimitFallsBackToDefault(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0o644))
+	}
+	write("modules/monitoring/budget.tf", "resource \"aws_budgets_budget\" \"monthly\" {\n  limit_amount = var.limit_amount\n}\n")
+	write("modules/monitoring/variables.tf", "variable \"limit_amount\" {\n  default = \"250.0\"\n}\n")
+	write("envs/dev/monitoring/main.tf", "module \"monitoring\" {\n  source = \"../../../modules/monitoring\"\n}\n")
+
+	limit, err := BudgetLimit(root, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, 250.0, limit)
+
+	write("modules/monitoring/budget.tf", "resource \"aws_budgets_budget\" \"monthly\" {\n  limit_amount = \"75\"\n}\n")
+	limit, err = BudgetLimit(root, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, limit)
+}
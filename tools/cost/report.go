@@ -0,0 +1,62 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markdown renders the estimate as a per-module summary followed by the
+// priced resources of every module
+func (e Estimate) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Cost estimate: %s\n\n", e.Env)
+	fmt.Fprintf(&b, "Prices: %s, version %s. Fixed charges only; usage (data processing, requests, storage) is not included.\n\n", e.Region, e.PriceVersion)
+
+	b.WriteString("| Module | Monthly |\n|--------|---------|\n")
+	for _, m := range e.Modules {
+		fmt.Fprintf(&b, "| %s | %s |\n", m.Module, e.money(m.Monthly))
+	}
+	fmt.Fprintf(&b, "| **Total** | **%s** |\n", e.money(e.Total))
+
+	switch {
+	case e.Budget == 0:
+		b.WriteString("\nNo budget limit found.\n")
+	case e.OverBudget():
+		fmt.Fprintf(&b, "\n❌ Over budget: %s exceeds the monthly limit of %s by %s.\n", e.money(e.Total), e.money(e.Budget), e.money(e.Total-e.Budget))
+	default:
+		fmt.Fprintf(&b, "\n✅ Within the monthly limit of %s (%.0f%% used).\n", e.money(e.Budget), 100*e.Total/e.Budget)
+	}
+
+	for _, m := range e.Modules {
+		if len(m.Lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n| Resource | Monthly | Detail |\n|----------|---------|--------|\n", m.Module)
+		for _, line := range m.Lines {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", line.Address, e.money(line.Monthly), line.Detail)
+		}
+	}
+	return b.String()
+}
+
+func (e Estimate) money(v float64) string {
+	if e.Currency == "USD" || e.Currency == "" {
+		return fmt.Sprintf("$%.2f", v)
+	}
+	return fmt.Sprintf("%.2f %s", v, e.Currency)
+}
+
+// WriteJSON writes the estimate as indented JSON
+func (e Estimate) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,358 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+)
+
+// Deploy applies the selected modules in dependency order and stops at the
+// first module that fails
+func (r *Runner) Deploy(ctx context.Context) error {
+	modules, err := r.preflight(ctx, "deploy", false)
+	if err != nil {
+		return err
+	}
+
+	if !r.config.DryRun && !r.config.AutoApprove {
+		answer := strings.ToLower(r.confirm("Do you want to proceed? (yes/no): "))
+		if answer != "yes" && answer != "y" {
+			return ErrCancelled
+		}
+	}
+
+	for _, module := range modules {
+		if err := r.deployModule(ctx, r.stack.Modules[module]); err != nil {
+			return err
+		}
+	}
+	r.log.Info("deployment complete", "modules", len(modules), "dry_run", r.config.DryRun)
+	return nil
+}
+
+// Plan runs terraform plan for the selected modules without changing any
+// state, local or remote, and prints the plan of every module with changes
+func (r *Runner) Plan(ctx context.Context) error {
+	modules, err := r.preflight(ctx, "plan", false)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, module := range modules {
+		m := r.stack.Modules[module]
+		log := r.log.With("module", module)
+
+		if err := cleanModule(m.Dir); err != nil {
+			return &ModuleError{Module: module, Op: "plan", Err: err}
+		}
+		bucket, _, err := r.prepare(ctx, m, false)
+		if err != nil {
+			return &ModuleError{Module: module, Op: "init", Err: err}
+		}
+		changes, err := r.planChanges(ctx, m.Dir, varArgs(bucket))
+		if err != nil {
+			return &ModuleError{Module: module, Op: "plan", Err: err}
+		}
+
+		log.Info("planned", "changes", changes)
+		if changes > 0 {
+			if err := r.printPlan(ctx, m.Dir); err != nil {
+				return &ModuleError{Module: module, Op: "plan", Err: err}
+			}
+		}
+		total += changes
+	}
+	r.log.Info("plan complete", "modules", len(modules), "changes", total)
+	return nil
+}
+
+// ModulePlan is the `terraform show -json` output of one module's plan
+type ModulePlan struct {
+	Module string
+	JSON   []byte
+}
+
+// PlanJSON plans the selected modules like Plan and returns every plan as
+// JSON, in deployment order
+func (r *Runner) PlanJSON(ctx context.Context) ([]ModulePlan, error) {
+	modules, err := r.preflight(ctx, "plan", false)
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]ModulePlan, 0, len(modules))
+	for _, module := range modules {
+		m := r.stack.Modules[module]
+		if err := cleanModule(m.Dir); err != nil {
+			return nil, &ModuleError{Module: module, Op: "plan", Err: err}
+		}
+		bucket, _, err := r.prepare(ctx, m, false)
+		if err != nil {
+			return nil, &ModuleError{Module: module, Op: "init", Err: err}
+		}
+		out, err := r.savePlan(ctx, m.Dir, varArgs(bucket))
+		if err != nil {
+			return nil, &ModuleError{Module: module, Op: "plan", Err: err}
+		}
+		plans = append(plans, ModulePlan{Module: module, JSON: []byte(out)})
+	}
+	return plans, nil
+}
+
+// preflight checks credentials and logs the selected modules with their status
+func (r *Runner) preflight(ctx context.Context, op string, destroy bool) ([]string, error) {
+	account, err := r.cloud.Account(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AWS credentials not configured: %w", err)
+	}
+
+	modules, err := r.modules(destroy)
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.Info("starting "+op, "env", r.config.Env, "account", account, "modules", len(modules), "dry_run", r.config.DryRun)
+	for i, module := range modules {
+		r.log.Info("module", "position", i+1, "module", module, "status", r.moduleStatus(ctx, module))
+	}
+	return modules, nil
+}
+
+// deployModule brings one module in line with its configuration
+func (r *Runner) deployModule(ctx context.Context, m *stack.Module) error {
+	log := r.log.With("module", m.Name)
+
+	if err := cleanModule(m.Dir); err != nil {
+		return &ModuleError{Module: m.Name, Op: "deploy", Err: err}
+	}
+	bucket, hasState, err := r.prepare(ctx, m, !r.config.DryRun)
+	if err != nil {
+		return &ModuleError{Module: m.Name, Op: "init", Err: err}
+	}
+	vars := varArgs(bucket)
+
+	if !hasState {
+		log.Info("no existing state, deploying fresh")
+		if r.config.DryRun {
+			out, err := r.terraform.Run(ctx, m.Dir, append([]string{"plan", "-input=false", "-no-color"}, vars...)...)
+			if err != nil {
+				return &ModuleError{Module: m.Name, Op: "plan", Err: err}
+			}
+			fmt.Fprint(r.config.Stdout, out)
+			log.Info("dry run complete")
+			return nil
+		}
+		if _, err := r.terraform.Run(ctx, m.Dir, append([]string{"apply", "-input=false", "-no-color", "-auto-approve"}, vars...)...); err != nil {
+			return &ModuleError{Module: m.Name, Op: "apply", Err: err}
+		}
+	} else {
+		changes, err := r.planChanges(ctx, m.Dir, vars)
+		if err != nil {
+			return &ModuleError{Module: m.Name, Op: "plan", Err: err}
+		}
+		if changes == 0 {
+			log.Info("up to date", "changes", 0)
+			return nil
+		}
+
+		log.Info("changes to apply", "changes", changes)
+		if r.config.DryRun {
+			if err := r.printPlan(ctx, m.Dir); err != nil {
+				return &ModuleError{Module: m.Name, Op: "plan", Err: err}
+			}
+			log.Info("dry run complete")
+			return nil
+		}
+		if _, err := r.terraform.Run(ctx, m.Dir, "apply", "-input=false", "-no-color", planFile); err != nil {
+			return &ModuleError{Module: m.Name, Op: "apply", Err: err}
+		}
+	}
+
+	resources := 0
+	if out, err := r.terraform.Run(ctx, m.Dir, "state", "list"); err == nil {
+		resources = len(strings.Fields(out))
+	}
+	log.Info("deployed", "resources", resources)
+
+	if err := r.uploadLocalState(ctx, m, bucket); err != nil {
+		log.Warn("failed to upload state to S3", "error", err)
+	}
+	return nil
+}
+
+// prepare selects the backend for a module, restores or migrates state, and
+// runs terraform init. It reports the state bucket ("" when there is none)
+// and whether the module already has state. With write false, nothing in S3
+// is changed.
+func (r *Runner) prepare(ctx context.Context, m *stack.Module, write bool) (string, bool, error) {
+	log := r.log.With("module", m.Name)
+
+	bucket, err := r.stateBucket(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	key := r.stateKey(m.Name)
+
+	var remote []byte
+	if bucket != "" {
+		body, err := r.cloud.GetObject(ctx, bucket, key)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return "", false, err
+		case validState(body):
+			remote = body
+			log.Info("found state in S3", "bucket", bucket, "key", key)
+		default:
+			log.Warn("state in S3 is corrupted, deploying fresh", "bucket", bucket, "key", key)
+			if write {
+				if err := r.cloud.DeleteObject(ctx, bucket, key); err != nil {
+					return "", false, err
+				}
+			}
+		}
+	}
+
+	localState := filepath.Join(m.Dir, localStateFile)
+	local, _ := os.ReadFile(localState)
+
+	if m.Backend != "s3" {
+		if remote != nil && local == nil {
+			if err := os.WriteFile(localState, remote, 0o600); err != nil {
+				return "", false, err
+			}
+			local = remote
+			log.Info("restored local state from S3")
+		}
+		return bucket, hasResources(local), r.init(ctx, m.Dir)
+	}
+
+	if bucket == "" {
+		log.Warn("no S3 state bucket found, using local backend")
+		if err := writeLocalOverride(m.Dir); err != nil {
+			return "", false, err
+		}
+		return "", hasResources(local), r.init(ctx, m.Dir)
+	}
+
+	lockTable := ""
+	if exists, err := r.cloud.TableExists(ctx, r.lockTable()); err != nil {
+		return "", false, err
+	} else if exists {
+		lockTable = r.lockTable()
+	} else {
+		log.Info("lock table not found, using S3 backend without locking", "table", r.lockTable())
+	}
+	if err := r.writeBackendConfig(m.Dir, m.Name, bucket, lockTable); err != nil {
+		return "", false, err
+	}
+
+	hasState := remote != nil
+	if !hasState && hasResources(local) {
+		if write {
+			if err := r.cloud.PutObject(ctx, bucket, key, local); err != nil {
+				return "", false, err
+			}
+			hasState = true
+			log.Info("migrated local state to S3", "bucket", bucket, "key", key)
+		} else {
+			log.Info("local state would be migrated to S3", "bucket", bucket, "key", key)
+		}
+	}
+	return bucket, hasState, r.init(ctx, m.Dir)
+}
+
+// uploadLocalState copies the local state of a locally-backed module (bootstrap,
+// or any module deployed before the bucket existed) to S3. The bucket is
+// rediscovered because bootstrap may just have created it.
+func (r *Runner) uploadLocalState(ctx context.Context, m *stack.Module, bucket string) error {
+	if m.Backend == "s3" && bucket != "" {
+		return nil
+	}
+
+	local, err := os.ReadFile(filepath.Join(m.Dir, localStateFile))
+	if err != nil || !validState(local) {
+		return nil
+	}
+
+	r.forgetStateBucket()
+	bucket, err = r.stateBucket(ctx)
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		r.log.Info("no S3 state bucket yet, state kept locally", "module", m.Name)
+		return nil
+	}
+
+	key := r.stateKey(m.Name)
+	if err := r.cloud.PutObject(ctx, bucket, key, local); err != nil {
+		return err
+	}
+	r.log.Info("uploaded state to S3", "module", m.Name, "bucket", bucket, "key", key)
+	return nil
+}
+
+// planChanges saves a plan to planFile and returns the number of resources
+// it changes
+func (r *Runner) planChanges(ctx context.Context, dir string, vars []string) (int, error) {
+	out, err := r.savePlan(ctx, dir, vars)
+	if err != nil {
+		return 0, err
+	}
+	return CountChanges([]byte(out))
+}
+
+// savePlan saves a plan to planFile and returns it as JSON
+func (r *Runner) savePlan(ctx context.Context, dir string, vars []string) (string, error) {
+	if _, err := r.terraform.Run(ctx, dir, append([]string{"plan", "-input=false", "-no-color", "-out=" + planFile}, vars...)...); err != nil {
+		return "", err
+	}
+	return r.terraform.Run(ctx, dir, "show", "-json", planFile)
+}
+
+// printPlan writes the human-readable saved plan to Stdout
+func (r *Runner) printPlan(ctx context.Context, dir string) error {
+	out, err := r.terraform.Run(ctx, dir, "show", "-no-color", planFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(r.config.Stdout, out)
+	return nil
+}
+
+// CountChanges returns the number of resource changes in `terraform show
+// -json` plan output that are neither no-ops nor data source reads
+func CountChanges(planJSON []byte) (int, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Change struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	cleaned := bytes.TrimSpace(tfoutput.Clean(planJSON))
+	if err := json.Unmarshal(cleaned, &plan); err != nil {
+		return 0, &tfoutput.ParseError{Raw: string(planJSON), Err: err}
+	}
+
+	changes := 0
+	for _, rc := range plan.ResourceChanges {
+		for _, action := range rc.Change.Actions {
+			if action != "no-op" && action != "read" {
+				changes++
+				break
+			}
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,130 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// ErrNotFound is returned by Cloud.GetObject for a missing key
+var ErrNotFound = errors.New("not found")
+
+// Cloud is the subset of AWS used to manage remote state
+type Cloud interface {
+	// Account returns the caller's account ID and fails without credentials
+	Account(ctx context.Context) (string, error)
+	ListBuckets(ctx context.Context) ([]string, error)
+	// GetObject returns ErrNotFound when the key does not exist
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	PutObject(ctx context.Context, bucket, key string, body []byte) error
+	DeleteObject(ctx context.Context, bucket, key string) error
+	TableExists(ctx context.Context, name string) (bool, error)
+}
+
+// FindStateBucket returns the first bucket named "<env>-terraform-state-*",
+// or "" when there is none
+func FindStateBucket(ctx context.Context, cloud Cloud, env string) (string, error) {
+	buckets, err := cloud.ListBuckets(ctx)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(buckets)
+
+	prefix := env + "-terraform-state-"
+	for _, bucket := range buckets {
+		if strings.HasPrefix(bucket, prefix) {
+			return bucket, nil
+		}
+	}
+	return "", nil
+}
+
+// AWSCloud implements Cloud with the AWS SDK
+type AWSCloud struct {
+	s3       *s3.S3
+	dynamodb *dynamodb.DynamoDB
+	sts      *sts.STS
+}
+
+// NewAWSCloud uses the default credential chain (environment, shared config,
+// OIDC web identity in GitHub Actions)
+func NewAWSCloud(region string) (*AWSCloud, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AWSCloud{s3: s3.New(sess), dynamodb: dynamodb.New(sess), sts: sts.New(sess)}, nil
+}
+
+func (c *AWSCloud) Account(ctx context.Context) (string, error) {
+	out, err := c.sts.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.Account), nil
+}
+
+func (c *AWSCloud) ListBuckets(ctx context.Context) ([]string, error) {
+	out, err := c.s3.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(out.Buckets))
+	for _, bucket := range out.Buckets {
+		names = append(names, aws.StringValue(bucket.Name))
+	}
+	return names, nil
+}
+
+func (c *AWSCloud) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	out, err := c.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (c *AWSCloud) PutObject(ctx context.Context, bucket, key string, body []byte) error {
+	_, err := c.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+	})
+	return err
+}
+
+func (c *AWSCloud) DeleteObject(ctx context.Context, bucket, key string) error {
+	_, err := c.s3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	return err
+}
+
+func (c *AWSCloud) TableExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.dynamodb.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,247 @@
+// Package deploy deploys, plans, checks for drift and destroys the root
+// modules of an environment in dependency order. It backs the ztctl command and replaces
+// scripts/deploy.sh and scripts/destroy.sh.
+//
+// State handling follows the scripts:
+//   - The state bucket is discovered by listing buckets with the prefix
+//     "<env>-terraform-state-", since it carries a random suffix.
+//   - S3-backed modules fall back to a local backend while the bucket does
+//     not exist yet (first deployment or recovery). Their local state is
+//     uploaded once the bucket is back.
+//   - The bootstrap module always uses a local backend. Its state is copied
+//     to S3 after every apply and restored from S3 when missing locally.
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+const (
+	backendConfigFile   = "backend-config.hcl"
+	backendOverrideFile = "backend_override.tf"
+	localStateFile      = "terraform.tfstate"
+	planFile            = "ztctl.tfplan"
+)
+
+// ErrCancelled is returned when the operator declines the confirmation prompt
+var ErrCancelled = errors.New("cancelled by operator")
+
+// ModuleError is returned when terraform fails for a module. Modules after
+// it in the order are not touched.
+type ModuleError struct {
+	Module string
+	Op     string
+	Err    error
+}
+
+func (e *ModuleError) Error() string {
+	return fmt.Sprintf("%s of module %s failed: %v", e.Op, e.Module, e.Err)
+}
+
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+// UsageError is returned for invalid options, such as an unknown module
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// Config holds the options shared by all commands
+type Config struct {
+	// Root is the repository root containing envs/<Env>
+	Root   string
+	Env    string
+	Region string
+	// Module limits the command to a single module
+	Module      string
+	DryRun      bool
+	AutoApprove bool
+	// Stdin and Stdout are used for confirmation prompts and dry-run plans
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Runner executes commands against one environment
+type Runner struct {
+	config    Config
+	terraform Terraform
+	cloud     Cloud
+	log       *slog.Logger
+	stack     *stack.Stack
+	stdin     *bufio.Reader
+
+	bucket       string
+	bucketLoaded bool
+}
+
+// New loads envs/<env> and returns a Runner for it
+func New(config Config, terraform Terraform, cloud Cloud, logger *slog.Logger) (*Runner, error) {
+	if config.Env == "" {
+		config.Env = "dev"
+	}
+	if config.Region == "" {
+		config.Region = "eu-north-1"
+	}
+	if config.Stdin == nil {
+		config.Stdin = os.Stdin
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
+
+	s, err := stack.Load(filepath.Join(config.Root, "envs", config.Env))
+	if err != nil {
+		return nil, err
+	}
+	if config.Module != "" {
+		if _, ok := s.Modules[config.Module]; !ok {
+			return nil, &UsageError{Message: fmt.Sprintf("unknown module %q, expected one of: %s", config.Module, strings.Join(s.Names(), ", "))}
+		}
+	}
+
+	return &Runner{config: config, terraform: terraform, cloud: cloud, log: logger, stack: s, stdin: bufio.NewReader(config.Stdin)}, nil
+}
+
+// modules returns the selected modules in deployment or destroy order
+func (r *Runner) modules(destroy bool) ([]string, error) {
+	if r.config.Module != "" {
+		return []string{r.config.Module}, nil
+	}
+	if destroy {
+		return r.stack.DestroyOrder()
+	}
+	return r.stack.Order()
+}
+
+// stateBucket returns the discovered state bucket, or "" when there is none
+func (r *Runner) stateBucket(ctx context.Context) (string, error) {
+	if r.bucketLoaded {
+		return r.bucket, nil
+	}
+
+	bucket, err := FindStateBucket(ctx, r.cloud, r.config.Env)
+	if err != nil {
+		return "", err
+	}
+	r.bucket, r.bucketLoaded = bucket, true
+	return bucket, nil
+}
+
+// forgetStateBucket clears the cached bucket, e.g. after bootstrap created it
+func (r *Runner) forgetStateBucket() {
+	r.bucket, r.bucketLoaded = "", false
+}
+
+func (r *Runner) stateKey(module string) string {
+	return stack.StateKey(r.config.Env, module)
+}
+
+func (r *Runner) lockTable() string {
+	return r.config.Env + "-terraform-locks"
+}
+
+// confirm asks a question on Stdout and reads one line from Stdin
+func (r *Runner) confirm(question string) string {
+	fmt.Fprint(r.config.Stdout, question)
+	line, _ := r.stdin.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// moduleStatus reports whether the module's state in S3 tracks resources
+func (r *Runner) moduleStatus(ctx context.Context, module string) string {
+	bucket, err := r.stateBucket(ctx)
+	if err != nil || bucket == "" {
+		return "NOT_DEPLOYED"
+	}
+	body, err := r.cloud.GetObject(ctx, bucket, r.stateKey(module))
+	if err != nil || !hasResources(body) {
+		return "NOT_DEPLOYED"
+	}
+	return "DEPLOYED"
+}
+
+// validState reports whether body looks like a terraform state file
+func validState(body []byte) bool {
+	var state struct {
+		Version *int `json:"version"`
+	}
+	return json.Unmarshal(body, &state) == nil && state.Version != nil
+}
+
+// hasResources reports whether a state file tracks at least one resource
+func hasResources(body []byte) bool {
+	var state struct {
+		Resources []json.RawMessage `json:"resources"`
+	}
+	return json.Unmarshal(body, &state) == nil && len(state.Resources) > 0
+}
+
+// cleanModule removes what a previous run left in the module directory so
+// backend selection starts from scratch; local state is kept for recovery
+func cleanModule(dir string) error {
+	for _, path := range []string{
+		filepath.Join(dir, ".terraform"),
+		filepath.Join(dir, backendConfigFile),
+		filepath.Join(dir, backendOverrideFile),
+		filepath.Join(dir, planFile),
+		filepath.Join(dir, refreshPlanFile),
+	} {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBackendConfig points an S3-backed module at the state bucket
+func (r *Runner) writeBackendConfig(dir, module, bucket, lockTable string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bucket         = %q\n", bucket)
+	fmt.Fprintf(&b, "key            = %q\n", r.stateKey(module))
+	fmt.Fprintf(&b, "region         = %q\n", r.config.Region)
+	fmt.Fprintf(&b, "encrypt        = true\n")
+	if lockTable != "" {
+		fmt.Fprintf(&b, "dynamodb_table = %q\n", lockTable)
+	}
+	return os.WriteFile(filepath.Join(dir, backendConfigFile), []byte(b.String()), 0o644)
+}
+
+// writeLocalOverride makes an S3-backed module use a local backend
+func writeLocalOverride(dir string) error {
+	content := "# Written by ztctl: the S3 state bucket does not exist yet\nterraform {\n  backend \"local\" {}\n}\n"
+	return os.WriteFile(filepath.Join(dir, backendOverrideFile), []byte(content), 0o644)
+}
+
+// init runs terraform init, with the backend config when one was written
+func (r *Runner) init(ctx context.Context, dir string) error {
+	args := []string{"init", "-input=false", "-no-color", "-reconfigure"}
+	if _, err := os.Stat(filepath.Join(dir, backendConfigFile)); err == nil {
+		args = append(args, "-backend-config="+backendConfigFile)
+	}
+	_, err := r.terraform.Run(ctx, dir, args...)
+	return err
+}
+
+// varArgs passes the state bucket to modules; every root module accepts it
+func varArgs(bucket string) []string {
+	if bucket == "" {
+		return nil
+	}
+	return []string{"-var", stack.StateBucketVariable + "=" + bucket}
+}
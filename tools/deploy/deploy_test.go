@@ -0,0 +1,476 @@
+package deploy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+	"github.com/y3gi/zero-trust-aws/tools/deploy/deploytest"
+)
+
+const (
+	account = "123456789012"
+	bucket  = "dev-terraform-state-a1b2c3"
+	state   = `{"version":4,"resources":[{"type":"aws_vpc","name":"main"}]}`
+	empty   = `{"version":4,"resources":[]}`
+	changes = `{"resource_changes":[
+		{"address":"aws_vpc.main","change":{"actions":["update"]}},
+		{"address":"data.aws_region.current","change":{"actions":["read"]}},
+		{"address":"aws_subnet.private","change":{"actions":["no-op"]}}]}`
+)
+
+func backend(backendType string) string {
+	return "terraform {\n    backend \"" + backendType + "\" {}\n}\n"
+}
+
+func remoteState(module string) string {
+	return `
+data "terraform_remote_state" "` + module + `" {
+    backend = "s3"
+    config = {
+        bucket = var.state_bucket
+        key    = "dev/` + module + `/terraform.tfstate"
+        region = "eu-north-1"
+    }
+}
+`
+}
+
+// newEnv writes a small envs/dev with the same shape as the real one:
+// bootstrap (local backend) -> security, vpc -> compute
+func newEnv(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	modules := map[string]string{
+		"bootstrap": backend("local"),
+		"security":  backend("s3"),
+		"vpc":       backend("s3"),
+		"compute":   backend("s3") + remoteState("vpc") + remoteState("security"),
+	}
+	for name, content := range modules {
+		dir := filepath.Join(root, "envs", "dev", name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0o644))
+	}
+	return root
+}
+
+func moduleDir(root, module string) string {
+	return filepath.Join(root, "envs", "dev", module)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func key(module string) string {
+	return "dev/" + module + "/terraform.tfstate"
+}
+
+// deployedCloud has a state bucket holding state for every module
+func deployedCloud() *deploytest.Cloud {
+	cloud := deploytest.NewCloud(account)
+	cloud.CreateBucket(bucket)
+	for _, module := range []string{"bootstrap", "security", "vpc", "compute"} {
+		cloud.Objects[bucket][key(module)] = []byte(state)
+	}
+	return cloud
+}
+
+type fixture struct {
+	root      string
+	terraform *deploytest.Terraform
+	cloud     *deploytest.Cloud
+	stdout    *bytes.Buffer
+}
+
+func newFixture(t *testing.T, cloud *deploytest.Cloud) *fixture {
+	return &fixture{root: newEnv(t), terraform: &deploytest.Terraform{}, cloud: cloud, stdout: &bytes.Buffer{}}
+}
+
+func (f *fixture) runner(t *testing.T, config deploy.Config) *deploy.Runner {
+	t.Helper()
+	config.Root = f.root
+	config.Stdout = f.stdout
+	if config.Stdin == nil {
+		config.Stdin = strings.NewReader("")
+	}
+	runner, err := deploy.New(config, f.terraform, f.cloud, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return runner
+}
+
+func TestDeployFreshEnvironment(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deploytest.NewCloud(account))
+	f.terraform.OnApply = func(module, dir, _ string) {
+		if module == "bootstrap" {
+			f.cloud.CreateBucket(bucket)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(state), 0o644))
+		}
+	}
+
+	require.NoError(t, f.runner(t, deploy.Config{AutoApprove: true}).Deploy(context.Background()))
+
+	assert.Equal(t, []string{"bootstrap", "security", "vpc", "compute"}, f.terraform.Modules("apply"))
+
+	uploaded, ok := f.cloud.Object(bucket, key("bootstrap"))
+	require.True(t, ok, "bootstrap state should be uploaded once the bucket exists")
+	assert.JSONEq(t, state, string(uploaded))
+
+	backendConfig := readFile(t, filepath.Join(moduleDir(f.root, "compute"), "backend-config.hcl"))
+	assert.Contains(t, backendConfig, `bucket         = "`+bucket+`"`)
+	assert.Contains(t, backendConfig, `key            = "dev/compute/terraform.tfstate"`)
+	assert.NotContains(t, backendConfig, "dynamodb_table", "the lock table does not exist yet")
+	assert.NoFileExists(t, filepath.Join(moduleDir(f.root, "compute"), "backend_override.tf"))
+
+	assert.Equal(t, []string{
+		"init -input=false -no-color -reconfigure -backend-config=backend-config.hcl",
+		"apply -input=false -no-color -auto-approve -var state_bucket=" + bucket,
+		"state list",
+	}, f.terraform.Commands("compute"))
+}
+
+func TestDeployCreatesLockTableFirst(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deploytest.NewCloud(account))
+	dir := moduleDir(f.root, "data_store")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(backend("s3")+remoteState("security")), 0o644))
+	f.terraform.OnApply = func(module, dir, _ string) {
+		switch module {
+		case "bootstrap":
+			f.cloud.CreateBucket(bucket)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(state), 0o644))
+		case "data_store":
+			f.cloud.Tables["dev-terraform-locks"] = true
+		}
+	}
+
+	require.NoError(t, f.runner(t, deploy.Config{AutoApprove: true}).Deploy(context.Background()))
+
+	assert.Equal(t, []string{"bootstrap", "security", "data_store", "vpc", "compute"}, f.terraform.Modules("apply"),
+		"data_store comes before every S3-backed module it does not read the state of")
+	assert.NotContains(t, readFile(t, filepath.Join(moduleDir(f.root, "security"), "backend-config.hcl")), "dynamodb_table")
+	for _, module := range []string{"vpc", "compute"} {
+		assert.Contains(t, readFile(t, filepath.Join(moduleDir(f.root, module), "backend-config.hcl")), `dynamodb_table = "dev-terraform-locks"`, module)
+	}
+}
+
+func TestDeployFallsBackToLocalBackend(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deploytest.NewCloud(account))
+	require.NoError(t, f.runner(t, deploy.Config{Module: "vpc", AutoApprove: true}).Deploy(context.Background()))
+
+	assert.FileExists(t, filepath.Join(moduleDir(f.root, "vpc"), "backend_override.tf"))
+	assert.NoFileExists(t, filepath.Join(moduleDir(f.root, "vpc"), "backend-config.hcl"))
+	assert.Equal(t, []string{
+		"init -input=false -no-color -reconfigure",
+		"apply -input=false -no-color -auto-approve",
+		"state list",
+	}, f.terraform.Commands("vpc"))
+	assert.Empty(t, f.terraform.Commands("bootstrap"), "--module should deploy only that module")
+}
+
+func TestDeploySkipsModulesWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	cloud.Tables["dev-terraform-locks"] = true
+	f := newFixture(t, cloud)
+	f.terraform.Plans = map[string]string{"vpc": changes}
+
+	require.NoError(t, f.runner(t, deploy.Config{AutoApprove: true}).Deploy(context.Background()))
+
+	assert.Equal(t, []string{"vpc"}, f.terraform.Modules("apply"))
+	assert.Contains(t, f.terraform.Commands("vpc"), "apply -input=false -no-color ztctl.tfplan", "the saved plan should be applied")
+	assert.Equal(t, []string{
+		"init -input=false -no-color -reconfigure -backend-config=backend-config.hcl",
+		"plan -input=false -no-color -out=ztctl.tfplan -var state_bucket=" + bucket,
+		"show -json ztctl.tfplan",
+	}, f.terraform.Commands("security"))
+	assert.Contains(t, readFile(t, filepath.Join(moduleDir(f.root, "vpc"), "backend-config.hcl")), `dynamodb_table = "dev-terraform-locks"`)
+}
+
+func TestDeployRestoresBootstrapStateFromS3(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	require.NoError(t, f.runner(t, deploy.Config{Module: "bootstrap", AutoApprove: true}).Deploy(context.Background()))
+
+	assert.JSONEq(t, state, readFile(t, filepath.Join(moduleDir(f.root, "bootstrap"), "terraform.tfstate")))
+	assert.Empty(t, f.terraform.Modules("apply"), "restored state has no changes, so nothing is applied")
+}
+
+func TestDeployMigratesLocalStateToS3(t *testing.T) {
+	t.Parallel()
+
+	cloud := deploytest.NewCloud(account)
+	cloud.CreateBucket(bucket)
+	f := newFixture(t, cloud)
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir(f.root, "vpc"), "terraform.tfstate"), []byte(state), 0o644))
+
+	require.NoError(t, f.runner(t, deploy.Config{Module: "vpc", AutoApprove: true}).Deploy(context.Background()))
+
+	migrated, ok := cloud.Object(bucket, key("vpc"))
+	require.True(t, ok)
+	assert.JSONEq(t, state, string(migrated))
+	assert.Contains(t, f.terraform.Commands("vpc"), "show -json ztctl.tfplan", "migrated state should be planned, not applied fresh")
+}
+
+func TestDeployReplacesCorruptedState(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	cloud.Objects[bucket][key("vpc")] = []byte("<Error>AccessDenied</Error>")
+	f := newFixture(t, cloud)
+
+	require.NoError(t, f.runner(t, deploy.Config{Module: "vpc", AutoApprove: true}).Deploy(context.Background()))
+
+	assert.Equal(t, []string{bucket + "/" + key("vpc")}, cloud.Deleted)
+	assert.Contains(t, f.terraform.Commands("vpc"), "apply -input=false -no-color -auto-approve -var state_bucket="+bucket)
+}
+
+func TestDeployDryRunChangesNothing(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	cloud.Objects[bucket][key("security")] = []byte("not json")
+	delete(cloud.Objects[bucket], key("vpc"))
+	f := newFixture(t, cloud)
+	f.terraform.Plans = map[string]string{"compute": changes}
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir(f.root, "vpc"), "terraform.tfstate"), []byte(state), 0o644))
+
+	require.NoError(t, f.runner(t, deploy.Config{DryRun: true}).Deploy(context.Background()))
+
+	assert.Empty(t, f.terraform.Modules("apply"))
+	assert.Empty(t, cloud.Deleted, "corrupted state must not be deleted in a dry run")
+	_, uploaded := cloud.Object(bucket, key("vpc"))
+	assert.False(t, uploaded, "local state must not be migrated in a dry run")
+	assert.Contains(t, f.stdout.String(), "Terraform will perform the following actions (compute)")
+}
+
+func TestDeployStopsAtFirstFailure(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	f.terraform.Plans = map[string]string{"security": changes, "vpc": changes}
+	f.terraform.Fail = map[string]error{"security:apply": errors.New("AccessDenied")}
+
+	err := f.runner(t, deploy.Config{AutoApprove: true}).Deploy(context.Background())
+
+	var moduleErr *deploy.ModuleError
+	require.ErrorAs(t, err, &moduleErr)
+	assert.Equal(t, "security", moduleErr.Module)
+	assert.Equal(t, "apply", moduleErr.Op)
+	assert.Empty(t, f.terraform.Commands("vpc"), "modules after the failure should not run")
+}
+
+func TestDeployAsksForConfirmation(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	err := f.runner(t, deploy.Config{Stdin: strings.NewReader("no\n")}).Deploy(context.Background())
+
+	assert.ErrorIs(t, err, deploy.ErrCancelled)
+	assert.Empty(t, f.terraform.Calls)
+	assert.Contains(t, f.stdout.String(), "Do you want to proceed?")
+}
+
+func TestDeployRequiresCredentials(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deploytest.NewCloud(""))
+	err := f.runner(t, deploy.Config{AutoApprove: true}).Deploy(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AWS credentials not configured")
+	var moduleErr *deploy.ModuleError
+	assert.False(t, errors.As(err, &moduleErr))
+}
+
+func TestPlanDoesNotChangeState(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	delete(cloud.Objects[bucket], key("vpc"))
+	f := newFixture(t, cloud)
+	f.terraform.Plans = map[string]string{"vpc": changes}
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir(f.root, "vpc"), "terraform.tfstate"), []byte(state), 0o644))
+
+	require.NoError(t, f.runner(t, deploy.Config{}).Plan(context.Background()))
+
+	assert.Empty(t, f.terraform.Modules("apply"))
+	_, uploaded := cloud.Object(bucket, key("vpc"))
+	assert.False(t, uploaded)
+	assert.Equal(t, "Terraform will perform the following actions (vpc)\n", f.stdout.String())
+}
+
+const sgDrift = `{"resource_drift":[{"address":"aws_security_group.app_sg","mode":"managed","type":"aws_security_group",
+	"change":{"actions":["update"],"before":{"ingress":[{"from_port":443}]},"after":{"ingress":[{"from_port":22}]}}}]}`
+
+func TestDriftChecksDeployedModules(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	delete(cloud.Objects[bucket], key("security"))
+	f := newFixture(t, cloud)
+	f.terraform.RefreshPlans = map[string]string{"compute": sgDrift}
+	f.terraform.Plans = map[string]string{"compute": changes}
+
+	report, err := f.runner(t, deploy.Config{}).Drift(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"init -input=false -no-color -reconfigure -backend-config=backend-config.hcl",
+		"plan -input=false -no-color -detailed-exitcode -out=ztctl-refresh.tfplan -refresh-only -var state_bucket=" + bucket,
+		"show -json ztctl-refresh.tfplan",
+		"plan -input=false -no-color -detailed-exitcode -out=ztctl.tfplan -var state_bucket=" + bucket,
+		"show -json ztctl.tfplan",
+	}, f.terraform.Commands("compute"))
+	assert.Empty(t, f.terraform.Modules("apply"))
+
+	require.Len(t, report.Modules, 4)
+	modules := map[string]int{}
+	for i, m := range report.Modules {
+		modules[m.Module] = i
+	}
+	assert.Equal(t, "not deployed", report.Modules[modules["security"]].Skipped)
+
+	compute := report.Modules[modules["compute"]]
+	require.Len(t, compute.Drift, 1)
+	assert.Equal(t, "ingress[0].from_port", compute.Drift[0].Attribute)
+	assert.Equal(t, "443", compute.Drift[0].Expected)
+	assert.Equal(t, "22", compute.Drift[0].Actual)
+	assert.True(t, report.HasCritical())
+}
+
+func TestDriftContinuesAfterModuleFailure(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	f.terraform.Fail = map[string]error{"security:plan": errors.New("Error: No valid credential sources found")}
+
+	report, err := f.runner(t, deploy.Config{}).Drift(context.Background())
+
+	var moduleErr *deploy.ModuleError
+	require.ErrorAs(t, err, &moduleErr)
+	assert.Equal(t, "security", moduleErr.Module)
+	assert.Contains(t, f.terraform.Modules("show"), "compute", "modules after the failure are still checked")
+	require.Len(t, report.Modules, 4)
+	assert.False(t, report.HasCritical())
+}
+
+func TestDestroyInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	cloud.Tables["dev-terraform-locks"] = true
+	f := newFixture(t, cloud)
+	require.NoError(t, os.MkdirAll(filepath.Join(moduleDir(f.root, "vpc"), ".terraform"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir(f.root, "bootstrap"), "terraform.tfstate"), []byte(state), 0o644))
+
+	require.NoError(t, f.runner(t, deploy.Config{AutoApprove: true}).Destroy(context.Background()))
+
+	assert.Equal(t, []string{"compute", "vpc", "security"}, f.terraform.Modules("destroy"), "bootstrap is protected")
+	assert.ElementsMatch(t, []string{bucket + "/" + key("compute"), bucket + "/" + key("vpc"), bucket + "/" + key("security")}, cloud.Deleted)
+	_, ok := cloud.Object(bucket, key("bootstrap"))
+	assert.True(t, ok)
+
+	assert.NotContains(t, readFile(t, filepath.Join(moduleDir(f.root, "vpc"), "backend-config.hcl")), "dynamodb_table")
+	assert.NoDirExists(t, filepath.Join(moduleDir(f.root, "vpc"), ".terraform"))
+	assert.FileExists(t, filepath.Join(moduleDir(f.root, "bootstrap"), "terraform.tfstate"))
+}
+
+func TestDestroySkipsModulesWithoutState(t *testing.T) {
+	t.Parallel()
+
+	cloud := deployedCloud()
+	delete(cloud.Objects[bucket], key("compute"))
+	f := newFixture(t, cloud)
+
+	require.NoError(t, f.runner(t, deploy.Config{AutoApprove: true}).Destroy(context.Background()))
+	assert.Equal(t, []string{"vpc", "security"}, f.terraform.Modules("destroy"))
+	assert.Empty(t, f.terraform.Commands("compute"))
+}
+
+func TestDestroyDryRunPlansOnly(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	require.NoError(t, f.runner(t, deploy.Config{Module: "vpc", DryRun: true}).Destroy(context.Background()))
+
+	assert.Empty(t, f.terraform.Modules("destroy"))
+	assert.Empty(t, f.cloud.Deleted)
+	assert.Contains(t, f.terraform.Commands("vpc"), "plan -destroy -input=false -no-color -var state_bucket="+bucket)
+}
+
+func TestDestroyRequiresAccountConfirmation(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t, deployedCloud())
+	err := f.runner(t, deploy.Config{Stdin: strings.NewReader("yes\n999999999999\n")}).Destroy(context.Background())
+	assert.ErrorIs(t, err, deploy.ErrCancelled)
+	assert.Empty(t, f.terraform.Modules("destroy"))
+
+	f = newFixture(t, deployedCloud())
+	require.NoError(t, f.runner(t, deploy.Config{Stdin: strings.NewReader("yes\n" + account + "\n")}).Destroy(context.Background()))
+	assert.Equal(t, []string{"compute", "vpc", "security"}, f.terraform.Modules("destroy"))
+}
+
+func TestNewRejectsUnknownModule(t *testing.T) {
+	t.Parallel()
+
+	_, err := deploy.New(deploy.Config{Root: newEnv(t), Module: "network"}, &deploytest.Terraform{}, deploytest.NewCloud(account), slog.Default())
+
+	var usage *deploy.UsageError
+	require.ErrorAs(t, err, &usage)
+	assert.Contains(t, err.Error(), "bootstrap, compute, security, vpc")
+}
+
+func TestFindStateBucket(t *testing.T) {
+	t.Parallel()
+
+	cloud := deploytest.NewCloud(account)
+	for _, name := range []string{"prod-terraform-state-000", "dev-terraform-state-zzz", "dev-cloudtrail-logs", bucket} {
+		cloud.CreateBucket(name)
+	}
+
+	found, err := deploy.FindStateBucket(context.Background(), cloud, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, bucket, found)
+
+	found, err = deploy.FindStateBucket(context.Background(), cloud, "staging")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestCountChanges(t *testing.T) {
+	t.Parallel()
+
+	count, err := deploy.CountChanges([]byte("[command]/usr/bin/terraform show -json ztctl.tfplan\n" + changes + "\n::debug::exitcode: 0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "only the update counts; read and no-op do not")
+
+	_, err = deploy.CountChanges([]byte("Error: Failed to load plugin schemas"))
+	assert.Error(t, err)
+
+	count, err = deploy.CountChanges([]byte(empty))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
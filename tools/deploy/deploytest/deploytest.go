@@ -0,0 +1,217 @@
+// Package deploytest provides in-memory fakes of deploy.Terraform and
+// deploy.Cloud for tests
+package deploytest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/y3gi/zero-trust-aws/tools/deploy"
+)
+
+// Call is one recorded terraform invocation
+type Call struct {
+	Module string
+	Args   []string
+}
+
+// Terraform records every call and answers like terraform would
+type Terraform struct {
+	mu    sync.Mutex
+	Calls []Call
+	// Plans maps a module to the `terraform show -json` output of its plan;
+	// modules without an entry plan no changes
+	Plans map[string]string
+	// RefreshPlans maps a module to the `terraform show -json` output of its
+	// refresh-only plan; modules without an entry have not drifted
+	RefreshPlans map[string]string
+	// Fail maps "module:command", e.g. "vpc:apply", to the error to return
+	Fail map[string]error
+	// OnApply runs after every successful apply or destroy, e.g. to create
+	// the state bucket when bootstrap is applied
+	OnApply func(module, dir, command string)
+}
+
+// Run implements deploy.Terraform
+func (f *Terraform) Run(_ context.Context, dir string, args ...string) (string, error) {
+	module := filepath.Base(dir)
+
+	f.mu.Lock()
+	f.Calls = append(f.Calls, Call{Module: module, Args: args})
+	f.mu.Unlock()
+
+	if err := f.Fail[module+":"+args[0]]; err != nil {
+		return "", err
+	}
+
+	switch args[0] {
+	case "show":
+		if args[1] == "-json" {
+			plans := f.Plans
+			if args[len(args)-1] == "ztctl-refresh.tfplan" {
+				plans = f.RefreshPlans
+			}
+			if plan, ok := plans[module]; ok {
+				return plan, nil
+			}
+			return `{"format_version":"1.2","resource_changes":[]}`, nil
+		}
+		return "Terraform will perform the following actions (" + module + ")\n", nil
+	case "plan":
+		if slices.Contains(args, "-detailed-exitcode") {
+			plans := f.Plans
+			if slices.Contains(args, "-refresh-only") {
+				plans = f.RefreshPlans
+			}
+			if _, ok := plans[module]; ok {
+				return "Plan for " + module + "\n", ExitError{Code: 2}
+			}
+		}
+		return "Plan for " + module + "\n", nil
+	case "state":
+		return "aws_s3_bucket.state\naws_s3_bucket.cloudtrail\n", nil
+	case "apply", "destroy":
+		if f.OnApply != nil {
+			f.OnApply(module, dir, args[0])
+		}
+	}
+	return "", nil
+}
+
+// ExitError is returned by the fake when terraform would exit with Code,
+// e.g. 2 for a -detailed-exitcode plan with changes
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+// ExitCode matches the method of *exec.ExitError
+func (e ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Commands returns the recorded calls for module as "command args..." strings
+func (f *Terraform) Commands(module string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var commands []string
+	for _, call := range f.Calls {
+		if call.Module == module {
+			commands = append(commands, strings.Join(call.Args, " "))
+		}
+	}
+	return commands
+}
+
+// Modules returns the modules in the order a command was first run for them
+func (f *Terraform) Modules(command string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var modules []string
+	for _, call := range f.Calls {
+		if call.Args[0] == command {
+			modules = append(modules, call.Module)
+		}
+	}
+	return modules
+}
+
+// Cloud is an in-memory S3, DynamoDB and STS
+type Cloud struct {
+	mu        sync.Mutex
+	AccountID string
+	Objects   map[string]map[string][]byte
+	Tables    map[string]bool
+	// Deleted records "bucket/key" for every deleted object
+	Deleted []string
+}
+
+// NewCloud returns a Cloud with no buckets
+func NewCloud(account string) *Cloud {
+	return &Cloud{AccountID: account, Objects: map[string]map[string][]byte{}, Tables: map[string]bool{}}
+}
+
+// CreateBucket adds an empty bucket
+func (c *Cloud) CreateBucket(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Objects[name]; !ok {
+		c.Objects[name] = map[string][]byte{}
+	}
+}
+
+// Object returns an object's content and whether it exists
+func (c *Cloud) Object(bucket, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body, ok := c.Objects[bucket][key]
+	return body, ok
+}
+
+func (c *Cloud) Account(context.Context) (string, error) {
+	if c.AccountID == "" {
+		return "", errors.New("NoCredentialProviders: no valid providers in chain")
+	}
+	return c.AccountID, nil
+}
+
+func (c *Cloud) ListBuckets(context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	for name := range c.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (c *Cloud) GetObject(_ context.Context, bucket, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	objects, ok := c.Objects[bucket]
+	if !ok {
+		return nil, errors.New("NoSuchBucket: " + bucket)
+	}
+	body, ok := objects[key]
+	if !ok {
+		return nil, deploy.ErrNotFound
+	}
+	return body, nil
+}
+
+func (c *Cloud) PutObject(_ context.Context, bucket, key string, body []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	objects, ok := c.Objects[bucket]
+	if !ok {
+		return errors.New("NoSuchBucket: " + bucket)
+	}
+	objects[key] = append([]byte(nil), body...)
+	return nil
+}
+
+func (c *Cloud) DeleteObject(_ context.Context, bucket, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.Objects[bucket], key)
+	c.Deleted = append(c.Deleted, bucket+"/"+key)
+	return nil
+}
+
+func (c *Cloud) TableExists(_ context.Context, name string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Tables[name], nil
+}
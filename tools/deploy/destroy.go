@@ -0,0 +1,131 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+// Destroy destroys the selected modules in reverse dependency order and
+// stops at the first failure. StateModule is never destroyed because it
+// holds the state bucket of every other module.
+func (r *Runner) Destroy(ctx context.Context) error {
+	modules, err := r.preflight(ctx, "destroy", true)
+	if err != nil {
+		return err
+	}
+
+	if !r.config.DryRun && !r.config.AutoApprove {
+		if r.confirm("Type 'yes' to confirm destruction: ") != "yes" {
+			return ErrCancelled
+		}
+		account, err := r.cloud.Account(ctx)
+		if err != nil {
+			return err
+		}
+		if r.confirm("Type the AWS account ID ("+account+") to confirm: ") != account {
+			r.log.Error("account ID mismatch", "account", account)
+			return ErrCancelled
+		}
+	}
+
+	for _, module := range modules {
+		if err := r.destroyModule(ctx, r.stack.Modules[module]); err != nil {
+			return err
+		}
+	}
+
+	if !r.config.DryRun && r.config.Module == "" {
+		if err := removeLocalState(r.stack.Dir); err != nil {
+			r.log.Warn("failed to clean up local terraform files", "error", err)
+		}
+	}
+	r.log.Info("destroy complete", "modules", len(modules), "dry_run", r.config.DryRun)
+	return nil
+}
+
+// destroyModule destroys a module whose state is in S3 and deletes that state
+func (r *Runner) destroyModule(ctx context.Context, m *stack.Module) error {
+	log := r.log.With("module", m.Name)
+
+	if m.Name == stack.StateModule {
+		log.Warn("protected, not destroyed: it holds the S3 state bucket")
+		return nil
+	}
+
+	bucket, err := r.stateBucket(ctx)
+	if err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+	if bucket == "" {
+		log.Warn("no S3 state bucket found, skipping")
+		return nil
+	}
+
+	key := r.stateKey(m.Name)
+	if _, err := r.cloud.GetObject(ctx, bucket, key); errors.Is(err, ErrNotFound) {
+		log.Info("no state in S3, skipping")
+		return nil
+	} else if err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+
+	// No lock table: data_store, which owns it, may already be gone
+	if err := cleanModule(m.Dir); err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+	if err := r.writeBackendConfig(m.Dir, m.Name, bucket, ""); err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+	if err := r.init(ctx, m.Dir); err != nil {
+		return &ModuleError{Module: m.Name, Op: "init", Err: err}
+	}
+
+	vars := varArgs(bucket)
+	if r.config.DryRun {
+		out, err := r.terraform.Run(ctx, m.Dir, append([]string{"plan", "-destroy", "-input=false", "-no-color"}, vars...)...)
+		if err != nil {
+			return &ModuleError{Module: m.Name, Op: "plan", Err: err}
+		}
+		fmt.Fprint(r.config.Stdout, out)
+		return nil
+	}
+
+	if _, err := r.terraform.Run(ctx, m.Dir, append([]string{"destroy", "-input=false", "-no-color", "-auto-approve"}, vars...)...); err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+	log.Info("destroyed")
+
+	if err := r.cloud.DeleteObject(ctx, bucket, key); err != nil {
+		return &ModuleError{Module: m.Name, Op: "destroy", Err: err}
+	}
+	log.Info("deleted state from S3", "bucket", bucket, "key", key)
+	return nil
+}
+
+// removeLocalState deletes .terraform directories and local state files left
+// under the environment, except the state of StateModule
+func removeLocalState(envDir string) error {
+	return filepath.WalkDir(envDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == filepath.Join(envDir, stack.StateModule) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() && d.Name() == ".terraform" {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && (d.Name() == localStateFile || d.Name() == localStateFile+".backup") {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/y3gi/zero-trust-aws/tools/drift"
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+// refreshPlanFile holds the refresh-only plan next to planFile
+const refreshPlanFile = "ztctl-refresh.tfplan"
+
+// Drift compares every deployed module with what is really in AWS. Each
+// module gets a refresh-only plan, whose resource_drift lists the changes
+// made outside terraform, and a normal plan, which tells whether the next
+// apply reverts them. Nothing is applied and no state is changed.
+//
+// A module that fails is recorded in the report and the remaining modules
+// are still checked; the first failure is returned as a *ModuleError.
+func (r *Runner) Drift(ctx context.Context) (drift.Report, error) {
+	report := drift.Report{Env: r.config.Env, Modules: []drift.ModuleReport{}}
+
+	modules, err := r.preflight(ctx, "drift", false)
+	if err != nil {
+		return report, err
+	}
+
+	var failed error
+	for _, module := range modules {
+		moduleReport, err := r.driftModule(ctx, r.stack.Modules[module])
+		if err != nil {
+			r.log.Error("drift check failed", "module", module, "error", err)
+			moduleReport = drift.ModuleReport{Module: module, Error: err.Error()}
+			if failed == nil {
+				failed = err
+			}
+		}
+		report.Modules = append(report.Modules, moduleReport)
+	}
+
+	r.log.Info("drift check complete",
+		"modules", len(modules),
+		"critical", report.Count(drift.SeverityCritical),
+		"warning", report.Count(drift.SeverityWarning),
+		"info", report.Count(drift.SeverityInfo))
+	return report, failed
+}
+
+func (r *Runner) driftModule(ctx context.Context, m *stack.Module) (drift.ModuleReport, error) {
+	log := r.log.With("module", m.Name)
+
+	if err := cleanModule(m.Dir); err != nil {
+		return drift.ModuleReport{}, &ModuleError{Module: m.Name, Op: "drift", Err: err}
+	}
+	bucket, hasState, err := r.prepare(ctx, m, false)
+	if err != nil {
+		return drift.ModuleReport{}, &ModuleError{Module: m.Name, Op: "init", Err: err}
+	}
+	if !hasState {
+		log.Info("not deployed, skipping")
+		return drift.ModuleReport{Module: m.Name, Skipped: "not deployed"}, nil
+	}
+	vars := varArgs(bucket)
+
+	refreshOnly, err := r.detailedPlan(ctx, m.Dir, refreshPlanFile, append([]string{"-refresh-only"}, vars...))
+	if err != nil {
+		return drift.ModuleReport{}, &ModuleError{Module: m.Name, Op: "refresh-only plan", Err: err}
+	}
+	plan, err := r.detailedPlan(ctx, m.Dir, planFile, vars)
+	if err != nil {
+		return drift.ModuleReport{}, &ModuleError{Module: m.Name, Op: "plan", Err: err}
+	}
+
+	report, err := drift.Analyze(m.Name, refreshOnly, plan)
+	if err != nil {
+		return drift.ModuleReport{}, &ModuleError{Module: m.Name, Op: "drift", Err: err}
+	}
+	log.Info("checked", "drift", len(report.Drift), "pending", len(report.Pending))
+	return report, nil
+}
+
+// detailedPlan saves a plan with -detailed-exitcode to file and returns it as
+// JSON. Exit code 2 only means that the plan is not empty.
+func (r *Runner) detailedPlan(ctx context.Context, dir, file string, args []string) ([]byte, error) {
+	args = append([]string{"plan", "-input=false", "-no-color", "-detailed-exitcode", "-out=" + file}, args...)
+	if _, err := r.terraform.Run(ctx, dir, args...); err != nil && ExitCode(err) != 2 {
+		return nil, err
+	}
+	out, err := r.terraform.Run(ctx, dir, "show", "-json", file)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// ExitCode returns the exit code carried by an error from Terraform.Run, or
+// -1 when terraform did not exit on its own
+func ExitCode(err error) int {
+	var exit interface{ ExitCode() int }
+	if errors.As(err, &exit) {
+		return exit.ExitCode()
+	}
+	return -1
+}
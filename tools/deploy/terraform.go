@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Terraform runs terraform commands in a module directory
+type Terraform interface {
+	// Run executes terraform with args in dir and returns its stdout
+	Run(ctx context.Context, dir string, args ...string) (string, error)
+}
+
+// ExecTerraform runs the terraform binary
+type ExecTerraform struct {
+	Binary string
+}
+
+// Run implements Terraform. On failure the error carries terraform's
+// stderr, which is where it reports what went wrong.
+func (e ExecTerraform) Run(ctx context.Context, dir string, args ...string) (string, error) {
+	binary := e.Binary
+	if binary == "" {
+		binary = "terraform"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, append([]string{"-chdir=" + dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("terraform %s: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,107 @@
+package drift
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// Rule classifies drift on resources whose type matches Type (a glob such as
+// "aws_iam_*"). When Attributes is set, only drift below one of those
+// top-level attributes matches.
+type Rule struct {
+	Type       string
+	Attributes []string
+	Severity   Severity
+	Reason     string
+}
+
+// Rules are checked in order and the first match wins. Anything that
+// controls who can reach or decrypt what is critical: IAM, security groups,
+// NACLs, routes, firewall rules, key, bucket, secret and endpoint policies,
+// and audit logging. Tag-only drift is informational, except on the resources
+// whose tags rbac-authorization grants access by: sts:AssumeRole on roles
+// tagged Environment and Tier, and session, secret, table and key access on
+// resources tagged Tier or Purpose.
+var Rules = []Rule{
+	{Type: "aws_iam_role", Attributes: []string{"tags", "tags_all"}, Severity: SeverityCritical, Reason: "tags changed, they decide who may assume the role"},
+	{Type: "aws_secretsmanager_secret", Attributes: []string{"tags", "tags_all"}, Severity: SeverityCritical, Reason: "tags changed, they decide who may read the secret"},
+	{Type: "aws_instance", Attributes: []string{"tags", "tags_all"}, Severity: SeverityCritical, Reason: "tags changed, they decide who may start a session"},
+	{Type: "aws_dynamodb_table", Attributes: []string{"tags", "tags_all"}, Severity: SeverityCritical, Reason: "tags changed, they decide who may access the table"},
+	{Type: "aws_kms_key", Attributes: []string{"tags", "tags_all"}, Severity: SeverityCritical, Reason: "tags changed, they decide who may use the key"},
+	{Type: "*", Attributes: []string{"tags", "tags_all"}, Severity: SeverityInfo, Reason: "tags changed"},
+
+	{Type: "aws_iam_*", Severity: SeverityCritical, Reason: "IAM permissions or trust changed"},
+
+	{Type: "aws_security_group", Attributes: []string{"ingress", "egress"}, Severity: SeverityCritical, Reason: "security group rules changed"},
+	{Type: "aws_security_group_rule", Severity: SeverityCritical, Reason: "security group rules changed"},
+	{Type: "aws_vpc_security_group_*_rule", Severity: SeverityCritical, Reason: "security group rules changed"},
+	{Type: "aws_network_acl*", Severity: SeverityCritical, Reason: "network ACL changed"},
+	{Type: "aws_route_table", Attributes: []string{"route"}, Severity: SeverityCritical, Reason: "routes changed, traffic may bypass the firewall"},
+	{Type: "aws_route", Severity: SeverityCritical, Reason: "routes changed, traffic may bypass the firewall"},
+	{Type: "aws_networkfirewall_*", Severity: SeverityCritical, Reason: "network firewall configuration changed"},
+
+	{Type: "aws_kms_key", Attributes: []string{"policy", "enable_key_rotation", "is_enabled", "deletion_window_in_days"}, Severity: SeverityCritical, Reason: "KMS key policy or rotation changed"},
+	{Type: "aws_kms_key_policy", Severity: SeverityCritical, Reason: "KMS key policy changed"},
+	{Type: "aws_kms_grant", Severity: SeverityCritical, Reason: "KMS grant changed"},
+
+	{Type: "aws_s3_bucket_policy", Severity: SeverityCritical, Reason: "bucket policy changed"},
+	{Type: "aws_s3_bucket_public_access_block", Severity: SeverityCritical, Reason: "public access block changed"},
+	{Type: "aws_s3_bucket_server_side_encryption_configuration", Severity: SeverityCritical, Reason: "bucket encryption changed"},
+	{Type: "aws_s3_bucket", Attributes: []string{"policy", "server_side_encryption_configuration"}, Severity: SeverityCritical, Reason: "bucket policy or encryption changed"},
+
+	{Type: "aws_secretsmanager_secret_policy", Severity: SeverityCritical, Reason: "secret resource policy changed"},
+	{Type: "aws_secretsmanager_secret", Attributes: []string{"policy", "kms_key_id"}, Severity: SeverityCritical, Reason: "secret policy or encryption key changed"},
+
+	{Type: "aws_vpc_endpoint_policy", Severity: SeverityCritical, Reason: "VPC endpoint policy changed"},
+	{Type: "aws_vpc_endpoint", Attributes: []string{"policy", "security_group_ids", "private_dns_enabled"}, Severity: SeverityCritical, Reason: "VPC endpoint policy or access changed"},
+
+	{Type: "aws_cloudtrail", Attributes: []string{"enable_logging", "event_selector", "advanced_event_selector", "is_multi_region_trail", "kms_key_id", "s3_bucket_name"}, Severity: SeverityCritical, Reason: "audit logging changed"},
+	{Type: "aws_flow_log", Severity: SeverityCritical, Reason: "flow logging changed"},
+}
+
+// Classify returns the severity of drift on attribute (a path as reported in
+// Item.Attribute) of a resource of the given type
+func Classify(resourceType, attribute string) (Severity, string) {
+	top := topLevel(attribute)
+	for _, rule := range Rules {
+		if !matchType(rule.Type, resourceType) {
+			continue
+		}
+		if len(rule.Attributes) > 0 && !slices.Contains(rule.Attributes, top) {
+			continue
+		}
+		return rule.Severity, rule.Reason
+	}
+	return SeverityWarning, "configuration changed outside terraform"
+}
+
+// ClassifyResource returns the severity of a resource of the given type being
+// deleted: the most severe rule that applies to any of its attributes. The
+// tag rules do not apply, deleting a resource grants nobody access by its tags.
+func ClassifyResource(resourceType string) (Severity, string) {
+	severity, reason := SeverityWarning, "configuration changed outside terraform"
+	for _, rule := range Rules {
+		if slices.Contains(rule.Attributes, "tags") || !matchType(rule.Type, resourceType) {
+			continue
+		}
+		if rule.Severity.rank() > severity.rank() {
+			severity, reason = rule.Severity, rule.Reason
+		}
+	}
+	return severity, reason
+}
+
+func matchType(pattern, resourceType string) bool {
+	ok, _ := path.Match(pattern, resourceType)
+	return ok
+}
+
+// topLevel returns the attribute name a path starts with, e.g. "ingress" for
+// "ingress[0].cidr_blocks[1]"
+func topLevel(attribute string) string {
+	if i := strings.IndexAny(attribute, ".["); i >= 0 {
+		return attribute[:i]
+	}
+	return attribute
+}
@@ -0,0 +1,451 @@
+// Package drift turns terraform plans into a drift report: every attribute
+// that was changed outside terraform, with the value terraform expected, the
+// value AWS reports, and how much the change matters for security.
+//
+// Drift is read from the resource_drift section of a refresh-only plan,
+// where "before" is the last-known state and "after" is what the refresh
+// found. The normal plan of the same module is used to tell whether the next
+// apply would revert the drift and to list changes that are pending anyway.
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+)
+
+// Severity ranks how much a drift matters for the security posture
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+// Change is one entry of resource_drift or resource_changes in `terraform
+// show -json` output
+type Change struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions         []string        `json:"actions"`
+		Before          json.RawMessage `json:"before"`
+		After           json.RawMessage `json:"after"`
+		BeforeSensitive json.RawMessage `json:"before_sensitive"`
+		AfterSensitive  json.RawMessage `json:"after_sensitive"`
+	} `json:"change"`
+}
+
+// Plan is the subset of a saved plan needed to report drift
+type Plan struct {
+	ResourceDrift   []Change `json:"resource_drift"`
+	ResourceChanges []Change `json:"resource_changes"`
+}
+
+// ParsePlan parses `terraform show -json <planfile>` output, tolerating the
+// annotations added by the setup-terraform wrapper in GitHub Actions
+func ParsePlan(raw []byte) (*Plan, error) {
+	cleaned := bytes.TrimSpace(tfoutput.Clean(raw))
+	if len(cleaned) == 0 {
+		return nil, &tfoutput.ParseError{Raw: string(raw), Err: fmt.Errorf("no JSON document found")}
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(cleaned, &plan); err != nil {
+		return nil, &tfoutput.ParseError{Raw: string(raw), Err: err}
+	}
+	return &plan, nil
+}
+
+// Item is one drifted attribute. Attribute is empty when the whole resource
+// was deleted outside terraform.
+type Item struct {
+	Module    string   `json:"module"`
+	Address   string   `json:"address"`
+	Type      string   `json:"type"`
+	Attribute string   `json:"attribute"`
+	Expected  string   `json:"expected"`
+	Actual    string   `json:"actual"`
+	Severity  Severity `json:"severity"`
+	Reason    string   `json:"reason"`
+	// Reverted is true when the normal plan changes the resource, i.e. the
+	// next apply puts the configured value back
+	Reverted bool `json:"reverted"`
+}
+
+// Pending is a change the normal plan would make
+type Pending struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+}
+
+// ModuleReport is the drift found in one root module
+type ModuleReport struct {
+	Module  string    `json:"module"`
+	Drift   []Item    `json:"drift"`
+	Pending []Pending `json:"pending"`
+	// Skipped explains why the module was not checked, e.g. not deployed
+	Skipped string `json:"skipped,omitempty"`
+	// Error is set when terraform failed for the module
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the drift found across an environment
+type Report struct {
+	Env     string         `json:"env"`
+	Modules []ModuleReport `json:"modules"`
+}
+
+// Analyze builds the report for one module from the `terraform show -json`
+// output of its refresh-only plan and of its normal plan
+func Analyze(module string, refreshOnly, normal []byte) (ModuleReport, error) {
+	report := ModuleReport{Module: module, Drift: []Item{}, Pending: []Pending{}}
+
+	refreshPlan, err := ParsePlan(refreshOnly)
+	if err != nil {
+		return report, fmt.Errorf("refresh-only plan of module %s: %w", module, err)
+	}
+	plan, err := ParsePlan(normal)
+	if err != nil {
+		return report, fmt.Errorf("plan of module %s: %w", module, err)
+	}
+
+	changed := map[string]bool{}
+	for _, c := range plan.ResourceChanges {
+		if c.Mode == "data" || !isChange(c.Change.Actions) {
+			continue
+		}
+		changed[c.Address] = true
+		report.Pending = append(report.Pending, Pending{Address: c.Address, Actions: c.Change.Actions})
+	}
+
+	for _, c := range refreshPlan.ResourceDrift {
+		if c.Mode == "data" {
+			continue
+		}
+		for _, item := range Diff(c) {
+			item.Module = module
+			item.Reverted = changed[c.Address]
+			report.Drift = append(report.Drift, item)
+		}
+	}
+
+	sort.SliceStable(report.Drift, func(i, j int) bool {
+		a, b := report.Drift[i], report.Drift[j]
+		if a.Severity != b.Severity {
+			return a.Severity.rank() > b.Severity.rank()
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Attribute < b.Attribute
+	})
+	return report, nil
+}
+
+func isChange(actions []string) bool {
+	for _, action := range actions {
+		if action != "no-op" && action != "read" {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists the attributes that differ between the before (expected) and
+// after (actual) values of a drifted resource. JSON documents stored in
+// string attributes, such as IAM and KMS policies, are compared by content
+// and diffed statement by statement, so reformatting is not reported.
+func Diff(c Change) []Item {
+	var before, after any
+	_ = json.Unmarshal(c.Change.Before, &before)
+	_ = json.Unmarshal(c.Change.After, &after)
+
+	if after == nil {
+		severity, reason := ClassifyResource(c.Type)
+		return []Item{{
+			Address:  c.Address,
+			Type:     c.Type,
+			Expected: "present",
+			Actual:   "deleted",
+			Severity: severity,
+			Reason:   "deleted outside terraform: " + reason,
+		}}
+	}
+
+	sensitive := map[string]bool{}
+	markSensitive(c.Change.BeforeSensitive, "", sensitive)
+	markSensitive(c.Change.AfterSensitive, "", sensitive)
+
+	expected := map[string]string{}
+	actual := map[string]string{}
+	flatten(before, "", expected)
+	flatten(after, "", actual)
+
+	paths := map[string]bool{}
+	for path := range expected {
+		paths[path] = true
+	}
+	for path := range actual {
+		paths[path] = true
+	}
+
+	var items []Item
+	for path := range paths {
+		want, ok := expected[path]
+		if !ok {
+			want = "null"
+		}
+		got, ok := actual[path]
+		if !ok {
+			got = "null"
+		}
+		if want == got {
+			continue
+		}
+		if isSensitive(path, sensitive) {
+			want, got = "(sensitive)", "(sensitive)"
+		}
+
+		severity, reason := Classify(c.Type, path)
+		items = append(items, Item{
+			Address:   c.Address,
+			Type:      c.Type,
+			Attribute: path,
+			Expected:  want,
+			Actual:    got,
+			Severity:  severity,
+			Reason:    reason,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Attribute < items[j].Attribute })
+	return items
+}
+
+// flatten writes every leaf of value to out keyed by its path, e.g.
+// "ingress[0].cidr_blocks[1]" or "policy.Statement[0].Action"
+func flatten(value any, path string, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			out[path] = "{}"
+		}
+		for key, child := range v {
+			flatten(child, join(path, key), out)
+		}
+	case []any:
+		if len(v) == 0 {
+			out[path] = "[]"
+		}
+		for i, child := range v {
+			flatten(child, path+"["+strconv.Itoa(i)+"]", out)
+		}
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var document any
+			if json.Unmarshal([]byte(trimmed), &document) == nil {
+				flatten(document, path, out)
+				return
+			}
+		}
+		out[path] = strconv.Quote(v)
+	case nil:
+	default:
+		encoded, _ := json.Marshal(v)
+		out[path] = string(encoded)
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// markSensitive records the paths that before_sensitive/after_sensitive
+// mark as true
+func markSensitive(raw json.RawMessage, path string, out map[string]bool) {
+	var value any
+	if json.Unmarshal(raw, &value) != nil {
+		return
+	}
+	var walk func(value any, path string)
+	walk = func(value any, path string) {
+		switch v := value.(type) {
+		case bool:
+			if v {
+				out[path] = true
+			}
+		case map[string]any:
+			for key, child := range v {
+				walk(child, join(path, key))
+			}
+		case []any:
+			for i, child := range v {
+				walk(child, path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	walk(value, path)
+}
+
+// isSensitive reports whether path or one of its parents is sensitive
+func isSensitive(path string, sensitive map[string]bool) bool {
+	if sensitive[""] {
+		return true
+	}
+	for p := range sensitive {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// Items returns the drift of all modules
+func (r Report) Items() []Item {
+	var items []Item
+	for _, m := range r.Modules {
+		items = append(items, m.Drift...)
+	}
+	return items
+}
+
+// Count returns the number of drifted attributes with the given severity
+func (r Report) Count(severity Severity) int {
+	n := 0
+	for _, item := range r.Items() {
+		if item.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// HasCritical reports whether any module drifted in a security-relevant way
+func (r Report) HasCritical() bool {
+	return r.Count(SeverityCritical) > 0
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(path string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// JSON returns the report as indented JSON
+func (r Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Markdown renders the report as a summary with one table of drifted
+// attributes per module
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Drift report: %s\n\n", r.Env)
+
+	critical, warning, info := r.Count(SeverityCritical), r.Count(SeverityWarning), r.Count(SeverityInfo)
+	if critical+warning+info == 0 {
+		b.WriteString("No drift detected.\n")
+	} else {
+		fmt.Fprintf(&b, "**%d critical**, %d warning, %d info\n", critical, warning, info)
+	}
+
+	for _, m := range r.Modules {
+		switch {
+		case m.Error != "":
+			fmt.Fprintf(&b, "\n### %s\n\n❌ %s\n", m.Module, firstLine(m.Error))
+			continue
+		case m.Skipped != "":
+			fmt.Fprintf(&b, "\n### %s\n\nSkipped: %s\n", m.Module, m.Skipped)
+			continue
+		case len(m.Drift) == 0 && len(m.Pending) == 0:
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n### %s\n\n", m.Module)
+		if len(m.Drift) > 0 {
+			b.WriteString("| Severity | Address | Attribute | Expected | Actual | Reverted by apply |\n")
+			b.WriteString("|----------|---------|-----------|----------|--------|-------------------|\n")
+			for _, item := range m.Drift {
+				attribute := item.Attribute
+				if attribute == "" {
+					attribute = "-"
+				}
+				reverted := "no"
+				if item.Reverted {
+					reverted = "yes"
+				}
+				fmt.Fprintf(&b, "| %s | `%s` | `%s` | %s | %s | %s |\n",
+					icon(item.Severity), item.Address, attribute, cell(item.Expected), cell(item.Actual), reverted)
+			}
+		}
+		if len(m.Pending) > 0 {
+			if len(m.Drift) > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("Pending changes in the normal plan:\n\n")
+			for _, p := range m.Pending {
+				fmt.Fprintf(&b, "- `%s` (%s)\n", p.Address, strings.Join(p.Actions, ", "))
+			}
+		}
+	}
+	return b.String()
+}
+
+func icon(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "🔴 critical"
+	case SeverityWarning:
+		return "🟡 warning"
+	}
+	return "⚪ info"
+}
+
+// cell escapes a value for a Markdown table and truncates long values
+func cell(value string) string {
+	const max = 80
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\n", " ")
+	if len(value) > max {
+		value = value[:max] + "…"
+	}
+	return "`" + value + "`"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,169 @@
+package drift
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func find(items []Item, address, attribute string) (Item, bool) {
+	for _, item := range items {
+		if item.Address == address && item.Attribute == attribute {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+func TestAnalyzeSecurityGroupDrift(t *testing.T) {
+	t.Parallel()
+
+	report, err := Analyze("compute", fixture(t, "compute_refresh_only.json"), fixture(t, "compute_plan.json"))
+	require.NoError(t, err)
+
+	ingress, ok := find(report.Drift, "module.compute.aws_security_group.bastion_sg", "ingress[0].cidr_blocks[0]")
+	require.True(t, ok, "the opened CIDR should be reported: %+v", report.Drift)
+	assert.Equal(t, `"10.0.0.0/16"`, ingress.Expected)
+	assert.Equal(t, `"0.0.0.0/0"`, ingress.Actual)
+	assert.Equal(t, SeverityCritical, ingress.Severity)
+	assert.Equal(t, "compute", ingress.Module)
+	assert.True(t, ingress.Reverted, "the normal plan updates the security group")
+
+	tag, ok := find(report.Drift, "module.compute.aws_security_group.bastion_sg", "tags.Owner")
+	require.True(t, ok)
+	assert.Equal(t, "null", tag.Expected)
+	assert.Equal(t, SeverityInfo, tag.Severity)
+
+	instanceType, ok := find(report.Drift, "module.compute.aws_instance.app_server", "instance_type")
+	require.True(t, ok)
+	assert.Equal(t, SeverityWarning, instanceType.Severity)
+	assert.False(t, instanceType.Reverted)
+
+	assert.Equal(t, SeverityCritical, report.Drift[0].Severity, "critical drift is listed first")
+	for _, item := range report.Drift {
+		assert.NotEqual(t, "data.terraform_remote_state.vpc", item.Address, "data sources are not drift")
+	}
+	assert.Equal(t, []Pending{{Address: "module.compute.aws_security_group.bastion_sg", Actions: []string{"update"}}}, report.Pending)
+}
+
+func TestAnalyzeMasksSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	report, err := Analyze("compute", fixture(t, "compute_refresh_only.json"), fixture(t, "no_drift.json"))
+	require.NoError(t, err)
+
+	userData, ok := find(report.Drift, "module.compute.aws_instance.app_server", "user_data")
+	require.True(t, ok)
+	assert.Equal(t, "(sensitive)", userData.Expected)
+	assert.Equal(t, "(sensitive)", userData.Actual)
+}
+
+func TestAnalyzeComparesPoliciesByContent(t *testing.T) {
+	t.Parallel()
+
+	report, err := Analyze("security", fixture(t, "security_refresh_only.json"), fixture(t, "no_drift.json"))
+	require.NoError(t, err)
+
+	for _, item := range report.Drift {
+		assert.NotEqual(t, "module.iam.aws_kms_key_policy.main", item.Address, "a reformatted policy is not drift")
+	}
+
+	action, ok := find(report.Drift, "module.iam.aws_iam_role_policy.app_secrets_policy", "policy.Statement[0].Action")
+	require.True(t, ok, "%+v", report.Drift)
+	assert.Equal(t, `"secretsmanager:GetSecretValue"`, action.Expected)
+	assert.Equal(t, `"secretsmanager:*"`, action.Actual)
+	assert.Equal(t, SeverityCritical, action.Severity)
+
+	deleted, ok := find(report.Drift, "module.iam.aws_iam_role.cloudtrail_role", "")
+	require.True(t, ok)
+	assert.Equal(t, "deleted", deleted.Actual)
+	assert.Equal(t, SeverityCritical, deleted.Severity)
+}
+
+func TestAnalyzeRejectsInvalidPlan(t *testing.T) {
+	t.Parallel()
+
+	_, err := Analyze("vpc", []byte("Error: Backend initialization required"), fixture(t, "no_drift.json"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refresh-only plan of module vpc")
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		resourceType, attribute string
+		want                    Severity
+	}{
+		{"aws_iam_role", "assume_role_policy.Statement[0].Principal.AWS", SeverityCritical},
+		{"aws_iam_role", "tags.Tier", SeverityCritical},
+		{"aws_iam_role", "tags_all.Environment", SeverityCritical},
+		{"aws_secretsmanager_secret", "tags.Tier", SeverityCritical},
+		{"aws_secretsmanager_secret", "description", SeverityWarning},
+		{"aws_instance", "tags.Tier", SeverityCritical},
+		{"aws_vpc", "tags.Team", SeverityInfo},
+		{"aws_security_group", "egress[1].to_port", SeverityCritical},
+		{"aws_security_group", "description", SeverityWarning},
+		{"aws_vpc_security_group_ingress_rule", "cidr_ipv4", SeverityCritical},
+		{"aws_kms_key", "enable_key_rotation", SeverityCritical},
+		{"aws_kms_key", "description", SeverityWarning},
+		{"aws_route_table", "route[0].nat_gateway_id", SeverityCritical},
+		{"aws_networkfirewall_rule_group", "rule_group[0].rules_source[0].rules_string", SeverityCritical},
+		{"aws_vpc_endpoint", "policy.Statement[0].Principal", SeverityCritical},
+		{"aws_cloudtrail", "enable_logging", SeverityCritical},
+		{"aws_cloudwatch_log_group", "retention_in_days", SeverityWarning},
+	}
+	for _, c := range cases {
+		got, _ := Classify(c.resourceType, c.attribute)
+		assert.Equal(t, c.want, got, "%s %s", c.resourceType, c.attribute)
+	}
+
+	deleted, _ := ClassifyResource("aws_kms_key")
+	assert.Equal(t, SeverityCritical, deleted)
+	deleted, _ = ClassifyResource("aws_cloudwatch_log_group")
+	assert.Equal(t, SeverityWarning, deleted)
+	deleted, _ = ClassifyResource("aws_instance")
+	assert.Equal(t, SeverityWarning, deleted)
+}
+
+func TestReportMarkdownAndJSON(t *testing.T) {
+	t.Parallel()
+
+	compute, err := Analyze("compute", fixture(t, "compute_refresh_only.json"), fixture(t, "compute_plan.json"))
+	require.NoError(t, err)
+	report := Report{Env: "dev", Modules: []ModuleReport{
+		{Module: "bootstrap", Drift: []Item{}, Pending: []Pending{}},
+		compute,
+		{Module: "vpc", Error: "terraform plan: exit status 1\nError: No valid credential sources found"},
+		{Module: "secrets", Skipped: "not deployed"},
+	}}
+
+	assert.True(t, report.HasCritical())
+	assert.Equal(t, 1, report.Count(SeverityCritical))
+
+	summary := report.Markdown()
+	assert.Contains(t, summary, "## Drift report: dev")
+	assert.Contains(t, summary, "**1 critical**")
+	assert.Contains(t, summary, "| 🔴 critical | `module.compute.aws_security_group.bastion_sg` | `ingress[0].cidr_blocks[0]` | `\"10.0.0.0/16\"` | `\"0.0.0.0/0\"` | yes |")
+	assert.Contains(t, summary, "❌ terraform plan: exit status 1\n")
+	assert.Contains(t, summary, "Skipped: not deployed")
+	assert.NotContains(t, summary, "### bootstrap", "modules without drift are left out")
+
+	path := filepath.Join(t.TempDir(), "reports", "drift.json")
+	require.NoError(t, report.WriteJSON(path))
+	assert.FileExists(t, path)
+
+	clean := Report{Env: "dev", Modules: []ModuleReport{{Module: "vpc"}}}
+	assert.False(t, clean.HasCritical())
+	assert.Contains(t, clean.Markdown(), "No drift detected.")
+}
@@ -0,0 +1,220 @@
+// Package endpoints compares the AWS services the IAM policies under
+// modules/ allow workloads to call with the VPC endpoints declared there, so
+// that no API call has to leave the VPC through the NAT gateway, and checks
+// that the endpoint policies only let our own principals reach our own
+// resources.
+package endpoints
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Endpoint is an aws_vpc_endpoint
+type Endpoint struct {
+	Address string
+	// Service is the service_name without "com.amazonaws.<region>.", e.g.
+	// "secretsmanager" or "ecr.dkr"
+	Service  string
+	Type     string
+	Position string
+}
+
+// Use is an Allow statement granting actions of a service
+type Use struct {
+	Policy    *iam.Policy
+	Statement *iam.Statement
+}
+
+// Config is the endpoints and the policies of the modules under a root
+type Config struct {
+	Endpoints []*Endpoint
+	Policies  []*iam.Policy
+}
+
+// serviceName matches com.amazonaws.<region>.<service>; the region is
+// usually an interpolation
+var serviceName = regexp.MustCompile(`^com\.amazonaws\.(?:\$\{[^}]*\}|[a-z0-9-]+)\.(.+)$`)
+
+// Load reads the VPC endpoints and IAM policies of every module under
+// root/modules. Positions are relative to root.
+func Load(root string) (*Config, error) {
+	policies, err := iam.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{Policies: policies}
+
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range module.BlocksOfType("resource", "aws_vpc_endpoint") {
+			e := &Endpoint{
+				Address:  block.Address(),
+				Service:  block.String("service_name"),
+				Type:     block.String("vpc_endpoint_type"),
+				Position: tfconfig.Position(root, block.File, block.Line),
+			}
+			if m := serviceName.FindStringSubmatch(e.Service); m != nil {
+				e.Service = m[1]
+			}
+			if e.Type == "" {
+				e.Type = "Gateway"
+			}
+			config.Endpoints = append(config.Endpoints, e)
+		}
+	}
+	return config, nil
+}
+
+// Endpoint returns the endpoint of a service, or nil
+func (c *Config) Endpoint(service string) *Endpoint {
+	for _, e := range c.Endpoints {
+		if e.Service == service {
+			return e
+		}
+	}
+	return nil
+}
+
+// workloadPrincipals are the service principals whose roles run code inside
+// the VPC. Roles only these can assume are used by workloads; roles of other
+// services, such as cloudtrail or vpc-flow-logs, call AWS from outside it.
+var workloadPrincipals = map[string]bool{
+	"ec2.amazonaws.com":       true,
+	"ecs-tasks.amazonaws.com": true,
+	"lambda.amazonaws.com":    true,
+	"eks.amazonaws.com":       true,
+	"batch.amazonaws.com":     true,
+}
+
+// Services returns the service prefixes of the actions workloads are
+// allowed, mapped to the statements allowing them. Identity policies count
+// unless every role they are attached to is assumed only by services that
+// run outside the VPC; unattached policies count, since workloads may get
+// them later.
+func (c *Config) Services() map[string][]Use {
+	external := map[string]bool{}
+	for _, p := range c.Policies {
+		if p.Kind == iam.KindTrust && !workloadTrust(p) {
+			external[p.Address] = true
+		}
+	}
+
+	services := map[string][]Use{}
+	for _, p := range c.Policies {
+		if p.Kind != iam.KindIdentity || len(p.Targets) > 0 && all(p.Targets, external) {
+			continue
+		}
+		for _, s := range p.Statements {
+			if !s.Allows() {
+				continue
+			}
+			seen := map[string]bool{}
+			for _, action := range s.Actions {
+				service := iam.Service(action)
+				if service == "" || tfconfig.IsExpression(action) || seen[service] {
+					continue
+				}
+				seen[service] = true
+				services[service] = append(services[service], Use{p, s})
+			}
+		}
+	}
+	return services
+}
+
+// workloadTrust reports whether a trust policy lets a workload or an AWS
+// principal assume its role
+func workloadTrust(p *iam.Policy) bool {
+	for _, s := range p.Statements {
+		if !s.Allows() {
+			continue
+		}
+		for principalType, identifiers := range s.Principals {
+			if principalType != "Service" {
+				return true
+			}
+			for _, identifier := range identifiers {
+				if workloadPrincipals[identifier] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func all(addresses []string, set map[string]bool) bool {
+	for _, address := range addresses {
+		if !set[address] {
+			return false
+		}
+	}
+	return true
+}
+
+// endpointServices maps the action prefixes whose endpoint service has a
+// different name to the endpoint services serving them
+var endpointServices = map[string][]string{
+	"cloudwatch":        {"monitoring"},
+	"ecr":               {"ecr.api", "ecr.dkr"},
+	"elasticfilesystem": {"elasticfilesystem", "elasticfilesystem-fips"},
+	"states":            {"states", "sync-states"},
+}
+
+// noEndpoint are action prefixes that are not API calls through a regional
+// endpoint, or whose service offers no VPC endpoint
+var noEndpoint = map[string]bool{
+	"iam":           true,
+	"organizations": true,
+	"rds-db":        true, // connect authorizes IAM database authentication tokens, generated locally
+	"route53":       true,
+	"cloudfront":    true,
+	"support":       true,
+}
+
+// covered returns the endpoint serving a service prefix, or nil
+func (c *Config) covered(service string) *Endpoint {
+	names, ok := endpointServices[service]
+	if !ok {
+		names = []string{service}
+	}
+	for _, name := range names {
+		if e := c.Endpoint(name); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// sortedServices returns the keys of services in order
+func sortedServices(services map[string][]Use) []string {
+	keys := make([]string, 0, len(services))
+	for service := range services {
+		keys = append(keys, service)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describe returns "address (Sid)" for a use
+func (u Use) describe() string {
+	return fmt.Sprintf("%s (%s)", u.Policy.Address, u.Statement.Describe())
+}
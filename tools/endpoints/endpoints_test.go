@@ -0,0 +1,74 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+func load(t *testing.T, root string) *Config {
+	t.Helper()
+	c, err := Load(root)
+	require.NoError(t, err)
+	return c
+}
+
+// checks groups findings by check
+func checks(findings []lint.Finding) map[string][]lint.Finding {
+	grouped := map[string][]lint.Finding{}
+	for _, f := range findings {
+		grouped[f.Check] = append(grouped[f.Check], f)
+	}
+	return grouped
+}
+
+func TestRepositoryEndpoints(t *testing.T) {
+	t.Parallel()
+
+	c := load(t, "../..")
+	var services []string
+	for _, e := range c.Endpoints {
+		services = append(services, e.Type+" "+e.Service)
+	}
+	assert.Equal(t, []string{
+		"Gateway dynamodb", "Gateway s3", "Interface secretsmanager", "Interface ssm",
+		"Interface ec2messages", "Interface ssmmessages", "Interface sts", "Interface logs", "Interface kms",
+	}, services)
+
+	assert.Equal(t, []string{"dynamodb", "kms", "logs", "secretsmanager", "ssm", "sts"}, sortedServices(c.Services()),
+		"cloudtrail_policy and vpc_flow_log_policy belong to roles of services outside the VPC, rds-db is only denied")
+	assert.Empty(t, checks(Lint(c))["endpoint-coverage"])
+}
+
+func TestServices(t *testing.T) {
+	t.Parallel()
+
+	c := load(t, "testdata")
+	services := c.Services()
+	assert.Equal(t, []string{"cloudwatch", "ecr", "iam", "kms", "sqs", "ssm"}, sortedServices(services),
+		"sns is only allowed to the CloudTrail role, s3 is only denied")
+
+	var sqs []string
+	for _, u := range services["sqs"] {
+		sqs = append(sqs, u.describe())
+	}
+	assert.Equal(t, []string{"aws_iam_policy.parameters (modules/workload/main.tf:47)", "aws_iam_role_policy.app (Queue)"}, sqs)
+	assert.Equal(t, "aws_vpc_endpoint.ecr_dkr", c.covered("ecr").Address)
+	assert.Nil(t, c.covered("cloudwatch"))
+}
+
+func TestLintCoverage(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	for _, f := range checks(Lint(load(t, "testdata")))["endpoint-coverage"] {
+		got = append(got, f.String())
+	}
+	assert.Equal(t, []string{
+		"modules/workload/main.tf:30: error: aws_iam_role_policy.app: cloudwatch actions are allowed by aws_iam_role_policy.app (Metrics), but no aws_vpc_endpoint serves com.amazonaws.<region>.cloudwatch; calls leave the VPC through the NAT gateway [endpoint-coverage]",
+		"modules/workload/main.tf:47: error: aws_iam_policy.parameters: sqs actions are allowed by aws_iam_policy.parameters (modules/workload/main.tf:47), aws_iam_role_policy.app (Queue), but no aws_vpc_endpoint serves com.amazonaws.<region>.sqs; calls leave the VPC through the NAT gateway [endpoint-coverage]",
+		"modules/workload/main.tf:47: error: aws_iam_policy.parameters: ssm actions are allowed by aws_iam_policy.parameters (modules/workload/main.tf:47), but no aws_vpc_endpoint serves com.amazonaws.<region>.ssm; calls leave the VPC through the NAT gateway [endpoint-coverage]",
+	}, got)
+}
@@ -0,0 +1,31 @@
+package endpoints
+
+import (
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Lint reports every service workloads are allowed to call that has no VPC
+// endpoint, at the first statement allowing it, and every endpoint whose
+// policy lets principals of other accounts, or our principals to buckets and
+// secrets of other accounts, through
+func Lint(c *Config) []lint.Finding {
+	var l lint.Collector
+	lintPolicies(c, &l)
+	services := c.Services()
+	for _, service := range sortedServices(services) {
+		if noEndpoint[service] || c.covered(service) != nil {
+			continue
+		}
+		uses := services[service]
+		var by []string
+		for _, u := range uses {
+			by = append(by, u.describe())
+		}
+		l.Add(lint.SeverityError, "endpoint-coverage", uses[0].Policy.Address, uses[0].Statement.Position,
+			"%s actions are allowed by %s, but no aws_vpc_endpoint serves com.amazonaws.<region>.%s; calls leave the VPC through the NAT gateway",
+			service, strings.Join(by, ", "), service)
+	}
+	return l.Findings()
+}
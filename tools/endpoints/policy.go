@@ -0,0 +1,130 @@
+package endpoints
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// resourceServices are the services whose endpoint policies must restrict
+// resources to our own, mapped to a resource of another account standing for
+// a resource pattern of a statement
+var resourceServices = map[string]func(pattern string) string{
+	"s3":             foreignBucket,
+	"secretsmanager": foreignSecret,
+}
+
+// Policy returns the statements of the endpoint policies set on e, or nil
+// when it has none and uses the default policy, which allows everything
+func (c *Config) Policy(e *Endpoint) []*iam.Statement {
+	var statements []*iam.Statement
+	for _, p := range c.Policies {
+		if p.Kind == iam.KindEndpoint && slices.Contains(p.Targets, e.Address) {
+			statements = append(statements, p.Statements...)
+		}
+	}
+	return statements
+}
+
+// lintPolicies evaluates the policy of every endpoint with a principal of
+// another account, and for s3 and secretsmanager with a resource of another
+// account
+func lintPolicies(c *Config, l *lint.Collector) {
+	for _, e := range c.Endpoints {
+		statements := c.Policy(e)
+		if statements == nil {
+			l.Add(lint.SeverityError, "endpoint-default-policy", e.Address, e.Position,
+				"the endpoint has no policy, so the default policy allows every principal full access to %s through it", e.Service)
+			continue
+		}
+
+		reported := map[*iam.Statement]bool{}
+		for _, action := range probeActions(statements) {
+			result := iam.Evaluate(statements, iam.Foreign(action))
+			if result.Decision == iam.Allowed && !reported[result.Statement] {
+				reported[result.Statement] = true
+				l.Add(lint.SeverityError, "endpoint-principal-scope", e.Address, result.Statement.Position,
+					"%s of the %s endpoint policy allows principals of any account; add an aws:PrincipalAccount or aws:PrincipalOrgID condition",
+					statement(result.Statement), e.Service)
+			}
+		}
+
+		foreign, ok := resourceServices[e.Service]
+		if !ok {
+			continue
+		}
+		reported = map[*iam.Statement]bool{}
+		for _, s := range statements {
+			if !s.Allows() || reported[s] {
+				continue
+			}
+			patterns := s.Resources
+			if patterns == nil {
+				patterns = []string{"*"}
+			}
+			for _, action := range probeActions([]*iam.Statement{s}) {
+				for _, pattern := range patterns {
+					resource := foreign(pattern)
+					result := iam.Evaluate(statements, iam.Request{Action: action, Resource: resource})
+					if result.Decision == iam.Allowed && result.Statement == s && !reported[s] {
+						reported[s] = true
+						l.Add(lint.SeverityError, "endpoint-resource-scope", e.Address, s.Position,
+							"%s of the %s endpoint policy allows %s on %s, which is not ours; restrict Resource to our own ARNs",
+							statement(s), e.Service, action, resource)
+					}
+				}
+			}
+		}
+	}
+}
+
+// statement returns "statement Sid", or "a statement" when it has no Sid
+func statement(s *iam.Statement) string {
+	if s.Sid == "" {
+		return "a statement"
+	}
+	return "statement " + s.Sid
+}
+
+// probeActions returns an action for every action pattern of the Allow
+// statements, with wildcards filled in
+func probeActions(statements []*iam.Statement) []string {
+	var actions []string
+	for _, s := range statements {
+		if !s.Allows() {
+			continue
+		}
+		for _, action := range s.Actions {
+			if probe := strings.ReplaceAll(action, "*", "Get"); !slices.Contains(actions, probe) {
+				actions = append(actions, probe)
+			}
+		}
+	}
+	return actions
+}
+
+// foreignBucket returns a bucket, or object, of another account matching
+// what pattern would match if its bucket were someone else's
+func foreignBucket(pattern string) string {
+	rest, ok := strings.CutPrefix(pattern, "arn:aws:s3:::")
+	if !ok {
+		return "arn:aws:s3:::foreign-bucket/object"
+	}
+	if _, key, ok := strings.Cut(rest, "/"); ok {
+		return "arn:aws:s3:::foreign-bucket/" + strings.ReplaceAll(key, "*", "object")
+	}
+	return "arn:aws:s3:::foreign-bucket"
+}
+
+// foreignSecret returns a secret of another account matching what pattern
+// would match if its account were someone else's
+func foreignSecret(pattern string) string {
+	fields := strings.SplitN(pattern, ":", 7)
+	if len(fields) < 7 {
+		return "arn:aws:secretsmanager:us-east-1:" + iam.ForeignAccount + ":secret:foreign"
+	}
+	fields[4] = iam.ForeignAccount
+	return strings.ReplaceAll(strings.Join(fields, ":"), "*", "foreign")
+}
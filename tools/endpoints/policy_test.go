@@ -0,0 +1,34 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintPolicies(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	for _, f := range Lint(load(t, "testdata")) {
+		if f.Check != "endpoint-coverage" {
+			got = append(got, f.String())
+		}
+	}
+	assert.Equal(t, []string{
+		"modules/endpoints/main.tf:25: error: aws_vpc_endpoint.ecr_dkr: the endpoint has no policy, so the default policy allows every principal full access to ecr.dkr through it [endpoint-default-policy]",
+		"modules/endpoints/main.tf:41: error: aws_vpc_endpoint.s3: statement Organization of the s3 endpoint policy allows s3:GetObject on arn:aws:s3:::foreign-bucket/object, which is not ours; restrict Resource to our own ARNs [endpoint-resource-scope]",
+		"modules/endpoints/main.tf:51: error: aws_vpc_endpoint.s3: a statement of the s3 endpoint policy allows principals of any account; add an aws:PrincipalAccount or aws:PrincipalOrgID condition [endpoint-principal-scope]",
+		"modules/endpoints/main.tf:75: error: aws_vpc_endpoint.secretsmanager: statement AnySecret of the secretsmanager endpoint policy allows secretsmanager:DescribeSecret on arn:aws:secretsmanager:us-east-1:999999999999:secret:foreign, which is not ours; restrict Resource to our own ARNs [endpoint-resource-scope]",
+	}, got, "the kms policy and OwnSecrets are scoped to our account")
+}
+
+func TestForeignResources(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "arn:aws:s3:::foreign-bucket", foreignBucket("arn:aws:s3:::${var.bucket}"))
+	assert.Equal(t, "arn:aws:s3:::foreign-bucket/logs/object", foreignBucket("arn:aws:s3:::${var.bucket}/logs/*"))
+	assert.Equal(t, "arn:aws:s3:::foreign-bucket/object", foreignBucket("*"))
+	assert.Equal(t, "arn:aws:secretsmanager:${var.region}:999999999999:secret:app/foreign",
+		foreignSecret("arn:aws:secretsmanager:${var.region}:*:secret:app/*"))
+}
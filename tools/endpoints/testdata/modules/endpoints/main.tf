@@ -0,0 +1,91 @@
+resource "aws_vpc_endpoint" "kms" {
+  vpc_id            = var.vpc_id
+  service_name      = "com.amazonaws.${var.region}.kms"
+  vpc_endpoint_type = "Interface"
+}
+
+resource "aws_vpc_endpoint_policy" "kms" {
+  vpc_endpoint_id = aws_vpc_endpoint.kms.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Sid       = "OwnAccount"
+      Effect    = "Allow"
+      Principal = "*"
+      Action    = ["kms:Decrypt", "kms:GenerateDataKey*"]
+      Resource  = "*"
+      Condition = {
+        StringEquals = { "aws:PrincipalAccount" = data.aws_caller_identity.current.account_id }
+      }
+    }]
+  })
+}
+
+# no policy: the default allows everything
+resource "aws_vpc_endpoint" "ecr_dkr" {
+  vpc_id            = var.vpc_id
+  service_name      = "com.amazonaws.eu-west-1.ecr.dkr"
+  vpc_endpoint_type = "Interface"
+}
+
+resource "aws_vpc_endpoint" "s3" {
+  vpc_id       = var.vpc_id
+  service_name = "com.amazonaws.${var.region}.s3"
+}
+
+resource "aws_vpc_endpoint_policy" "s3" {
+  vpc_endpoint_id = aws_vpc_endpoint.s3.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Organization"
+        Effect    = "Allow"
+        Principal = "*"
+        Action    = "s3:GetObject"
+        Resource  = "arn:aws:s3:::*/*"
+        Condition = {
+          StringEquals = { "aws:PrincipalOrgID" = var.org_id }
+        }
+      },
+      {
+        Effect    = "Allow"
+        Principal = { AWS = "*" }
+        Action    = "s3:PutObject"
+        Resource  = "arn:aws:s3:::${var.bucket}/*"
+      },
+    ]
+  })
+}
+
+resource "aws_vpc_endpoint" "secretsmanager" {
+  vpc_id            = var.vpc_id
+  service_name      = "com.amazonaws.${var.region}.secretsmanager"
+  vpc_endpoint_type = "Interface"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "OwnSecrets"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "secretsmanager:GetSecretValue"
+        Resource  = "arn:aws:secretsmanager:${var.region}:${data.aws_caller_identity.current.account_id}:secret:app/*"
+      },
+      {
+        Sid       = "AnySecret"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "secretsmanager:DescribeSecret"
+        Resource  = "*"
+      },
+      {
+        Sid       = "NoDeletes"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:Delete*"
+        Resource  = "*"
+      },
+    ]
+  })
+}
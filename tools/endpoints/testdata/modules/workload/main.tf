@@ -0,0 +1,85 @@
+resource "aws_iam_role" "app" {
+  name = "app"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "ec2.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+}
+
+resource "aws_iam_role_policy" "app" {
+  role = aws_iam_role.app.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "Queue"
+        Effect   = "Allow"
+        Action   = ["sqs:SendMessage", "sqs:ReceiveMessage", "kms:Decrypt"]
+        Resource = "*"
+      },
+      {
+        Sid      = "Images"
+        Effect   = "Allow"
+        Action   = ["ecr:GetAuthorizationToken", "ecr:BatchGetImage", "iam:PassRole"]
+        Resource = "*"
+      },
+      {
+        Sid      = "Metrics"
+        Effect   = "Allow"
+        Action   = "cloudwatch:PutMetricData"
+        Resource = "*"
+      },
+      {
+        Sid      = "NoBuckets"
+        Effect   = "Deny"
+        Action   = "s3:*"
+        Resource = "*"
+      },
+    ]
+  })
+}
+
+data "aws_iam_policy_document" "parameters" {
+  statement {
+    actions   = ["ssm:GetParameter", "sqs:DeleteMessage"]
+    resources = ["*"]
+  }
+}
+
+resource "aws_iam_policy" "parameters" {
+  policy = data.aws_iam_policy_document.parameters.json
+}
+
+resource "aws_iam_role_policy_attachment" "parameters" {
+  role       = aws_iam_role.app.name
+  policy_arn = aws_iam_policy.parameters.arn
+}
+
+# CloudTrail publishes from outside the VPC
+resource "aws_iam_role" "trail" {
+  name = "trail"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "cloudtrail.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+}
+
+resource "aws_iam_role_policy" "trail" {
+  role = aws_iam_role.trail.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = "sns:Publish"
+      Resource = "*"
+    }]
+  })
+}
@@ -0,0 +1,171 @@
+// Package escalation searches the IAM configuration under modules/ for
+// privilege-escalation paths: chains of roles a principal can assume, or
+// pass to a service that runs code for it, that end at a principal allowed a
+// sensitive capability such as iam:PutRolePolicy or kms:PutKeyPolicy.
+//
+// Principals are the roles and users of the modules, and the holders of
+// every aws_iam_policy the modules do not attach themselves. Policies are
+// evaluated with tools/iam; conditions on keys the analysis cannot know,
+// such as resource tags, are assumed to hold for Allow statements.
+package escalation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// account is how the modules name their own account
+const account = "${data.aws_caller_identity.current.account_id}"
+
+// Principal is an identity that can make requests
+type Principal struct {
+	// Address is the aws_iam_role or aws_iam_user, or the aws_iam_policy
+	// whose holders the principal stands for
+	Address string
+	Module  string
+	// ARN is the ARN of the principal, with the expressions of its name
+	// left as they are
+	ARN string
+	// Trust is the trust policy of a role
+	Trust    *iam.Policy
+	Policies []*iam.Policy
+	Position string
+}
+
+// identifiers returns how policies can name the principal
+func (p *Principal) identifiers() []string {
+	return []string{p.ARN, "${" + p.Address + ".arn}"}
+}
+
+// Graph is the principals of the modules under a root and the resource
+// policies that may grant them more
+type Graph struct {
+	Principals []*Principal
+	Resources  []*iam.Policy
+}
+
+// Load reads the principals and policies of every module under root/modules.
+// Positions are relative to root.
+func Load(root string) (*Graph, error) {
+	policies, err := iam.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{}
+	principals := map[string]*Principal{}
+	add := func(p *Principal) {
+		principals[p.Module+"/"+p.Address] = p
+		g.Principals = append(g.Principals, p)
+	}
+
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, kind := range []string{"role", "user"} {
+			for _, block := range module.BlocksOfType("resource", "aws_iam_"+kind) {
+				name := block.String("name")
+				if name == "" {
+					name = "${" + block.Address() + ".name}"
+				}
+				add(&Principal{
+					Address:  block.Address(),
+					Module:   filepath.Base(dir),
+					ARN:      fmt.Sprintf("arn:aws:iam::%s:%s/%s", account, kind, name),
+					Position: tfconfig.Position(root, block.File, block.Line),
+				})
+			}
+		}
+	}
+
+	for _, policy := range policies {
+		switch policy.Kind {
+		case iam.KindTrust:
+			if p := principals[policy.Module+"/"+policy.Address]; p != nil {
+				p.Trust = policy
+			}
+		case iam.KindResource:
+			g.Resources = append(g.Resources, policy)
+		case iam.KindIdentity:
+			targets := policy.Targets
+			if len(targets) == 0 {
+				// an unattached policy stands for whoever it is attached to
+				// outside the modules
+				targets = []string{policy.Address}
+			}
+			for _, target := range targets {
+				p := principals[policy.Module+"/"+target]
+				if p == nil {
+					p = &Principal{
+						Address:  target,
+						Module:   policy.Module,
+						ARN:      fmt.Sprintf("arn:aws:iam::%s:role/${%s}", account, target),
+						Position: policy.Position,
+					}
+					add(p)
+				}
+				p.Policies = append(p.Policies, policy)
+			}
+		}
+	}
+	return g, nil
+}
+
+// Grant is a statement of a policy that allows a step of a path
+type Grant struct {
+	Policy    *iam.Policy
+	Statement *iam.Statement
+}
+
+// String returns `statement "Sid" of <address>`, and the condition keys
+// assumed to hold
+func (g Grant) String() string {
+	name := "statement at " + g.Statement.Position
+	if g.Statement.Sid != "" {
+		name = fmt.Sprintf("statement %q", g.Statement.Sid)
+	}
+	text := name + " of " + g.Policy.Address
+	if len(g.Statement.Conditions) > 0 {
+		var keys []string
+		for _, c := range g.Statement.Conditions {
+			keys = append(keys, c.Key)
+		}
+		text += " under conditions on " + strings.Join(keys, ", ")
+	}
+	return text
+}
+
+// grant returns the statement of policies allowing r, if they allow it
+func grant(policies []*iam.Policy, r iam.Request) (Grant, bool) {
+	var statements []*iam.Statement
+	for _, policy := range policies {
+		statements = append(statements, policy.Statements...)
+	}
+	result := iam.Evaluate(statements, r)
+	if result.Decision != iam.Allowed {
+		return Grant{}, false
+	}
+	for _, policy := range policies {
+		for _, s := range policy.Statements {
+			if s == result.Statement {
+				return Grant{Policy: policy, Statement: s}, true
+			}
+		}
+	}
+	return Grant{}, false
+}
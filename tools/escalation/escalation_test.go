@@ -0,0 +1,97 @@
+package escalation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+func load(t *testing.T, root string) *Graph {
+	t.Helper()
+	g, err := Load(root)
+	require.NoError(t, err)
+	return g
+}
+
+// paths returns "from -> ... -> end: capabilities" for every path
+func paths(g *Graph) []string {
+	var described []string
+	for _, p := range g.Paths() {
+		text := p.From.Address
+		for _, step := range p.Steps {
+			text += " -> " + step.To.Address
+		}
+		for i, c := range p.Capabilities {
+			if i == 0 {
+				text += ": "
+			} else {
+				text += ", "
+			}
+			text += c.Action
+		}
+		described = append(described, text)
+	}
+	return described
+}
+
+func TestRepositoryPaths(t *testing.T) {
+	t.Parallel()
+
+	g := load(t, "../..")
+	var principals []string
+	for _, p := range g.Principals {
+		principals = append(principals, p.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_iam_role.app_instance_role", "aws_iam_role.vpc_flow_log_role", "aws_iam_role.cloudtrail_role",
+		"aws_iam_policy.app_server_restricted_access", "aws_iam_policy.bastion_restricted_access",
+		"aws_iam_policy.database_restricted_access", "aws_iam_policy.enforce_tagging",
+	}, principals)
+	assert.Empty(t, g.steps(g.Principals[4]),
+		"the bastion policy allows assuming the environment's roles, but they only trust services")
+	assert.Empty(t, Lint(g))
+}
+
+func TestPaths(t *testing.T) {
+	t.Parallel()
+
+	g := load(t, "testdata")
+	assert.Equal(t, []string{
+		"aws_iam_role.admin: iam:PutRolePolicy, iam:AttachRolePolicy",
+		"aws_iam_role.admin -> aws_iam_role.deployer -> aws_iam_role.functions: kms:PutKeyPolicy, secretsmanager:PutResourcePolicy",
+		"aws_iam_role.deployer -> aws_iam_role.functions: kms:PutKeyPolicy, secretsmanager:PutResourcePolicy",
+		"aws_iam_role.functions: kms:PutKeyPolicy, secretsmanager:PutResourcePolicy",
+		"aws_iam_policy.developer -> aws_iam_role.admin: iam:PutRolePolicy, iam:AttachRolePolicy",
+		"aws_iam_policy.developer -> aws_iam_role.admin -> aws_iam_role.deployer -> aws_iam_role.functions: kms:PutKeyPolicy, secretsmanager:PutResourcePolicy",
+	}, paths(g), "ci runs functions but cannot pass them a role, the account's access to the secret is delegated to identity policies")
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	findings := Lint(load(t, "testdata"))
+	assert.Equal(t, 4, lint.Count(findings, lint.SeverityError))
+	assert.Equal(t, 2, lint.Count(findings, lint.SeverityWarning))
+
+	var messages []string
+	for _, f := range findings {
+		if f.Address == "aws_iam_role.deployer" {
+			messages = append(messages, f.String())
+		}
+	}
+	assert.Equal(t, []string{
+		`modules/iam/main.tf:90: error: aws_iam_role.deployer: aws_iam_role.deployer can act as aws_iam_role.functions: ` +
+			`it passes aws_iam_role.functions to lambda.amazonaws.com (allowed by statement "PassFunctions" of aws_iam_role_policy.deployer ` +
+			`and statement "Functions" of aws_iam_role_policy.deployer, trusted by statement at modules/iam/main.tf:139 of aws_iam_role.functions), ` +
+			`which is allowed kms:PutKeyPolicy by statement "Keys" of aws_iam_role_policy.functions; ` +
+			`secretsmanager:PutResourcePolicy by statement "Functions" of aws_secretsmanager_secret.token [escalation-path]`,
+	}, messages)
+
+	for _, f := range findings {
+		if f.Address == "aws_iam_policy.developer" {
+			assert.Contains(t, f.Message, `trusted by statement "Account" of aws_iam_role.admin under conditions on aws:MultiFactorAuthPresent`)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package escalation
+
+import (
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Lint reports every path from a principal through other roles to a
+// capability as an error, at the statement allowing the first step, and every
+// principal allowed a capability itself as a warning
+func Lint(g *Graph) []lint.Finding {
+	var l lint.Collector
+	for _, p := range g.Paths() {
+		if len(p.Steps) == 0 {
+			l.Add(lint.SeverityWarning, "sensitive-capability", p.From.Address, p.Capabilities[0].Grant.Statement.Position,
+				"%s %s", p.From.Address, p)
+			continue
+		}
+		first := p.Steps[0].Trusted
+		if len(p.Steps[0].Allowed) > 0 {
+			first = p.Steps[0].Allowed[0]
+		}
+		l.Add(lint.SeverityError, "escalation-path", p.From.Address, first.Statement.Position,
+			"%s can act as %s: it %s", p.From.Address, p.End().Address, p)
+	}
+	return l.Findings()
+}
@@ -0,0 +1,278 @@
+package escalation
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+)
+
+// Capabilities are the sensitive actions a path can end at: each lets its
+// holder grant itself, or someone else, more access
+var Capabilities = []string{
+	"iam:PutRolePolicy",
+	"iam:AttachRolePolicy",
+	"iam:UpdateAssumeRolePolicy",
+	"iam:PutUserPolicy",
+	"iam:AttachUserPolicy",
+	"iam:PutGroupPolicy",
+	"iam:AttachGroupPolicy",
+	"iam:AddUserToGroup",
+	"iam:CreatePolicyVersion",
+	"iam:SetDefaultPolicyVersion",
+	"iam:CreateAccessKey",
+	"iam:CreateLoginProfile",
+	"iam:UpdateLoginProfile",
+	"kms:PutKeyPolicy",
+	"kms:CreateGrant",
+	"secretsmanager:PutResourcePolicy",
+	"s3:PutBucketPolicy",
+}
+
+// launchers maps the service principals that run code with a role passed to
+// them to the actions that make them do so
+var launchers = map[string][]string{
+	"ec2.amazonaws.com":            {"ec2:RunInstances"},
+	"lambda.amazonaws.com":         {"lambda:CreateFunction", "lambda:InvokeFunction"},
+	"ecs-tasks.amazonaws.com":      {"ecs:RunTask"},
+	"cloudformation.amazonaws.com": {"cloudformation:CreateStack"},
+	"codebuild.amazonaws.com":      {"codebuild:CreateProject", "codebuild:StartBuild"},
+	"glue.amazonaws.com":           {"glue:CreateDevEndpoint"},
+}
+
+// Step is a principal acting as a role
+type Step struct {
+	To *Principal
+	// Service is the service principal the role is passed to, or "" when
+	// it is assumed
+	Service string
+	// Allowed are the statements allowing sts:AssumeRole, or iam:PassRole
+	// and the actions making the service run code. A role whose trust
+	// policy names the principal needs none.
+	Allowed []Grant
+	// Trusted is the statement of the trust policy letting the principal,
+	// or the service, assume the role
+	Trusted Grant
+}
+
+// Capability is a sensitive action a principal is allowed
+type Capability struct {
+	Action string
+	Grant  Grant
+}
+
+// Path is a chain of steps from a principal to one allowed capabilities
+type Path struct {
+	From         *Principal
+	Steps        []Step
+	Capabilities []Capability
+}
+
+// End returns the principal holding the capabilities
+func (p Path) End() *Principal {
+	if len(p.Steps) == 0 {
+		return p.From
+	}
+	return p.Steps[len(p.Steps)-1].To
+}
+
+// String describes the path, e.g. "assumes aws_iam_role.admin (allowed by
+// statement "Assume" of aws_iam_policy.dev, trusted by statement "Account" of
+// aws_iam_role.admin), which is allowed iam:PutRolePolicy by statement
+// "Admin" of aws_iam_role_policy.admin"
+func (p Path) String() string {
+	var b strings.Builder
+	for _, step := range p.Steps {
+		var allowed []string
+		for _, g := range step.Allowed {
+			if grant := g.String(); !slices.Contains(allowed, grant) {
+				allowed = append(allowed, grant)
+			}
+		}
+		if step.Service == "" {
+			fmt.Fprintf(&b, "assumes %s (", step.To.Address)
+		} else {
+			fmt.Fprintf(&b, "passes %s to %s (", step.To.Address, step.Service)
+		}
+		if len(allowed) > 0 {
+			fmt.Fprintf(&b, "allowed by %s, ", strings.Join(allowed, " and "))
+		}
+		fmt.Fprintf(&b, "trusted by %s), which ", step.Trusted)
+	}
+
+	// group the capabilities by the statement allowing them
+	var grants []string
+	actions := map[string][]string{}
+	for _, c := range p.Capabilities {
+		g := c.Grant.String()
+		if actions[g] == nil {
+			grants = append(grants, g)
+		}
+		actions[g] = append(actions[g], c.Action)
+	}
+	var allowed []string
+	for _, g := range grants {
+		allowed = append(allowed, strings.Join(actions[g], ", ")+" by "+g)
+	}
+	b.WriteString("is allowed " + strings.Join(allowed, "; "))
+	return b.String()
+}
+
+// Paths returns, for every principal, the shortest path to each principal it
+// can act as, itself included, that is allowed a capability
+func (g *Graph) Paths() []Path {
+	var paths []Path
+	for _, from := range g.Principals {
+		type node struct {
+			principal *Principal
+			steps     []Step
+		}
+		visited := map[*Principal]bool{from: true}
+		queue := []node{{principal: from}}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if capabilities := g.capabilities(n.principal); len(capabilities) > 0 {
+				paths = append(paths, Path{From: from, Steps: n.steps, Capabilities: capabilities})
+			}
+			for _, step := range g.steps(n.principal) {
+				if visited[step.To] {
+					continue
+				}
+				visited[step.To] = true
+				queue = append(queue, node{principal: step.To, steps: append(append([]Step{}, n.steps...), step)})
+			}
+		}
+	}
+	return paths
+}
+
+// steps returns the roles p can assume, or pass to a service it can make
+// run code
+func (g *Graph) steps(p *Principal) []Step {
+	var steps []Step
+	for _, role := range g.Principals {
+		if role == p || role.Trust == nil {
+			continue
+		}
+		if trusted, named, ok := g.trusts(role, p); ok {
+			// a trust policy naming the principal is enough, one naming the
+			// account delegates to the identity policies
+			if named {
+				steps = append(steps, Step{To: role, Trusted: trusted})
+				continue
+			}
+			if allowed, ok := grantOn(p.Policies, "sts:AssumeRole", role); ok {
+				steps = append(steps, Step{To: role, Allowed: []Grant{allowed}, Trusted: trusted})
+				continue
+			}
+		}
+
+		pass, ok := grantOn(p.Policies, "iam:PassRole", role)
+		if !ok {
+			continue
+		}
+		for _, service := range sortedLaunchers() {
+			trusted, ok := grant([]*iam.Policy{role.Trust}, iam.Request{Principal: service, Action: "sts:AssumeRole"})
+			if !ok {
+				continue
+			}
+			allowed := []Grant{pass}
+			for _, action := range launchers[service] {
+				if g, ok := grant(p.Policies, iam.Request{Action: action}); ok {
+					allowed = append(allowed, g)
+				}
+			}
+			if len(allowed) == 1+len(launchers[service]) {
+				steps = append(steps, Step{To: role, Service: service, Allowed: allowed, Trusted: trusted})
+				break
+			}
+		}
+	}
+	return steps
+}
+
+// grantOn returns the statement of policies allowing action on role, named
+// by its ARN or its arn attribute
+func grantOn(policies []*iam.Policy, action string, role *Principal) (Grant, bool) {
+	for _, identifier := range role.identifiers() {
+		if allowed, ok := grant(policies, iam.Request{Action: action, Resource: identifier}); ok {
+			return allowed, true
+		}
+	}
+	return Grant{}, false
+}
+
+// trusts returns the statement of the trust policy of role letting p assume
+// it, and whether the statement names p rather than its account
+func (g *Graph) trusts(role, p *Principal) (trusted Grant, named bool, ok bool) {
+	for _, identifier := range p.identifiers() {
+		if trusted, ok := grant([]*iam.Policy{role.Trust}, iam.Request{Principal: identifier, Action: "sts:AssumeRole"}); ok {
+			return trusted, names(trusted.Statement, identifier), true
+		}
+	}
+	return Grant{}, false, false
+}
+
+// capabilities returns the capabilities p is allowed by its identity
+// policies, or by resource policies naming it
+func (g *Graph) capabilities(p *Principal) []Capability {
+	var capabilities []Capability
+	for _, action := range Capabilities {
+		if allowed, ok := grant(p.Policies, iam.Request{Action: action}); ok {
+			capabilities = append(capabilities, Capability{Action: action, Grant: allowed})
+			continue
+		}
+		for _, policy := range g.Resources {
+			if allowed, ok := g.resourceGrant(policy, p, action); ok {
+				capabilities = append(capabilities, Capability{Action: action, Grant: allowed})
+				break
+			}
+		}
+	}
+	return capabilities
+}
+
+// resourceGrant returns the statement of a resource policy allowing p an
+// action by naming it. Statements naming the account, as key policies do,
+// only delegate to identity policies and are not grants.
+func (g *Graph) resourceGrant(policy *iam.Policy, p *Principal, action string) (Grant, bool) {
+	for _, identifier := range p.identifiers() {
+		var named []*iam.Statement
+		for _, s := range policy.Statements {
+			if !s.Allows() || names(s, identifier) {
+				named = append(named, s)
+			}
+		}
+		result := iam.Evaluate(named, iam.Request{Principal: identifier, Action: action})
+		if result.Decision == iam.Allowed {
+			return Grant{Policy: policy, Statement: result.Statement}, true
+		}
+	}
+	return Grant{}, false
+}
+
+// names reports whether a statement names identifier, or every principal,
+// among its AWS principals
+func names(s *iam.Statement, identifier string) bool {
+	if _, ok := s.Principals["*"]; ok {
+		return true
+	}
+	for _, principal := range s.Principals["AWS"] {
+		if principal == identifier || principal == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedLaunchers() []string {
+	services := make([]string, 0, len(launchers))
+	for service := range launchers {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
@@ -0,0 +1,189 @@
+# Roles chaining to sensitive capabilities
+
+data "aws_caller_identity" "current" {}
+
+# Attached to developers outside the modules
+resource "aws_iam_policy" "developer" {
+  name = "developer"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "AssumeRoles"
+        Effect   = "Allow"
+        Action   = "sts:AssumeRole"
+        Resource = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:role/${var.env}-*"
+      }
+    ]
+  })
+}
+
+# Trusts the whole account
+resource "aws_iam_role" "admin" {
+  name = "${var.env}-admin"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Account"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          Bool = { "aws:MultiFactorAuthPresent" = "true" }
+        }
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "admin" {
+  name = "admin"
+  role = aws_iam_role.admin.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "Roles"
+        Effect   = "Allow"
+        Action   = ["iam:PutRolePolicy", "iam:AttachRolePolicy"]
+        Resource = "*"
+      }
+    ]
+  })
+}
+
+# Trusts the admin role only, and can deploy functions running as the
+# functions role
+resource "aws_iam_role" "deployer" {
+  name = "${var.env}-deployer"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Admin"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.admin.arn }
+        Action    = "sts:AssumeRole"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "deployer" {
+  name = "deployer"
+  role = aws_iam_role.deployer.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "Functions"
+        Effect   = "Allow"
+        Action   = ["lambda:CreateFunction", "lambda:InvokeFunction"]
+        Resource = "*"
+      },
+      {
+        Sid      = "PassFunctions"
+        Effect   = "Allow"
+        Action   = "iam:PassRole"
+        Resource = aws_iam_role.functions.arn
+      }
+    ]
+  })
+}
+
+# Also deploys functions, but cannot pass a role to them
+resource "aws_iam_role" "ci" {
+  name = "ci"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect    = "Allow"
+        Principal = { Service = "codebuild.amazonaws.com" }
+        Action    = "sts:AssumeRole"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "ci" {
+  name = "ci"
+  role = aws_iam_role.ci.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "Functions"
+        Effect   = "Allow"
+        Action   = "lambda:*"
+        Resource = "*"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role" "functions" {
+  name = "functions"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect    = "Allow"
+        Principal = { Service = "lambda.amazonaws.com" }
+        Action    = "sts:AssumeRole"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "functions" {
+  name = "functions"
+  role = aws_iam_role.functions.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "Keys"
+        Effect   = "Allow"
+        Action   = "kms:PutKeyPolicy"
+        Resource = "*"
+      }
+    ]
+  })
+}
+
+# Lets the functions role manage the secret's policy, the rest of the
+# account only read it
+resource "aws_secretsmanager_secret" "token" {
+  name = "token"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Account"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "secretsmanager:*"
+        Resource  = "*"
+      },
+      {
+        Sid       = "Functions"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.functions.arn }
+        Action    = "secretsmanager:PutResourcePolicy"
+        Resource  = "*"
+      }
+    ]
+  })
+}
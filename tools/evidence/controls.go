@@ -0,0 +1,273 @@
+package evidence
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tenet is one of the seven tenets of zero trust in NIST SP 800-207,
+// section 2.1
+type Tenet struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// Tenets lists the NIST SP 800-207 tenets that docs/SECURITY.md claims
+var Tenets = []Tenet{
+	{1, "All data sources and computing services are considered resources."},
+	{2, "All communication is secured regardless of network location."},
+	{3, "Access to individual enterprise resources is granted on a per-session basis."},
+	{4, "Access to resources is determined by dynamic policy."},
+	{5, "The enterprise monitors and measures the integrity and security posture of all owned and associated assets."},
+	{6, "All resource authentication and authorization are dynamic and strictly enforced before access is allowed."},
+	{7, "The enterprise collects as much information as possible about the current state of assets, network infrastructure and communications and uses it to improve its security posture."},
+}
+
+// Control is a zero-trust control that auditors ask evidence for
+type Control struct {
+	ID     string
+	Title  string
+	Tenets []int
+	// UnitTests are the test/unit tests that check the control
+	UnitTests []string
+	// Resources maps the resource types that implement the control to the
+	// planned attributes collected as evidence
+	Resources map[string][]string
+	// PlanRules check planned values of those resources
+	PlanRules []PlanRule
+}
+
+// PlanRule checks the planned values of every resource of Type. Check
+// returns "" when the resource complies, or what is wrong with it.
+type PlanRule struct {
+	Name  string
+	Type  string
+	Check func(after map[string]interface{}) string
+}
+
+// Controls are the controls collected into the bundle
+var Controls = []Control{
+	{
+		ID:        "encryption-at-rest",
+		Title:     "Encryption at rest with customer managed KMS keys",
+		Tenets:    []int{1, 5},
+		UnitTests: []string{"TestS3BucketEncryption", "TestDatabaseEncryption", "TestRDSEncryption", "TestKMSKeyRotation"},
+		Resources: map[string][]string{
+			"aws_kms_key":        {"enable_key_rotation", "deletion_window_in_days", "key_usage"},
+			"aws_kms_key_policy": {"policy"},
+			"aws_s3_bucket_server_side_encryption_configuration": {"rule"},
+			"aws_dynamodb_table":        {"name", "server_side_encryption"},
+			"aws_secretsmanager_secret": {"name", "kms_key_id"},
+			"aws_instance":              {"root_block_device"},
+		},
+		PlanRules: []PlanRule{
+			{Name: "kms-key-rotation", Type: "aws_kms_key", Check: func(after map[string]interface{}) string {
+				return unless(after["enable_key_rotation"] == true, "enable_key_rotation is not true")
+			}},
+			{Name: "s3-default-encryption", Type: "aws_s3_bucket_server_side_encryption_configuration", Check: func(after map[string]interface{}) string {
+				for _, rule := range list(after["rule"]) {
+					for _, d := range list(field(rule, "apply_server_side_encryption_by_default")) {
+						if algorithm, _ := field(d, "sse_algorithm").(string); algorithm == "aws:kms" || algorithm == "aws:kms:dsse" || algorithm == "AES256" {
+							return ""
+						}
+					}
+				}
+				return "no default sse_algorithm"
+			}},
+			{Name: "dynamodb-kms-encryption", Type: "aws_dynamodb_table", Check: func(after map[string]interface{}) string {
+				for _, sse := range list(after["server_side_encryption"]) {
+					if field(sse, "enabled") == true {
+						return ""
+					}
+				}
+				return "server_side_encryption with a customer managed key is not enabled"
+			}},
+			{Name: "secret-kms-key", Type: "aws_secretsmanager_secret", Check: func(after map[string]interface{}) string {
+				return unless(after["kms_key_id"] != "", "kms_key_id is empty, the AWS managed key is used")
+			}},
+			{Name: "ebs-root-volume-encrypted", Type: "aws_instance", Check: func(after map[string]interface{}) string {
+				for _, volume := range list(after["root_block_device"]) {
+					if field(volume, "encrypted") != true {
+						return "root_block_device is not encrypted"
+					}
+				}
+				return ""
+			}},
+		},
+	},
+	{
+		ID:        "least-privilege-iam",
+		Title:     "Least-privilege IAM",
+		Tenets:    []int{3, 4, 6},
+		UnitTests: []string{"TestNoWildcardActions", "TestNoWildcardResources", "TestNoHardcodedAccountID", "TestNoHardcodedSecrets"},
+		Resources: map[string][]string{
+			"aws_iam_role":             {"name", "assume_role_policy", "max_session_duration"},
+			"aws_iam_role_policy":      {"name", "policy"},
+			"aws_iam_policy":           {"name", "policy"},
+			"aws_iam_instance_profile": {"name", "role"},
+		},
+		PlanRules: []PlanRule{
+			{Name: "no-wildcard-actions", Type: "aws_iam_role_policy", Check: noWildcards},
+			{Name: "no-wildcard-actions", Type: "aws_iam_policy", Check: noWildcards},
+			{Name: "no-public-trust", Type: "aws_iam_role", Check: func(after map[string]interface{}) string {
+				for _, statement := range statements(after["assume_role_policy"]) {
+					principal := field(statement, "Principal")
+					if principal == "*" || field(principal, "AWS") == "*" {
+						return "role can be assumed by any principal"
+					}
+				}
+				return ""
+			}},
+		},
+	},
+	{
+		ID:        "audit-logging",
+		Title:     "Audit logging of API calls and network flows",
+		Tenets:    []int{5, 7},
+		UnitTests: []string{"TestCloudTrailEnabled", "TestVPCFlowLogs"},
+		Resources: map[string][]string{
+			"aws_cloudtrail":              {"name", "enable_logging", "is_multi_region_trail", "enable_log_file_validation", "kms_key_id", "cloud_watch_logs_group_arn"},
+			"aws_flow_log":                {"traffic_type", "log_destination_type", "vpc_id"},
+			"aws_cloudwatch_log_group":    {"name", "retention_in_days", "kms_key_id"},
+			"aws_cloudwatch_metric_alarm": {"alarm_name", "metric_name", "threshold"},
+		},
+		PlanRules: []PlanRule{
+			{Name: "cloudtrail-multi-region-validated", Type: "aws_cloudtrail", Check: func(after map[string]interface{}) string {
+				switch {
+				case after["enable_logging"] == false:
+					return "logging is disabled"
+				case after["is_multi_region_trail"] != true:
+					return "trail is not multi-region"
+				case after["enable_log_file_validation"] != true:
+					return "log file validation is disabled"
+				}
+				return ""
+			}},
+			{Name: "flow-logs-all-traffic", Type: "aws_flow_log", Check: func(after map[string]interface{}) string {
+				return unless(after["traffic_type"] == "ALL", fmt.Sprintf("traffic_type is %v, not ALL", after["traffic_type"]))
+			}},
+			{Name: "log-retention", Type: "aws_cloudwatch_log_group", Check: func(after map[string]interface{}) string {
+				days, _ := after["retention_in_days"].(float64)
+				return unless(days > 0, "retention_in_days is not set")
+			}},
+		},
+	},
+	{
+		ID:        "network-segmentation",
+		Title:     "Network segmentation and inspection",
+		Tenets:    []int{2, 4},
+		UnitTests: []string{"TestSecurityGroupRestrictedSSH", "TestNoPublicRDS"},
+		Resources: map[string][]string{
+			"aws_security_group":                  {"name", "ingress", "egress"},
+			"aws_subnet":                          {"cidr_block", "map_public_ip_on_launch", "tags"},
+			"aws_route_table":                     {"route", "tags"},
+			"aws_networkfirewall_firewall":        {"name", "subnet_mapping"},
+			"aws_networkfirewall_firewall_policy": {"firewall_policy"},
+			"aws_networkfirewall_rule_group":      {"name", "type", "rule_group"},
+			"aws_vpc_endpoint":                    {"service_name", "vpc_endpoint_type", "private_dns_enabled", "policy"},
+		},
+		PlanRules: []PlanRule{
+			{Name: "no-admin-ports-from-internet", Type: "aws_security_group", Check: func(after map[string]interface{}) string {
+				for _, rule := range list(after["ingress"]) {
+					if !openToInternet(rule) {
+						continue
+					}
+					from, _ := field(rule, "from_port").(float64)
+					to, _ := field(rule, "to_port").(float64)
+					for _, port := range []float64{22, 3389} {
+						if from <= port && port <= to {
+							return fmt.Sprintf("port %.0f is open to the internet", port)
+						}
+					}
+				}
+				return ""
+			}},
+			{Name: "firewall-has-endpoints", Type: "aws_networkfirewall_firewall", Check: func(after map[string]interface{}) string {
+				return unless(len(list(after["subnet_mapping"])) > 0, "firewall has no subnet_mapping")
+			}},
+		},
+	},
+}
+
+func unless(ok bool, problem string) string {
+	if ok {
+		return ""
+	}
+	return problem
+}
+
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func field(v interface{}, name string) interface{} {
+	m, _ := v.(map[string]interface{})
+	return m[name]
+}
+
+func strs(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// statements returns the statements of a policy document stored as JSON in
+// a planned string attribute
+func statements(policy interface{}) []interface{} {
+	document := decodeJSON(policy)
+	if s := list(field(document, "Statement")); s != nil {
+		return s
+	}
+	if s := field(document, "Statement"); s != nil {
+		return []interface{}{s}
+	}
+	return nil
+}
+
+func noWildcards(after map[string]interface{}) string {
+	var problems []string
+	for _, statement := range statements(after["policy"]) {
+		if field(statement, "Effect") != "Allow" {
+			continue
+		}
+		for _, action := range strs(field(statement, "Action")) {
+			if action == "*" || strings.HasSuffix(action, ":*") {
+				problems = append(problems, "action "+action)
+			}
+		}
+		for _, resource := range strs(field(statement, "Resource")) {
+			if resource == "*" {
+				problems = append(problems, "resource *")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return "allows " + strings.Join(problems, ", ")
+	}
+	return ""
+}
+
+func openToInternet(rule interface{}) bool {
+	for _, cidr := range strs(field(rule, "cidr_blocks")) {
+		if cidr == "0.0.0.0/0" {
+			return true
+		}
+	}
+	for _, cidr := range strs(field(rule, "ipv6_cidr_blocks")) {
+		if cidr == "::/0" {
+			return true
+		}
+	}
+	return false
+}
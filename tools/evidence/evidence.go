@@ -0,0 +1,429 @@
+// Package evidence builds the compliance evidence bundle handed to auditors.
+//
+// For every zero-trust control (encryption at rest, least-privilege IAM,
+// audit logging, network segmentation) the bundle holds the results of the
+// test/unit rules that check it, the results of plan-level rules, the HCL of
+// the resources that implement it and their planned attributes. Controls are
+// mapped to the NIST SP 800-207 tenets.
+//
+// The bundle is a tar.gz archive with a manifest.json recording the git
+// commit and the SHA-256 of every evidence file, and a SHA256SUMS file that
+// `sha256sum -c` can verify after extraction.
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+)
+
+// Rule statuses. NotRun means the rule's input (unit test output or plans)
+// was not provided.
+const (
+	StatusPass   = "pass"
+	StatusFail   = "fail"
+	StatusSkip   = "skip"
+	StatusNotRun = "not_run"
+)
+
+// Input is everything the bundle is built from
+type Input struct {
+	// Root is the repository root containing modules/
+	Root      string
+	Env       string
+	GitCommit string
+	// GitDirty is true when the working tree had uncommitted changes
+	GitDirty bool
+	// Unit holds test/unit results by test name; nil when not run
+	Unit map[string]UnitResult
+	// Plans holds `terraform show -json` output by module; nil when no
+	// plans were provided
+	Plans map[string][]byte
+	Now   time.Time
+}
+
+// RuleResult is the outcome of one unit test or of one plan rule on one
+// resource
+type RuleResult struct {
+	Rule     string `json:"rule"`
+	Kind     string `json:"kind"`
+	Resource string `json:"resource,omitempty"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ControlResult is the evidence collected for one control
+type ControlResult struct {
+	ID       string       `json:"id"`
+	Title    string       `json:"title"`
+	Tenets   []int        `json:"tenets"`
+	Status   string       `json:"status"`
+	Rules    []RuleResult `json:"rules"`
+	Evidence []string     `json:"evidence"`
+}
+
+// File is one file of the bundle with its hash
+type File struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// Manifest describes the bundle
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	GitCommit   string          `json:"git_commit"`
+	GitDirty    bool            `json:"git_dirty"`
+	Env         string          `json:"env"`
+	Framework   string          `json:"framework"`
+	Tenets      []Tenet         `json:"tenets"`
+	Controls    []ControlResult `json:"controls"`
+	Files       []File          `json:"files"`
+}
+
+// Bundle is the manifest and the content of every evidence file
+type Bundle struct {
+	Manifest Manifest
+	Files    map[string][]byte
+}
+
+// Build runs the plan rules and collects the evidence for every control
+func Build(in Input) (*Bundle, error) {
+	b := &Bundle{
+		Manifest: Manifest{
+			GeneratedAt: in.Now.UTC(),
+			GitCommit:   in.GitCommit,
+			GitDirty:    in.GitDirty,
+			Env:         in.Env,
+			Framework:   "NIST SP 800-207",
+			Tenets:      Tenets,
+		},
+		Files: map[string][]byte{},
+	}
+
+	modules, err := loadModules(filepath.Join(in.Root, "modules"))
+	if err != nil {
+		return nil, err
+	}
+	plans, err := parsePlans(in.Plans)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, control := range Controls {
+		result := ControlResult{ID: control.ID, Title: control.Title, Tenets: control.Tenets, Rules: []RuleResult{}, Evidence: []string{}}
+		dir := "controls/" + control.ID + "/"
+
+		unit := unitResults(control, in.Unit)
+		result.Rules = append(result.Rules, unit...)
+		if in.Unit != nil {
+			if err := b.addJSON(dir+"unit-tests.json", selectUnit(control, in.Unit), &result); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, m := range modules {
+			for _, block := range m.blocks {
+				if _, ok := control.Resources[block.Labels[0]]; !ok {
+					continue
+				}
+				path := fmt.Sprintf("%shcl/%s/%s.tf", dir, m.name, block.Address())
+				header := fmt.Sprintf("# modules/%s/%s:%d\n", m.name, filepath.Base(block.File), block.Line)
+				b.add(path, []byte(header+block.Text()+"\n"), &result)
+			}
+		}
+
+		if plans == nil {
+			result.Rules = append(result.Rules, RuleResult{Rule: "plan rules", Kind: "plan", Status: StatusNotRun, Detail: "no plans provided"})
+		}
+		for _, module := range sortedKeys(plans) {
+			attributes, rules := evaluatePlan(control, plans[module])
+			result.Rules = append(result.Rules, rules...)
+			if len(attributes) > 0 {
+				if err := b.addJSON(fmt.Sprintf("%splan/%s.json", dir, module), attributes, &result); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		result.Status = controlStatus(result.Rules)
+		b.Manifest.Controls = append(b.Manifest.Controls, result)
+	}
+
+	b.add("summary.md", []byte(b.summary()), nil)
+	return b, nil
+}
+
+func (b *Bundle) add(path string, content []byte, control *ControlResult) {
+	b.Files[path] = content
+	if control != nil {
+		control.Evidence = append(control.Evidence, path)
+	}
+}
+
+func (b *Bundle) addJSON(path string, value interface{}, control *ControlResult) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	b.add(path, append(data, '\n'), control)
+	return nil
+}
+
+// controlStatus fails a control when any rule failed and passes it when at
+// least one rule passed
+func controlStatus(rules []RuleResult) string {
+	status := StatusNotRun
+	for _, r := range rules {
+		switch r.Status {
+		case StatusFail:
+			return StatusFail
+		case StatusPass:
+			status = StatusPass
+		}
+	}
+	return status
+}
+
+type module struct {
+	name   string
+	blocks []*tfconfig.Block
+}
+
+// loadModules returns the resource blocks of every module under dir
+func loadModules(dir string) ([]module, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []module
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		m, err := tfconfig.LoadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, module{name: entry.Name(), blocks: m.BlocksOfType("resource")})
+	}
+	return modules, nil
+}
+
+// plannedResource is a managed resource as it will be once the plan is applied
+type plannedResource struct {
+	Address string                 `json:"address"`
+	Type    string                 `json:"type"`
+	After   map[string]interface{} `json:"after"`
+}
+
+func parsePlans(raw map[string][]byte) (map[string][]plannedResource, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	plans := map[string][]plannedResource{}
+	for module, planJSON := range raw {
+		var p struct {
+			ResourceChanges []struct {
+				Address string `json:"address"`
+				Mode    string `json:"mode"`
+				Type    string `json:"type"`
+				Change  struct {
+					After map[string]interface{} `json:"after"`
+				} `json:"change"`
+			} `json:"resource_changes"`
+		}
+		if err := json.Unmarshal(bytes.TrimSpace(tfoutput.Clean(planJSON)), &p); err != nil {
+			return nil, fmt.Errorf("plan of module %s: %w", module, &tfoutput.ParseError{Raw: string(planJSON), Err: err})
+		}
+
+		resources := []plannedResource{}
+		for _, rc := range p.ResourceChanges {
+			if rc.Mode == "managed" && rc.Change.After != nil {
+				resources = append(resources, plannedResource{Address: rc.Address, Type: rc.Type, After: rc.Change.After})
+			}
+		}
+		plans[module] = resources
+	}
+	return plans, nil
+}
+
+// evaluatePlan runs the control's plan rules on one module's plan and
+// returns the collected attributes with the rule results
+func evaluatePlan(control Control, resources []plannedResource) ([]plannedResource, []RuleResult) {
+	var attributes []plannedResource
+	var results []RuleResult
+
+	for _, r := range resources {
+		keys, ok := control.Resources[r.Type]
+		if !ok {
+			continue
+		}
+		selected := map[string]interface{}{}
+		for _, key := range keys {
+			if v, ok := r.After[key]; ok {
+				selected[key] = decodeJSON(v)
+			}
+		}
+		attributes = append(attributes, plannedResource{Address: r.Address, Type: r.Type, After: selected})
+
+		for _, rule := range control.PlanRules {
+			if rule.Type != r.Type {
+				continue
+			}
+			result := RuleResult{Rule: rule.Name, Kind: "plan", Resource: r.Address, Status: StatusPass}
+			if problem := rule.Check(r.After); problem != "" {
+				result.Status, result.Detail = StatusFail, problem
+			}
+			results = append(results, result)
+		}
+	}
+	return attributes, results
+}
+
+// decodeJSON returns the decoded document when v is a JSON object or array
+// stored as a string, such as a policy, and v otherwise
+func decodeJSON(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return v
+	}
+	var document interface{}
+	if json.Unmarshal([]byte(trimmed), &document) != nil {
+		return v
+	}
+	return document
+}
+
+func sortedKeys(m map[string][]plannedResource) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// summary renders the tenet mapping and control results for humans
+func (b *Bundle) summary() string {
+	var s strings.Builder
+	m := b.Manifest
+	fmt.Fprintf(&s, "# Zero trust evidence: %s\n\n", m.Env)
+	fmt.Fprintf(&s, "- Generated: %s\n- Commit: %s", m.GeneratedAt.Format(time.RFC3339), m.GitCommit)
+	if m.GitDirty {
+		s.WriteString(" (with uncommitted changes)")
+	}
+	fmt.Fprintf(&s, "\n- Framework: %s\n\n", m.Framework)
+
+	s.WriteString("## Tenets\n\n| Tenet | Controls |\n|-------|----------|\n")
+	for _, tenet := range m.Tenets {
+		var controls []string
+		for _, c := range m.Controls {
+			for _, id := range c.Tenets {
+				if id == tenet.ID {
+					controls = append(controls, fmt.Sprintf("%s (%s)", c.ID, c.Status))
+				}
+			}
+		}
+		if len(controls) == 0 {
+			controls = []string{"-"}
+		}
+		fmt.Fprintf(&s, "| %d. %s | %s |\n", tenet.ID, tenet.Text, strings.Join(controls, ", "))
+	}
+
+	for _, c := range m.Controls {
+		fmt.Fprintf(&s, "\n## %s: %s\n\nStatus: **%s**\n\n| Rule | Kind | Resource | Status | Detail |\n|------|------|----------|--------|--------|\n", c.ID, c.Title, c.Status)
+		for _, r := range c.Rules {
+			fmt.Fprintf(&s, "| %s | %s | %s | %s | %s |\n", r.Rule, r.Kind, r.Resource, r.Status, r.Detail)
+		}
+		fmt.Fprintf(&s, "\n%d evidence file(s) under `controls/%s/`.\n", len(c.Evidence), c.ID)
+	}
+	return s.String()
+}
+
+// Failed returns the controls with a failing rule
+func (b *Bundle) Failed() []string {
+	var failed []string
+	for _, c := range b.Manifest.Controls {
+		if c.Status == StatusFail {
+			failed = append(failed, c.ID)
+		}
+	}
+	return failed
+}
+
+// WriteArchive writes the bundle as a tar.gz: every evidence file, then
+// manifest.json with their hashes, then SHA256SUMS covering all of them
+func (b *Bundle) WriteArchive(w io.Writer) error {
+	paths := make([]string, 0, len(b.Files))
+	for path := range b.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	b.Manifest.Files = make([]File, 0, len(paths))
+	for _, path := range paths {
+		b.Manifest.Files = append(b.Manifest.Files, File{Path: path, SHA256: hash(b.Files[path]), Size: len(b.Files[path])})
+	}
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifest = append(manifest, '\n')
+
+	var sums strings.Builder
+	for _, f := range b.Manifest.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	fmt.Fprintf(&sums, "%s  %s\n", hash(manifest), "manifest.json")
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	write := func(path string, content []byte) error {
+		header := &tar.Header{Name: path, Mode: 0o644, Size: int64(len(content)), ModTime: b.Manifest.GeneratedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	for _, path := range paths {
+		if err := write(path, b.Files[path]); err != nil {
+			return err
+		}
+	}
+	if err := write("manifest.json", manifest); err != nil {
+		return err
+	}
+	if err := write("SHA256SUMS", []byte(sums.String())); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
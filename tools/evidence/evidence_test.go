@@ -0,0 +1,192 @@
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInput(t *testing.T) Input {
+	t.Helper()
+
+	unitJSON, err := os.Open(filepath.Join("testdata", "unit.json"))
+	require.NoError(t, err)
+	defer unitJSON.Close()
+	unit, err := ParseTestJSON(unitJSON)
+	require.NoError(t, err)
+
+	plans := map[string][]byte{}
+	for _, module := range []string{"security", "monitoring"} {
+		data, err := os.ReadFile(filepath.Join("testdata", module+".json"))
+		require.NoError(t, err)
+		plans[module] = data
+	}
+
+	return Input{
+		Root:      "../..",
+		Env:       "dev",
+		GitCommit: "0123456789abcdef0123456789abcdef01234567",
+		Unit:      unit,
+		Plans:     plans,
+		Now:       time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func control(t *testing.T, b *Bundle, id string) ControlResult {
+	t.Helper()
+	for _, c := range b.Manifest.Controls {
+		if c.ID == id {
+			return c
+		}
+	}
+	t.Fatalf("no control %s", id)
+	return ControlResult{}
+}
+
+func rule(c ControlResult, name, resource string) (RuleResult, bool) {
+	for _, r := range c.Rules {
+		if r.Rule == name && r.Resource == resource {
+			return r, true
+		}
+	}
+	return RuleResult{}, false
+}
+
+func TestParseTestJSON(t *testing.T) {
+	t.Parallel()
+
+	unit := testInput(t).Unit
+	assert.Equal(t, StatusPass, unit["TestKMSKeyRotation"].Status)
+	assert.Contains(t, unit["TestKMSKeyRotation"].Output, "--- PASS: TestKMSKeyRotation")
+	assert.Equal(t, StatusFail, unit["TestNoWildcardActions"].Status)
+	assert.Equal(t, StatusSkip, unit["TestRDSEncryption"].Status)
+	assert.Equal(t, StatusPass, unit["TestCloudTrailEnabled"].Status, "subtests do not override their parent")
+
+	_, err := ParseTestJSON(strings.NewReader("FAIL\tgithub.com/y3gi/zero-trust-aws/test/unit [build failed]\n"))
+	assert.Error(t, err)
+}
+
+func TestBuildEvaluatesRules(t *testing.T) {
+	t.Parallel()
+
+	b, err := Build(testInput(t))
+	require.NoError(t, err)
+	require.Len(t, b.Manifest.Controls, len(Controls))
+
+	encryption := control(t, b, "encryption-at-rest")
+	assert.Equal(t, StatusPass, encryption.Status)
+	rotation, ok := rule(encryption, "kms-key-rotation", "module.iam.aws_kms_key.main")
+	require.True(t, ok)
+	assert.Equal(t, StatusPass, rotation.Status)
+	unit, ok := rule(encryption, "TestS3BucketEncryption", "")
+	require.True(t, ok)
+	assert.Equal(t, StatusNotRun, unit.Status, "tests missing from the output are not run")
+
+	iam := control(t, b, "least-privilege-iam")
+	assert.Equal(t, StatusFail, iam.Status)
+	wildcard, ok := rule(iam, "no-wildcard-actions", "module.iam.aws_iam_role_policy.vpc_flow_log_policy")
+	require.True(t, ok)
+	assert.Equal(t, "allows resource *", wildcard.Detail)
+	trust, ok := rule(iam, "no-public-trust", "module.iam.aws_iam_role.app_instance_role")
+	require.True(t, ok)
+	assert.Equal(t, StatusPass, trust.Status)
+
+	logging := control(t, b, "audit-logging")
+	flowLogs, ok := rule(logging, "flow-logs-all-traffic", "module.monitoring.aws_flow_log.vpc")
+	require.True(t, ok)
+	assert.Equal(t, "traffic_type is REJECT, not ALL", flowLogs.Detail)
+
+	assert.ElementsMatch(t, []string{"least-privilege-iam", "audit-logging"}, b.Failed())
+}
+
+func TestBuildCollectsEvidence(t *testing.T) {
+	t.Parallel()
+
+	b, err := Build(testInput(t))
+	require.NoError(t, err)
+
+	kms := string(b.Files["controls/encryption-at-rest/hcl/security/aws_kms_key.main.tf"])
+	assert.True(t, strings.HasPrefix(kms, "# modules/security/kms.tf:2\nresource \"aws_kms_key\" \"main\" {"), kms)
+	assert.Contains(t, kms, "enable_key_rotation")
+
+	var attributes []plannedResource
+	require.NoError(t, json.Unmarshal(b.Files["controls/least-privilege-iam/plan/security.json"], &attributes))
+	require.Len(t, attributes, 2)
+	policy := attributes[0].After["policy"].(map[string]interface{})
+	assert.Equal(t, "2012-10-17", policy["Version"], "policies are stored decoded")
+	_, collected := attributes[0].After["description"]
+	assert.False(t, collected, "only the listed attributes are collected")
+
+	var unit []UnitResult
+	require.NoError(t, json.Unmarshal(b.Files["controls/encryption-at-rest/unit-tests.json"], &unit))
+	assert.Len(t, unit, 2)
+
+	summary := string(b.Files["summary.md"])
+	assert.Contains(t, summary, "- Commit: 0123456789abcdef0123456789abcdef01234567\n")
+	assert.Contains(t, summary, "| 5. The enterprise monitors and measures the integrity and security posture of all owned and associated assets. | encryption-at-rest (pass), audit-logging (fail) |")
+}
+
+func TestBuildWithoutPlans(t *testing.T) {
+	t.Parallel()
+
+	in := testInput(t)
+	in.Plans, in.Unit = nil, nil
+	b, err := Build(in)
+	require.NoError(t, err)
+
+	segmentation := control(t, b, "network-segmentation")
+	assert.Equal(t, StatusNotRun, segmentation.Status)
+	notRun, ok := rule(segmentation, "plan rules", "")
+	require.True(t, ok)
+	assert.Equal(t, "no plans provided", notRun.Detail)
+	assert.NotEmpty(t, segmentation.Evidence, "HCL is collected without plans")
+}
+
+func TestWriteArchiveHashesEveryFile(t *testing.T) {
+	t.Parallel()
+
+	b, err := Build(testInput(t))
+	require.NoError(t, err)
+
+	var archive bytes.Buffer
+	require.NoError(t, b.WriteArchive(&archive))
+
+	gz, err := gzip.NewReader(&archive)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = content
+	}
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", manifest.GitCommit)
+	assert.Equal(t, "NIST SP 800-207", manifest.Framework)
+	assert.Len(t, manifest.Tenets, 7)
+	assert.Len(t, manifest.Files, len(files)-2, "every file except the manifest and SHA256SUMS is listed")
+	for _, f := range manifest.Files {
+		assert.Equal(t, hash(files[f.Path]), f.SHA256, f.Path)
+	}
+
+	sums := string(files["SHA256SUMS"])
+	assert.Contains(t, sums, hash(files["manifest.json"])+"  manifest.json\n")
+	assert.Contains(t, sums, hash(files["summary.md"])+"  summary.md\n")
+}
@@ -0,0 +1,84 @@
+package evidence
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UnitResult is the outcome of one test/unit test
+type UnitResult struct {
+	Test   string `json:"test"`
+	Status string `json:"status"`
+	Output string `json:"output"`
+}
+
+// ParseTestJSON reads `go test -json` output and returns the result of every
+// top-level test. Lines that are not test events are ignored, so output of
+// a failing `go test` run can be parsed as well.
+func ParseTestJSON(r io.Reader) (map[string]UnitResult, error) {
+	results := map[string]UnitResult{}
+	output := map[string]*strings.Builder{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			Action string
+			Test   string
+			Output string
+		}
+		if json.Unmarshal(scanner.Bytes(), &event) != nil || event.Test == "" || strings.Contains(event.Test, "/") {
+			continue
+		}
+
+		switch event.Action {
+		case "output":
+			if output[event.Test] == nil {
+				output[event.Test] = &strings.Builder{}
+			}
+			output[event.Test].WriteString(event.Output)
+		case StatusPass, StatusFail, StatusSkip:
+			result := UnitResult{Test: event.Test, Status: event.Action}
+			if out := output[event.Test]; out != nil {
+				result.Output = out.String()
+			}
+			results[event.Test] = result
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no test results found in go test -json output")
+	}
+	return results, nil
+}
+
+// unitResults returns a rule result per unit test of the control; a test
+// missing from the results is reported as not run
+func unitResults(control Control, unit map[string]UnitResult) []RuleResult {
+	var results []RuleResult
+	for _, test := range control.UnitTests {
+		result := RuleResult{Rule: test, Kind: "unit", Status: StatusNotRun}
+		if r, ok := unit[test]; ok {
+			result.Status = r.Status
+		} else if unit != nil {
+			result.Detail = "test not found in test/unit output"
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func selectUnit(control Control, unit map[string]UnitResult) []UnitResult {
+	selected := []UnitResult{}
+	for _, test := range control.UnitTests {
+		if r, ok := unit[test]; ok {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
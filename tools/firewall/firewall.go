@@ -0,0 +1,296 @@
+// Package firewall parses the AWS Network Firewall rule groups and policies
+// declared under modules/ and checks them without deploying anything.
+//
+// Stateful rules are read from stateful_rule blocks: an action, a Suricata
+// 5-tuple header and rule options such as sid. They are also read from
+// Suricata rules in rules_string, and from the rules Network Firewall
+// generates for the domain lists of rules_source_list. Policies reference
+// rule groups by address through resource_arn = <group>.arn.
+package firewall
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Stateful rule actions
+const (
+	ActionPass   = "PASS"
+	ActionDrop   = "DROP"
+	ActionReject = "REJECT"
+	ActionAlert  = "ALERT"
+)
+
+// Rule orders of a firewall policy
+const (
+	DefaultActionOrder = "DEFAULT_ACTION_ORDER"
+	StrictOrder        = "STRICT_ORDER"
+)
+
+// Header is the 5-tuple and direction a stateful rule matches
+type Header struct {
+	Protocol        string
+	Source          string
+	SourcePort      string
+	Direction       string
+	Destination     string
+	DestinationPort string
+}
+
+func (h Header) String() string {
+	arrow := "->"
+	if h.Direction == "ANY" {
+		arrow = "<>"
+	}
+	return fmt.Sprintf("%s %s %s %s %s %s", strings.ToLower(h.Protocol), h.Source, h.SourcePort, arrow, h.Destination, h.DestinationPort)
+}
+
+// Option is a Suricata rule option, e.g. keyword "sid" with settings ["1"]
+type Option struct {
+	Keyword  string
+	Settings []string
+}
+
+// Rule is a stateful rule of a rule group
+type Rule struct {
+	Action  string
+	Header  Header
+	Options []Option
+	// SID and Rev are the sid and rev options, or 0 when the rule has none
+	SID int
+	Rev int
+	// Text is the rule as written in rules_string, or "" for stateful_rule
+	// blocks and generated rules
+	Text string
+	// Generated describes the domain list target a rule was generated for,
+	// e.g. "allowlist TLS_SNI .example.com"
+	Generated string
+	Position  string
+}
+
+// Describe returns "sid:N", the domain list target of a generated rule or,
+// for other rules without a sid, the rule's position
+func (r *Rule) Describe() string {
+	switch {
+	case r.SID != 0:
+		return fmt.Sprintf("sid:%d", r.SID)
+	case r.Generated != "":
+		return r.Generated
+	}
+	return r.Position
+}
+
+// RuleGroup is an aws_networkfirewall_rule_group
+type RuleGroup struct {
+	Address string
+	Name    string
+	Type    string
+	// Capacity is 0 when it is not a literal number
+	Capacity int
+	// RuleOrder is the stateful_rule_options rule_order of the group
+	RuleOrder string
+	Rules     []*Rule
+	// IPSets and PortSets are the rule_variables of the group
+	IPSets      map[string][]string
+	PortSets    map[string][]string
+	DomainLists []*DomainList
+	// Problems are rules in rules_string and domain lists that cannot be
+	// parsed; they are not in Rules
+	Problems []Problem
+	Position string
+}
+
+// GroupReference is a stateful_rule_group_reference of a policy
+type GroupReference struct {
+	Group *RuleGroup
+	// Address is the referenced group's address, or the raw resource_arn
+	// when it does not reference a group of the configuration
+	Address  string
+	Priority int
+}
+
+// Policy is an aws_networkfirewall_firewall_policy
+type Policy struct {
+	Address                         string
+	StatelessDefaultActions         []string
+	StatelessFragmentDefaultActions []string
+	StatefulDefaultActions          []string
+	RuleOrder                       string
+	StatefulGroups                  []GroupReference
+	Position                        string
+}
+
+// Config is every rule group and policy declared under modules/
+type Config struct {
+	RuleGroups []*RuleGroup
+	Policies   []*Policy
+}
+
+// Load reads the rule groups and policies of every module under
+// root/modules. Positions are relative to root.
+func Load(root string) (*Config, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	config := &Config{}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		groups := map[string]*RuleGroup{}
+		for _, block := range module.BlocksOfType("resource", "aws_networkfirewall_rule_group") {
+			group := parseRuleGroup(root, block)
+			groups[group.Address] = group
+			config.RuleGroups = append(config.RuleGroups, group)
+		}
+		for _, block := range module.BlocksOfType("resource", "aws_networkfirewall_firewall_policy") {
+			config.Policies = append(config.Policies, parsePolicy(root, block, groups))
+		}
+	}
+	return config, nil
+}
+
+func parseRuleGroup(root string, block *tfconfig.Block) *RuleGroup {
+	group := &RuleGroup{
+		Address:   block.Address(),
+		Name:      block.String("name"),
+		Type:      block.String("type"),
+		RuleOrder: DefaultActionOrder,
+		IPSets:    map[string][]string{},
+		PortSets:  map[string][]string{},
+		Position:  tfconfig.Position(root, block.File, block.Line),
+	}
+	capacity, _ := block.Attr("capacity")
+	if c, ok := capacity.(float64); ok {
+		group.Capacity = int(c)
+	}
+
+	for _, rg := range block.Nested("rule_group") {
+		for _, options := range rg.Nested("stateful_rule_options") {
+			if order := options.String("rule_order"); order != "" {
+				group.RuleOrder = order
+			}
+		}
+		for _, variables := range rg.Nested("rule_variables") {
+			for _, set := range variables.Nested("ip_sets") {
+				for _, ipSet := range set.Nested("ip_set") {
+					definition, _ := ipSet.Attr("definition")
+					group.IPSets[set.String("key")] = tfconfig.Strings(definition)
+				}
+			}
+			for _, set := range variables.Nested("port_sets") {
+				for _, portSet := range set.Nested("port_set") {
+					definition, _ := portSet.Attr("definition")
+					group.PortSets[set.String("key")] = tfconfig.Strings(definition)
+				}
+			}
+		}
+		for _, source := range rg.Nested("rules_source") {
+			for _, stateful := range source.Nested("stateful_rule") {
+				group.Rules = append(group.Rules, parseStatefulRule(root, stateful))
+			}
+			if source.Has("rules_string") {
+				rules, problems := parseRulesString(root, source)
+				group.Rules = append(group.Rules, rules...)
+				group.Problems = append(group.Problems, problems...)
+			}
+			for _, list := range source.Nested("rules_source_list") {
+				domains, rules, problems := parseDomainList(root, list)
+				group.DomainLists = append(group.DomainLists, domains)
+				group.Rules = append(group.Rules, rules...)
+				group.Problems = append(group.Problems, problems...)
+			}
+		}
+	}
+	return group
+}
+
+func parseStatefulRule(root string, block *tfconfig.Block) *Rule {
+	rule := &Rule{Action: block.String("action"), Position: tfconfig.Position(root, block.File, block.Line)}
+	for _, header := range block.Nested("header") {
+		rule.Header = Header{
+			Protocol:        header.String("protocol"),
+			Source:          header.String("source"),
+			SourcePort:      header.String("source_port"),
+			Direction:       header.String("direction"),
+			Destination:     header.String("destination"),
+			DestinationPort: header.String("destination_port"),
+		}
+	}
+	for _, option := range block.Nested("rule_option") {
+		settings, _ := option.Attr("settings")
+		rule.Options = append(rule.Options, parseOption(option.String("keyword"), tfconfig.Strings(settings)))
+	}
+
+	rule.SID, rule.Rev = sidOf(rule.Options), revOf(rule.Options)
+	return rule
+}
+
+// sidOf returns the value of the sid option, or 0 when it is missing or not
+// a positive number
+func sidOf(options []Option) int {
+	for _, option := range options {
+		if option.Keyword == "sid" && len(option.Settings) == 1 {
+			if sid, err := strconv.Atoi(option.Settings[0]); err == nil && sid > 0 {
+				return sid
+			}
+		}
+	}
+	return 0
+}
+
+// parseOption splits keywords written as "sid:1" into keyword and setting
+func parseOption(keyword string, settings []string) Option {
+	if name, value, ok := strings.Cut(keyword, ":"); ok && len(settings) == 0 {
+		return Option{Keyword: strings.TrimSpace(name), Settings: []string{strings.TrimSpace(value)}}
+	}
+	return Option{Keyword: strings.TrimSpace(keyword), Settings: settings}
+}
+
+func parsePolicy(root string, block *tfconfig.Block, groups map[string]*RuleGroup) *Policy {
+	policy := &Policy{Address: block.Address(), RuleOrder: DefaultActionOrder, Position: tfconfig.Position(root, block.File, block.Line)}
+	for _, fp := range block.Nested("firewall_policy") {
+		stateless, _ := fp.Attr("stateless_default_actions")
+		policy.StatelessDefaultActions = tfconfig.Strings(stateless)
+		fragment, _ := fp.Attr("stateless_fragment_default_actions")
+		policy.StatelessFragmentDefaultActions = tfconfig.Strings(fragment)
+		stateful, _ := fp.Attr("stateful_default_actions")
+		policy.StatefulDefaultActions = tfconfig.Strings(stateful)
+		for _, options := range fp.Nested("stateful_engine_options") {
+			if order := options.String("rule_order"); order != "" {
+				policy.RuleOrder = order
+			}
+		}
+
+		for _, ref := range fp.Nested("stateful_rule_group_reference") {
+			arn := ref.String("resource_arn")
+			address := strings.TrimSuffix(strings.TrimPrefix(arn, "${"), ".arn}")
+			reference := GroupReference{Group: groups[address], Address: arn}
+			if reference.Group != nil {
+				reference.Address = address
+			}
+			if priority, ok := ref.Attr("priority"); ok {
+				p, _ := priority.(float64)
+				reference.Priority = int(p)
+			}
+			policy.StatefulGroups = append(policy.StatefulGroups, reference)
+		}
+	}
+	sort.SliceStable(policy.StatefulGroups, func(i, j int) bool {
+		return policy.StatefulGroups[i].Priority < policy.StatefulGroups[j].Priority
+	})
+	return policy
+}
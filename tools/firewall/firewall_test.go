@@ -0,0 +1,132 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+func findings(t *testing.T, root string) []lint.Finding {
+	t.Helper()
+	config, err := Load(root)
+	require.NoError(t, err)
+	return Lint(config)
+}
+
+// messages returns "check: message" of the findings at position
+func messages(all []lint.Finding, position string) []string {
+	var matched []string
+	for _, f := range all {
+		if f.Position == position {
+			matched = append(matched, f.Check+": "+f.Message)
+		}
+	}
+	return matched
+}
+
+func TestLoadRepositoryFirewall(t *testing.T) {
+	t.Parallel()
+
+	config, err := Load("../..")
+	require.NoError(t, err)
+	require.Len(t, config.RuleGroups, 1)
+
+	group := config.RuleGroups[0]
+	assert.Equal(t, "aws_networkfirewall_rule_group.allow_web", group.Address)
+	assert.Equal(t, 100, group.Capacity)
+	require.Len(t, group.Rules, 2)
+	assert.Equal(t, ActionPass, group.Rules[0].Action)
+	assert.Equal(t, "tcp ANY ANY -> ANY 443", group.Rules[0].Header.String())
+	assert.Equal(t, 1, group.Rules[0].SID)
+	assert.Equal(t, 2, group.Rules[1].SID)
+
+	require.Len(t, config.Policies, 1)
+	policy := config.Policies[0]
+	assert.Equal(t, []string{"aws:forward_to_sfe"}, policy.StatelessDefaultActions)
+	assert.Equal(t, DefaultActionOrder, policy.RuleOrder)
+	require.Len(t, policy.StatefulGroups, 1)
+	assert.Same(t, group, policy.StatefulGroups[0].Group)
+}
+
+func TestLintRuleGroups(t *testing.T) {
+	t.Parallel()
+
+	all := findings(t, "testdata/lint")
+
+	assert.Equal(t, []string{"capacity: 5 rules exceed the declared capacity of 3; capacity cannot be changed after creation"},
+		messages(all, "modules/firewall/main.tf:1"))
+	assert.Equal(t, []string{"shadowed: sid:2 never takes effect: sid:1 passes all traffic it matches first"},
+		messages(all, "modules/firewall/main.tf:22"), "TLS from 10.0.0.0/16 is within TCP from ANY")
+	assert.Equal(t, []string{
+		"sid: sid:1 is already used by the rule at modules/firewall/main.tf:8",
+		"shadowed: sid:1 is redundant: sid:1 already passes all traffic it matches",
+	}, messages(all, "modules/firewall/main.tf:36"))
+	assert.Equal(t, []string{"contradictory: sid:4 drops the same traffic that sid:1 passes; sid:1 always wins"},
+		messages(all, "modules/firewall/main.tf:50"), `keyword "sid" with settings is read like "sid:4"`)
+	assert.Equal(t, []string{
+		`header: invalid action "ALLOW", expected PASS, DROP, REJECT or ALERT`,
+		`header: invalid protocol "XYZ"`,
+		`header: invalid direction "BACKWARD", expected FORWARD or ANY`,
+		`header: destination_port: invalid port "70000"`,
+		"sid: rule has no valid sid option",
+	}, messages(all, "modules/firewall/main.tf:65"))
+
+	assert.Empty(t, messages(all, "modules/firewall/main.tf:81"), "PASS rules are evaluated before the catch-all DROP")
+}
+
+func TestLintDefaultDeny(t *testing.T) {
+	t.Parallel()
+
+	all := findings(t, "testdata/lint")
+
+	forwarding := messages(all, "modules/firewall/main.tf:127")
+	require.Len(t, forwarding, 2)
+	assert.Equal(t, "default-deny: stateless_fragment_default_actions contain aws:pass, which lets traffic bypass the stateful rules", forwarding[0])
+	assert.Contains(t, forwarding[1], "default-deny: no explicit default deny")
+
+	assert.Equal(t, []string{`default-deny: stateful_default_actions aws:drop_established requires stateful_engine_options rule_order = "STRICT_ORDER"`},
+		messages(all, "modules/firewall/main.tf:140"))
+	assert.Empty(t, messages(all, "modules/firewall/main.tf:154"), "a DROP IP ANY <> ANY rule is an explicit default deny")
+
+	assert.Equal(t, []string{
+		"default-deny: stateless_default_actions contain aws:pass, which lets traffic bypass the stateful rules",
+		"default-deny: stateless_fragment_default_actions contain aws:pass, which lets traffic bypass the stateful rules",
+	}, messages(all, "modules/firewall/main.tf:167"), "both findings, in the order of the policy's arguments")
+}
+
+func TestAddressesAndPorts(t *testing.T) {
+	t.Parallel()
+
+	sets := map[string][]string{"APP_NET": {"10.0.2.0/24", "10.0.3.0/24"}}
+	parse := func(value string) addresses {
+		a, err := parseAddresses(value, sets)
+		require.NoError(t, err, value)
+		return a
+	}
+	assert.True(t, parse("$APP_NET").within(parse("10.0.0.0/16")))
+	assert.True(t, parse("[10.0.2.5, 10.0.3.0/25]").within(parse("$APP_NET")))
+	assert.False(t, parse("10.0.0.0/16").within(parse("$APP_NET")))
+	assert.False(t, parse("10.0.2.0/24").within(parse("[ANY, !10.0.2.128/25]")))
+	assert.True(t, parse("$HOME_NET").within(parse("ANY")))
+	assert.False(t, parse("$HOME_NET").within(parse("10.0.0.0/8")), "undefined variables only match themselves")
+
+	_, err := parseAddresses("10.0.0.300", nil)
+	assert.Error(t, err)
+
+	ranges := func(value string) ports {
+		p, err := parsePorts(value, map[string][]string{"WEB": {"80", "443"}})
+		require.NoError(t, err, value)
+		return p
+	}
+	assert.True(t, ranges("[80,443]").within(ranges("$WEB")))
+	assert.True(t, ranges("1024:2048").within(ranges("1024:")))
+	assert.False(t, ranges("ANY").within(ranges("!22")))
+	assert.False(t, ranges("20:25").within(ranges("[ANY,!22]")))
+	_, err = parsePorts("443:80", nil)
+	assert.Error(t, err)
+
+	assert.True(t, protocolWithin("TLS", "IP"))
+	assert.False(t, protocolWithin("UDP", "TCP"))
+}
@@ -0,0 +1,378 @@
+package firewall
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// transports are the protocols of a Flow; a rule for IP matches them all
+var transports = map[string]bool{"IP": true, "TCP": true, "UDP": true, "ICMP": true}
+
+// Flow is a 5-tuple seen by the firewall. Ports are 0 for ICMP. A TCP flow
+// with SNI is a TLS flow, one with Host an HTTP flow.
+type Flow struct {
+	Protocol        string
+	Source          netip.Addr
+	SourcePort      int
+	Destination     netip.Addr
+	DestinationPort int
+	SNI             string
+	Host            string
+}
+
+// ParseFlow parses "TCP 10.0.2.10:51515 -> 93.184.216.34:443"; ICMP flows
+// have no ports, e.g. "ICMP 10.0.2.10 -> 1.1.1.1". TLS and HTTP flows end
+// with the server name, e.g. "sni=example.com" or "host=example.com".
+func ParseFlow(value string) (Flow, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 4 && len(fields) != 5 || fields[2] != "->" {
+		return Flow{}, fmt.Errorf("invalid flow %q, expected \"PROTO SRC:PORT -> DST:PORT [sni=NAME|host=NAME]\"", value)
+	}
+	flow := Flow{Protocol: strings.ToUpper(fields[0])}
+	if !transports[flow.Protocol] || flow.Protocol == "IP" {
+		return Flow{}, fmt.Errorf("invalid flow %q: protocol must be TCP, UDP or ICMP", value)
+	}
+
+	var err error
+	if flow.Source, flow.SourcePort, err = parseEndpoint(fields[1], flow.Protocol); err != nil {
+		return Flow{}, fmt.Errorf("invalid flow %q: %w", value, err)
+	}
+	if flow.Destination, flow.DestinationPort, err = parseEndpoint(fields[3], flow.Protocol); err != nil {
+		return Flow{}, fmt.Errorf("invalid flow %q: %w", value, err)
+	}
+	if len(fields) == 5 {
+		key, name, _ := strings.Cut(fields[4], "=")
+		switch {
+		case flow.Protocol != "TCP" || name == "":
+			return Flow{}, fmt.Errorf("invalid flow %q: only TCP flows have an sni or host", value)
+		case key == "sni":
+			flow.SNI = name
+		case key == "host":
+			flow.Host = name
+		default:
+			return Flow{}, fmt.Errorf("invalid flow %q: expected sni=NAME or host=NAME, got %q", value, fields[4])
+		}
+	}
+	return flow, nil
+}
+
+func parseEndpoint(value, protocol string) (netip.Addr, int, error) {
+	if protocol == "ICMP" {
+		addr, err := netip.ParseAddr(value)
+		return addr, 0, err
+	}
+	addrPort, err := netip.ParseAddrPort(value)
+	if err != nil {
+		return netip.Addr{}, 0, err
+	}
+	return addrPort.Addr(), int(addrPort.Port()), nil
+}
+
+func (f Flow) String() string {
+	if f.Protocol == "ICMP" {
+		return fmt.Sprintf("ICMP %s -> %s", f.Source, f.Destination)
+	}
+	s := fmt.Sprintf("%s %s -> %s", f.Protocol,
+		netip.AddrPortFrom(f.Source, uint16(f.SourcePort)), netip.AddrPortFrom(f.Destination, uint16(f.DestinationPort)))
+	switch {
+	case f.SNI != "":
+		s += " sni=" + f.SNI
+	case f.Host != "":
+		s += " host=" + f.Host
+	}
+	return s
+}
+
+// reversed returns the flow seen from the other end
+func (f Flow) reversed() Flow {
+	r := f
+	r.Source, r.SourcePort, r.Destination, r.DestinationPort = f.Destination, f.DestinationPort, f.Source, f.SourcePort
+	return r
+}
+
+// Verdict is the outcome of evaluating a flow
+type Verdict struct {
+	// Action is PASS, DROP or REJECT
+	Action string
+	// Rule is the rule that decided the flow, or nil when a default action did
+	Rule *Rule
+	// Alerts are the ALERT rules that matched before the flow was decided
+	Alerts []*Rule
+	Reason string
+}
+
+// Evaluator evaluates flows against a firewall policy
+type Evaluator struct {
+	policy *Policy
+	rules  []*compiledRule
+}
+
+// NewEvaluator compiles the stateful rules of policy. variables define
+// rule variables that are set at deploy time, such as HOME_NET, which
+// defaults to the VPC CIDR; a group's own rule_variables take precedence.
+// EXTERNAL_NET defaults to everything outside HOME_NET. Rules that cannot
+// be evaluated offline are an error.
+func NewEvaluator(policy *Policy, variables map[string][]string) (*Evaluator, error) {
+	e := &Evaluator{policy: policy}
+	var groups []*RuleGroup
+	for _, ref := range policy.StatefulGroups {
+		if ref.Group == nil {
+			return nil, fmt.Errorf("%s references rule group %s, which is not in the configuration", policy.Address, ref.Address)
+		}
+		groups = append(groups, ref.Group)
+	}
+
+	for _, group := range groups {
+		if len(group.Problems) > 0 {
+			return nil, fmt.Errorf("%s: %s", group.Problems[0].Position, group.Problems[0].Message)
+		}
+		scoped := &RuleGroup{IPSets: map[string][]string{}, PortSets: group.PortSets}
+		for name, definition := range variables {
+			scoped.IPSets[name] = definition
+		}
+		for name, definition := range group.IPSets {
+			scoped.IPSets[name] = definition
+		}
+		if _, ok := scoped.IPSets["EXTERNAL_NET"]; !ok && scoped.IPSets["HOME_NET"] != nil {
+			scoped.IPSets["EXTERNAL_NET"] = []string{"!$HOME_NET"}
+		}
+
+		for _, rule := range group.Rules {
+			compiled, problems := compile(scoped, rule)
+			if len(problems) > 0 {
+				return nil, fmt.Errorf("%s: %s: %s", rule.Position, rule.Describe(), strings.Join(problems, "; "))
+			}
+			compiled.group = group
+			if err := compiled.compileConditions(); err != nil {
+				return nil, fmt.Errorf("%s: %s %w", rule.Position, rule.Describe(), err)
+			}
+			e.rules = append(e.rules, compiled)
+		}
+	}
+	if policy.RuleOrder != StrictOrder {
+		sortByAction(e.rules)
+	}
+	return e, nil
+}
+
+// condition is a content match in a sticky buffer, e.g.
+// tls.sni; dotprefix; content:".example.com"; endswith;
+type condition struct {
+	buffer                     string
+	content                    string
+	negated, dotprefix, nocase bool
+	startswith, endswith       bool
+}
+
+// compileConditions reads the rule options into conditions on the flow, or
+// returns why the rule cannot be matched against flows
+func (r *compiledRule) compileConditions() error {
+	for _, set := range [][]string{r.src.vars, r.dst.vars, r.srcPorts.vars, r.dstPorts.vars} {
+		if len(set) > 0 {
+			return fmt.Errorf("uses undefined variable $%s", set[0])
+		}
+	}
+	if !transports[r.protocol] && r.protocol != "TLS" && r.protocol != "HTTP" {
+		return fmt.Errorf("matches application protocol %s, which flows do not carry", r.protocol)
+	}
+	if problems := optionProblems(r.Rule); len(problems) > 0 {
+		return fmt.Errorf("has invalid options: %s", strings.Join(problems, "; "))
+	}
+
+	buffer, dotprefix := "", false
+	for _, option := range r.Options {
+		last := len(r.conditions) - 1
+		switch keyword := option.Keyword; {
+		case informational[keyword]:
+		case keyword == "flow":
+			for _, setting := range strings.Split(strings.Join(option.Settings, ","), ",") {
+				switch strings.TrimSpace(setting) {
+				case "to_server", "from_client":
+				case "established":
+					r.established = true
+				case "to_client", "from_server":
+					r.toClient = true
+				default:
+					return fmt.Errorf("has flow setting %q, which the evaluator does not support", setting)
+				}
+			}
+		case stickyBuffers[keyword] != "":
+			buffer, dotprefix = keyword, false
+		case keyword == "dotprefix":
+			dotprefix = true
+		case keyword == "content":
+			if buffer == "" {
+				return fmt.Errorf("matches content outside of tls.sni or http.host, which flows do not carry")
+			}
+			content, negated, ok := contentString(option.Settings)
+			if !ok {
+				return fmt.Errorf("has content %q, which is not a quoted string", strings.Join(option.Settings, ","))
+			}
+			r.conditions = append(r.conditions, condition{
+				buffer:    buffer,
+				content:   content,
+				negated:   negated,
+				dotprefix: dotprefix,
+			})
+		case keyword == "nocase":
+			r.conditions[last].nocase = true
+		case keyword == "startswith":
+			r.conditions[last].startswith = true
+		case keyword == "endswith":
+			r.conditions[last].endswith = true
+		default:
+			return fmt.Errorf("has rule option %q, which the evaluator does not support", keyword)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the sticky buffer of flow matches c
+func (c condition) matches(flow Flow) bool {
+	value := map[string]string{"tls.sni": flow.SNI, "http.host": flow.Host}[c.buffer]
+	content := c.content
+	if c.dotprefix {
+		value = "." + value
+	}
+	if c.nocase || c.buffer == "http.host" {
+		value, content = strings.ToLower(value), strings.ToLower(content)
+	}
+
+	var matched bool
+	switch {
+	case c.startswith && c.endswith:
+		matched = value == content
+	case c.startswith:
+		matched = strings.HasPrefix(value, content)
+	case c.endswith:
+		matched = strings.HasSuffix(value, content)
+	default:
+		matched = strings.Contains(value, content)
+	}
+	return matched != c.negated
+}
+
+// Evaluate returns what the policy does with flow: the stateless default
+// actions first, then the stateful rules in evaluation order, then the
+// stateful default actions, which only apply under STRICT_ORDER. A flow no
+// rule decides passes.
+//
+// A TLS or HTTP flow starts with a TCP handshake, which the stateful engine
+// inspects before the SNI or Host is known. Rules for TCP or IP without
+// flow:established decide the flow on the handshake, so a "drop tcp" rule
+// drops it even when a "pass tls" rule would match its SNI, and under
+// STRICT_ORDER aws:drop_strict drops a handshake no rule passes.
+func (e *Evaluator) Evaluate(flow Flow) Verdict {
+	switch {
+	case slices.Contains(e.policy.StatelessDefaultActions, "aws:pass"):
+		return Verdict{Action: ActionPass, Reason: "stateless default action aws:pass"}
+	case slices.Contains(e.policy.StatelessDefaultActions, "aws:drop"):
+		return Verdict{Action: ActionDrop, Reason: "stateless default action aws:drop"}
+	case !slices.Contains(e.policy.StatelessDefaultActions, "aws:forward_to_sfe"):
+		return Verdict{Action: ActionDrop, Reason: "no stateless default action forwards to the stateful engine"}
+	}
+
+	if flow.SNI != "" || flow.Host != "" {
+		handshake := flow
+		handshake.SNI, handshake.Host = "", ""
+		verdict, decided := e.evaluateRules(handshake, true)
+		if !decided && e.policy.RuleOrder == StrictOrder && slices.Contains(e.policy.StatefulDefaultActions, "aws:drop_strict") {
+			verdict.Action, verdict.Reason, decided = ActionDrop, "stateful default action aws:drop_strict", true
+		}
+		if decided {
+			verdict.Reason += " on the TCP handshake, before the " + map[bool]string{true: "SNI", false: "Host"}[flow.SNI != ""] + " is known"
+			return verdict
+		}
+	}
+
+	verdict, decided := e.evaluateRules(flow, false)
+	if decided {
+		return verdict
+	}
+	for _, action := range e.policy.StatefulDefaultActions {
+		if statefulDenyActions[action] && e.policy.RuleOrder == StrictOrder {
+			verdict.Action, verdict.Reason = ActionDrop, "stateful default action "+action
+			return verdict
+		}
+	}
+	verdict.Action, verdict.Reason = ActionPass, "no stateful rule matched and there is no stateful default drop"
+	return verdict
+}
+
+// evaluateRules returns the verdict of the first rule in evaluation order
+// that decides flow, and whether one does. On the handshake only rules for
+// TCP or IP that match packets before the connection is established apply.
+func (e *Evaluator) evaluateRules(flow Flow, handshake bool) (Verdict, bool) {
+	var verdict Verdict
+	for _, rule := range e.rules {
+		if handshake && (rule.established || len(rule.conditions) > 0 || rule.protocol == "TLS" || rule.protocol == "HTTP") {
+			continue
+		}
+		if !rule.matches(flow) {
+			continue
+		}
+		action := strings.ToUpper(rule.Action)
+		if action == ActionAlert {
+			verdict.Alerts = append(verdict.Alerts, rule.Rule)
+			continue
+		}
+		verdict.Action, verdict.Rule = action, rule.Rule
+		verdict.Reason = fmt.Sprintf("%s at %s", rule.Describe(), rule.Position)
+		return verdict, true
+	}
+	return verdict, false
+}
+
+// matches reports whether the rule matches flow, or the reversed flow for
+// rules in direction ANY
+func (r *compiledRule) matches(flow Flow) bool {
+	switch r.protocol {
+	case "IP":
+	case "TLS":
+		if flow.Protocol != "TCP" || flow.SNI == "" {
+			return false
+		}
+	case "HTTP":
+		if flow.Protocol != "TCP" || flow.Host == "" {
+			return false
+		}
+	default:
+		if r.protocol != flow.Protocol {
+			return false
+		}
+	}
+	for _, c := range r.conditions {
+		if !c.matches(flow) {
+			return false
+		}
+	}
+
+	// flow:to_client matches the responses of the flow, which are in the
+	// opposite direction
+	if r.toClient {
+		flow = flow.reversed()
+	}
+	if r.matchesEndpoints(flow) {
+		return true
+	}
+	return r.direction == "ANY" && r.matchesEndpoints(flow.reversed())
+}
+
+func (r *compiledRule) matchesEndpoints(flow Flow) bool {
+	return r.src.covers(netip.PrefixFrom(flow.Source, flow.Source.BitLen())) &&
+		r.dst.covers(netip.PrefixFrom(flow.Destination, flow.Destination.BitLen())) &&
+		(flow.Protocol == "ICMP" || r.srcPorts.covers([2]int{flow.SourcePort, flow.SourcePort}) &&
+			r.dstPorts.covers([2]int{flow.DestinationPort, flow.DestinationPort}))
+}
+
+// Policy returns the policy with the given address, or nil
+func (c *Config) Policy(address string) *Policy {
+	for _, p := range c.Policies {
+		if p.Address == address {
+			return p
+		}
+	}
+	return nil
+}
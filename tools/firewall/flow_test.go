@@ -0,0 +1,154 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var homeNet = map[string][]string{"HOME_NET": {"10.0.0.0/16"}}
+
+func evaluator(t *testing.T, root, address string, variables map[string][]string) *Evaluator {
+	t.Helper()
+	config, err := Load(root)
+	require.NoError(t, err)
+	policy := config.Policy(address)
+	require.NotNil(t, policy, address)
+	e, err := NewEvaluator(policy, variables)
+	require.NoError(t, err)
+	return e
+}
+
+// decision returns "ACTION sid:N", or "ACTION default" when no rule decided
+func decision(v Verdict) string {
+	if v.Rule == nil {
+		return v.Action + " default"
+	}
+	return v.Action + " " + v.Rule.Describe()
+}
+
+func TestEvaluateRepositoryPolicy(t *testing.T) {
+	t.Parallel()
+
+	e := evaluator(t, "../..", "aws_networkfirewall_firewall_policy.main", nil)
+	for _, tc := range []struct {
+		flow string
+		want string
+	}{
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443", "PASS sid:1"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:80", "DROP sid:2"},
+		// no default deny: traffic no rule matches passes
+		{"UDP 10.0.2.10:51515 -> 8.8.8.8:53", "PASS default"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:22", "PASS default"},
+		{"ICMP 10.0.2.10 -> 93.184.216.34", "PASS default"},
+		{"TCP 93.184.216.34:443 -> 10.0.2.10:51515", "PASS default"},
+	} {
+		flow, err := ParseFlow(tc.flow)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, decision(e.Evaluate(flow)), tc.flow)
+	}
+}
+
+func TestEvaluateActionOrder(t *testing.T) {
+	t.Parallel()
+
+	actionOrder := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.action_order", homeNet)
+	strict := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.strict", homeNet)
+	statelessDrop := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.stateless_drop", homeNet)
+
+	for _, tc := range []struct {
+		flow                string
+		actionOrder, strict string
+		alerts              int
+		comment             string
+	}{
+		{"TCP 10.0.2.10:51515 -> 203.0.113.5:443", "PASS sid:2", "DROP sid:3", 0, "PASS is evaluated before DROP unless the order is strict"},
+		{"TCP 10.0.2.10:51515 -> 203.0.113.5:8080", "DROP sid:3", "DROP sid:3", 0, ""},
+		{"TCP 10.0.2.10:51515 -> 198.51.100.1:22", "PASS default", "DROP default", 1, "ALERT does not decide the flow"},
+		{"UDP 10.0.2.10:40000 -> 10.0.0.2:53", "PASS sid:4", "PASS sid:4", 0, ""},
+		{"UDP 10.0.0.2:53 -> 10.0.2.10:40000", "PASS sid:4", "PASS sid:4", 0, "direction ANY matches the reversed flow"},
+		{"UDP 10.0.9.5:40000 -> 10.0.0.2:53", "PASS default", "DROP default", 0, "10.0.9.0/24 is excluded"},
+		{"TCP 192.0.2.1:51515 -> 198.51.100.1:443", "PASS default", "DROP default", 0, "the source is not in HOME_NET"},
+		{"ICMP 10.0.2.10 -> 1.1.1.1", "PASS default", "DROP default", 0, ""},
+	} {
+		flow, err := ParseFlow(tc.flow)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.actionOrder, decision(actionOrder.Evaluate(flow)), "%s: %s", tc.flow, tc.comment)
+		verdict := strict.Evaluate(flow)
+		assert.Equal(t, tc.strict, decision(verdict), "%s: %s", tc.flow, tc.comment)
+		assert.Len(t, verdict.Alerts, tc.alerts, tc.flow)
+		assert.Equal(t, "DROP default", decision(statelessDrop.Evaluate(flow)), tc.flow)
+	}
+}
+
+func TestEvaluateHandshake(t *testing.T) {
+	t.Parallel()
+
+	handshake := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.handshake", homeNet)
+	established := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.established", homeNet)
+	strict := evaluator(t, "testdata/flow", "aws_networkfirewall_firewall_policy.established_strict", homeNet)
+
+	for _, tc := range []struct {
+		flow                           string
+		handshake, established, strict string
+		comment                        string
+	}{
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=www.example.com", "DROP sid:11", "PASS sid:20", "DROP default",
+			"drop tcp matches the handshake before pass tls can match the SNI; aws:drop_strict drops a handshake no rule passes"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=evil.test", "DROP sid:11", "DROP sid:21", "DROP default", ""},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443", "DROP sid:11", "DROP sid:21", "DROP sid:21", "a flow without SNI has no separate handshake"},
+	} {
+		flow, err := ParseFlow(tc.flow)
+		require.NoError(t, err)
+		assert.Equal(t, tc.handshake, decision(handshake.Evaluate(flow)), "%s: %s", tc.flow, tc.comment)
+		assert.Equal(t, tc.established, decision(established.Evaluate(flow)), "%s: %s", tc.flow, tc.comment)
+		assert.Equal(t, tc.strict, decision(strict.Evaluate(flow)), "%s: %s", tc.flow, tc.comment)
+	}
+
+	flow, err := ParseFlow("TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=www.example.com")
+	require.NoError(t, err)
+	assert.Contains(t, handshake.Evaluate(flow).Reason, "on the TCP handshake, before the SNI is known")
+}
+
+func TestNewEvaluatorErrors(t *testing.T) {
+	t.Parallel()
+
+	config, err := Load("testdata/flow")
+	require.NoError(t, err)
+	_, err = NewEvaluator(config.Policy("aws_networkfirewall_firewall_policy.strict"), nil)
+	assert.ErrorContains(t, err, "sid:1 uses undefined variable $HOME_NET")
+
+	config, err = Load("testdata/lint")
+	require.NoError(t, err)
+	_, err = NewEvaluator(config.Policy("aws_networkfirewall_firewall_policy.forwarding"), homeNet)
+	assert.Error(t, err, "rules with invalid headers cannot be evaluated")
+}
+
+func TestParseFlow(t *testing.T) {
+	t.Parallel()
+
+	flow, err := ParseFlow("tcp 10.0.2.10:51515 -> 93.184.216.34:443")
+	require.NoError(t, err)
+	assert.Equal(t, "TCP", flow.Protocol)
+	assert.Equal(t, 443, flow.DestinationPort)
+	assert.Equal(t, "TCP 10.0.2.10:51515 -> 93.184.216.34:443", flow.String())
+
+	flow, err = ParseFlow("TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", flow.SNI)
+	assert.Equal(t, "TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=example.com", flow.String())
+
+	for _, invalid := range []string{
+		"TCP 10.0.2.10 -> 93.184.216.34:443",
+		"TLS 10.0.2.10:1 -> 93.184.216.34:443",
+		"TCP 10.0.2.10:1 => 93.184.216.34:443",
+		"ICMP 10.0.2.10:1 -> 1.1.1.1",
+		"UDP 10.0.2.10:1 -> 1.1.1.1:443 sni=example.com",
+		"TCP 10.0.2.10:1 -> 1.1.1.1:443 path=/",
+	} {
+		_, err := ParseFlow(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,324 @@
+package firewall
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// actionPriority is the order DEFAULT_ACTION_ORDER evaluates actions in
+var actionPriority = map[string]int{ActionPass: 0, ActionDrop: 1, ActionReject: 2, ActionAlert: 3}
+
+// statefulDenyActions are the stateful_default_actions that drop traffic no
+// rule matches
+var statefulDenyActions = map[string]bool{"aws:drop_strict": true, "aws:drop_established": true}
+
+// informational rule options do not narrow what a rule matches
+var informational = map[string]bool{
+	"sid": true, "rev": true, "gid": true, "msg": true, "metadata": true,
+	"classtype": true, "priority": true, "reference": true,
+}
+
+// compiledRule is a rule with its header parsed for matching
+type compiledRule struct {
+	*Rule
+	group     *RuleGroup
+	protocol  string
+	src, dst  addresses
+	srcPorts  ports
+	dstPorts  ports
+	direction string
+	// options are the matching options as "keyword:settings"
+	options []string
+	// conditions, toClient and established are what the evaluator matches
+	// options with
+	conditions  []condition
+	toClient    bool
+	established bool
+}
+
+func compile(group *RuleGroup, rule *Rule) (*compiledRule, []string) {
+	c := &compiledRule{
+		Rule:      rule,
+		group:     group,
+		protocol:  strings.ToUpper(rule.Header.Protocol),
+		direction: strings.ToUpper(rule.Header.Direction),
+	}
+
+	var problems []string
+	if !actions[strings.ToUpper(rule.Action)] {
+		problems = append(problems, fmt.Sprintf("invalid action %q, expected PASS, DROP, REJECT or ALERT", rule.Action))
+	}
+	if _, ok := protocols[c.protocol]; !ok {
+		problems = append(problems, fmt.Sprintf("invalid protocol %q", rule.Header.Protocol))
+	}
+	if !directions[c.direction] {
+		problems = append(problems, fmt.Sprintf("invalid direction %q, expected FORWARD or ANY", rule.Header.Direction))
+	}
+
+	var err error
+	for _, field := range []struct {
+		name  string
+		value string
+		addr  *addresses
+		port  *ports
+	}{
+		{"source", rule.Header.Source, &c.src, nil},
+		{"destination", rule.Header.Destination, &c.dst, nil},
+		{"source_port", rule.Header.SourcePort, nil, &c.srcPorts},
+		{"destination_port", rule.Header.DestinationPort, nil, &c.dstPorts},
+	} {
+		if field.value == "" {
+			problems = append(problems, field.name+" is not set")
+			continue
+		}
+		if field.addr != nil {
+			*field.addr, err = parseAddresses(field.value, group.IPSets)
+		} else {
+			*field.port, err = parsePorts(field.value, group.PortSets)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field.name, err))
+		}
+	}
+
+	for _, option := range rule.Options {
+		if !informational[option.Keyword] {
+			c.options = append(c.options, option.Keyword+":"+strings.Join(option.Settings, ","))
+		}
+	}
+	sort.Strings(c.options)
+	return c, problems
+}
+
+// within reports whether every flow r matches is also matched by other
+func (r *compiledRule) within(other *compiledRule) bool {
+	if !protocolWithin(r.protocol, other.protocol) {
+		return false
+	}
+	if r.direction != other.direction && other.direction != "ANY" {
+		return false
+	}
+	for _, option := range other.options {
+		if !slices.Contains(r.options, option) {
+			return false
+		}
+	}
+	return r.src.within(other.src) && r.dst.within(other.dst) &&
+		r.srcPorts.within(other.srcPorts) && r.dstPorts.within(other.dstPorts)
+}
+
+// catchAll reports whether r matches every flow in both directions
+func (r *compiledRule) catchAll() bool {
+	return r.protocol == "IP" && r.direction == "ANY" && len(r.options) == 0 &&
+		r.src.any && len(r.src.except) == 0 && r.dst.any && len(r.dst.except) == 0 &&
+		r.srcPorts.any && len(r.srcPorts.except) == 0 && r.dstPorts.any && len(r.dstPorts.except) == 0
+}
+
+func (r *compiledRule) denies() bool {
+	action := strings.ToUpper(r.Action)
+	return action == ActionDrop || action == ActionReject
+}
+
+// Lint checks every rule group and policy of the configuration
+func Lint(config *Config) []lint.Finding {
+	l := &linter{compiled: map[*Rule]*compiledRule{}}
+	for _, group := range config.RuleGroups {
+		l.checkGroup(group)
+	}
+	l.checkSIDs(config.RuleGroups)
+
+	referenced := map[*RuleGroup]bool{}
+	for _, policy := range config.Policies {
+		var groups []*RuleGroup
+		for _, ref := range policy.StatefulGroups {
+			if ref.Group != nil {
+				groups = append(groups, ref.Group)
+				referenced[ref.Group] = true
+			}
+		}
+		l.checkOrder(groups, policy.RuleOrder)
+		l.checkDefaultDeny(policy, groups)
+	}
+	for _, group := range config.RuleGroups {
+		if !referenced[group] {
+			l.checkOrder([]*RuleGroup{group}, group.RuleOrder)
+		}
+	}
+
+	return l.Findings()
+}
+
+type linter struct {
+	lint.Collector
+	compiled map[*Rule]*compiledRule
+}
+
+func (l *linter) checkGroup(group *RuleGroup) {
+	if group.Type != "STATEFUL" {
+		return
+	}
+	if group.Capacity > 0 && len(group.Rules) > group.Capacity {
+		l.Add(lint.SeverityError, "capacity", group.Address, group.Position,
+			"%d rules exceed the declared capacity of %d; capacity cannot be changed after creation", len(group.Rules), group.Capacity)
+	}
+	for _, problem := range group.Problems {
+		l.Add(lint.SeverityError, "syntax", group.Address, problem.Position, "%s", problem.Message)
+	}
+	if len(group.Rules) == 0 && len(group.Problems) == 0 {
+		l.Add(lint.SeverityWarning, "rules", group.Address, group.Position, "rule group has no stateful rules")
+	}
+
+	for _, rule := range group.Rules {
+		compiled, problems := compile(group, rule)
+		for _, problem := range problems {
+			l.Add(lint.SeverityError, "header", group.Address, rule.Position, "%s", problem)
+		}
+		options := optionProblems(rule)
+		for _, problem := range options {
+			l.Add(lint.SeverityError, "options", group.Address, rule.Position, "%s", problem)
+		}
+		if len(problems) == 0 && len(options) == 0 {
+			l.compiled[rule] = compiled
+		}
+	}
+}
+
+// checkSIDs requires every stateful rule to have a sid that no other rule
+// of any group uses, and rules in rules_string to have a rev. Rules
+// generated for domain lists get their sids from Network Firewall.
+func (l *linter) checkSIDs(groups []*RuleGroup) {
+	first := map[int]*Rule{}
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			if rule.Generated != "" {
+				continue
+			}
+			if rule.Text != "" && rule.Rev == 0 {
+				l.Add(lint.SeverityWarning, "rev", group.Address, rule.Position, "rule has no valid rev option")
+			}
+			if rule.SID == 0 {
+				l.Add(lint.SeverityError, "sid", group.Address, rule.Position, "rule has no valid sid option")
+				continue
+			}
+			if other, ok := first[rule.SID]; ok {
+				l.Add(lint.SeverityError, "sid", group.Address, rule.Position, "sid:%d is already used by the rule at %s", rule.SID, other.Position)
+				continue
+			}
+			first[rule.SID] = rule
+		}
+	}
+}
+
+// evaluationOrder returns the compiled rules of groups in the order the
+// stateful engine evaluates them: by action for DEFAULT_ACTION_ORDER, as
+// written for STRICT_ORDER
+func (l *linter) evaluationOrder(groups []*RuleGroup, order string) []*compiledRule {
+	var rules []*compiledRule
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			if compiled, ok := l.compiled[rule]; ok {
+				rules = append(rules, compiled)
+			}
+		}
+	}
+	if order != StrictOrder {
+		sortByAction(rules)
+	}
+	return rules
+}
+
+// sortByAction orders rules the way DEFAULT_ACTION_ORDER evaluates them,
+// keeping the written order among rules with the same action
+func sortByAction(rules []*compiledRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return actionPriority[strings.ToUpper(rules[i].Action)] < actionPriority[strings.ToUpper(rules[j].Action)]
+	})
+}
+
+// checkOrder reports rules that never take effect because an earlier rule
+// in evaluation order already decides every flow they match
+func (l *linter) checkOrder(groups []*RuleGroup, order string) {
+	rules := l.evaluationOrder(groups, order)
+	for j, rule := range rules {
+		for _, earlier := range rules[:j] {
+			if !rule.within(earlier) {
+				continue
+			}
+			switch {
+			case strings.EqualFold(rule.Action, earlier.Action):
+				l.Add(lint.SeverityWarning, "shadowed", rule.group.Address, rule.Position,
+					"%s is redundant: %s already %s all traffic it matches", rule.Describe(), earlier.Describe(), verb(earlier.Action))
+			case earlier.within(rule):
+				l.Add(lint.SeverityError, "contradictory", rule.group.Address, rule.Position,
+					"%s %s the same traffic that %s %s; %s always wins", rule.Describe(), verb(rule.Action),
+					earlier.Describe(), verb(earlier.Action), earlier.Describe())
+			default:
+				l.Add(lint.SeverityWarning, "shadowed", rule.group.Address, rule.Position,
+					"%s never takes effect: %s %s all traffic it matches first", rule.Describe(), earlier.Describe(), verb(earlier.Action))
+			}
+			break
+		}
+	}
+}
+
+// verb returns the action as used in findings, e.g. "passes"
+func verb(action string) string {
+	switch strings.ToUpper(action) {
+	case ActionPass:
+		return "passes"
+	case ActionAlert:
+		return "alerts on"
+	}
+	return strings.ToLower(action) + "s"
+}
+
+// checkDefaultDeny requires traffic that no rule matches to be dropped,
+// either by the stateless or the stateful default actions or by a final
+// rule dropping everything in both directions
+func (l *linter) checkDefaultDeny(policy *Policy, groups []*RuleGroup) {
+	for _, field := range []struct {
+		name    string
+		actions []string
+	}{
+		{"stateless_default_actions", policy.StatelessDefaultActions},
+		{"stateless_fragment_default_actions", policy.StatelessFragmentDefaultActions},
+	} {
+		if slices.Contains(field.actions, "aws:pass") {
+			l.Add(lint.SeverityError, "default-deny", policy.Address, policy.Position,
+				"%s contain aws:pass, which lets traffic bypass the stateful rules", field.name)
+		}
+	}
+	if slices.Contains(policy.StatelessDefaultActions, "aws:drop") && !slices.Contains(policy.StatelessDefaultActions, "aws:forward_to_sfe") {
+		return
+	}
+
+	for _, action := range policy.StatefulDefaultActions {
+		if statefulDenyActions[action] {
+			if policy.RuleOrder != StrictOrder {
+				l.Add(lint.SeverityError, "default-deny", policy.Address, policy.Position,
+					"stateful_default_actions %s requires stateful_engine_options rule_order = %q", action, StrictOrder)
+			}
+			return
+		}
+	}
+
+	rules := l.evaluationOrder(groups, policy.RuleOrder)
+	for i, rule := range rules {
+		if !rule.denies() || !rule.catchAll() {
+			continue
+		}
+		if policy.RuleOrder == StrictOrder && i != len(rules)-1 {
+			continue
+		}
+		return
+	}
+
+	l.Add(lint.SeverityError, "default-deny", policy.Address, policy.Position,
+		"no explicit default deny: stateless_default_actions %v forward to the stateful engine, which passes traffic no rule matches; "+
+			"set stateful_default_actions = [\"aws:drop_established\"] with rule_order %s, or end with a DROP rule for IP ANY ANY in direction ANY",
+		policy.StatelessDefaultActions, StrictOrder)
+}
@@ -0,0 +1,295 @@
+package firewall
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// protocols are the protocols a stateful rule header accepts, mapped to the
+// transport they run over; "IP" matches every protocol
+var protocols = map[string]string{
+	"IP":     "",
+	"TCP":    "IP",
+	"UDP":    "IP",
+	"ICMP":   "IP",
+	"HTTP":   "TCP",
+	"HTTP2":  "TCP",
+	"TLS":    "TCP",
+	"FTP":    "TCP",
+	"SMB":    "TCP",
+	"SSH":    "TCP",
+	"SMTP":   "TCP",
+	"IMAP":   "TCP",
+	"MSN":    "TCP",
+	"DCERPC": "TCP",
+	"KRB5":   "IP",
+	"DNS":    "IP",
+	"IKEV2":  "UDP",
+	"TFTP":   "UDP",
+	"NTP":    "UDP",
+	"DHCP":   "UDP",
+	"QUIC":   "UDP",
+}
+
+// directions are the directions a stateful rule header accepts
+var directions = map[string]bool{"FORWARD": true, "ANY": true}
+
+// actions are the actions of a stateful rule
+var actions = map[string]bool{ActionPass: true, ActionDrop: true, ActionReject: true, ActionAlert: true}
+
+// protocolWithin reports whether every flow of protocol a is also a flow of
+// protocol b, e.g. TLS within TCP within IP
+func protocolWithin(a, b string) bool {
+	for p := strings.ToUpper(a); p != ""; p = protocols[p] {
+		if p == strings.ToUpper(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// addresses is a Suricata address: ANY, an IP or CIDR, a $VARIABLE or a
+// [list] of them, where !item excludes an address
+type addresses struct {
+	any      bool
+	prefixes []netip.Prefix
+	except   []netip.Prefix
+	// vars are variables without a definition in the rule group, such as
+	// $HOME_NET, which defaults to the VPC CIDR at deploy time
+	vars []string
+}
+
+func parseAddresses(value string, sets map[string][]string) (addresses, error) {
+	var a addresses
+	err := a.add(value, false, sets, 0)
+	return a, err
+}
+
+func (a *addresses) add(value string, negated bool, sets map[string][]string, depth int) error {
+	if depth > 8 {
+		return fmt.Errorf("address variables nest too deep in %q", value)
+	}
+	for _, item := range listItems(value) {
+		negate := negated
+		if strings.HasPrefix(item, "!") {
+			negate, item = !negate, strings.TrimPrefix(item, "!")
+		}
+		switch {
+		case strings.EqualFold(item, "any"):
+			if negate {
+				return fmt.Errorf("!any matches nothing")
+			}
+			a.any = true
+		case strings.HasPrefix(item, "$"):
+			name := strings.TrimPrefix(item, "$")
+			definition, ok := sets[name]
+			if !ok {
+				if negate {
+					return fmt.Errorf("cannot exclude undefined variable %s", item)
+				}
+				a.vars = append(a.vars, name)
+				continue
+			}
+			if err := a.add("["+strings.Join(definition, ",")+"]", negate, sets, depth+1); err != nil {
+				return err
+			}
+		default:
+			prefix, err := parsePrefix(item)
+			if err != nil {
+				return err
+			}
+			if negate {
+				a.except = append(a.except, prefix)
+			} else {
+				a.prefixes = append(a.prefixes, prefix)
+			}
+		}
+	}
+	if !a.any && len(a.prefixes) == 0 && len(a.vars) == 0 && len(a.except) > 0 {
+		a.any = true
+	}
+	return nil
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid address %q", value)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q", value)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// listItems splits "[a, b]" into its items; a single value is a list of one
+func listItems(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// within reports whether every address of a is an address of b. Undefined
+// variables only match themselves.
+func (a addresses) within(b addresses) bool {
+	if a.any {
+		return b.any && len(b.except) == 0
+	}
+	for _, v := range a.vars {
+		if !b.any && !slices.Contains(b.vars, v) || len(b.except) > 0 {
+			return false
+		}
+	}
+	for _, p := range a.prefixes {
+		if !b.covers(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// covers reports whether every address of prefix p is in a
+func (a addresses) covers(p netip.Prefix) bool {
+	for _, e := range a.except {
+		if e.Overlaps(p) {
+			return false
+		}
+	}
+	if a.any {
+		return true
+	}
+	for _, q := range a.prefixes {
+		if q.Bits() <= p.Bits() && q.Contains(p.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ports is a Suricata port: ANY, a port, a range "low:high", a $VARIABLE
+// or a [list] of them, where !item excludes ports
+type ports struct {
+	any    bool
+	ranges [][2]int
+	except [][2]int
+	vars   []string
+}
+
+func parsePorts(value string, sets map[string][]string) (ports, error) {
+	var p ports
+	err := p.add(value, false, sets, 0)
+	return p, err
+}
+
+func (p *ports) add(value string, negated bool, sets map[string][]string, depth int) error {
+	if depth > 8 {
+		return fmt.Errorf("port variables nest too deep in %q", value)
+	}
+	for _, item := range listItems(value) {
+		negate := negated
+		if strings.HasPrefix(item, "!") {
+			negate, item = !negate, strings.TrimPrefix(item, "!")
+		}
+		switch {
+		case strings.EqualFold(item, "any"):
+			if negate {
+				return fmt.Errorf("!any matches no port")
+			}
+			p.any = true
+		case strings.HasPrefix(item, "$"):
+			name := strings.TrimPrefix(item, "$")
+			definition, ok := sets[name]
+			if !ok {
+				if negate {
+					return fmt.Errorf("cannot exclude undefined variable %s", item)
+				}
+				p.vars = append(p.vars, name)
+				continue
+			}
+			if err := p.add("["+strings.Join(definition, ",")+"]", negate, sets, depth+1); err != nil {
+				return err
+			}
+		default:
+			r, err := parsePortRange(item)
+			if err != nil {
+				return err
+			}
+			if negate {
+				p.except = append(p.except, r)
+			} else {
+				p.ranges = append(p.ranges, r)
+			}
+		}
+	}
+	if !p.any && len(p.ranges) == 0 && len(p.vars) == 0 && len(p.except) > 0 {
+		p.any = true
+	}
+	return nil
+}
+
+func parsePortRange(value string) ([2]int, error) {
+	low, high, isRange := strings.Cut(value, ":")
+	if !isRange {
+		high = low
+	}
+	if low == "" {
+		low = "0"
+	}
+	if high == "" {
+		high = "65535"
+	}
+	l, errLow := strconv.Atoi(low)
+	h, errHigh := strconv.Atoi(high)
+	if errLow != nil || errHigh != nil || l < 0 || h > 65535 || l > h {
+		return [2]int{}, fmt.Errorf("invalid port %q", value)
+	}
+	return [2]int{l, h}, nil
+}
+
+// within reports whether every port of p is a port of q
+func (p ports) within(q ports) bool {
+	if p.any {
+		return q.any && len(q.except) == 0
+	}
+	for _, v := range p.vars {
+		if !q.any && !slices.Contains(q.vars, v) || len(q.except) > 0 {
+			return false
+		}
+	}
+	for _, r := range p.ranges {
+		if !q.covers(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p ports) covers(r [2]int) bool {
+	for _, e := range p.except {
+		if e[0] <= r[1] && r[0] <= e[1] {
+			return false
+		}
+	}
+	if p.any {
+		return true
+	}
+	for _, q := range p.ranges {
+		if q[0] <= r[0] && r[1] <= q[1] {
+			return true
+		}
+	}
+	return false
+}
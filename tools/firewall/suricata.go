@@ -0,0 +1,329 @@
+package firewall
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Problem is a rule or domain list of a rule group that cannot be parsed
+type Problem struct {
+	Position string
+	Message  string
+}
+
+// DomainList is a rules_source_list, which Network Firewall turns into
+// Suricata rules matching the TLS SNI or HTTP host of outbound traffic
+type DomainList struct {
+	// Type is ALLOWLIST or DENYLIST
+	Type string
+	// TargetTypes are TLS_SNI and HTTP_HOST
+	TargetTypes []string
+	// Targets are domains; ".example.com" also matches every subdomain
+	Targets  []string
+	Position string
+}
+
+// targetTypes maps the target types of a domain list to the protocol and
+// sticky buffer of the rules generated for them
+var targetTypes = map[string]struct{ protocol, buffer string }{
+	"TLS_SNI":   {"tls", "tls.sni"},
+	"HTTP_HOST": {"http", "http.host"},
+}
+
+// moduleFile matches rules_string = file("${path.module}/...")
+var moduleFile = regexp.MustCompile(`^file\("\$\{path\.module\}/([^"$]+)"\)$`)
+
+// parseRulesString reads the rules_string attribute of a rules_source block,
+// either a literal or a file() in the module directory
+func parseRulesString(root string, source *tfconfig.Block) ([]*Rule, []Problem) {
+	at := func(line int) string { return tfconfig.Position(root, source.File, line) }
+	text, _ := source.Attr("rules_string")
+	rules, ok := text.(string)
+	switch {
+	case moduleFile.MatchString(source.Source("rules_string")):
+		file := filepath.Join(filepath.Dir(source.File), moduleFile.FindStringSubmatch(source.Source("rules_string"))[1])
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, []Problem{{at(source.AttrLine("rules_string")), fmt.Sprintf("cannot read rules_string: %v", err)}}
+		}
+		return parseRules(string(content), func(line int) string { return tfconfig.Position(root, file, line) })
+	case ok && !tfconfig.IsExpression(rules):
+		first := source.AttrLine("rules_string")
+		if strings.HasPrefix(source.Source("rules_string"), "<<") {
+			first++
+		}
+		return parseRules(rules, func(line int) string { return at(first + line - 1) })
+	}
+	return nil, []Problem{{at(source.AttrLine("rules_string")), "rules_string is not a literal or a file() in the module, so its rules cannot be checked"}}
+}
+
+// parseRules parses Suricata rules, one per line; blank lines and comments
+// are skipped. position returns the position of a line of text.
+func parseRules(text string, position func(line int) string) ([]*Rule, []Problem) {
+	var rules []*Rule
+	var problems []Problem
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseRule(line)
+		if err != nil {
+			problems = append(problems, Problem{position(i + 1), err.Error()})
+			continue
+		}
+		rule.Position = position(i + 1)
+		rules = append(rules, rule)
+	}
+	return rules, problems
+}
+
+// parseRule parses a rule like
+//
+//	pass tls $HOME_NET any -> any 443 (tls.sni; content:"example.com"; sid:1;)
+func parseRule(text string) (*Rule, error) {
+	open := strings.Index(text, "(")
+	if open < 0 || !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("rule options must be enclosed in parentheses: %s", text)
+	}
+	fields := headerFields(text[:open])
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("rule header must be \"action protocol source port -> destination port\", got %q", strings.TrimSpace(text[:open]))
+	}
+	direction := fields[4]
+	switch direction {
+	case "->":
+		direction = "FORWARD"
+	case "<>":
+		direction = "ANY"
+	}
+
+	options, err := parseOptions(text[open+1 : len(text)-1])
+	if err != nil {
+		return nil, err
+	}
+	rule := &Rule{
+		Action: strings.ToUpper(fields[0]),
+		Header: Header{
+			Protocol:        fields[1],
+			Source:          fields[2],
+			SourcePort:      fields[3],
+			Direction:       direction,
+			Destination:     fields[5],
+			DestinationPort: fields[6],
+		},
+		Options: options,
+		Text:    text,
+	}
+	rule.SID, rule.Rev = sidOf(options), revOf(options)
+	return rule, nil
+}
+
+// headerFields splits a rule header on whitespace outside of [lists]
+func headerFields(header string) []string {
+	var fields []string
+	var field strings.Builder
+	depth := 0
+	for _, r := range header {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// parseOptions splits "msg:\"a;b\"; sid:1;" into options. Every option ends
+// with a semicolon; quoted values may contain escaped quotes and semicolons.
+func parseOptions(text string) ([]Option, error) {
+	var options []Option
+	var option strings.Builder
+	quoted, escaped := false, false
+	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			keyword, value, hasValue := strings.Cut(strings.TrimSpace(option.String()), ":")
+			keyword = strings.TrimSpace(keyword)
+			if keyword == "" {
+				return nil, fmt.Errorf("empty rule option in (%s)", text)
+			}
+			o := Option{Keyword: keyword}
+			if hasValue {
+				o.Settings = []string{strings.TrimSpace(value)}
+			}
+			options = append(options, o)
+			option.Reset()
+			continue
+		}
+		option.WriteRune(r)
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in rule options (%s)", text)
+	}
+	if rest := strings.TrimSpace(option.String()); rest != "" {
+		return nil, fmt.Errorf("rule option %q must end with a semicolon", rest)
+	}
+	return options, nil
+}
+
+// revOf returns the value of the rev option, or 0 when it is missing or not
+// a positive number
+func revOf(options []Option) int {
+	for _, option := range options {
+		if option.Keyword == "rev" && len(option.Settings) == 1 {
+			if rev, err := strconv.Atoi(option.Settings[0]); err == nil && rev > 0 {
+				return rev
+			}
+		}
+	}
+	return 0
+}
+
+// domain matches a domain list target, optionally with a leading dot
+var domain = regexp.MustCompile(`^\.?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// parseDomainList reads a rules_source_list and returns the rules Network
+// Firewall generates for it: a PASS (ALLOWLIST) or DROP (DENYLIST) rule per
+// target and target type, and for an ALLOWLIST a DROP rule per target type
+// for the traffic no target matches
+func parseDomainList(root string, block *tfconfig.Block) (*DomainList, []*Rule, []Problem) {
+	targetTypesValue, _ := block.Attr("target_types")
+	targetsValue, _ := block.Attr("targets")
+	list := &DomainList{
+		Type:        block.String("generated_rules_type"),
+		TargetTypes: tfconfig.Strings(targetTypesValue),
+		Targets:     tfconfig.Strings(targetsValue),
+		Position:    tfconfig.Position(root, block.File, block.Line),
+	}
+
+	var problems []Problem
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, Problem{list.Position, fmt.Sprintf(format, args...)})
+	}
+	action := map[string]string{"ALLOWLIST": ActionPass, "DENYLIST": ActionDrop}[list.Type]
+	if action == "" {
+		problem("invalid generated_rules_type %q, expected ALLOWLIST or DENYLIST", list.Type)
+	}
+	if len(list.TargetTypes) == 0 {
+		problem("target_types is empty, expected TLS_SNI and/or HTTP_HOST")
+	}
+	for _, t := range list.TargetTypes {
+		if _, ok := targetTypes[t]; !ok {
+			problem("invalid target type %q, expected TLS_SNI or HTTP_HOST", t)
+		}
+	}
+	switch {
+	case tfconfig.IsExpression(targetsValue):
+		problem("targets is not a literal list, so its domains cannot be checked")
+	case len(list.Targets) == 0:
+		problem("targets is empty")
+	default:
+		for _, target := range list.Targets {
+			if !domain.MatchString(target) {
+				problem("invalid target %q, expected a lowercase domain name such as \"example.com\" or \".example.com\"", target)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return list, nil, problems
+	}
+
+	var rules []*Rule
+	generate := func(text, description string) {
+		rule, err := parseRule(text)
+		if err != nil {
+			problem("%s: %v", description, err)
+			return
+		}
+		rule.Position, rule.Generated = list.Position, description
+		rules = append(rules, rule)
+	}
+	for _, t := range list.TargetTypes {
+		target := targetTypes[t]
+		for _, name := range list.Targets {
+			match := fmt.Sprintf(`%s; content:"%s"; startswith; endswith;`, target.buffer, name)
+			if strings.HasPrefix(name, ".") {
+				match = fmt.Sprintf(`%s; dotprefix; content:"%s"; endswith;`, target.buffer, name)
+			}
+			generate(fmt.Sprintf(`%s %s $HOME_NET any -> $EXTERNAL_NET any (%s flow:to_server, established;)`, strings.ToLower(action), target.protocol, match),
+				fmt.Sprintf("%s %s %s", strings.ToLower(list.Type), t, name))
+		}
+		if list.Type == "ALLOWLIST" {
+			generate(fmt.Sprintf(`drop %s $HOME_NET any -> $EXTERNAL_NET any (flow:to_server, established;)`, target.protocol),
+				fmt.Sprintf("allowlist %s default drop", t))
+		}
+	}
+	return list, rules, nil
+}
+
+// stickyBuffers are the buffers content can be matched in, mapped to the
+// protocol a rule using them must have
+var stickyBuffers = map[string]string{"tls.sni": "TLS", "http.host": "HTTP"}
+
+// contentModifiers modify the content option before them
+var contentModifiers = map[string]bool{"nocase": true, "startswith": true, "endswith": true}
+
+// contentString returns the unescaped string of the settings of a content
+// option, e.g. example.com for !"example.com", and whether it is negated;
+// ok is false unless the settings are one string quoted at both ends
+func contentString(settings []string) (content string, negated, ok bool) {
+	if len(settings) != 1 {
+		return "", false, false
+	}
+	value := settings[0]
+	negated = strings.HasPrefix(value, "!")
+	value = strings.TrimPrefix(value, "!")
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return "", false, false
+	}
+	return strings.NewReplacer(`\"`, `"`, `\;`, ";", `\\`, `\`).Replace(value[1 : len(value)-1]), negated, true
+}
+
+// optionProblems returns misplaced options of a rule: sticky buffers on the
+// wrong protocol, and content modifiers without content
+func optionProblems(rule *Rule) []string {
+	var problems []string
+	protocol := strings.ToUpper(rule.Header.Protocol)
+	content := false
+	for _, option := range rule.Options {
+		switch {
+		case stickyBuffers[option.Keyword] != "":
+			if want := stickyBuffers[option.Keyword]; protocol != want {
+				problems = append(problems, fmt.Sprintf("%s only matches %s traffic, but the rule is for %s", option.Keyword, want, protocol))
+			}
+			content = false
+		case option.Keyword == "content":
+			if _, _, ok := contentString(option.Settings); !ok {
+				problems = append(problems, fmt.Sprintf("content must be a quoted string, got %q", strings.Join(option.Settings, ",")))
+			}
+			content = true
+		case contentModifiers[option.Keyword] && !content:
+			problems = append(problems, fmt.Sprintf("%s must follow a content option", option.Keyword))
+		}
+	}
+	return problems
+}
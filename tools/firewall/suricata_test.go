@@ -0,0 +1,175 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	t.Parallel()
+
+	rule, err := parseRule(`pass tls [10.0.0.0/16, !10.0.9.0/24] any <> $EXTERNAL_NET 443 (msg:"a \"quoted\"; text"; tls.sni; content:!"example.com"; nocase; sid:7; rev:3;)`)
+	require.NoError(t, err)
+	assert.Equal(t, ActionPass, rule.Action)
+	assert.Equal(t, "tls [10.0.0.0/16, !10.0.9.0/24] any <> $EXTERNAL_NET 443", rule.Header.String())
+	assert.Equal(t, []Option{
+		{Keyword: "msg", Settings: []string{`"a \"quoted\"; text"`}},
+		{Keyword: "tls.sni"},
+		{Keyword: "content", Settings: []string{`!"example.com"`}},
+		{Keyword: "nocase"},
+		{Keyword: "sid", Settings: []string{"7"}},
+		{Keyword: "rev", Settings: []string{"3"}},
+	}, rule.Options)
+	assert.Equal(t, 7, rule.SID)
+	assert.Equal(t, 3, rule.Rev)
+
+	for _, invalid := range []string{
+		`pass tcp any any -> any 443`,
+		`pass tcp any any -> any (sid:1;)`,
+		`pass tcp any any -> any 443 (sid:1; rev:1)`,
+		`pass tcp any any -> any 443 (msg:"open; sid:1;)`,
+		`pass tcp any any -> any 443 (; sid:1;)`,
+	} {
+		_, err := parseRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadRulesString(t *testing.T) {
+	t.Parallel()
+
+	config, err := Load("testdata/suricata")
+	require.NoError(t, err)
+	require.Len(t, config.RuleGroups, 4)
+
+	fromFile := config.RuleGroups[0]
+	require.Len(t, fromFile.Rules, 3)
+	assert.Equal(t, "modules/firewall/egress.rules:2", fromFile.Rules[0].Position)
+	assert.Equal(t, "modules/firewall/egress.rules:5", fromFile.Rules[2].Position)
+	assert.Empty(t, fromFile.Problems)
+
+	domains := config.RuleGroups[1]
+	require.Len(t, domains.DomainLists, 1)
+	assert.Equal(t, []string{".example.com", "api.github.com"}, domains.DomainLists[0].Targets)
+	var generated []string
+	for _, rule := range domains.Rules {
+		generated = append(generated, rule.Action+" "+rule.Describe())
+	}
+	assert.Equal(t, []string{
+		"PASS allowlist TLS_SNI .example.com",
+		"PASS allowlist TLS_SNI api.github.com",
+		"DROP allowlist TLS_SNI default drop",
+		"PASS allowlist HTTP_HOST .example.com",
+		"PASS allowlist HTTP_HOST api.github.com",
+		"DROP allowlist HTTP_HOST default drop",
+	}, generated)
+
+	heredoc := config.RuleGroups[2]
+	assert.Equal(t, "modules/firewall/main.tf:37", heredoc.Rules[0].Position, "heredoc rules start on the line after <<-EOT")
+}
+
+func TestLintRulesString(t *testing.T) {
+	t.Parallel()
+
+	all := findings(t, "testdata/suricata")
+
+	assert.Empty(t, messages(all, "modules/firewall/main.tf:37"))
+	assert.Equal(t, []string{"rev: rule has no valid rev option"}, messages(all, "modules/firewall/main.tf:38"))
+	assert.Equal(t, []string{"sid: sid:200 is already used by the rule at modules/firewall/main.tf:37"}, messages(all, "modules/firewall/main.tf:39"))
+	assert.Equal(t, []string{`syntax: rule header must be "action protocol source port -> destination port", got "pass tcp any any any 80"`},
+		messages(all, "modules/firewall/main.tf:40"))
+	assert.Equal(t, []string{`syntax: unterminated quote in rule options (msg:"unterminated; sid:203; rev:1;)`},
+		messages(all, "modules/firewall/main.tf:41"))
+	assert.Equal(t, []string{`syntax: rule option "rev:1" must end with a semicolon`}, messages(all, "modules/firewall/main.tf:42"))
+	assert.Equal(t, []string{"options: tls.sni only matches TLS traffic, but the rule is for TCP"}, messages(all, "modules/firewall/main.tf:43"))
+	assert.Equal(t, []string{"options: nocase must follow a content option"}, messages(all, "modules/firewall/main.tf:44"))
+	assert.Equal(t, []string{"shadowed: sid:207 is redundant: sid:200 already passes all traffic it matches"},
+		messages(all, "modules/firewall/main.tf:45"), "TLS from part of 10.0.0.0/16 is within TCP from ANY")
+
+	assert.Equal(t, []string{
+		`syntax: invalid generated_rules_type "BLOCKLIST", expected ALLOWLIST or DENYLIST`,
+		`syntax: invalid target type "DNS", expected TLS_SNI or HTTP_HOST`,
+		`syntax: invalid target "Example.com", expected a lowercase domain name such as "example.com" or ".example.com"`,
+		`syntax: invalid target "*.example.org", expected a lowercase domain name such as "example.com" or ".example.com"`,
+	}, messages(all, "modules/firewall/main.tf:58"))
+
+	assert.Empty(t, messages(all, "modules/firewall/main.tf:20"), "generated rules have no sid of their own")
+	for _, f := range all {
+		assert.NotContains(t, f.Position, "egress.rules", f.String())
+	}
+}
+
+func TestEvaluateDomainLists(t *testing.T) {
+	t.Parallel()
+
+	e := evaluator(t, "testdata/suricata", "aws_networkfirewall_firewall_policy.egress", homeNet)
+	for _, tc := range []struct {
+		flow string
+		want string
+	}{
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=www.example.com", "PASS allowlist TLS_SNI .example.com"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=example.com", "PASS allowlist TLS_SNI .example.com"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443 sni=badexample.com", "DROP allowlist TLS_SNI default drop"},
+		{"TCP 10.0.2.10:51515 -> 140.82.112.5:443 sni=api.github.com", "PASS allowlist TLS_SNI api.github.com"},
+		{"TCP 10.0.2.10:51515 -> 140.82.112.5:443 sni=gist.api.github.com", "DROP allowlist TLS_SNI default drop"},
+		{"TCP 10.0.2.10:51515 -> 198.51.100.7:443 sni=UPDATES.example.org", "PASS sid:100"},
+		{"TCP 10.0.2.10:51515 -> 198.51.100.7:8443 sni=updates.example.org", "DROP allowlist TLS_SNI default drop"},
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:80 host=www.example.com", "PASS allowlist HTTP_HOST .example.com"},
+		{"TCP 10.0.2.10:51515 -> 198.51.100.7:80 host=Downloads.example.net", "DROP sid:101"},
+		{"TCP 10.0.2.10:51515 -> 198.51.100.7:80 host=example.net", "DROP allowlist HTTP_HOST default drop"},
+		// the domain lists only apply to TLS and HTTP from HOME_NET to EXTERNAL_NET
+		{"TCP 10.0.2.10:51515 -> 93.184.216.34:443", "PASS default"},
+		{"TCP 192.0.2.1:51515 -> 93.184.216.34:443 sni=evil.test", "PASS default"},
+		{"TCP 10.0.2.10:51515 -> 10.0.3.5:443 sni=evil.test", "PASS default"},
+	} {
+		flow, err := ParseFlow(tc.flow)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, decision(e.Evaluate(flow)), tc.flow)
+	}
+
+	flow, err := ParseFlow("TCP 10.0.2.10:51515 -> 198.51.100.7:22")
+	require.NoError(t, err)
+	verdict := e.Evaluate(flow)
+	assert.Equal(t, "PASS default", decision(verdict))
+	require.Len(t, verdict.Alerts, 1)
+	assert.Equal(t, 102, verdict.Alerts[0].SID)
+}
+
+func TestConditionMatches(t *testing.T) {
+	t.Parallel()
+
+	flow := Flow{Protocol: "TCP", SNI: "www.Example.com"}
+	for _, tc := range []struct {
+		condition condition
+		want      bool
+	}{
+		{condition{buffer: "tls.sni", content: "example"}, false},
+		{condition{buffer: "tls.sni", content: "example", nocase: true}, true},
+		{condition{buffer: "tls.sni", content: ".Example.com", endswith: true}, true},
+		{condition{buffer: "tls.sni", content: "www.Example.com", startswith: true, endswith: true}, true},
+		{condition{buffer: "tls.sni", content: ".www.Example.com", dotprefix: true, endswith: true}, true},
+		{condition{buffer: "tls.sni", content: "example.org", negated: true, nocase: true}, true},
+		{condition{buffer: "http.host", content: "www"}, false},
+	} {
+		assert.Equal(t, tc.want, tc.condition.matches(flow), "%+v", tc.condition)
+	}
+}
+
+func TestSingleQuoteContent(t *testing.T) {
+	t.Parallel()
+
+	all := findings(t, "testdata/quoting")
+	assert.Equal(t, []string{`syntax: unterminated quote in rule options (tls.sni; content:"; sid:30; rev:1;)`},
+		messages(all, "modules/firewall/main.tf:12"))
+	assert.Equal(t, []string{`options: content must be a quoted string, got "\""`}, messages(all, "modules/firewall/main.tf:25"))
+	assert.Equal(t, []string{`options: content must be a quoted string, got "!\""`}, messages(all, "modules/firewall/main.tf:47"))
+
+	config, err := Load("testdata/quoting")
+	require.NoError(t, err)
+	_, err = NewEvaluator(config.Policy("aws_networkfirewall_firewall_policy.rules_string"), homeNet)
+	assert.ErrorContains(t, err, "unterminated quote")
+	_, err = NewEvaluator(config.Policy("aws_networkfirewall_firewall_policy.stateful_rule"), homeNet)
+	assert.ErrorContains(t, err, "content must be a quoted string")
+}
@@ -0,0 +1,197 @@
+resource "aws_networkfirewall_rule_group" "egress" {
+  capacity = 100
+  name     = "egress"
+  type     = "STATEFUL"
+
+  rule_group {
+    rule_variables {
+      port_sets {
+        key = "WEB_PORTS"
+        port_set {
+          definition = ["80", "443"]
+        }
+      }
+    }
+
+    rules_source {
+      stateful_rule {
+        action = "ALERT"
+        header {
+          destination      = "ANY"
+          destination_port = "22"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "$HOME_NET"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:1"
+        }
+      }
+      stateful_rule {
+        action = "DROP"
+        header {
+          destination      = "203.0.113.0/24"
+          destination_port = "ANY"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "ANY"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:3"
+        }
+      }
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "$WEB_PORTS"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "$HOME_NET"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:2"
+        }
+      }
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "10.0.0.2"
+          destination_port = "53"
+          direction        = "ANY"
+          protocol         = "UDP"
+          source           = "[10.0.0.0/16, !10.0.9.0/24]"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:4"
+        }
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "action_order" {
+  name = "action-order"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.egress.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "strict" {
+  name = "strict"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+    stateful_default_actions           = ["aws:drop_strict"]
+
+    stateful_engine_options {
+      rule_order = "STRICT_ORDER"
+    }
+
+    stateful_rule_group_reference {
+      priority     = 10
+      resource_arn = aws_networkfirewall_rule_group.egress.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "stateless_drop" {
+  name = "stateless-drop"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:drop"]
+    stateless_fragment_default_actions = ["aws:drop"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.egress.arn
+    }
+  }
+}
+
+# A pass tls rule cannot pass the TCP handshake: the SNI is only known after
+# it, and a drop tcp rule matches its packets first
+resource "aws_networkfirewall_rule_group" "handshake" {
+  capacity = 10
+  name     = "handshake"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_string = <<-EOT
+        pass tls $HOME_NET any -> any 443 (tls.sni; content:"example.com"; endswith; sid:10;)
+        drop tcp $HOME_NET any -> any any (sid:11;)
+      EOT
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "established" {
+  capacity = 10
+  name     = "established"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_string = <<-EOT
+        pass tls $HOME_NET any -> any 443 (tls.sni; content:"example.com"; endswith; sid:20;)
+        drop tcp $HOME_NET any -> any any (flow:established,to_server; sid:21;)
+      EOT
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "handshake" {
+  name = "handshake"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.handshake.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "established" {
+  name = "established"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.established.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "established_strict" {
+  name = "established-strict"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+    stateful_default_actions           = ["aws:drop_strict"]
+
+    stateful_engine_options {
+      rule_order = "STRICT_ORDER"
+    }
+
+    stateful_rule_group_reference {
+      priority     = 10
+      resource_arn = aws_networkfirewall_rule_group.established.arn
+    }
+  }
+}
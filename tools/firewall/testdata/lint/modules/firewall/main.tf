@@ -0,0 +1,178 @@
+resource "aws_networkfirewall_rule_group" "mixed" {
+  capacity = 3
+  name     = "mixed"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "ANY"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:1"
+        }
+      }
+      stateful_rule {
+        action = "DROP"
+        header {
+          destination      = "ANY"
+          destination_port = "[443]"
+          direction        = "FORWARD"
+          protocol         = "TLS"
+          source           = "10.0.0.0/16"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:2"
+        }
+      }
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "10.0.1.0/24"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:1"
+        }
+      }
+      stateful_rule {
+        action = "DROP"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TCP"
+          source           = "ANY"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword  = "sid"
+          settings = ["4"]
+        }
+      }
+      stateful_rule {
+        action = "ALLOW"
+        header {
+          destination      = "ANY"
+          destination_port = "70000"
+          direction        = "BACKWARD"
+          protocol         = "XYZ"
+          source           = "ANY"
+          source_port      = "ANY"
+        }
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "deny_by_default" {
+  capacity = 10
+  name     = "deny-by-default"
+  type     = "STATEFUL"
+
+  rule_group {
+    rule_variables {
+      ip_sets {
+        key = "APP_NET"
+        ip_set {
+          definition = ["10.0.2.0/24", "10.0.3.0/24"]
+        }
+      }
+    }
+    rules_source {
+      stateful_rule {
+        action = "DROP"
+        header {
+          destination      = "ANY"
+          destination_port = "ANY"
+          direction        = "ANY"
+          protocol         = "IP"
+          source           = "ANY"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:10"
+        }
+      }
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TLS"
+          source           = "$APP_NET"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "sid:11"
+        }
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "forwarding" {
+  name = "forwarding"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:pass"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.mixed.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "drop_established" {
+  name = "drop-established"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+    stateful_default_actions           = ["aws:drop_established"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.mixed.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "catch_all" {
+  name = "catch-all"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.deny_by_default.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "passing" {
+  name = "passing"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:pass"]
+    stateless_fragment_default_actions = ["aws:pass"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.deny_by_default.arn
+    }
+  }
+}
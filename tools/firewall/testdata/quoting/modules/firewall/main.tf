@@ -0,0 +1,97 @@
+# content settings of a single quote, which HasPrefix and HasSuffix both
+# take for the quotes around the string
+
+resource "aws_networkfirewall_rule_group" "rules_string" {
+  capacity = 10
+  name     = "rules-string"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_string = <<-EOT
+        pass tls $HOME_NET any -> any 443 (tls.sni; content:"; sid:30; rev:1;)
+      EOT
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "stateful_rule" {
+  capacity = 10
+  name     = "stateful-rule"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TLS"
+          source           = "$HOME_NET"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "tls.sni"
+        }
+        rule_option {
+          keyword  = "content"
+          settings = ["\""]
+        }
+        rule_option {
+          keyword  = "sid"
+          settings = ["31"]
+        }
+      }
+      stateful_rule {
+        action = "PASS"
+        header {
+          destination      = "ANY"
+          destination_port = "443"
+          direction        = "FORWARD"
+          protocol         = "TLS"
+          source           = "$HOME_NET"
+          source_port      = "ANY"
+        }
+        rule_option {
+          keyword = "tls.sni"
+        }
+        rule_option {
+          keyword  = "content"
+          settings = ["!\""]
+        }
+        rule_option {
+          keyword  = "sid"
+          settings = ["32"]
+        }
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "rules_string" {
+  name = "rules-string"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.rules_string.arn
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "stateful_rule" {
+  name = "stateful-rule"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.stateful_rule.arn
+    }
+  }
+}
@@ -0,0 +1,82 @@
+resource "aws_networkfirewall_rule_group" "egress_rules" {
+  capacity = 100
+  name     = "egress-rules"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_string = file("${path.module}/egress.rules")
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "egress_domains" {
+  capacity = 100
+  name     = "egress-domains"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_source_list {
+        generated_rules_type = "ALLOWLIST"
+        target_types         = ["TLS_SNI", "HTTP_HOST"]
+        targets              = [".example.com", "api.github.com"]
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "broken_rules" {
+  capacity = 100
+  name     = "broken-rules"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_string = <<-EOT
+        pass tcp any any -> any 443 (msg:"https"; sid:200; rev:1;)
+        pass tcp any any -> any 8443 (msg:"no rev"; sid:201;)
+        drop tcp any any -> any 23 (msg:"telnet"; sid:200; rev:1;)
+        pass tcp any any any 80 (sid:202; rev:1;)
+        pass tcp any any -> any 80 (msg:"unterminated; sid:203; rev:1;)
+        pass tcp any any -> any 80 (sid:204; rev:1)
+        pass tcp any any -> any 443 (tls.sni; content:"example.com"; sid:205; rev:1;)
+        pass tls any any -> any 443 (nocase; content:"example.com"; sid:206; rev:1;)
+        pass tls [10.0.0.0/16, !10.0.9.0/24] any -> any 443 (tls.sni; content:"example.com"; sid:207; rev:1;)
+      EOT
+    }
+  }
+}
+
+resource "aws_networkfirewall_rule_group" "broken_domains" {
+  capacity = 100
+  name     = "broken-domains"
+  type     = "STATEFUL"
+
+  rule_group {
+    rules_source {
+      rules_source_list {
+        generated_rules_type = "BLOCKLIST"
+        target_types         = ["DNS"]
+        targets              = ["Example.com", "*.example.org"]
+      }
+    }
+  }
+}
+
+resource "aws_networkfirewall_firewall_policy" "egress" {
+  name = "egress"
+
+  firewall_policy {
+    stateless_default_actions          = ["aws:forward_to_sfe"]
+    stateless_fragment_default_actions = ["aws:forward_to_sfe"]
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.egress_rules.arn
+    }
+
+    stateful_rule_group_reference {
+      resource_arn = aws_networkfirewall_rule_group.egress_domains.arn
+    }
+  }
+}
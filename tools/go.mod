@@ -0,0 +1,21 @@
+module github.com/y3gi/zero-trust-aws/tools
+
+go 1.21
+
+require (
+	github.com/aws/aws-sdk-go v1.48.6
+	github.com/hashicorp/hcl/v2 v2.9.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/stretchr/testify v1.8.4
+	github.com/zclconf/go-cty v1.9.1
+)
+
+require (
+	github.com/agext/levenshtein v1.2.3 // indirect
+	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,241 @@
+package iam
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+//go:embed catalog/*.json
+var catalogFiles embed.FS
+
+// Catalog is a checked-in extract of the AWS Service Authorization Reference
+// (catalog/<service>.json): the actions of the services the modules use,
+// their resource types and the condition keys they support. The global aws:
+// keys are in catalog/aws.json, and the prefixes of every service, including
+// those without a file, in catalog/prefixes.json.
+type Catalog struct {
+	Services map[string]*CatalogService
+	Prefixes []string
+}
+
+// CatalogService is the catalog of one service prefix
+type CatalogService struct {
+	Version string `json:"version"`
+	Prefix  string `json:"service"`
+	Source  string `json:"source"`
+	// ConditionKeys maps the condition keys of the service to their type:
+	// String, ARN, Bool, Date, Numeric, IPAddress or ArrayOfString
+	ConditionKeys map[string]string        `json:"condition_keys"`
+	Resources     map[string]*ResourceType `json:"resources"`
+	Actions       map[string]*Action       `json:"actions"`
+}
+
+// ResourceType is a type of resource an action can be allowed on
+type ResourceType struct {
+	ARN           string   `json:"arn"`
+	ConditionKeys []string `json:"condition_keys"`
+}
+
+// Action is an action of a service. Its condition keys are those of the
+// action itself; the keys of its resource types apply too.
+type Action struct {
+	Service       string   `json:"-"`
+	Name          string   `json:"-"`
+	Resources     []string `json:"resources"`
+	ConditionKeys []string `json:"condition_keys"`
+}
+
+// String returns the action as used in policies, e.g. kms:Decrypt
+func (a *Action) String() string {
+	return a.Service + ":" + a.Name
+}
+
+// resourceBoundKeys are the global keys present only in the requests of
+// actions, or on the resource types, that list them
+var resourceBoundKeys = []string{"aws:ResourceTag/${TagKey}", "aws:RequestTag/${TagKey}", "aws:TagKeys"}
+
+// LoadCatalog returns the checked-in catalog
+func LoadCatalog() (*Catalog, error) {
+	entries, err := catalogFiles.ReadDir("catalog")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{Services: map[string]*CatalogService{}}
+	for _, entry := range entries {
+		data, err := catalogFiles.ReadFile(path.Join("catalog", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if entry.Name() == "prefixes.json" {
+			var prefixes struct {
+				Prefixes []string `json:"prefixes"`
+			}
+			if err := json.Unmarshal(data, &prefixes); err != nil {
+				return nil, fmt.Errorf("invalid catalog %s: %w", entry.Name(), err)
+			}
+			c.Prefixes = prefixes.Prefixes
+			continue
+		}
+		var s CatalogService
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("invalid catalog %s: %w", entry.Name(), err)
+		}
+		if s.Prefix == "" || s.Version == "" {
+			return nil, fmt.Errorf("invalid catalog %s: version and service are required", entry.Name())
+		}
+		for name, a := range s.Actions {
+			a.Service, a.Name = s.Prefix, name
+			for _, resource := range a.Resources {
+				if s.Resources[resource] == nil {
+					return nil, fmt.Errorf("invalid catalog %s: %s uses unknown resource type %s", entry.Name(), a, resource)
+				}
+			}
+		}
+		c.Services[s.Prefix] = &s
+	}
+	return c, nil
+}
+
+// KnownService reports whether prefix is the prefix of an AWS service,
+// whether or not the catalog has its actions
+func (c *Catalog) KnownService(prefix string) bool {
+	for _, known := range c.Prefixes {
+		if strings.EqualFold(known, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ARNs returns the ARN templates of the resource types whose ARNs name
+// service, e.g. arn:${Partition}:kms:${Region}:${Account}:key/${KeyId} for
+// kms. Resource types of a service may have the ARNs of another one, such as
+// the EC2 instances of Systems Manager.
+func (c *Catalog) ARNs(service string) []string {
+	var templates []string
+	for _, s := range c.Services {
+		for _, r := range s.Resources {
+			if fields := strings.SplitN(r.ARN, ":", 6); len(fields) == 6 && fields[2] == service && !slices.Contains(templates, r.ARN) {
+				templates = append(templates, r.ARN)
+			}
+		}
+	}
+	sort.Strings(templates)
+	return templates
+}
+
+// variable matches a "${...}" variable of an ARN template, or an expression
+// of a policy
+var variable = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// MatchARN reports whether a Resource of a policy, which may have wildcards
+// and "${...}" expressions, can name a resource whose ARN has the form of
+// template
+func MatchARN(template, resource string) bool {
+	// a concrete resource matches the template...
+	sample := variable.ReplaceAllString(resource, "x")
+	sample = strings.NewReplacer("*", "x", "?", "x").Replace(sample)
+	var pattern strings.Builder
+	last := 0
+	for _, loc := range variable.FindAllStringIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		switch template[loc[0]:loc[1]] {
+		case "${Partition}", "${Region}", "${Account}":
+			pattern.WriteString(`[^:]+`)
+		default:
+			pattern.WriteString(`.+`)
+		}
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	if regexp.MustCompile("^" + pattern.String() + "$").MatchString(sample) {
+		return true
+	}
+
+	// ...or a wildcard of the resource matches a resource of the template
+	example := strings.NewReplacer("${Partition}", "aws", "${Region}", "us-east-1", "${Account}", "123456789012").Replace(template)
+	return wildcard(variable.ReplaceAllString(resource, "*"), variable.ReplaceAllString(example, "x"), false)
+}
+
+// Actions returns the actions of the catalog an action pattern matches,
+// sorted, and whether the catalog has the service of the pattern
+func (c *Catalog) Actions(pattern string) ([]*Action, bool) {
+	s := c.Services[Service(pattern)]
+	if s == nil || s.Prefix == "aws" {
+		return nil, false
+	}
+	var actions []*Action
+	for _, a := range s.Actions {
+		if MatchAction(pattern, a.String()) {
+			actions = append(actions, a)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name < actions[j].Name })
+	return actions, true
+}
+
+// Supports reports whether a condition key is present in the requests of an
+// action: a global key other than the tag keys, a key of the action or a key
+// of one of its resource types
+func (c *Catalog) Supports(a *Action, key string) bool {
+	if strings.HasPrefix(strings.ToLower(key), "aws:") && !matchesKey(resourceBoundKeys, key) {
+		return true
+	}
+	if matchesKey(a.ConditionKeys, key) {
+		return true
+	}
+	s := c.Services[a.Service]
+	for _, resource := range a.Resources {
+		if matchesKey(s.Resources[resource].ConditionKeys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyType returns the type of a condition key, or "" when the catalog does
+// not know it
+func (c *Catalog) KeyType(key string) string {
+	prefix, _, _ := strings.Cut(key, ":")
+	services := []*CatalogService{c.Services[strings.ToLower(prefix)]}
+	for _, s := range c.Services {
+		services = append(services, s)
+	}
+	for _, s := range services {
+		if s == nil {
+			continue
+		}
+		for template, keyType := range s.ConditionKeys {
+			if matchKey(template, key) {
+				return keyType
+			}
+		}
+	}
+	return ""
+}
+
+// matchKey reports whether a condition key of the catalog, such as
+// aws:ResourceTag/${TagKey}, matches a key of a policy. Keys are
+// case-insensitive.
+func matchKey(template, key string) bool {
+	prefix, _, variable := strings.Cut(template, "${")
+	if !variable {
+		return strings.EqualFold(template, key)
+	}
+	return len(key) > len(prefix) && strings.EqualFold(key[:len(prefix)], prefix)
+}
+
+func matchesKey(templates []string, key string) bool {
+	for _, template := range templates {
+		if matchKey(template, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	c, err := LoadCatalog()
+	require.NoError(t, err)
+
+	actions, ok := c.Actions("kms:GenerateDataKey*")
+	require.True(t, ok)
+	var names []string
+	for _, a := range actions {
+		names = append(names, a.String())
+	}
+	assert.Equal(t, []string{"kms:GenerateDataKey", "kms:GenerateDataKeyPair", "kms:GenerateDataKeyPairWithoutPlaintext", "kms:GenerateDataKeyWithoutPlaintext"}, names)
+	_, ok = c.Actions("acm-pca:IssueCertificate")
+	assert.False(t, ok, "services outside the catalog are unknown")
+
+	decrypt := c.Services["kms"].Actions["Decrypt"]
+	assert.True(t, c.Supports(decrypt, "kms:ViaService"), "a key of the action")
+	assert.True(t, c.Supports(decrypt, "aws:resourcetag/Tier"), "a key of its resource type, case-insensitive")
+	assert.True(t, c.Supports(decrypt, "aws:SourceVpce"), "a global key")
+	assert.False(t, c.Supports(decrypt, "aws:RequestTag/Tier"), "tag keys must be listed")
+	assert.False(t, c.Supports(decrypt, "secretsmanager:SecretId"))
+	assert.False(t, c.Supports(c.Services["secretsmanager"].Actions["ListSecrets"], "aws:ResourceTag/Tier"), "ListSecrets has no resource type")
+
+	assert.Equal(t, "Bool", c.KeyType("aws:SecureTransport"))
+	assert.Equal(t, "String", c.KeyType("kms:EncryptionContext:app"))
+	assert.Equal(t, "", c.KeyType("kms:ResourceTag/Tier"))
+}
+
+func TestMatchARN(t *testing.T) {
+	t.Parallel()
+
+	key := "arn:${Partition}:kms:${Region}:${Account}:key/${KeyId}"
+	assert.True(t, MatchARN(key, "arn:aws:kms:eu-north-1:111122223333:key/1234"))
+	assert.True(t, MatchARN(key, "arn:aws:kms:${var.region}:${data.aws_caller_identity.current.account_id}:key/*"))
+	assert.True(t, MatchARN(key, "arn:aws:kms:*:*:*"), "a wildcard matching keys")
+	assert.False(t, MatchARN(key, "arn:aws:kms:eu-north-1:111122223333:keys/1234"))
+	assert.False(t, MatchARN(key, "arn:aws:kms:eu-north-1::key/1234"), "the account is required")
+
+	bucket := "arn:${Partition}:s3:::${BucketName}"
+	assert.True(t, MatchARN(bucket, "arn:aws:s3:::logs"))
+	assert.False(t, MatchARN(bucket, "arn:aws:s3:eu-north-1:111122223333:logs"), "buckets have no region or account")
+}
@@ -0,0 +1,267 @@
+package iam
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Decisions of Evaluate
+const (
+	Allowed      = "allowed"
+	ExplicitDeny = "explicit-deny"
+	ImplicitDeny = "implicit-deny"
+)
+
+// The principal of another account that Foreign requests come from
+const (
+	ForeignAccount   = "999999999999"
+	ForeignPrincipal = "arn:aws:iam::" + ForeignAccount + ":role/foreign"
+	ForeignOrg       = "o-foreign"
+)
+
+// Request is an API call evaluated against the statements of a policy.
+//
+// An empty Principal or Resource is unknown and matches every principal or
+// resource of a statement. A Context key without values is known to be
+// absent from the request; keys missing from Context are unknown, and
+// conditions on them are assumed to hold for Allow statements and not for
+// Deny statements, so that an evaluation errs towards access. The same
+// applies to condition operators the evaluator does not support.
+type Request struct {
+	// Principal is an ARN such as arn:aws:iam::111122223333:role/app, or a
+	// service principal such as ec2.amazonaws.com
+	Principal string
+	Action    string
+	Resource  string
+	Context   map[string][]string
+}
+
+// Foreign returns a request for action by a principal of another account
+// and organization
+func Foreign(action string) Request {
+	return Request{
+		Principal: ForeignPrincipal,
+		Action:    action,
+		Context: map[string][]string{
+			"aws:PrincipalAccount": {ForeignAccount},
+			"aws:PrincipalOrgID":   {ForeignOrg},
+			"aws:PrincipalArn":     {ForeignPrincipal},
+		},
+	}
+}
+
+// Result is the decision for a request and the statement that made it
+type Result struct {
+	Decision  string
+	Statement *Statement
+}
+
+// Evaluate applies the policy evaluation logic to statements: an explicit
+// Deny wins, otherwise a matching Allow allows the request. Values of the
+// policy that need evaluation ("${...}") only match the same text in the
+// request.
+func Evaluate(statements []*Statement, r Request) Result {
+	result := Result{Decision: ImplicitDeny}
+	for _, s := range statements {
+		if !s.matches(r) {
+			continue
+		}
+		if !s.Allows() {
+			return Result{Decision: ExplicitDeny, Statement: s}
+		}
+		if result.Statement == nil {
+			result = Result{Decision: Allowed, Statement: s}
+		}
+	}
+	return result
+}
+
+func (s *Statement) matches(r Request) bool {
+	switch {
+	case s.Actions != nil && !matchAny(s.Actions, r.Action, true):
+		return false
+	case s.NotActions != nil && matchAny(s.NotActions, r.Action, true):
+		return false
+	case r.Resource != "" && s.Resources != nil && !matchAny(s.Resources, r.Resource, false):
+		return false
+	case r.Resource != "" && s.NotResources != nil && matchAny(s.NotResources, r.Resource, false):
+		return false
+	case r.Principal != "" && s.Principals != nil && !principalMatches(s.Principals, r.Principal):
+		return false
+	case r.Principal != "" && s.NotPrincipals != nil && principalMatches(s.NotPrincipals, r.Principal):
+		return false
+	}
+	for _, c := range s.Conditions {
+		if holds, known := c.evaluate(r.Context); !known && !s.Allows() || known && !holds {
+			return false
+		}
+	}
+	return true
+}
+
+// principalMatches reports whether principal is one of the principals of a
+// statement. An account, as its id or root ARN, matches every principal in
+// the account.
+func principalMatches(principals map[string][]string, principal string) bool {
+	if _, ok := principals["*"]; ok {
+		return true
+	}
+	for principalType, identifiers := range principals {
+		for _, identifier := range identifiers {
+			switch {
+			case identifier == "*" && principalType == "AWS":
+				return true
+			case identifier == principal:
+				return true
+			case principalType == "AWS" && account(principal) != "" && account(principal) == AccountOf(identifier):
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// account returns the account of an ARN
+func account(arn string) string {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) < 6 || fields[0] != "arn" {
+		return ""
+	}
+	return fields[4]
+}
+
+// AccountOf returns the account an identifier stands for when it is an
+// account id, such as "111122223333" or an expression ending in account_id,
+// or a root ARN, and "" otherwise
+func AccountOf(identifier string) string {
+	if id := account(identifier); id != "" && strings.HasSuffix(identifier, ":root") {
+		return id
+	}
+	if accountID.MatchString(identifier) {
+		return identifier
+	}
+	return ""
+}
+
+// accountID matches an account id or an expression naming one
+var accountID = regexp.MustCompile(`^(\d{12}|\$\{[^}]*account_id\})$`)
+
+// evaluate returns whether a condition holds for context, and whether that
+// is known
+func (c Condition) evaluate(context map[string][]string) (holds, known bool) {
+	operator := c.Operator
+	forAll := strings.HasPrefix(operator, "ForAllValues:")
+	forAny := strings.HasPrefix(operator, "ForAnyValue:")
+	operator = strings.TrimPrefix(strings.TrimPrefix(operator, "ForAllValues:"), "ForAnyValue:")
+	ifExists := strings.HasSuffix(operator, "IfExists")
+	operator = strings.TrimSuffix(operator, "IfExists")
+
+	values, present := context[c.Key]
+	if operator == "Null" {
+		if !present {
+			return false, false
+		}
+		return len(c.Values) == 1 && (c.Values[0] == "true") == (len(values) == 0), true
+	}
+	if !present {
+		return false, false
+	}
+	if len(values) == 0 {
+		// ForAnyValue holds for no value of an empty set, negated or not;
+		// otherwise only negated, IfExists and ForAllValues operators hold
+		if forAny {
+			return ifExists, true
+		}
+		return ifExists || forAll || strings.Contains(operator, "Not"), true
+	}
+
+	negated := strings.Contains(operator, "Not")
+	var match func(pattern, value string) bool
+	switch strings.Replace(operator, "Not", "", 1) {
+	case "StringEquals", "ArnEquals", "Bool":
+		match = func(pattern, value string) bool { return pattern == value }
+	case "StringEqualsIgnoreCase":
+		match = strings.EqualFold
+	case "StringLike", "ArnLike":
+		match = func(pattern, value string) bool { return wildcard(pattern, value, false) }
+	default:
+		return false, false
+	}
+
+	// a value matches when it equals any of the condition values. The set
+	// operators negate per value: ForAllValues:StringNotEquals holds when no
+	// value matches, ForAnyValue:StringNotEquals when some value does not.
+	// Unqualified negated operators hold when no value matches.
+	holds = forAll
+	anyMatched := false
+	for _, value := range values {
+		matched := false
+		for _, pattern := range c.Values {
+			if match(pattern, value) {
+				matched = true
+				break
+			}
+		}
+		anyMatched = anyMatched || matched
+		switch {
+		case forAll && matched == negated:
+			holds = false
+		case forAny && matched != negated:
+			holds = true
+		}
+	}
+	if !forAll && !forAny {
+		holds = anyMatched != negated
+	}
+	return holds, true
+}
+
+func matchAny(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		if wildcard(pattern, value, ignoreCase) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcard matches value against a pattern where * matches any sequence of
+// characters and ? any single character
+func wildcard(pattern, value string, ignoreCase bool) bool {
+	if ignoreCase {
+		pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	}
+	p, v := 0, 0
+	star, next := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, v
+			p++
+		case star >= 0:
+			next++
+			p, v = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// MatchAction reports whether an action pattern of a statement, such as
+// "kms:GenerateDataKey*", matches action; actions are case-insensitive
+func MatchAction(pattern, action string) bool {
+	return wildcard(pattern, action, true)
+}
+
+// MatchResource reports whether a resource pattern of a statement matches
+// resource; resources are case-sensitive
+func MatchResource(pattern, resource string) bool {
+	return wildcard(pattern, resource, false)
+}
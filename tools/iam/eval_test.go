@@ -0,0 +1,103 @@
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	statements := []*Statement{
+		{Sid: "Read", Effect: "Allow", Principals: map[string][]string{"AWS": {"111122223333"}}, Actions: []string{"s3:Get*"}, Resources: []string{"arn:aws:s3:::${var.bucket}/*"}},
+		{Sid: "Org", Effect: "Allow", Principals: map[string][]string{"*": {"*"}}, Actions: []string{"s3:ListBucket"},
+			Conditions: []Condition{{Operator: "StringEquals", Key: "aws:PrincipalOrgID", Values: []string{"o-ours"}}}},
+		{Sid: "TLS", Effect: "Deny", Principals: map[string][]string{"*": {"*"}}, NotActions: []string{"s3:ListBucket"},
+			Conditions: []Condition{{Operator: "Bool", Key: "aws:SecureTransport", Values: []string{"false"}}}},
+	}
+	own := "arn:aws:iam::111122223333:role/app"
+	for _, tc := range []struct {
+		request  Request
+		decision string
+		sid      string
+	}{
+		{Request{Principal: own, Action: "s3:GetObject", Resource: "arn:aws:s3:::${var.bucket}/key"}, Allowed, "Read"},
+		{Request{Principal: own, Action: "S3:getobject", Resource: "arn:aws:s3:::${var.bucket}/key"}, Allowed, "Read"},
+		{Request{Principal: own, Action: "s3:GetObject", Resource: "arn:aws:s3:::other/key"}, ImplicitDeny, ""},
+		{Request{Principal: "arn:aws:iam::999999999999:role/app", Action: "s3:GetObject", Resource: "arn:aws:s3:::${var.bucket}/key"}, ImplicitDeny, ""},
+		{Request{Principal: own, Action: "s3:GetObject", Context: map[string][]string{"aws:SecureTransport": {"false"}}}, ExplicitDeny, "TLS"},
+		{Request{Action: "s3:ListBucket", Context: map[string][]string{"aws:PrincipalOrgID": {"o-ours"}}}, Allowed, "Org"},
+		{Request{Action: "s3:ListBucket", Context: map[string][]string{"aws:PrincipalOrgID": {"o-other"}}}, ImplicitDeny, ""},
+		{Request{Action: "s3:ListBucket", Context: map[string][]string{"aws:PrincipalOrgID": nil}}, ImplicitDeny, ""},
+		// unknown keys: Allow conditions hold, Deny conditions do not
+		{Request{Action: "s3:ListBucket"}, Allowed, "Org"},
+		{Request{Principal: own, Action: "s3:GetObject"}, Allowed, "Read"},
+	} {
+		result := Evaluate(statements, tc.request)
+		assert.Equal(t, tc.decision, result.Decision, "%+v", tc.request)
+		if tc.sid != "" {
+			assert.Equal(t, tc.sid, result.Statement.Sid, "%+v", tc.request)
+		}
+	}
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	t.Parallel()
+
+	context := map[string][]string{"aws:SourceVpc": {"vpc-1"}, "aws:TagKeys": {"env", "tier"}, "aws:Referer": nil}
+	for _, tc := range []struct {
+		condition    Condition
+		holds, known bool
+	}{
+		{Condition{"StringEquals", "aws:SourceVpc", []string{"vpc-1", "vpc-2"}}, true, true},
+		{Condition{"StringNotEquals", "aws:SourceVpc", []string{"vpc-1"}}, false, true},
+		{Condition{"StringLike", "aws:SourceVpc", []string{"vpc-*"}}, true, true},
+		{Condition{"StringEqualsIgnoreCase", "aws:SourceVpc", []string{"VPC-1"}}, true, true},
+		{Condition{"ForAllValues:StringEquals", "aws:TagKeys", []string{"env", "tier", "owner"}}, true, true},
+		{Condition{"ForAllValues:StringEquals", "aws:TagKeys", []string{"env"}}, false, true},
+		{Condition{"ForAnyValue:StringEquals", "aws:TagKeys", []string{"env"}}, true, true},
+		{Condition{"ForAllValues:StringNotEquals", "aws:TagKeys", []string{"secret"}}, true, true},
+		{Condition{"ForAllValues:StringNotEquals", "aws:TagKeys", []string{"tier"}}, false, true},
+		{Condition{"ForAllValues:StringNotLike", "aws:TagKeys", []string{"t*"}}, false, true},
+		{Condition{"ForAnyValue:StringNotEquals", "aws:TagKeys", []string{"tier"}}, true, true},
+		{Condition{"ForAnyValue:StringNotEquals", "aws:TagKeys", []string{"env", "tier"}}, false, true},
+		{Condition{"ForAllValues:StringNotEquals", "aws:Referer", []string{"x"}}, true, true},
+		{Condition{"ForAnyValue:StringNotEquals", "aws:Referer", []string{"x"}}, false, true},
+		{Condition{"ForAnyValue:StringNotLike", "aws:Referer", []string{"x*"}}, false, true},
+		{Condition{"ForAnyValue:StringEquals", "aws:Referer", []string{"x"}}, false, true},
+		{Condition{"StringEquals", "aws:Referer", []string{"x"}}, false, true},
+		{Condition{"StringEqualsIfExists", "aws:Referer", []string{"x"}}, true, true},
+		{Condition{"StringNotEquals", "aws:Referer", []string{"x"}}, true, true},
+		{Condition{"Null", "aws:Referer", []string{"true"}}, true, true},
+		{Condition{"Null", "aws:SourceVpc", []string{"true"}}, false, true},
+		{Condition{"StringEquals", "aws:SourceVpce", []string{"vpce-1"}}, false, false},
+		{Condition{"IpAddress", "aws:SourceVpc", []string{"10.0.0.0/8"}}, false, false},
+	} {
+		holds, known := tc.condition.evaluate(context)
+		assert.Equal(t, []bool{tc.holds, tc.known}, []bool{holds, known}, "%+v", tc.condition)
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, wildcard("arn:aws:s3:::*/*", "arn:aws:s3:::bucket/key", false))
+	assert.True(t, wildcard("kms:ReEncrypt*", "kms:ReEncryptFrom", false))
+	assert.True(t, wildcard("s3:Get?bject", "s3:GetObject", false))
+	assert.False(t, wildcard("arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket", false))
+	assert.False(t, wildcard("s3:get*", "s3:GetObject", false))
+	assert.True(t, wildcard("s3:get*", "s3:GetObject", true))
+}
+
+func TestAccountOf(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "111122223333", AccountOf("111122223333"))
+	assert.Equal(t, "111122223333", AccountOf("arn:aws:iam::111122223333:root"))
+	assert.Equal(t, "${data.aws_caller_identity.current.account_id}", AccountOf("arn:aws:iam::${data.aws_caller_identity.current.account_id}:root"))
+	assert.Equal(t, "${var.account_id}", AccountOf("${var.account_id}"))
+	assert.Equal(t, "", AccountOf("arn:aws:iam::111122223333:role/app"))
+	assert.Equal(t, "", AccountOf("${var.app_instance_role_arn}"))
+	assert.Equal(t, "", AccountOf("*"))
+}
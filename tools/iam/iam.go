@@ -0,0 +1,422 @@
+// Package iam loads the IAM policy documents declared under modules/:
+// identity policies of roles, users and groups, trust policies, resource
+// policies of keys, secrets and buckets, and VPC endpoint policies.
+//
+// Documents are read from jsonencode(...) expressions and from
+// aws_iam_policy_document data sources referenced as .json. Values that
+// need evaluation are kept as "${...}" (see tfconfig).
+package iam
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Policy kinds
+const (
+	KindIdentity = "identity"
+	KindTrust    = "trust"
+	KindResource = "resource"
+	KindEndpoint = "endpoint"
+)
+
+// Condition is one key of a condition operator, e.g. StringEquals
+// aws:SourceAccount ["123456789012"]
+type Condition struct {
+	Operator string
+	Key      string
+	Values   []string
+}
+
+// Statement is a policy statement. Principals map a principal type (AWS,
+// Service, Federated, CanonicalUser) to its identifiers; Principal "*" is
+// type "*".
+type Statement struct {
+	Sid           string
+	Effect        string
+	Principals    map[string][]string
+	NotPrincipals map[string][]string
+	Actions       []string
+	NotActions    []string
+	Resources     []string
+	NotResources  []string
+	Conditions    []Condition
+	Position      string
+}
+
+// Describe returns "Sid" or, for statements without one, the position
+func (s *Statement) Describe() string {
+	if s.Sid != "" {
+		return s.Sid
+	}
+	return s.Position
+}
+
+// Allows reports whether the statement is an Allow statement
+func (s *Statement) Allows() bool {
+	return s.Effect == "Allow"
+}
+
+// Policy is a policy document set on a resource
+type Policy struct {
+	// Address is the resource the document is set on, e.g.
+	// aws_iam_role_policy.app_secrets_policy
+	Address string
+	// Module is the directory name of the module under modules/
+	Module string
+	// Attribute is the attribute holding the document, e.g. "policy" or
+	// "assume_role_policy"
+	Attribute string
+	Kind      string
+	// Targets are the addresses the policy applies to: the roles, users or
+	// groups of an identity policy, the role of a trust policy, or the key,
+	// secret, bucket or endpoint of a resource or endpoint policy
+	Targets    []string
+	Statements []*Statement
+	Position   string
+}
+
+// policyAttrs are the resource types holding a policy document, mapped to
+// the attribute and the kind of the document
+var policyAttrs = map[string]struct{ attr, kind string }{
+	"aws_iam_policy":                     {"policy", KindIdentity},
+	"aws_iam_role_policy":                {"policy", KindIdentity},
+	"aws_iam_user_policy":                {"policy", KindIdentity},
+	"aws_iam_group_policy":               {"policy", KindIdentity},
+	"aws_iam_role":                       {"assume_role_policy", KindTrust},
+	"aws_kms_key":                        {"policy", KindResource},
+	"aws_kms_key_policy":                 {"policy", KindResource},
+	"aws_secretsmanager_secret":          {"policy", KindResource},
+	"aws_secretsmanager_secret_policy":   {"policy", KindResource},
+	"aws_s3_bucket_policy":               {"policy", KindResource},
+	"aws_sns_topic_policy":               {"policy", KindResource},
+	"aws_sqs_queue_policy":               {"policy", KindResource},
+	"aws_ecr_repository_policy":          {"policy", KindResource},
+	"aws_cloudwatch_log_resource_policy": {"policy_document", KindResource},
+	"aws_vpc_endpoint":                   {"policy", KindEndpoint},
+	"aws_vpc_endpoint_policy":            {"policy", KindEndpoint},
+}
+
+// targetAttrs are the attributes referencing the resource a policy of the
+// given type applies to; policies without one apply to their own resource
+var targetAttrs = map[string]string{
+	"aws_iam_role_policy":              "role",
+	"aws_iam_user_policy":              "user",
+	"aws_iam_group_policy":             "group",
+	"aws_kms_key_policy":               "key_id",
+	"aws_secretsmanager_secret_policy": "secret_arn",
+	"aws_s3_bucket_policy":             "bucket",
+	"aws_sns_topic_policy":             "arn",
+	"aws_sqs_queue_policy":             "queue_url",
+	"aws_ecr_repository_policy":        "repository",
+	"aws_vpc_endpoint_policy":          "vpc_endpoint_id",
+}
+
+// attachments are the resources attaching an aws_iam_policy, mapped to the
+// attributes referencing what it is attached to
+var attachments = map[string][]string{
+	"aws_iam_role_policy_attachment":  {"role"},
+	"aws_iam_user_policy_attachment":  {"user"},
+	"aws_iam_group_policy_attachment": {"group"},
+	"aws_iam_policy_attachment":       {"roles", "users", "groups"},
+}
+
+// Load reads the policy documents of every module under root/modules.
+// Positions are relative to root.
+func Load(root string) ([]*Policy, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	var policies []*Policy
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		l := &loader{root: root, module: module, name: filepath.Base(dir)}
+		policies = append(policies, l.policies()...)
+	}
+	return policies, nil
+}
+
+type loader struct {
+	root   string
+	module *tfconfig.Module
+	name   string
+}
+
+func (l *loader) policies() []*Policy {
+	var policies []*Policy
+	for _, block := range l.module.BlocksOfType("resource") {
+		if len(block.Labels) == 0 {
+			continue
+		}
+		resourceType := block.Labels[0]
+		if spec, ok := policyAttrs[resourceType]; ok && block.Has(spec.attr) {
+			if p := l.policy(block, spec.attr, spec.kind); p != nil {
+				p.Targets = l.targets(block, resourceType)
+				policies = append(policies, p)
+			}
+		}
+		if resourceType == "aws_iam_role" {
+			for _, inline := range block.Nested("inline_policy") {
+				if p := l.policy(inline, "policy", KindIdentity); p != nil {
+					p.Address, p.Targets = block.Address(), []string{block.Address()}
+					policies = append(policies, p)
+				}
+			}
+		}
+	}
+	sort.SliceStable(policies, func(i, j int) bool { return policies[i].Address < policies[j].Address })
+	return policies
+}
+
+// targets returns what a policy set on block applies to
+func (l *loader) targets(block *tfconfig.Block, resourceType string) []string {
+	if attr, ok := targetAttrs[resourceType]; ok {
+		if refs := references(block.Source(attr)); len(refs) > 0 {
+			return refs
+		}
+		return []string{block.Source(attr)}
+	}
+	if resourceType != "aws_iam_policy" {
+		return []string{block.Address()}
+	}
+
+	var targets []string
+	for _, attachment := range l.module.BlocksOfType("resource") {
+		attrs, ok := attachments[attachment.Labels[0]]
+		if !ok || !slices.Contains(references(attachment.Source("policy_arn")), block.Address()) {
+			continue
+		}
+		for _, attr := range attrs {
+			for _, ref := range references(attachment.Source(attr)) {
+				if !slices.Contains(targets, ref) {
+					targets = append(targets, ref)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// policy reads the document of attr, or returns nil when it is not a
+// jsonencode object or an aws_iam_policy_document of the module
+func (l *loader) policy(block *tfconfig.Block, attr, kind string) *Policy {
+	p := &Policy{
+		Address:   block.Address(),
+		Module:    l.name,
+		Attribute: attr,
+		Kind:      kind,
+		Position:  l.position(block, block.AttrLine(attr)),
+	}
+	value, _ := block.Attr(attr)
+	if document, ok := value.(map[string]interface{}); ok {
+		lines := statementLines(block.Body.Attributes[attr].Expr)
+		for i, item := range list(document["Statement"]) {
+			s := parseStatement(item)
+			s.Position = p.Position
+			if i < len(lines) {
+				s.Position = l.position(block, lines[i])
+			}
+			p.Statements = append(p.Statements, s)
+		}
+		return p
+	}
+
+	source := block.Source(attr)
+	for _, data := range l.module.BlocksOfType("data", "aws_iam_policy_document") {
+		if source == data.Address()+".json" {
+			for _, statement := range data.Nested("statement") {
+				s := parseStatementBlock(statement)
+				s.Position = l.position(statement, statement.Line)
+				p.Statements = append(p.Statements, s)
+			}
+			return p
+		}
+	}
+	return nil
+}
+
+// statementLines returns the line of every statement of a jsonencode
+// document, in order
+func statementLines(expr hclsyntax.Expression) []int {
+	if call, ok := expr.(*hclsyntax.FunctionCallExpr); ok && call.Name == "jsonencode" && len(call.Args) == 1 {
+		expr = call.Args[0]
+	}
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+	for _, item := range object.Items {
+		key, ok := item.KeyExpr.(*hclsyntax.ObjectConsKeyExpr)
+		if !ok || hcl.ExprAsKeyword(key.Wrapped) != "Statement" && !isStatementString(key.Wrapped) {
+			continue
+		}
+		if tuple, ok := item.ValueExpr.(*hclsyntax.TupleConsExpr); ok {
+			var lines []int
+			for _, statement := range tuple.Exprs {
+				lines = append(lines, statement.Range().Start.Line)
+			}
+			return lines
+		}
+		return []int{item.ValueExpr.Range().Start.Line}
+	}
+	return nil
+}
+
+func isStatementString(expr hclsyntax.Expression) bool {
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok || len(template.Parts) != 1 {
+		return false
+	}
+	literal, ok := template.Parts[0].(*hclsyntax.LiteralValueExpr)
+	return ok && literal.Val.AsString() == "Statement"
+}
+
+func parseStatement(value interface{}) *Statement {
+	fields, _ := value.(map[string]interface{})
+	s := &Statement{
+		Effect:       str(fields["Effect"]),
+		Sid:          str(fields["Sid"]),
+		Actions:      tfconfig.Strings(fields["Action"]),
+		NotActions:   tfconfig.Strings(fields["NotAction"]),
+		Resources:    tfconfig.Strings(fields["Resource"]),
+		NotResources: tfconfig.Strings(fields["NotResource"]),
+	}
+	s.Principals = principals(fields["Principal"])
+	s.NotPrincipals = principals(fields["NotPrincipal"])
+
+	conditions, _ := fields["Condition"].(map[string]interface{})
+	for _, operator := range sortedKeys(conditions) {
+		keys, _ := conditions[operator].(map[string]interface{})
+		for _, key := range sortedKeys(keys) {
+			s.Conditions = append(s.Conditions, Condition{Operator: operator, Key: key, Values: tfconfig.Strings(keys[key])})
+		}
+	}
+	return s
+}
+
+func principals(value interface{}) map[string][]string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return map[string][]string{v: {v}}
+	case map[string]interface{}:
+		principals := map[string][]string{}
+		for principalType, identifiers := range v {
+			principals[principalType] = tfconfig.Strings(identifiers)
+		}
+		return principals
+	}
+	return map[string][]string{"${}": nil}
+}
+
+func parseStatementBlock(block *tfconfig.Block) *Statement {
+	s := &Statement{Sid: block.String("sid"), Effect: block.String("effect")}
+	if s.Effect == "" {
+		s.Effect = "Allow"
+	}
+	for _, field := range []struct {
+		attr   string
+		values *[]string
+	}{
+		{"actions", &s.Actions},
+		{"not_actions", &s.NotActions},
+		{"resources", &s.Resources},
+		{"not_resources", &s.NotResources},
+	} {
+		value, _ := block.Attr(field.attr)
+		*field.values = tfconfig.Strings(value)
+	}
+	for _, field := range []struct {
+		blockType  string
+		principals *map[string][]string
+	}{
+		{"principals", &s.Principals},
+		{"not_principals", &s.NotPrincipals},
+	} {
+		for _, principal := range block.Nested(field.blockType) {
+			if *field.principals == nil {
+				*field.principals = map[string][]string{}
+			}
+			identifiers, _ := principal.Attr("identifiers")
+			principalType := principal.String("type")
+			(*field.principals)[principalType] = append((*field.principals)[principalType], tfconfig.Strings(identifiers)...)
+		}
+	}
+	for _, condition := range block.Nested("condition") {
+		values, _ := condition.Attr("values")
+		s.Conditions = append(s.Conditions, Condition{Operator: condition.String("test"), Key: condition.String("variable"), Values: tfconfig.Strings(values)})
+	}
+	return s
+}
+
+func (l *loader) position(block *tfconfig.Block, line int) string {
+	return tfconfig.Position(l.root, block.File, line)
+}
+
+// Service returns the service prefix of an action, e.g. "kms" for
+// "kms:Decrypt", or "" for "*"
+func Service(action string) string {
+	service, _, ok := strings.Cut(action, ":")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(service)
+}
+
+func str(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func list(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case nil:
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// references returns the resource addresses an expression references, e.g.
+// aws_iam_role.app for "aws_iam_role.app.id"
+func references(source string) []string {
+	var refs []string
+	for _, field := range strings.FieldsFunc(source, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-')
+	}) {
+		parts := strings.Split(field, ".")
+		if len(parts) >= 2 && strings.HasPrefix(parts[0], "aws_") {
+			if ref := parts[0] + "." + parts[1]; !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,91 @@
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// find returns the policy set on address
+func find(t *testing.T, policies []*Policy, address, attribute string) *Policy {
+	t.Helper()
+	for _, p := range policies {
+		if p.Address == address && p.Attribute == attribute {
+			return p
+		}
+	}
+	require.Failf(t, "policy not found", "%s %s", address, attribute)
+	return nil
+}
+
+func TestLoadRepository(t *testing.T) {
+	t.Parallel()
+
+	policies, err := Load("../..")
+	require.NoError(t, err)
+
+	secrets := find(t, policies, "aws_iam_role_policy.app_secrets_policy", "policy")
+	assert.Equal(t, KindIdentity, secrets.Kind)
+	assert.Equal(t, "security", secrets.Module)
+	assert.Equal(t, []string{"aws_iam_role.app_instance_role"}, secrets.Targets)
+	require.Len(t, secrets.Statements, 1)
+	assert.Equal(t, "AccessSecretsAndKMS", secrets.Statements[0].Sid)
+	assert.Equal(t, []string{"secretsmanager:GetSecretValue", "kms:Decrypt"}, secrets.Statements[0].Actions)
+	assert.Equal(t, "modules/security/iam.tf:20", secrets.Statements[0].Position)
+
+	trust := find(t, policies, "aws_iam_role.app_instance_role", "assume_role_policy")
+	assert.Equal(t, KindTrust, trust.Kind)
+	require.Len(t, trust.Statements, 1)
+	assert.Equal(t, map[string][]string{"Service": {"ec2.amazonaws.com"}}, trust.Statements[0].Principals)
+	assert.Equal(t, "modules/security/data.tf:8", trust.Statements[0].Position, "statements of policy documents are at their block")
+
+	key := find(t, policies, "aws_kms_key_policy.main", "policy")
+	assert.Equal(t, KindResource, key.Kind)
+	assert.Equal(t, []string{"Enable IAM User Permissions", "Allow App Role Use"}, []string{key.Statements[0].Sid, key.Statements[1].Sid})
+
+	endpoint := find(t, policies, "aws_vpc_endpoint_policy.secretsmanager", "policy")
+	assert.Equal(t, KindEndpoint, endpoint.Kind)
+	assert.Equal(t, []string{"aws_vpc_endpoint.secretsmanager"}, endpoint.Targets)
+	assert.Equal(t, []Condition{{Operator: "StringNotEquals", Key: "aws:SourceVpc", Values: []string{"${var.vpc_id}"}}}, endpoint.Statements[1].Conditions)
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	policies, err := Load("testdata")
+	require.NoError(t, err)
+	require.Len(t, policies, 4, "the document read with file() is skipped")
+
+	trust := find(t, policies, "aws_iam_role.worker", "assume_role_policy")
+	require.Len(t, trust.Statements, 1)
+	s := trust.Statements[0]
+	assert.Equal(t, "Allow", s.Effect, "policy document statements allow by default")
+	assert.Equal(t, map[string][]string{"Service": {"lambda.amazonaws.com"}}, s.Principals)
+	assert.Equal(t, []Condition{{Operator: "StringEquals", Key: "aws:SourceAccount", Values: []string{"${var.account_id}"}}}, s.Conditions)
+	assert.Equal(t, "modules/app/main.tf:2", s.Describe())
+
+	inline := find(t, policies, "aws_iam_role.worker", "policy")
+	assert.Equal(t, KindIdentity, inline.Kind)
+	assert.Equal(t, []string{"aws_iam_role.worker"}, inline.Targets)
+	require.Len(t, inline.Statements, 1, "a single statement object")
+	assert.Equal(t, "modules/app/main.tf:24", inline.Statements[0].Position)
+	assert.Equal(t, []string{"${aws_sqs_queue.jobs.arn}"}, inline.Statements[0].Resources)
+
+	shared := find(t, policies, "aws_iam_policy.shared", "policy")
+	assert.Equal(t, []string{"aws_iam_role.worker", "aws_iam_user.ops"}, shared.Targets)
+	assert.Equal(t, []string{"kms:Decrypt"}, shared.Statements[0].NotActions)
+	assert.Equal(t, []Condition{{Operator: "Bool", Key: "aws:SecureTransport", Values: []string{"false"}}}, shared.Statements[0].Conditions)
+
+	queue := find(t, policies, "aws_sqs_queue_policy.jobs", "policy")
+	assert.Equal(t, []string{"aws_sqs_queue.jobs"}, queue.Targets)
+	assert.Equal(t, map[string][]string{"*": {"*"}}, queue.Statements[0].Principals)
+}
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "kms", Service("kms:Decrypt"))
+	assert.Equal(t, "ec2messages", Service("EC2Messages:GetMessages"))
+	assert.Equal(t, "", Service("*"))
+}
@@ -0,0 +1,73 @@
+data "aws_iam_policy_document" "trust" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["lambda.amazonaws.com"]
+    }
+    condition {
+      test     = "StringEquals"
+      variable = "aws:SourceAccount"
+      values   = [var.account_id]
+    }
+  }
+}
+
+resource "aws_iam_role" "worker" {
+  name               = "worker"
+  assume_role_policy = data.aws_iam_policy_document.trust.json
+
+  inline_policy {
+    name = "queue"
+    policy = jsonencode({
+      Version = "2012-10-17"
+      Statement = {
+        Sid      = "Consume"
+        Effect   = "Allow"
+        Action   = "sqs:ReceiveMessage"
+        Resource = aws_sqs_queue.jobs.arn
+      }
+    })
+  }
+}
+
+resource "aws_iam_policy" "shared" {
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect    = "Deny"
+        NotAction = ["kms:Decrypt"]
+        Resource  = "*"
+        Condition = {
+          Bool = { "aws:SecureTransport" = "false" }
+        }
+      },
+    ]
+  })
+}
+
+resource "aws_iam_policy_attachment" "shared" {
+  name       = "shared"
+  roles      = [aws_iam_role.worker.name]
+  users      = [aws_iam_user.ops.name]
+  policy_arn = aws_iam_policy.shared.arn
+}
+
+resource "aws_sqs_queue_policy" "jobs" {
+  queue_url = aws_sqs_queue.jobs.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = "*"
+      Action    = "sqs:SendMessage"
+      Resource  = aws_sqs_queue.jobs.arn
+    }]
+  })
+}
+
+resource "aws_s3_bucket_policy" "external" {
+  bucket = aws_s3_bucket.logs.id
+  policy = file("${path.module}/policy.json")
+}
@@ -0,0 +1,300 @@
+// Package impact selects the integration tests and E2E profiles affected by
+// a change.
+//
+// Changed files are mapped to the root modules of an environment: a file
+// under envs/<env>/<module> changes that module, and a file under
+// modules/<name> changes every root module calling it. The changed modules
+// are expanded through the remote-state graph of tools/stack to every
+// downstream module, and a test is selected when it deploys one of them.
+// Changes to a suite's shared files or to the tools packages it imports
+// select the whole suite.
+package impact
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+// Suites are the test suites impact selects tests from, by name
+var Suites = []struct{ Name, Dir string }{
+	{"integration", "test/integration"},
+	{"e2e", "test/e2e"},
+}
+
+// Selection is the tests of one suite to run
+type Selection struct {
+	Suite string   `json:"suite"`
+	Dir   string   `json:"dir"`
+	Tests []string `json:"tests"`
+	// All is set when the whole suite is selected, with the reason
+	All string `json:"all,omitempty"`
+}
+
+// Run returns the -run regular expression matching exactly the selected
+// tests. An empty selection matches no test, so `go test -run` never falls
+// back to running everything.
+func (s Selection) Run() string {
+	if len(s.Tests) == 0 {
+		return "^$"
+	}
+	return "^(" + strings.Join(s.Tests, "|") + ")$"
+}
+
+// Impact is the result of Analyze
+type Impact struct {
+	Env   string   `json:"env"`
+	Files []string `json:"files"`
+	// Changed are the root modules changed by the files
+	Changed []string `json:"changed_modules"`
+	// Affected are Changed plus every module downstream of them
+	Affected []string    `json:"affected_modules"`
+	Suites   []Selection `json:"suites"`
+	// Ignored are the files that affect no test
+	Ignored []string `json:"ignored_files"`
+}
+
+// Suite returns the selection of the named suite
+func (i *Impact) Suite(name string) Selection {
+	for _, s := range i.Suites {
+		if s.Suite == name {
+			return s
+		}
+	}
+	return Selection{Suite: name}
+}
+
+// Analyze maps files, relative to the repository root, to the tests to run.
+// env is the environment whose remote-state graph is used.
+func Analyze(root, env string, files []string) (*Impact, error) {
+	s, err := stack.Load(filepath.Join(root, "envs", env))
+	if err != nil {
+		return nil, err
+	}
+	imports, err := toolsImports(filepath.Join(root, "tools"))
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &Impact{Env: env, Files: files}
+	selected := map[string]map[string]bool{}
+	all := map[string]string{}
+	suites := map[string]*Suite{}
+	for _, def := range Suites {
+		suite, err := LoadSuite(def.Name, filepath.Join(root, filepath.FromSlash(def.Dir)), s.Names())
+		if err != nil {
+			return nil, err
+		}
+		suites[def.Name] = suite
+		selected[def.Name] = map[string]bool{}
+	}
+
+	for _, file := range files {
+		file = path.Clean(filepath.ToSlash(file))
+		affects := false
+
+		if modules := changedModules(root, s, file); len(modules) > 0 {
+			for _, m := range modules {
+				if !slices.Contains(impact.Changed, m) {
+					impact.Changed = append(impact.Changed, m)
+				}
+			}
+			affects = true
+		}
+
+		for _, def := range Suites {
+			suite := suites[def.Name]
+			if reason := suiteChange(def.Dir, suite, file, imports); reason != "" {
+				if all[def.Name] == "" {
+					all[def.Name] = reason
+				}
+				affects = true
+			} else if test := changedTest(def.Dir, suite, file); test != nil {
+				for _, t := range test {
+					selected[def.Name][t] = true
+				}
+				affects = true
+			}
+		}
+
+		if !affects {
+			impact.Ignored = append(impact.Ignored, file)
+		}
+	}
+
+	sort.Strings(impact.Changed)
+	impact.Affected = s.Downstream(impact.Changed...)
+	affected := map[string]bool{}
+	for _, m := range impact.Affected {
+		affected[m] = true
+	}
+
+	for _, def := range Suites {
+		suite := suites[def.Name]
+		selection := Selection{Suite: def.Name, Dir: def.Dir, Tests: []string{}, All: all[def.Name]}
+		for _, test := range suite.Tests {
+			if selection.All != "" || selected[def.Name][test.Name] || deploysAny(test, affected) {
+				selection.Tests = append(selection.Tests, test.Name)
+			}
+		}
+		sort.Strings(selection.Tests)
+		impact.Suites = append(impact.Suites, selection)
+	}
+	return impact, nil
+}
+
+// changedModules returns the root modules a file belongs to: envs/*/<module>
+// in any environment, or every root module calling modules/<name>
+func changedModules(root string, s *stack.Stack, file string) []string {
+	parts := strings.Split(file, "/")
+	switch {
+	case parts[0] == "envs":
+		for _, part := range parts[1 : len(parts)-1] {
+			if _, ok := s.Modules[part]; ok {
+				return []string{part}
+			}
+		}
+	case parts[0] == "modules" && len(parts) > 2:
+		dir := filepath.Join(root, "modules", parts[1])
+		var modules []string
+		for _, name := range s.Names() {
+			for _, source := range s.Modules[name].Sources {
+				if source == dir {
+					modules = append(modules, name)
+				}
+			}
+		}
+		return modules
+	}
+	return nil
+}
+
+// suiteChange returns why a change to file selects the whole suite, or ""
+func suiteChange(dir string, suite *Suite, file string, imports map[string][]string) string {
+	if strings.HasPrefix(file, dir+"/") {
+		name := strings.TrimPrefix(file, dir+"/")
+		if strings.HasSuffix(name, ".md") || changedTest(dir, suite, file) != nil {
+			return ""
+		}
+		return file + " is shared by every test of the suite"
+	}
+
+	if !strings.HasPrefix(file, "tools/") || len(suite.Imports) == 0 {
+		return ""
+	}
+	name := strings.TrimPrefix(file, "tools/")
+	if name == "go.mod" || name == "go.sum" {
+		return file + " changes the tools module the suite imports"
+	}
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return ""
+	}
+	if pkg := path.Dir(name); dependencies(suite.Imports, imports)[pkg] {
+		return fmt.Sprintf("the suite imports tools/%s", pkg)
+	}
+	return ""
+}
+
+// changedTest returns the tests defined in file when it is a test file of
+// the suite defining tests, or nil
+func changedTest(dir string, suite *Suite, file string) []string {
+	if path.Dir(file) != dir {
+		return nil
+	}
+	var tests []string
+	for _, test := range suite.Tests {
+		if test.File == path.Base(file) {
+			tests = append(tests, test.Name)
+		}
+	}
+	return tests
+}
+
+func deploysAny(test Test, modules map[string]bool) bool {
+	for _, m := range test.Modules {
+		if modules[m] {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDiff returns the files changed by a unified diff (`git diff`
+// output). Input without diff headers is read as one path per line, as
+// printed by `git diff --name-only`.
+func ParseDiff(r io.Reader) ([]string, error) {
+	var files, lines []string
+	headers := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			headers = true
+		case strings.HasPrefix(line, "--- a/"):
+			if file := strings.TrimPrefix(line, "--- a/"); !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		case strings.HasPrefix(line, "+++ b/"):
+			if file := strings.TrimPrefix(line, "+++ b/"); !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		case strings.TrimSpace(line) != "":
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if headers || len(files) > 0 {
+		return files, nil
+	}
+
+	files = nil
+	for _, line := range lines {
+		if !slices.Contains(files, line) {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// Text writes a human readable summary followed by a `go test` command per
+// suite
+func (i *Impact) Text(w io.Writer) {
+	fmt.Fprintf(w, "Changed modules:  %s\n", list(i.Changed))
+	fmt.Fprintf(w, "Affected modules: %s (envs/%s remote-state graph)\n", list(i.Affected), i.Env)
+	for _, s := range i.Suites {
+		fmt.Fprintf(w, "\n%s: %d test(s)\n", s.Suite, len(s.Tests))
+		if s.All != "" {
+			fmt.Fprintf(w, "  whole suite: %s\n", s.All)
+		}
+		for _, test := range s.Tests {
+			fmt.Fprintf(w, "  %s\n", test)
+		}
+		if len(s.Tests) > 0 {
+			fmt.Fprintf(w, "  (cd %s && go test -v -run '%s' ./...)\n", s.Dir, s.Run())
+		}
+	}
+}
+
+// JSON returns the impact as indented JSON
+func (i *Impact) JSON() ([]byte, error) {
+	return json.MarshalIndent(i, "", "  ")
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,138 @@
+package impact
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analyze(t *testing.T, files ...string) *Impact {
+	t.Helper()
+	impact, err := Analyze("../..", "dev", files)
+	require.NoError(t, err)
+	return impact
+}
+
+func TestLoadSuiteReadsTerraformDirs(t *testing.T) {
+	t.Parallel()
+
+	suite, err := LoadSuite("e2e", "../../test/e2e", []string{"bootstrap", "security", "vpc", "compute"})
+	require.NoError(t, err)
+
+	modules := map[string][]string{}
+	for _, test := range suite.Tests {
+		modules[test.Name] = test.Modules
+	}
+	assert.Equal(t, []string{"bootstrap", "security", "vpc"}, modules["TestE2ECriticalPath"])
+	assert.Contains(t, modules["TestE2EStackDeployment"], "compute")
+	assert.Contains(t, modules["TestE2EStackCleanup"], "compute", "module names listed in the test count as well")
+	assert.NotContains(t, suite.Names(), "TestMain")
+	assert.Contains(t, suite.Imports, "timing")
+}
+
+func TestModuleChangeSelectsOnlyItsTests(t *testing.T) {
+	t.Parallel()
+
+	impact := analyze(t, "modules/certificates/main.tf")
+	assert.Equal(t, []string{"certificates"}, impact.Changed)
+	assert.Equal(t, []string{"certificates"}, impact.Affected)
+
+	integration := impact.Suite("integration")
+	assert.Equal(t, []string{"TestCertificatesModule"}, integration.Tests)
+	assert.Equal(t, "^(TestCertificatesModule)$", integration.Run())
+	assert.NotContains(t, impact.Suite("e2e").Tests, "TestE2ECriticalPath", "certificates is not on the critical path")
+}
+
+func TestChangesExpandDownstream(t *testing.T) {
+	t.Parallel()
+
+	impact := analyze(t, "envs/dev/vpc/main.tf")
+	assert.Equal(t, []string{"compute", "firewall", "monitoring", "vpc", "vpc-endpoints"}, impact.Affected)
+
+	integration := impact.Suite("integration")
+	assert.Contains(t, integration.Tests, "TestVpcCreation")
+	assert.Contains(t, integration.Tests, "TestFirewallNACLs", "firewall reads the vpc state")
+	assert.NotContains(t, integration.Tests, "TestSecretsModule")
+	assert.Contains(t, impact.Suite("e2e").Tests, "TestE2ECriticalPath")
+}
+
+func TestSharedFilesSelectWholeSuite(t *testing.T) {
+	t.Parallel()
+
+	impact := analyze(t, "test/integration/retryable_test.go", "tools/timing/timing.go", "docs/TESTING.md")
+	integration := impact.Suite("integration")
+	assert.Equal(t, "test/integration/retryable_test.go is shared by every test of the suite", integration.All)
+	assert.Contains(t, integration.Tests, "TestSecretsModule")
+	assert.Equal(t, "the suite imports tools/timing", impact.Suite("e2e").All)
+	assert.Equal(t, []string{"docs/TESTING.md"}, impact.Ignored)
+
+	impact = analyze(t, "test/integration/compute_test.go", "tools/stack/stack.go")
+	assert.Equal(t, []string{"TestComputeEBSEncryption", "TestComputeModule", "TestComputeUserData"}, impact.Suite("integration").Tests)
+	assert.Empty(t, impact.Suite("e2e").Tests, "no suite imports tools/stack")
+	assert.Equal(t, "^$", impact.Suite("e2e").Run(), "an empty selection runs nothing")
+}
+
+func TestToolsImportsAreTransitive(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(file, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(file)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, file), []byte(content), 0o644))
+	}
+	write("envs/dev/vpc/main.tf", "module \"vpc\" {\n  source = \"../../../modules/vpc\"\n}\n")
+	write("tools/a/a.go", "package a\n\nimport _ \""+toolsModule+"b\"\n")
+	write("tools/b/b.go", "package b\n")
+	write("test/integration/vpc_test.go", "package integration\n\nimport (\n\t\"testing\"\n\n\t_ \""+toolsModule+"a\"\n)\n\n"+
+		"func TestVpc(t *testing.T) {\n\t_ = \"../../envs/test/integration/vpc\"\n}\n")
+
+	impact, err := Analyze(root, "dev", []string{"tools/b/b.go"})
+	require.NoError(t, err)
+	assert.Equal(t, "the suite imports tools/b", impact.Suite("integration").All)
+
+	impact, err = Analyze(root, "dev", []string{"modules/vpc/variables.tf"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TestVpc"}, impact.Suite("integration").Tests)
+}
+
+func TestParseDiff(t *testing.T) {
+	t.Parallel()
+
+	diff := `diff --git a/modules/certificates/main.tf b/modules/certificates/main.tf
+index 1111111..2222222 100644
+--- a/modules/certificates/main.tf
++++ b/modules/certificates/main.tf
+@@ -1 +1 @@
+-  validity = 30
++  validity = 60
+diff --git a/docs/old.md b/docs/new.md
+similarity index 90%
+--- a/docs/old.md
++++ b/docs/new.md
+diff --git a/test/e2e/new_test.go b/test/e2e/new_test.go
+new file mode 100644
+--- /dev/null
++++ b/test/e2e/new_test.go
+`
+	files, err := ParseDiff(strings.NewReader(diff))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"modules/certificates/main.tf", "docs/old.md", "docs/new.md", "test/e2e/new_test.go"}, files)
+
+	files, err = ParseDiff(strings.NewReader("modules/vpc/main.tf\n\nenvs/dev/vpc/main.tf\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"modules/vpc/main.tf", "envs/dev/vpc/main.tf"}, files, "name-only output is one path per line")
+}
+
+func TestText(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	analyze(t, "modules/certificates/main.tf").Text(&out)
+	assert.Contains(t, out.String(), "Changed modules:  certificates\n")
+	assert.Contains(t, out.String(), "(cd test/integration && go test -v -run '^(TestCertificatesModule)$' ./...)")
+}
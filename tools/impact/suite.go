@@ -0,0 +1,186 @@
+package impact
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// toolsModule is the import path prefix of the packages under tools/
+const toolsModule = "github.com/y3gi/zero-trust-aws/tools/"
+
+// Suite is a directory of terratest tests, e.g. test/integration
+type Suite struct {
+	Name  string
+	Dir   string
+	Tests []Test
+	// Imports are the tools packages the suite imports, e.g. "timing"
+	Imports []string
+}
+
+// Test is a top-level test function of a suite
+type Test struct {
+	Name string
+	File string
+	// Modules are the root modules the test deploys, read from
+	// TerraformDir paths such as "../../envs/test/integration/vpc" and
+	// from string literals naming a module
+	Modules []string
+}
+
+// Names returns the names of the suite's tests, sorted
+func (s *Suite) Names() []string {
+	names := make([]string, 0, len(s.Tests))
+	for _, test := range s.Tests {
+		names = append(names, test.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadSuite parses the _test.go files in dir. modules are the names of the
+// environment's root modules.
+func LoadSuite(name, dir string, modules []string) (*Suite, error) {
+	known := map[string]bool{}
+	for _, m := range modules {
+		known[m] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	suite := &Suite{Name: name, Dir: dir}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range f.Imports {
+			if p, _ := strconv.Unquote(spec.Path.Value); strings.HasPrefix(p, toolsModule) {
+				if imported := strings.TrimPrefix(p, toolsModule); !slices.Contains(suite.Imports, imported) {
+					suite.Imports = append(suite.Imports, imported)
+				}
+			}
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !isTest(fn) {
+				continue
+			}
+			suite.Tests = append(suite.Tests, Test{
+				Name:    fn.Name.Name,
+				File:    filepath.Base(file),
+				Modules: testModules(fn, known),
+			})
+		}
+	}
+	sort.Strings(suite.Imports)
+	return suite, nil
+}
+
+// isTest reports whether fn is a func TestXxx(t *testing.T)
+func isTest(fn *ast.FuncDecl) bool {
+	if fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") || fn.Name.Name == "TestMain" {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
+
+func testModules(fn *ast.FuncDecl, known map[string]bool) []string {
+	var modules []string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+		if strings.Contains(value, "envs/") {
+			value = path.Base(value)
+		}
+		if known[value] {
+			if !slices.Contains(modules, value) {
+				modules = append(modules, value)
+			}
+		}
+		return true
+	})
+	sort.Strings(modules)
+	return modules
+}
+
+// toolsImports maps every package under tools/ to the tools packages its
+// non-test files import
+func toolsImports(dir string) (map[string][]string, error) {
+	imports := map[string][]string{}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == "testdata" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		pkg := filepath.ToSlash(rel)
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			if p, _ := strconv.Unquote(spec.Path.Value); strings.HasPrefix(p, toolsModule) {
+				if imported := strings.TrimPrefix(p, toolsModule); !slices.Contains(imports[pkg], imported) {
+					imports[pkg] = append(imports[pkg], imported)
+				}
+			}
+		}
+		return nil
+	})
+	return imports, err
+}
+
+// dependencies returns pkgs plus every tools package they import,
+// directly or transitively
+func dependencies(pkgs []string, imports map[string][]string) map[string]bool {
+	deps := map[string]bool{}
+	queue := append([]string(nil), pkgs...)
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		if deps[pkg] {
+			continue
+		}
+		deps[pkg] = true
+		queue = append(queue, imports[pkg]...)
+	}
+	return deps
+}
@@ -0,0 +1,191 @@
+package interfaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// descriptions of the input changes that break the calls not passing the
+// input
+const (
+	addedRequired  = "added without a default"
+	defaultRemoved = "default removed"
+)
+
+// Change is a difference between the interface of a module in two snapshots
+type Change struct {
+	Module string
+	// Kind is "input", "output", or "module" when the whole module is added
+	// or removed
+	Kind string
+	Name string
+	// Description says what changed, e.g. "removed" or "type string ->
+	// list(string)"
+	Description string
+	// Breaking changes need the callers, or the readers of their state, to
+	// change in the same deploy
+	Breaking bool
+	// Consumers are the positions under envs/ a breaking change breaks
+	Consumers []string
+}
+
+// String returns e.g. "breaking: modules/security output kms_key_arn
+// removed; used by envs/dev/compute/main.tf:54"
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	text := fmt.Sprintf("%s: modules/%s %s %s %s", kind, c.Module, c.Kind, c.Name, c.Description)
+	if c.Kind == "module" {
+		text = fmt.Sprintf("%s: modules/%s %s", kind, c.Module, c.Description)
+	}
+	if len(c.Consumers) > 0 {
+		text += "; used by " + strings.Join(c.Consumers, ", ")
+	}
+	return text
+}
+
+// Compare returns the changes from the snapshot old to current, sorted by
+// module, kind and name. Consumers are filled in from uses, which may be nil.
+func Compare(old, current *Snapshot, uses *Uses) []Change {
+	var changes []Change
+	add := func(c Change) {
+		if c.Breaking && uses != nil {
+			c.Consumers = uses.consumers(c)
+		}
+		changes = append(changes, c)
+	}
+
+	for _, module := range moduleNames(old, current) {
+		before, after := old.Modules[module], current.Modules[module]
+		switch {
+		case before == nil:
+			add(Change{Module: module, Kind: "module", Description: "added"})
+			continue
+		case after == nil:
+			add(Change{Module: module, Kind: "module", Description: "removed", Breaking: true})
+			continue
+		}
+
+		inputs := map[string]Input{}
+		for _, input := range after.Inputs {
+			inputs[input.Name] = input
+		}
+		for _, input := range before.Inputs {
+			now, ok := inputs[input.Name]
+			delete(inputs, input.Name)
+			switch {
+			case !ok:
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: "removed", Breaking: true})
+				continue
+			case now.Type != input.Type:
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: fmt.Sprintf("type %s -> %s", describeType(input.Type), describeType(now.Type)), Breaking: true})
+			}
+			switch {
+			case now.Required && !input.Required:
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: defaultRemoved, Breaking: true})
+			case !now.Required && input.Required:
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: "default added"})
+			case !now.Required && !sameValue(now.Default, input.Default):
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: fmt.Sprintf("default %s -> %s", jsonText(input.Default), jsonText(now.Default))})
+			}
+			if now.Sensitive != input.Sensitive {
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: fmt.Sprintf("sensitive %t -> %t", input.Sensitive, now.Sensitive)})
+			}
+		}
+		for _, input := range after.Inputs {
+			if _, ok := inputs[input.Name]; !ok {
+				continue
+			}
+			if input.Required {
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: addedRequired, Breaking: true})
+			} else {
+				add(Change{Module: module, Kind: "input", Name: input.Name, Description: "added"})
+			}
+		}
+
+		outputs := map[string]Output{}
+		for _, output := range after.Outputs {
+			outputs[output.Name] = output
+		}
+		for _, output := range before.Outputs {
+			now, ok := outputs[output.Name]
+			delete(outputs, output.Name)
+			switch {
+			case !ok:
+				add(Change{Module: module, Kind: "output", Name: output.Name, Description: "removed", Breaking: true})
+			case now.Sensitive && !output.Sensitive:
+				// root module outputs exposing it must be marked sensitive too
+				add(Change{Module: module, Kind: "output", Name: output.Name, Description: "sensitive false -> true", Breaking: true})
+			case !now.Sensitive && output.Sensitive:
+				add(Change{Module: module, Kind: "output", Name: output.Name, Description: "sensitive true -> false"})
+			}
+		}
+		for _, output := range after.Outputs {
+			if _, ok := outputs[output.Name]; ok {
+				add(Change{Module: module, Kind: "output", Name: output.Name, Description: "added"})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Module != changes[j].Module {
+			return changes[i].Module < changes[j].Module
+		}
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// Breaking returns the breaking changes
+func Breaking(changes []Change) []Change {
+	var breaking []Change
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+func moduleNames(snapshots ...*Snapshot) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, s := range snapshots {
+		for name := range s.Modules {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeType(t string) string {
+	if t == "" {
+		return "any"
+	}
+	return t
+}
+
+// sameValue compares two defaults as JSON, so that a snapshot read back
+// equals the one written
+func sameValue(a, b interface{}) bool {
+	return jsonText(a) == jsonText(b)
+}
+
+func jsonText(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
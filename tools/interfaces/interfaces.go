@@ -0,0 +1,128 @@
+// Package interfaces snapshots the inputs and outputs of the modules under
+// modules/ and compares a snapshot with the current code. Removing an
+// output, or adding a required input, breaks the root modules under envs/
+// that call the module, and the root modules reading their state, on the
+// next deploy: Compare classifies every change as breaking or not and
+// LoadUses finds the consumers a breaking change affects.
+//
+// The committed snapshot is tools/interfaces/snapshot.json; `ztctl
+// interfaces` rewrites it.
+package interfaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// SnapshotPath is the committed snapshot, relative to the repository root
+const SnapshotPath = "tools/interfaces/snapshot.json"
+
+// Snapshot maps the directory name of every module under modules/ to its
+// interface
+type Snapshot struct {
+	Modules map[string]*Interface `json:"modules"`
+}
+
+// Interface is the inputs and outputs of a module, sorted by name
+type Interface struct {
+	Inputs  []Input  `json:"inputs"`
+	Outputs []Output `json:"outputs"`
+}
+
+// Input is a variable of a module
+type Input struct {
+	Name string `json:"name"`
+	// Type is the type constraint as written, or "" for any type
+	Type string `json:"type,omitempty"`
+	// Default is the default value, with expressions kept as "${...}"; it
+	// is only meaningful when Required is false
+	Default   interface{} `json:"default,omitempty"`
+	Required  bool        `json:"required"`
+	Sensitive bool        `json:"sensitive"`
+}
+
+// Output is an output of a module
+type Output struct {
+	Name      string `json:"name"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// Take snapshots every module under root/modules
+func Take(root string) (*Snapshot, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Modules: map[string]*Interface{}}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(module.Blocks) == 0 {
+			continue
+		}
+		s.Modules[filepath.Base(dir)] = interfaceOf(module)
+	}
+	return s, nil
+}
+
+func interfaceOf(module *tfconfig.Module) *Interface {
+	i := &Interface{Inputs: []Input{}, Outputs: []Output{}}
+	for _, block := range module.BlocksOfType("variable") {
+		input := Input{Name: block.Name(), Type: strings.Join(strings.Fields(block.Source("type")), "")}
+		input.Default, input.Required = block.Attr("default")
+		input.Required = !input.Required
+		input.Sensitive, _ = attrBool(block, "sensitive")
+		i.Inputs = append(i.Inputs, input)
+	}
+	for _, block := range module.BlocksOfType("output") {
+		sensitive, _ := attrBool(block, "sensitive")
+		i.Outputs = append(i.Outputs, Output{Name: block.Name(), Sensitive: sensitive})
+	}
+	sort.Slice(i.Inputs, func(a, b int) bool { return i.Inputs[a].Name < i.Inputs[b].Name })
+	sort.Slice(i.Outputs, func(a, b int) bool { return i.Outputs[a].Name < i.Outputs[b].Name })
+	return i
+}
+
+func attrBool(block *tfconfig.Block, name string) (bool, bool) {
+	value, _ := block.Attr(name)
+	b, ok := value.(bool)
+	return b, ok
+}
+
+// Read reads a snapshot written by Write
+func Read(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if s.Modules == nil {
+		s.Modules = map[string]*Interface{}
+	}
+	return &s, nil
+}
+
+// Write writes the snapshot as indented JSON
+func (s *Snapshot) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,114 @@
+package interfaces
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func take(t *testing.T, root string) *Snapshot {
+	t.Helper()
+	s, err := Take(root)
+	require.NoError(t, err)
+	return s
+}
+
+// TestSnapshot fails when the modules no longer match the committed
+// snapshot. Review the breaking changes with their consumers, then run
+// `go run ./cmd/ztctl interfaces` to update the snapshot.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	committed, err := Read(filepath.Base(SnapshotPath))
+	require.NoError(t, err)
+	uses, err := LoadUses("../..")
+	require.NoError(t, err)
+
+	changes := Compare(committed, take(t, "../.."), uses)
+	for _, c := range changes {
+		t.Error(c)
+	}
+	if len(changes) > 0 {
+		t.Log("run `go run ./cmd/ztctl interfaces` in tools/ to update the snapshot once the consumers are updated")
+	}
+}
+
+func TestTake(t *testing.T) {
+	t.Parallel()
+
+	s := take(t, "testdata")
+	require.Contains(t, s.Modules, "net")
+	net := s.Modules["net"]
+	assert.Equal(t, []Input{
+		{Name: "api_token", Type: "string", Required: true, Sensitive: true},
+		{Name: "cidr", Type: "string", Default: "10.0.0.0/16"},
+		{Name: "env", Type: "string", Required: true},
+		{Name: "subnet_cidrs", Type: "list(string)", Default: []interface{}{}},
+	}, net.Inputs)
+	assert.Equal(t, []Output{{Name: "subnet_ids"}, {Name: "token", Sensitive: true}, {Name: "vpc_id"}}, net.Outputs)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf))
+	assert.Contains(t, buf.String(), `"name": "subnet_cidrs",
+          "type": "list(string)",
+          "default": [],
+          "required": false,`)
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	uses, err := LoadUses("testdata")
+	require.NoError(t, err)
+	old := take(t, "testdata")
+	current := take(t, "testdata")
+	assert.Empty(t, Compare(old, current, uses))
+
+	net := current.Modules["net"]
+	net.Inputs = append(net.Inputs[:1], net.Inputs[2:]...)
+	net.Inputs[0].Type = "list(string)"
+	net.Inputs = append(net.Inputs, Input{Name: "region", Type: "string", Required: true}, Input{Name: "tags", Type: "map(string)", Default: map[string]interface{}{}})
+	net.Inputs[2].Default = []interface{}{"10.0.1.0/24"}
+	net.Outputs = []Output{{Name: "subnet_ids", Sensitive: true}, {Name: "token"}, {Name: "arn"}}
+	current.Modules["dns"] = &Interface{}
+
+	var described []string
+	for _, c := range Compare(old, current, uses) {
+		described = append(described, c.String())
+	}
+	assert.Equal(t, []string{
+		"non-breaking: modules/dns added",
+		"breaking: modules/net input api_token type string -> list(string); used by envs/dev/net/main.tf:6, envs/e2e/app/main.tf:12",
+		"breaking: modules/net input cidr removed; used by envs/dev/net/main.tf:5",
+		"breaking: modules/net input region added without a default; used by envs/dev/net/main.tf:1, envs/e2e/app/main.tf:8",
+		"non-breaking: modules/net input subnet_cidrs default [] -> [\"10.0.1.0/24\"]",
+		"non-breaking: modules/net input tags added",
+		"non-breaking: modules/net output arn added",
+		"breaking: modules/net output subnet_ids sensitive false -> true; used by envs/dev/app/main.tf:18, envs/dev/net/main.tf:14",
+		"non-breaking: modules/net output token sensitive true -> false",
+		"breaking: modules/net output vpc_id removed; used by envs/dev/app/main.tf:14, envs/dev/net/main.tf:10, envs/e2e/app/main.tf:16",
+	}, described)
+}
+
+func TestReadWrite(t *testing.T) {
+	t.Parallel()
+
+	s := take(t, "../..")
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf))
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	read, err := Read(path)
+	require.NoError(t, err)
+	assert.Empty(t, Compare(s, read, nil), "defaults compare equal after a JSON round trip")
+
+	_, err = Read(filepath.Join("testdata", "modules", "net", "outputs.tf"))
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "invalid snapshot"))
+}
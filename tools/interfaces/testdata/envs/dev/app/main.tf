@@ -0,0 +1,19 @@
+data "terraform_remote_state" "net" {
+  backend = "s3"
+  config = {
+    bucket = var.state_bucket
+    key    = "dev/net/terraform.tfstate"
+  }
+}
+
+locals {
+  net = data.terraform_remote_state.net.outputs
+}
+
+resource "aws_security_group" "app" {
+  vpc_id = try(local.net.vpc_id, "vpc-error")
+}
+
+resource "aws_instance" "app" {
+  subnet_id = data.terraform_remote_state.net.outputs.subnet_ids[0]
+}
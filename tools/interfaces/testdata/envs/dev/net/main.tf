@@ -0,0 +1,15 @@
+module "network" {
+  source = "../../../modules/net"
+
+  env       = "dev"
+  cidr      = "10.1.0.0/16"
+  api_token = "secret"
+}
+
+output "vpc_id" {
+  value = module.network.vpc_id
+}
+
+output "subnet_ids" {
+  value = module.network.subnet_ids
+}
@@ -0,0 +1,17 @@
+data "terraform_remote_state" "net" {
+  backend = "local"
+  config = {
+    path = "../../dev/net/terraform.tfstate"
+  }
+}
+
+module "network" {
+  source = "../../../modules/net"
+
+  env       = "e2e"
+  api_token = "secret"
+}
+
+resource "aws_security_group" "app" {
+  vpc_id = data.terraform_remote_state.net.outputs.vpc_id
+}
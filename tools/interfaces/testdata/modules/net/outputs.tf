@@ -0,0 +1,12 @@
+output "vpc_id" {
+  value = "vpc-0123"
+}
+
+output "subnet_ids" {
+  value = []
+}
+
+output "token" {
+  value     = var.api_token
+  sensitive = true
+}
@@ -0,0 +1,22 @@
+variable "env" {
+  description = "Environment name"
+  type        = string
+}
+
+variable "cidr" {
+  description = "CIDR block of the VPC"
+  type        = string
+  default     = "10.0.0.0/16"
+}
+
+variable "subnet_cidrs" {
+  description = "CIDR blocks of the subnets"
+  type        = list(string)
+  default     = []
+}
+
+variable "api_token" {
+  description = "Token of the IPAM API"
+  type        = string
+  sensitive   = true
+}
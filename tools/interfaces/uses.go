@@ -0,0 +1,218 @@
+package interfaces
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// call is a module block of a root module calling a module under modules/
+type call struct {
+	dir    string
+	name   string
+	module string
+	// arguments maps the arguments passed to their position
+	arguments map[string]string
+	position  string
+}
+
+// reference is a reference to a module output or remote state, e.g.
+// module.iam.kms_key_arn or local.security_state.kms_key_arn
+type reference struct {
+	path     []string
+	position string
+	// output is the output block of the root module the reference is in,
+	// if any
+	output string
+}
+
+// Uses is how the root modules under envs/ use the modules under modules/:
+// the module calls, the references to the outputs of the calls and the
+// remote states they read
+type Uses struct {
+	calls      []*call
+	references map[string][]reference
+	// states maps the directory of every root module to the prefixes of the
+	// references to the remote states it reads, e.g.
+	// data.terraform_remote_state.vpc.outputs or local.vpc_state, and the
+	// directory of the root module whose state they are
+	states map[string]map[string]string
+}
+
+// LoadUses reads every directory of .tf files under root/envs. Positions are
+// relative to root.
+func LoadUses(root string) (*Uses, error) {
+	u := &Uses{references: map[string][]reference{}, states: map[string]map[string]string{}}
+	err := filepath.WalkDir(filepath.Join(root, "envs"), func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case os.IsNotExist(err) && path == filepath.Join(root, "envs"):
+			return filepath.SkipDir
+		case err != nil:
+			return err
+		case !entry.IsDir():
+			return nil
+		case strings.HasPrefix(entry.Name(), "."):
+			return filepath.SkipDir
+		}
+		module, err := tfconfig.LoadDir(path)
+		if err != nil {
+			return err
+		}
+		u.load(root, path, module)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *Uses) load(root, dir string, module *tfconfig.Module) {
+	rel := tfconfig.Relative(root, dir)
+	states := map[string]string{}
+	for _, block := range module.BlocksOfType("data", "terraform_remote_state") {
+		settings, _ := block.Attr("config")
+		values, _ := settings.(map[string]interface{})
+		var state string
+		if key, ok := values["key"].(string); ok && !tfconfig.IsExpression(key) {
+			state = filepath.Join(filepath.Dir(dir), stack.ModuleForKey(key))
+		} else if path, ok := values["path"].(string); ok && !tfconfig.IsExpression(path) {
+			state = filepath.Dir(filepath.Join(dir, path))
+		} else {
+			continue
+		}
+		states[block.Address()+".outputs"] = tfconfig.Relative(root, state)
+	}
+	for _, block := range module.BlocksOfType("locals") {
+		for name, attr := range block.Body.Attributes {
+			if state, ok := states[traversalPath(attr.Expr)]; ok {
+				states["local."+name] = state
+			}
+		}
+	}
+	u.states[rel] = states
+
+	for _, block := range module.Blocks {
+		if block.Type == "module" {
+			source := block.String("source")
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				target := tfconfig.Relative(root, filepath.Join(dir, source))
+				if strings.HasPrefix(target, "modules/") {
+					c := &call{dir: rel, name: block.Name(), module: strings.TrimPrefix(target, "modules/"), arguments: map[string]string{},
+						position: tfconfig.Position(root, block.File, block.Line)}
+					for name, attr := range block.Body.Attributes {
+						c.arguments[name] = tfconfig.Position(root, block.File, attr.SrcRange.Start.Line)
+					}
+					u.calls = append(u.calls, c)
+				}
+			}
+		}
+		output := ""
+		if block.Type == "output" {
+			output = block.Name()
+		}
+		hclsyntax.VisitAll(block.Body, func(node hclsyntax.Node) hcl.Diagnostics {
+			if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok {
+				u.references[rel] = append(u.references[rel], reference{
+					path:     strings.Split(traversalPath(expr), "."),
+					position: tfconfig.Position(root, block.File, expr.SrcRange.Start.Line),
+					output:   output,
+				})
+			}
+			return nil
+		})
+	}
+}
+
+// consumers returns the positions a breaking change breaks
+func (u *Uses) consumers(c Change) []string {
+	var positions []string
+	for _, call := range u.calls {
+		if call.module != c.Module {
+			continue
+		}
+		switch {
+		case c.Kind == "module":
+			positions = append(positions, call.position)
+		case c.Kind == "input" && (c.Description == addedRequired || c.Description == defaultRemoved):
+			if _, ok := call.arguments[c.Name]; !ok {
+				positions = append(positions, call.position)
+			}
+		case c.Kind == "input":
+			if position, ok := call.arguments[c.Name]; ok {
+				positions = append(positions, position)
+			}
+		case c.Kind == "output":
+			for _, ref := range u.references[call.dir] {
+				if len(ref.path) < 3 || ref.path[0] != "module" || ref.path[1] != call.name || ref.path[2] != c.Name {
+					continue
+				}
+				positions = append(positions, ref.position)
+				if ref.output != "" {
+					positions = append(positions, u.readers(call.dir, ref.output)...)
+				}
+			}
+		}
+	}
+	sort.Strings(positions)
+	return unique(positions)
+}
+
+// readers returns the positions of the references to an output of the root
+// module in dir by the root modules reading its state
+func (u *Uses) readers(dir, output string) []string {
+	var positions []string
+	for reader, states := range u.states {
+		for prefix, state := range states {
+			if state != dir {
+				continue
+			}
+			parts := strings.Split(prefix, ".")
+			for _, ref := range u.references[reader] {
+				if len(ref.path) > len(parts) && strings.Join(ref.path[:len(parts)], ".") == prefix && ref.path[len(parts)] == output {
+					positions = append(positions, ref.position)
+				}
+			}
+		}
+	}
+	return positions
+}
+
+// traversalPath returns the attribute names of a reference joined with dots,
+// e.g. "local.vpc_state.vpc_id", up to its first index, or "" when expr is
+// not a reference
+func traversalPath(expr hclsyntax.Expression) string {
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok {
+		return ""
+	}
+	var names []string
+	for _, step := range traversal.Traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, s.Name)
+		case hcl.TraverseAttr:
+			names = append(names, s.Name)
+		default:
+			return strings.Join(names, ".")
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+func unique(sorted []string) []string {
+	var values []string
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,92 @@
+// Package lint holds the findings reported by the static analyzers of the
+// configuration, such as tools/firewall.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found in the configuration
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Check names the check, e.g. "sid" or "default-deny"
+	Check   string `json:"check"`
+	Address string `json:"address"`
+	// Position is "file:line" relative to the repository root
+	Position string `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s [%s]", f.Position, f.Severity, f.Address, f.Message, f.Check)
+}
+
+// HasErrors reports whether any finding is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of findings of a severity
+func Count(findings []Finding, severity Severity) int {
+	count := 0
+	for _, f := range findings {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Sort orders findings by file, then numerically by line
+func Sort(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		fileA, lineA, _ := strings.Cut(findings[i].Position, ":")
+		fileB, lineB, _ := strings.Cut(findings[j].Position, ":")
+		if fileA != fileB {
+			return fileA < fileB
+		}
+		numberA, _ := strconv.Atoi(lineA)
+		numberB, _ := strconv.Atoi(lineB)
+		return numberA < numberB
+	})
+}
+
+// Collector accumulates findings, dropping exact duplicates
+type Collector struct {
+	findings []Finding
+	seen     map[string]bool
+}
+
+// Add records a finding with a formatted message
+func (c *Collector) Add(severity Severity, check, address, position, format string, args ...interface{}) {
+	f := Finding{Severity: severity, Check: check, Address: address, Position: position, Message: fmt.Sprintf(format, args...)}
+	if c.seen == nil {
+		c.seen = map[string]bool{}
+	}
+	if key := f.String(); !c.seen[key] {
+		c.seen[key] = true
+		c.findings = append(c.findings, f)
+	}
+}
+
+// Findings returns the findings sorted by position
+func (c *Collector) Findings() []Finding {
+	Sort(c.findings)
+	return c.findings
+}
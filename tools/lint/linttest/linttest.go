@@ -0,0 +1,21 @@
+// Package linttest selects findings in the tests of the analyzers, such as
+// tools/policies.
+package linttest
+
+import (
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Messages returns "position check: message" for the findings of the checks
+// starting with prefix, e.g. "kms-" or a whole check name
+func Messages(findings []lint.Finding, prefix string) []string {
+	var selected []string
+	for _, f := range findings {
+		if strings.HasPrefix(f.Check, prefix) {
+			selected = append(selected, f.Position+" "+f.Check+": "+f.Message)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,191 @@
+package minimize
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is a CloudTrail record of an API call made by a role
+type Event struct {
+	Time time.Time
+	// Action is the IAM action of the call, e.g. secretsmanager:GetSecretValue
+	Action string
+	// Resources are the ARNs of the resources the call used, when the record
+	// names them
+	Resources []string
+}
+
+// record is the part of a CloudTrail record the minimizer reads
+type record struct {
+	EventTime         time.Time                  `json:"eventTime"`
+	EventSource       string                     `json:"eventSource"`
+	EventName         string                     `json:"eventName"`
+	AWSRegion         string                     `json:"awsRegion"`
+	ErrorCode         string                     `json:"errorCode"`
+	RecipientAccount  string                     `json:"recipientAccountId"`
+	RequestParameters map[string]json.RawMessage `json:"requestParameters"`
+	Resources         []struct {
+		ARN string `json:"ARN"`
+	} `json:"resources"`
+	UserIdentity struct {
+		Type           string `json:"type"`
+		ARN            string `json:"arn"`
+		SessionContext struct {
+			SessionIssuer struct {
+				ARN      string `json:"arn"`
+				UserName string `json:"userName"`
+			} `json:"sessionIssuer"`
+		} `json:"sessionContext"`
+	} `json:"userIdentity"`
+}
+
+// eventSources maps the event sources whose action prefix differs from the
+// host name
+var eventSources = map[string]string{
+	"monitoring.amazonaws.com": "cloudwatch",
+}
+
+// resourceParameters are request parameters naming a resource, mapped to
+// the ARN of a resource named that way in a region and account. Values that
+// are ARNs already are used as they are.
+var resourceParameters = map[string]func(region, account, name string) string{
+	"secretId": func(region, account, name string) string {
+		return fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s", region, account, name)
+	},
+	"keyId": func(region, account, name string) string {
+		if strings.HasPrefix(name, "alias/") {
+			return fmt.Sprintf("arn:aws:kms:%s:%s:%s", region, account, name)
+		}
+		return fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, account, name)
+	},
+	"tableName": func(region, account, name string) string {
+		return fmt.Sprintf("arn:aws:dynamodb:%s:%s:table/%s", region, account, name)
+	},
+	"logGroupName": func(region, account, name string) string {
+		return fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", region, account, name)
+	},
+	"bucketName": func(region, account, name string) string {
+		return "arn:aws:s3:::" + name
+	},
+}
+
+// ReadLogs reads the CloudTrail log files (*.json.gz, as delivered to the
+// trail bucket, or *.json) under dir and returns the calls role made from
+// since until until, oldest first. role is a role name or ARN. Calls denied
+// by IAM are skipped: they used no permission.
+func ReadLogs(dir, role string, since, until time.Time) ([]Event, error) {
+	var events []Event
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !strings.HasSuffix(path, ".json.gz") && !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		records, err := readFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, r := range records {
+			if r.EventTime.Before(since) || !r.EventTime.Before(until) || !r.by(role) || denied(r.ErrorCode) {
+				continue
+			}
+			events = append(events, r.event())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+func readFile(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var file struct {
+		Records []record `json:"Records"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	return file.Records, nil
+}
+
+// by reports whether the record is a call made with role
+func (r record) by(role string) bool {
+	issuer := r.UserIdentity.SessionContext.SessionIssuer
+	if r.UserIdentity.Type != "AssumedRole" {
+		return false
+	}
+	return issuer.UserName == role || issuer.ARN == role || strings.HasSuffix(issuer.ARN, ":role/"+role)
+}
+
+// denied reports whether an error code means IAM denied the call
+func denied(code string) bool {
+	return strings.Contains(code, "AccessDenied") || strings.Contains(code, "Unauthorized")
+}
+
+func (r record) event() Event {
+	prefix, ok := eventSources[r.EventSource]
+	if !ok {
+		prefix = strings.TrimSuffix(r.EventSource, ".amazonaws.com")
+	}
+	e := Event{Time: r.EventTime, Action: prefix + ":" + r.EventName}
+
+	add := func(arn string) {
+		for _, existing := range e.Resources {
+			if existing == arn {
+				return
+			}
+		}
+		e.Resources = append(e.Resources, arn)
+	}
+	for _, resource := range r.Resources {
+		add(resource.ARN)
+	}
+	for _, parameter := range sortedParameters(r.RequestParameters) {
+		var value string
+		if json.Unmarshal(r.RequestParameters[parameter], &value) != nil || value == "" {
+			continue
+		}
+		switch arn, ok := resourceParameters[parameter]; {
+		case strings.HasPrefix(value, "arn:"):
+			add(value)
+		case ok:
+			add(arn(r.AWSRegion, r.RecipientAccount, value))
+		}
+	}
+	return e
+}
+
+func sortedParameters(parameters map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,335 @@
+// Package minimize proposes least-privilege versions of the IAM policies
+// under modules/ from the calls a role actually made, as recorded by
+// CloudTrail: allowed actions and resources that no call used over a window
+// are removed, and the result is shown as a diff against the HCL.
+package minimize
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Unused is what a statement allows that no call used
+type Unused struct {
+	Statement *iam.Statement
+	Actions   []string
+	Resources []string
+}
+
+// Result is the minimized version of a policy
+type Result struct {
+	Policy *iam.Policy
+	Events int
+	// Statements are the proposed statements: Allow statements reduced to
+	// what was used, or dropped when nothing was, and Deny statements as
+	// they are
+	Statements []*iam.Statement
+	Unused     []Unused
+	// Kept are Allow statements that cannot be minimized, such as those
+	// with NotAction
+	Kept []*iam.Statement
+}
+
+// Minimize reduces the Allow statements of p to the actions and resources
+// used by events
+func Minimize(p *iam.Policy, events []Event) Result {
+	result := Result{Policy: p, Events: len(events)}
+	for _, s := range p.Statements {
+		if !s.Allows() || len(s.NotActions) > 0 || len(s.NotResources) > 0 {
+			if s.Allows() {
+				result.Kept = append(result.Kept, s)
+			}
+			result.Statements = append(result.Statements, s)
+			continue
+		}
+
+		minimized, unused := minimizeStatement(s, events)
+		if minimized != nil {
+			result.Statements = append(result.Statements, minimized)
+		}
+		if len(unused.Actions) > 0 || len(unused.Resources) > 0 {
+			result.Unused = append(result.Unused, unused)
+		}
+	}
+	return result
+}
+
+func minimizeStatement(s *iam.Statement, events []Event) (*iam.Statement, Unused) {
+	usedActions := map[string]bool{}
+	usedResources := map[string]bool{}
+	for _, e := range events {
+		if !matchesAny(s.Actions, e.Action, iam.MatchAction) {
+			continue
+		}
+		var resources []string
+		for _, pattern := range s.Resources {
+			for _, resource := range e.Resources {
+				if matchResource(pattern, resource) {
+					resources = append(resources, pattern)
+					break
+				}
+			}
+		}
+		if len(e.Resources) == 0 {
+			// the record names no resource, so any of them may have been used
+			resources = s.Resources
+		}
+		if len(resources) == 0 {
+			continue
+		}
+		usedActions[e.Action] = true
+		for _, pattern := range resources {
+			usedResources[pattern] = true
+		}
+	}
+
+	unused := Unused{Statement: s}
+	minimized := *s
+	minimized.Actions, minimized.Resources = nil, nil
+	for _, pattern := range s.Actions {
+		var used []string
+		for action := range usedActions {
+			if iam.MatchAction(pattern, action) {
+				used = append(used, action)
+			}
+		}
+		switch {
+		case len(used) == 0:
+			unused.Actions = append(unused.Actions, pattern)
+		case strings.Contains(pattern, "*"):
+			sort.Strings(used)
+			for _, action := range used {
+				if !slices.Contains(minimized.Actions, action) {
+					minimized.Actions = append(minimized.Actions, action)
+				}
+			}
+		default:
+			if !slices.Contains(minimized.Actions, pattern) {
+				minimized.Actions = append(minimized.Actions, pattern)
+			}
+		}
+	}
+	for _, pattern := range s.Resources {
+		if usedResources[pattern] {
+			minimized.Resources = append(minimized.Resources, pattern)
+		} else {
+			unused.Resources = append(unused.Resources, pattern)
+		}
+	}
+	if len(minimized.Actions) == 0 {
+		return nil, unused
+	}
+	return &minimized, unused
+}
+
+// expression matches a "${...}" value of a resource pattern; events name
+// real resources, so any text may stand in its place
+var expression = regexp.MustCompile(`\$\{[^}]*\}`)
+
+func matchResource(pattern, resource string) bool {
+	return iam.MatchResource(expression.ReplaceAllString(pattern, "*"), resource)
+}
+
+func matchesAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text writes what each statement allows that was not used
+func (r Result) Text(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d call(s)\n", r.Policy.Address, r.Events)
+	if len(r.Unused) == 0 {
+		fmt.Fprintln(w, "  every allowed action and resource was used")
+	}
+	for _, u := range r.Unused {
+		fmt.Fprintf(w, "\nunused in %s (%s):\n", u.Statement.Describe(), u.Statement.Position)
+		for _, action := range u.Actions {
+			fmt.Fprintf(w, "  action   %s\n", action)
+		}
+		for _, resource := range u.Resources {
+			fmt.Fprintf(w, "  resource %s\n", resource)
+		}
+	}
+	for _, s := range r.Kept {
+		fmt.Fprintf(w, "\nkept as is: %s (%s) uses NotAction or NotResource\n", s.Describe(), s.Position)
+	}
+}
+
+// Diff returns the change to the file declaring the policy that replaces its
+// document with the minimized statements, as a unified diff relative to
+// root. Only jsonencode documents can be rewritten.
+func (r Result) Diff(root string) (string, error) {
+	p := r.Policy
+	module, err := tfconfig.LoadDir(filepath.Join(root, "modules", p.Module))
+	if err != nil {
+		return "", err
+	}
+	var block *tfconfig.Block
+	for _, b := range module.BlocksOfType("resource") {
+		if b.Address() == p.Address && b.Has(p.Attribute) {
+			block = b
+		}
+	}
+	if block == nil || !strings.HasPrefix(block.Source(p.Attribute), "jsonencode(") {
+		return "", fmt.Errorf("%s: only %s = jsonencode({...}) documents can be rewritten", p.Address, p.Attribute)
+	}
+
+	src, err := os.ReadFile(block.File)
+	if err != nil {
+		return "", err
+	}
+	expr := block.Body.Attributes[p.Attribute].Expr.Range()
+	lineStart := strings.LastIndex(string(src[:expr.Start.Byte]), "\n") + 1
+	indent := src[lineStart:expr.Start.Byte]
+	indent = indent[:len(indent)-len(strings.TrimLeft(string(indent), " \t"))]
+	proposed := string(src[:expr.Start.Byte]) + Render(r.Statements, string(indent)) + string(src[expr.End.Byte:])
+
+	name := tfconfig.Relative(root, block.File)
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(src)),
+		B:        difflib.SplitLines(proposed),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+}
+
+// Render returns statements as a jsonencode({...}) expression whose lines
+// after the first are indented by indent
+func Render(statements []*iam.Statement, indent string) string {
+	const step = "    "
+	var b strings.Builder
+	b.WriteString("jsonencode({\n")
+	fmt.Fprintf(&b, "%s%sVersion = \"2012-10-17\"\n", indent, step)
+	fmt.Fprintf(&b, "%s%sStatement = [\n", indent, step)
+	for _, s := range statements {
+		in := indent + step + step
+		fmt.Fprintf(&b, "%s{\n", in)
+		var fields [][2]string
+		if s.Sid != "" {
+			fields = append(fields, [2]string{"Sid", quote(s.Sid)})
+		}
+		fields = append(fields, [2]string{"Effect", quote(s.Effect)})
+		for _, f := range []struct {
+			name       string
+			principals map[string][]string
+		}{{"Principal", s.Principals}, {"NotPrincipal", s.NotPrincipals}} {
+			if f.principals != nil {
+				fields = append(fields, [2]string{f.name, principals(f.principals)})
+			}
+		}
+		for _, f := range []struct {
+			name   string
+			values []string
+		}{{"Action", s.Actions}, {"NotAction", s.NotActions}, {"Resource", s.Resources}, {"NotResource", s.NotResources}} {
+			if f.values != nil {
+				fields = append(fields, [2]string{f.name, list(f.values, in+step)})
+			}
+		}
+		if len(s.Conditions) > 0 {
+			fields = append(fields, [2]string{"Condition", conditions(s.Conditions, in+step)})
+		}
+
+		width := 0
+		for _, f := range fields {
+			if len(f[0]) > width {
+				width = len(f[0])
+			}
+		}
+		for _, f := range fields {
+			fmt.Fprintf(&b, "%s%s%-*s = %s\n", in, step, width, f[0], f[1])
+		}
+		fmt.Fprintf(&b, "%s},\n", in)
+	}
+	fmt.Fprintf(&b, "%s%s]\n", indent, step)
+	fmt.Fprintf(&b, "%s})", indent)
+	return b.String()
+}
+
+// quote returns value as an HCL string; "${...}" stays an interpolation
+func quote(value string) string {
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") && strings.Count(value, "${") == 1 {
+		return value[2 : len(value)-1]
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// list returns a single value as a string and several as a list, one per line
+func list(values []string, indent string) string {
+	if len(values) == 1 {
+		return quote(values[0])
+	}
+	var b strings.Builder
+	b.WriteString("[\n")
+	for _, value := range values {
+		fmt.Fprintf(&b, "%s    %s,\n", indent, quote(value))
+	}
+	b.WriteString(indent + "]")
+	return b.String()
+}
+
+func principals(p map[string][]string) string {
+	if ids, ok := p["*"]; ok && len(p) == 1 && len(ids) == 1 && ids[0] == "*" {
+		return `"*"`
+	}
+	types := make([]string, 0, len(p))
+	for principalType := range p {
+		types = append(types, principalType)
+	}
+	sort.Strings(types)
+	var parts []string
+	for _, principalType := range types {
+		parts = append(parts, fmt.Sprintf("%s = %s", principalType, inline(p[principalType])))
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+func conditions(conditions []iam.Condition, indent string) string {
+	var operators []string
+	keys := map[string][]string{}
+	for _, c := range conditions {
+		if _, ok := keys[c.Operator]; !ok {
+			operators = append(operators, c.Operator)
+		}
+		keys[c.Operator] = append(keys[c.Operator], fmt.Sprintf("%s = %s", quote(c.Key), inline(c.Values)))
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, operator := range operators {
+		name := operator
+		if strings.Contains(name, ":") {
+			name = quote(name)
+		}
+		fmt.Fprintf(&b, "%s    %s = { %s }\n", indent, name, strings.Join(keys[operator], ", "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// inline returns a single value as a string and several as a list on one
+// line
+func inline(values []string) string {
+	if len(values) == 1 {
+		return quote(values[0])
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,202 @@
+package minimize
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+)
+
+// trail is a CloudTrail log file of calls by the app instance role: a
+// secret read, one denied and one before the window, and a call by another
+// role
+const trail = `{"Records": [
+  {
+    "eventTime": "2026-03-02T10:00:00Z",
+    "eventSource": "secretsmanager.amazonaws.com",
+    "eventName": "GetSecretValue",
+    "awsRegion": "us-east-1",
+    "recipientAccountId": "111111111111",
+    "requestParameters": {"secretId": "app/db"},
+    "userIdentity": {"type": "AssumedRole", "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::111111111111:role/dev-ZT-App-Role", "userName": "dev-ZT-App-Role"}}}
+  },
+  {
+    "eventTime": "2026-03-01T10:00:00Z",
+    "eventSource": "kms.amazonaws.com",
+    "eventName": "Decrypt",
+    "awsRegion": "us-east-1",
+    "recipientAccountId": "111111111111",
+    "errorCode": "AccessDenied",
+    "userIdentity": {"type": "AssumedRole", "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::111111111111:role/dev-ZT-App-Role", "userName": "dev-ZT-App-Role"}}}
+  },
+  {
+    "eventTime": "2025-01-01T10:00:00Z",
+    "eventSource": "kms.amazonaws.com",
+    "eventName": "Decrypt",
+    "awsRegion": "us-east-1",
+    "recipientAccountId": "111111111111",
+    "resources": [{"ARN": "arn:aws:kms:us-east-1:111111111111:key/1234"}],
+    "userIdentity": {"type": "AssumedRole", "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::111111111111:role/dev-ZT-App-Role", "userName": "dev-ZT-App-Role"}}}
+  },
+  {
+    "eventTime": "2026-03-01T11:00:00Z",
+    "eventSource": "kms.amazonaws.com",
+    "eventName": "Decrypt",
+    "awsRegion": "us-east-1",
+    "recipientAccountId": "111111111111",
+    "userIdentity": {"type": "AssumedRole", "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::111111111111:role/other", "userName": "other"}}}
+  },
+  {
+    "eventTime": "2026-03-01T12:00:00Z",
+    "eventSource": "monitoring.amazonaws.com",
+    "eventName": "PutMetricData",
+    "awsRegion": "us-east-1",
+    "recipientAccountId": "111111111111",
+    "userIdentity": {"type": "AssumedRole", "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::111111111111:role/dev-ZT-App-Role", "userName": "dev-ZT-App-Role"}}}
+  }
+]}`
+
+var (
+	since = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func logs(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "AWSLogs", "111111111111", "CloudTrail", "us-east-1")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	f, err := os.Create(filepath.Join(dir, "trail.json.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(trail))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+	return filepath.Dir(filepath.Dir(filepath.Dir(dir)))
+}
+
+func appSecretsPolicy(t *testing.T) *iam.Policy {
+	t.Helper()
+
+	policies, err := iam.Load("../..")
+	require.NoError(t, err)
+	for _, p := range policies {
+		if p.Address == "aws_iam_role_policy.app_secrets_policy" {
+			return p
+		}
+	}
+	t.Fatal("aws_iam_role_policy.app_secrets_policy not found")
+	return nil
+}
+
+func TestReadLogs(t *testing.T) {
+	t.Parallel()
+
+	events, err := ReadLogs(logs(t), "dev-ZT-App-Role", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, []Event{
+		{Time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Action: "cloudwatch:PutMetricData"},
+		{
+			Time:      time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+			Action:    "secretsmanager:GetSecretValue",
+			Resources: []string{"arn:aws:secretsmanager:us-east-1:111111111111:secret:app/db"},
+		},
+	}, events, "the denied call, the call before the window and the call of the other role are skipped")
+
+	events, err = ReadLogs(logs(t), "arn:aws:iam::111111111111:role/other", since, until)
+	require.NoError(t, err)
+	assert.Equal(t, []Event{{Time: time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), Action: "kms:Decrypt"}}, events)
+}
+
+func TestMinimize(t *testing.T) {
+	t.Parallel()
+
+	events, err := ReadLogs(logs(t), "dev-ZT-App-Role", since, until)
+	require.NoError(t, err)
+	result := Minimize(appSecretsPolicy(t), events)
+
+	require.Len(t, result.Unused, 1)
+	assert.Equal(t, []string{"kms:Decrypt"}, result.Unused[0].Actions)
+	assert.Equal(t, []string{"arn:aws:kms:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:key/*"}, result.Unused[0].Resources)
+	require.Len(t, result.Statements, 1)
+	assert.Equal(t, []string{"secretsmanager:GetSecretValue"}, result.Statements[0].Actions)
+
+	var text strings.Builder
+	result.Text(&text)
+	assert.Equal(t, `aws_iam_role_policy.app_secrets_policy: 2 call(s)
+
+unused in AccessSecretsAndKMS (modules/security/iam.tf:20):
+  action   kms:Decrypt
+  resource arn:aws:kms:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:key/*
+`, text.String())
+}
+
+func TestMinimizeWildcards(t *testing.T) {
+	t.Parallel()
+
+	p := &iam.Policy{Statements: []*iam.Statement{
+		{Effect: "Allow", Actions: []string{"logs:*", "s3:GetObject"}, Resources: []string{"*"}},
+		{Effect: "Allow", NotActions: []string{"iam:*"}, Resources: []string{"*"}},
+		{Effect: "Deny", Actions: []string{"s3:DeleteObject"}, Resources: []string{"*"}},
+	}}
+	result := Minimize(p, []Event{
+		{Action: "logs:PutLogEvents"},
+		{Action: "logs:CreateLogStream"},
+		{Action: "logs:PutLogEvents"},
+	})
+
+	require.Len(t, result.Statements, 3)
+	assert.Equal(t, []string{"logs:CreateLogStream", "logs:PutLogEvents"}, result.Statements[0].Actions)
+	assert.Equal(t, []string{"s3:GetObject"}, result.Unused[0].Actions)
+	assert.Equal(t, p.Statements[1:2], result.Kept, "NotAction statements cannot be minimized")
+	assert.Same(t, p.Statements[2], result.Statements[2], "Deny statements are kept as they are")
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `jsonencode({
+      Version = "2012-10-17"
+      Statement = [
+          {
+              Effect    = "Allow"
+              Principal = { AWS = aws_iam_role.app.arn }
+              Action    = [
+                  "s3:GetObject",
+                  "s3:PutObject",
+              ]
+              Resource  = aws_s3_bucket.logs.arn
+              Condition = {
+                  StringEquals = { "aws:SourceVpce" = var.endpoint_id }
+              }
+          },
+      ]
+  })`, Render([]*iam.Statement{{
+		Effect:     "Allow",
+		Principals: map[string][]string{"AWS": {"${aws_iam_role.app.arn}"}},
+		Actions:    []string{"s3:GetObject", "s3:PutObject"},
+		Resources:  []string{"${aws_s3_bucket.logs.arn}"},
+		Conditions: []iam.Condition{{Operator: "StringEquals", Key: "aws:SourceVpce", Values: []string{"${var.endpoint_id}"}}},
+	}}, "  "))
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	events, err := ReadLogs(logs(t), "dev-ZT-App-Role", since, until)
+	require.NoError(t, err)
+	diff, err := Minimize(appSecretsPolicy(t), events).Diff("../..")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(diff, "--- a/modules/security/iam.tf\n+++ b/modules/security/iam.tf\n"), diff)
+	assert.Contains(t, diff, "-            \"kms:Decrypt\"\n")
+	assert.Contains(t, diff, "+                Action   = \"secretsmanager:GetSecretValue\"\n")
+	assert.Contains(t, diff, "+                Resource = \"arn:aws:secretsmanager:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:secret:app/*\"\n")
+	assert.NotContains(t, diff, "+                Resource = \"arn:aws:kms")
+}
@@ -0,0 +1,134 @@
+package policies
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// partitions are the partitions an ARN can name
+var partitions = []string{"aws", "aws-cn", "aws-us-gov"}
+
+// lintActions checks that the actions and resources of every statement
+// exist: a misspelled action or ARN is accepted by the provider and grants,
+// or denies, nothing
+func lintActions(p *iam.Policy, catalog *iam.Catalog, l *lint.Collector) {
+	for _, s := range p.Statements {
+		for _, pattern := range append(append([]string{}, s.Actions...), s.NotActions...) {
+			lintAction(p, s, pattern, catalog, l)
+		}
+		for _, resource := range append(append([]string{}, s.Resources...), s.NotResources...) {
+			lintResource(p, s, resource, catalog, l)
+		}
+	}
+}
+
+func lintAction(p *iam.Policy, s *iam.Statement, pattern string, catalog *iam.Catalog, l *lint.Collector) {
+	if pattern == "*" || strings.Contains(pattern, "${") {
+		return
+	}
+	service, name, ok := strings.Cut(pattern, ":")
+	if !ok || service == "" || name == "" {
+		l.Add(lint.SeverityError, "action-unknown", p.Address, s.Position,
+			"%s names %s, which is not of the form service:Action", statement(s), pattern)
+		return
+	}
+	if !catalog.KnownService(service) {
+		l.Add(lint.SeverityError, "action-unknown", p.Address, s.Position,
+			"%s names %s, but %s is not a service prefix%s", statement(s), pattern, service, suggest(service, catalog.Prefixes))
+		return
+	}
+
+	actions, ok := catalog.Actions(pattern)
+	switch {
+	case !ok || len(actions) > 0:
+	case strings.ContainsAny(name, "*?"):
+		l.Add(lint.SeverityError, "action-pattern-empty", p.Address, s.Position,
+			"%s names %s, which matches no %s action", statement(s), pattern, service)
+	default:
+		var names []string
+		for name := range catalog.Services[iam.Service(pattern)].Actions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		l.Add(lint.SeverityError, "action-unknown", p.Address, s.Position,
+			"%s names %s, which is not a %s action%s", statement(s), pattern, service, suggest(name, names))
+	}
+}
+
+func lintResource(p *iam.Policy, s *iam.Statement, resource string, catalog *iam.Catalog, l *lint.Collector) {
+	if resource == "*" || strings.HasPrefix(resource, "${") {
+		return
+	}
+	fields := strings.SplitN(resource, ":", 6)
+	if len(fields) < 6 || fields[0] != "arn" {
+		l.Add(lint.SeverityError, "resource-arn", p.Address, s.Position,
+			"%s names resource %q, which is not an ARN (arn:partition:service:region:account:resource)", statement(s), resource)
+		return
+	}
+	partition, service := fields[1], fields[2]
+	if !slices.Contains(partitions, partition) && !strings.ContainsAny(partition, "*?$") {
+		l.Add(lint.SeverityError, "resource-arn", p.Address, s.Position,
+			"%s names resource %q, but %s is not a partition", statement(s), resource, partition)
+		return
+	}
+	if strings.ContainsAny(service, "*?$") {
+		return
+	}
+	if !catalog.KnownService(service) {
+		l.Add(lint.SeverityError, "resource-arn", p.Address, s.Position,
+			"%s names resource %q, but %s is not a service prefix%s", statement(s), resource, service, suggest(service, catalog.Prefixes))
+		return
+	}
+
+	templates := catalog.ARNs(service)
+	for _, template := range templates {
+		if iam.MatchARN(template, resource) {
+			return
+		}
+	}
+	if len(templates) > 0 {
+		l.Add(lint.SeverityError, "resource-arn", p.Address, s.Position,
+			"%s names resource %q, which has the form of no %s resource type: %s",
+			statement(s), resource, service, strings.Join(templates, ", "))
+	}
+}
+
+// suggest returns "; did you mean X?" for the closest of candidates to
+// value, when one is a typo away
+func suggest(value string, candidates []string) string {
+	best, distance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(value), strings.ToLower(candidate)); d < distance {
+			best, distance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return "; did you mean " + best + "?"
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,35 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLintActions(t *testing.T) {
+	t.Parallel()
+
+	all := findings(t, "testdata")
+	assert.Equal(t, []string{
+		`modules/typos/main.tf:11 action-unknown: statement "Secrets" names secretmanager:GetSecretValue, but secretmanager is not a service prefix; did you mean secretsmanager?`,
+		`modules/typos/main.tf:11 action-unknown: statement "Secrets" names secretsmanager:GetSecretValues, which is not a secretsmanager action; did you mean GetSecretValue?`,
+		`modules/typos/main.tf:26 action-pattern-empty: statement "Buckets" names s3:ListObjects*, which matches no s3 action`,
+	}, linttest.Messages(all, "action-"), "cloudtrail is a service outside the catalog")
+	assert.Equal(t, []string{
+		`modules/typos/main.tf:11 resource-arn: statement "Secrets" names resource "arn:aws:secretsmanager:eu-north-1:${data.aws_caller_identity.current.account_id}:secrets:app/*", which has the form of no secretsmanager resource type: arn:${Partition}:secretsmanager:${Region}:${Account}:secret:${SecretId}`,
+		`modules/typos/main.tf:20 resource-arn: statement "Keys" names resource "arn:amazon:kms:eu-north-1:111122223333:key/1234", but amazon is not a partition`,
+		`modules/typos/main.tf:20 resource-arn: statement "Keys" names resource "key/1234", which is not an ARN (arn:partition:service:region:account:resource)`,
+		`modules/typos/main.tf:26 resource-arn: statement "Buckets" names resource "arn:aws:s3:eu-north-1:111122223333:data", which has the form of no s3 resource type: arn:${Partition}:s3:${Region}:${Account}:accesspoint/${AccessPointName}, arn:${Partition}:s3:::${BucketName}, arn:${Partition}:s3:::${BucketName}/${ObjectName}`,
+		`modules/typos/main.tf:26 resource-arn: statement "Buckets" names resource "arn:aws:s4:::data", but s4 is not a service prefix; did you mean s3?`,
+	}, linttest.Messages(all, "resource-"))
+}
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, editDistance("kms", "kms"))
+	assert.Equal(t, 1, editDistance("secretmanager", "secretsmanager"))
+	assert.Equal(t, 3, editDistance("", "sts"))
+	assert.Equal(t, "", suggest("cloudtrail", []string{"kms", "s3"}))
+}
@@ -0,0 +1,157 @@
+package policies
+
+import (
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// keyFormats are the values some condition keys can take; any other value
+// never matches
+var keyFormats = map[string]struct {
+	pattern     *regexp.Regexp
+	description string
+}{
+	"aws:requestedregion":  {regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`), "a region name such as us-east-1"},
+	"aws:sourcevpce":       {regexp.MustCompile(`^vpce-[0-9a-f]+$`), "a VPC endpoint ID"},
+	"aws:sourcevpc":        {regexp.MustCompile(`^vpc-[0-9a-f]+$`), "a VPC ID"},
+	"aws:principalaccount": {regexp.MustCompile(`^\d{12}$`), "an account ID"},
+	"aws:resourceaccount":  {regexp.MustCompile(`^\d{12}$`), "an account ID"},
+	"aws:sourceaccount":    {regexp.MustCompile(`^\d{12}$`), "an account ID"},
+	"kms:calleraccount":    {regexp.MustCompile(`^\d{12}$`), "an account ID"},
+	"aws:principalorgid":   {regexp.MustCompile(`^o-[a-z0-9]{10,32}$`), "an organization ID"},
+	"aws:resourceorgid":    {regexp.MustCompile(`^o-[a-z0-9]{10,32}$`), "an organization ID"},
+	"kms:viaservice":       {regexp.MustCompile(`^[a-z0-9-]+\.[a-z0-9-]+\.amazonaws\.com$`), "a service endpoint such as secretsmanager.us-east-1.amazonaws.com"},
+}
+
+// lintConditions checks the condition keys of every statement against the
+// catalog: a key the requests of an action never carry, such as a resource
+// tag on a resource type without tags, makes the condition fail for that
+// action, and a value a key can never take makes it fail for all of them
+func lintConditions(p *iam.Policy, catalog *iam.Catalog, l *lint.Collector) {
+	for _, s := range p.Statements {
+		for _, c := range s.Conditions {
+			lintConditionKey(p, s, c, catalog, l)
+			lintConditionValues(p, s, c, catalog, l)
+		}
+	}
+}
+
+func lintConditionKey(p *iam.Policy, s *iam.Statement, c iam.Condition, catalog *iam.Catalog, l *lint.Collector) {
+	var unsupported []string
+	for _, pattern := range s.Actions {
+		actions, _ := catalog.Actions(pattern)
+		var supported bool
+		var missing []string
+		for _, a := range actions {
+			if catalog.Supports(a, c.Key) {
+				supported = true
+			} else {
+				missing = append(missing, a.String())
+			}
+		}
+		// a wildcard is reported only when no action it grants has the key
+		switch {
+		case len(missing) == 0:
+		case !strings.ContainsAny(pattern, "*?"):
+			unsupported = append(unsupported, missing...)
+		case !supported:
+			unsupported = append(unsupported, pattern)
+		}
+	}
+	if len(unsupported) == 0 {
+		return
+	}
+
+	check, reason := "condition-key-unsupported", "requests for "+strings.Join(unsupported, ", ")+" never carry it"
+	if tag := strings.ToLower(c.Key); strings.Contains(tag, ":resourcetag/") || strings.HasPrefix(tag, "aws:requesttag/") || tag == "aws:tagkeys" {
+		check, reason = "condition-tag-unsupported", "tag conditions are not honored for "+strings.Join(unsupported, ", ")
+	}
+	l.Add(lint.SeverityError, check, p.Address, s.Position,
+		"%s conditions on %s, but %s, %s",
+		statement(s), c.Key, reason, consequence(s, c))
+}
+
+// consequence describes what a condition on a key absent from the request
+// does to a statement
+func consequence(s *iam.Statement, c iam.Condition) string {
+	always := strings.HasSuffix(c.Operator, "IfExists") || c.Operator == "Null" && slices.Contains(c.Values, "true")
+	switch {
+	case s.Allows() && always:
+		return "so the condition does not restrict them"
+	case s.Allows():
+		return "so the statement never allows them"
+	case always:
+		return "so the statement always denies them"
+	default:
+		return "so the statement never denies them"
+	}
+}
+
+func lintConditionValues(p *iam.Policy, s *iam.Statement, c iam.Condition, catalog *iam.Catalog, l *lint.Collector) {
+	operator := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(c.Operator, "ForAllValues:"), "ForAnyValue:"), "IfExists")
+	if operator == "Null" {
+		return
+	}
+	for _, value := range c.Values {
+		if strings.Contains(value, "${") {
+			continue
+		}
+		var problem string
+		switch keyType := catalog.KeyType(c.Key); {
+		case value == "" && strings.HasPrefix(operator, "String"):
+			problem = "an empty value, which matches only a key set to the empty string and never a missing one; use Null to test whether the key is present"
+		case keyType == "Bool" && value != "true" && value != "false":
+			problem = strconv.Quote(value) + ", which is not true or false"
+		case keyType == "Numeric" && !isNumber(value):
+			problem = strconv.Quote(value) + ", which is not a number"
+		case keyType == "Date" && !isDate(value):
+			problem = strconv.Quote(value) + ", which is not a date"
+		case keyType == "IPAddress" && !isIP(value):
+			problem = strconv.Quote(value) + ", which is not an IP address or CIDR block"
+		default:
+			format, ok := keyFormats[strings.ToLower(c.Key)]
+			if !ok || !strings.HasPrefix(operator, "String") || strings.HasSuffix(operator, "Like") && strings.ContainsAny(value, "*?") {
+				continue
+			}
+			if !format.pattern.MatchString(value) {
+				problem = strconv.Quote(value) + ", which is not " + format.description
+			}
+		}
+		if problem != "" {
+			l.Add(lint.SeverityError, "condition-value", p.Address, s.Position,
+				"%s compares %s with %s",
+				statement(s), c.Key, problem)
+		}
+	}
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func isDate(value string) bool {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05Z", "2006-01-02"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isIP(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
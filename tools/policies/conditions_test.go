@@ -0,0 +1,21 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLintConditions(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{
+		`modules/rbac/main.tf:9 condition-tag-unsupported: statement "SecretsByTag" conditions on aws:ResourceTag/Tier, but tag conditions are not honored for secretsmanager:ListSecrets, so the statement never allows them`,
+		`modules/rbac/main.tf:18 condition-tag-unsupported: statement "ObjectsByTag" conditions on aws:ResourceTag/Tier, but tag conditions are not honored for s3:GetObject, so the condition does not restrict them`,
+		`modules/rbac/main.tf:27 condition-key-unsupported: statement "KMSViaSecrets" conditions on kms:ViaService, but requests for secretsmanager:GetSecretValue never carry it, so the statement never allows them`,
+		`modules/rbac/main.tf:45 condition-value: statement "DenyNonTLS" compares aws:SecureTransport with "no", which is not true or false`,
+		`modules/rbac/main.tf:65 condition-value: statement "DenyPublic" compares aws:RequestedRegion with "public", which is not a region name such as us-east-1`,
+		`modules/rbac/main.tf:74 condition-value: statement "DenyUntagged" compares aws:RequestTag/Tier with an empty value, which matches only a key set to the empty string and never a missing one; use Null to test whether the key is present`,
+	}, linttest.Messages(findings(t, "testdata"), "condition-"), "logs:* has actions on tagged log groups, and DenyOutsideRegions is valid")
+}
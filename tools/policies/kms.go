@@ -0,0 +1,175 @@
+package policies
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// kmsAdminActions are the key administration actions, which must not be
+// granted to the principals using the key
+var kmsAdminActions = []string{
+	"kms:PutKeyPolicy",
+	"kms:ScheduleKeyDeletion",
+	"kms:CancelKeyDeletion",
+	"kms:DisableKey",
+	"kms:DisableKeyRotation",
+	"kms:EnableKey",
+	"kms:EnableKeyRotation",
+	"kms:CreateAlias",
+	"kms:UpdateAlias",
+	"kms:DeleteAlias",
+	"kms:UpdateKeyDescription",
+	"kms:CreateGrant",
+	"kms:RevokeGrant",
+	"kms:DeleteImportedKeyMaterial",
+	"kms:TagResource",
+	"kms:UntagResource",
+}
+
+// kmsUsageActions are the only actions usage principals may be granted
+var kmsUsageActions = []string{"kms:Encrypt", "kms:Decrypt", "kms:GenerateDataKey*"}
+
+// kmsServiceConditions are the condition keys that confine what a service
+// principal may use a key for
+var kmsServiceConditions = []string{"kms:ViaService", "aws:SourceArn"}
+
+// lintKMS checks the separation of duties in key policies: the principals
+// using a key must not administer it and may only encrypt, decrypt and
+// generate data keys, and service principals must be confined to a service
+// or source. The account root, to which key policies delegate
+// administration through IAM, is an administrator and not checked.
+func lintKMS(p *iam.Policy, l *lint.Collector) {
+	if !strings.HasPrefix(p.Address, "aws_kms_key") {
+		return
+	}
+
+	for _, principal := range usagePrincipals(p.Statements) {
+		reported := map[*iam.Statement]bool{}
+		for _, action := range kmsAdminActions {
+			result := iam.Evaluate(p.Statements, iam.Request{Principal: principal, Action: action})
+			if result.Decision == iam.Allowed && !reported[result.Statement] {
+				reported[result.Statement] = true
+				l.Add(lint.SeverityError, "kms-admin-usage", p.Address, result.Statement.Position,
+					"%s grants %s, which uses the key, administrative actions such as %s",
+					statement(result.Statement), principal, action)
+			}
+		}
+	}
+
+	for _, s := range p.Statements {
+		if !s.Allows() {
+			continue
+		}
+		if principals := awsPrincipals(s); len(principals) > 0 && grantsUsage(s) {
+			var extra []string
+			for _, action := range s.Actions {
+				if !matchesAny(kmsUsageActions, action) && !grantsAdmin(action) {
+					extra = append(extra, action)
+				}
+			}
+			if len(s.NotActions) > 0 {
+				extra = append(extra, "NotAction "+strings.Join(s.NotActions, ", "))
+			}
+			if len(extra) > 0 {
+				l.Add(lint.SeverityError, "kms-usage-actions", p.Address, s.Position,
+					"%s grants %s %s; usage grants are limited to %s",
+					statement(s), strings.Join(principals, ", "), strings.Join(extra, ", "), strings.Join(kmsUsageActions, ", "))
+			}
+		}
+		if services := s.Principals["Service"]; len(services) > 0 && !hasCondition(s, kmsServiceConditions...) {
+			l.Add(lint.SeverityError, "kms-service-principal", p.Address, s.Position,
+				"%s grants %s without a %s condition",
+				statement(s), strings.Join(services, ", "), strings.Join(kmsServiceConditions, " or "))
+		}
+	}
+}
+
+// usagePrincipals returns the AWS principals other than account roots that
+// are allowed a usage action
+func usagePrincipals(statements []*iam.Statement) []string {
+	var principals []string
+	for _, s := range statements {
+		if !s.Allows() || !grantsUsage(s) {
+			continue
+		}
+		for _, principal := range awsPrincipals(s) {
+			if !slices.Contains(principals, principal) {
+				principals = append(principals, principal)
+			}
+		}
+	}
+	sort.Strings(principals)
+	return principals
+}
+
+// awsPrincipals returns the AWS principals of a statement other than
+// account roots
+func awsPrincipals(s *iam.Statement) []string {
+	var principals []string
+	for _, identifier := range s.Principals["AWS"] {
+		if iam.AccountOf(identifier) == "" {
+			principals = append(principals, identifier)
+		}
+	}
+	if _, ok := s.Principals["*"]; ok {
+		principals = append(principals, "*")
+	}
+	return principals
+}
+
+// grantsUsage reports whether a statement allows a usage action
+func grantsUsage(s *iam.Statement) bool {
+	for _, action := range []string{"kms:Encrypt", "kms:Decrypt", "kms:GenerateDataKey"} {
+		if iam.Evaluate([]*iam.Statement{s}, iam.Request{Action: action}).Decision == iam.Allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsAdmin reports whether an action pattern grants an administrative
+// action, which kms-admin-usage reports
+func grantsAdmin(pattern string) bool {
+	for _, action := range kmsAdminActions {
+		if iam.MatchAction(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether action is one of patterns, or a pattern of
+// only actions within them
+func matchesAny(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		if iam.MatchAction(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCondition reports whether a statement has a condition on one of keys
+func hasCondition(s *iam.Statement, keys ...string) bool {
+	for _, c := range s.Conditions {
+		for _, key := range keys {
+			if strings.EqualFold(c.Key, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statement returns `statement "Sid"`, or "statement without Sid"
+func statement(s *iam.Statement) string {
+	if s.Sid == "" {
+		return "statement without Sid"
+	}
+	return fmt.Sprintf("statement %q", s.Sid)
+}
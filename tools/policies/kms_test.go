@@ -0,0 +1,32 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func findings(t *testing.T, root string) []lint.Finding {
+	t.Helper()
+	policies, err := iam.Load(root)
+	require.NoError(t, err)
+	catalog, err := iam.LoadCatalog()
+	require.NoError(t, err)
+	return Lint(policies, catalog)
+}
+
+func TestLintKMS(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{
+		`modules/security/kms.tf:31 kms-admin-usage: statement "AppManage" grants ${aws_iam_role.app.arn}, which uses the key, administrative actions such as kms:ScheduleKeyDeletion`,
+		`modules/security/kms.tf:38 kms-admin-usage: statement "Worker" grants ${aws_iam_role.worker.arn}, which uses the key, administrative actions such as kms:PutKeyPolicy`,
+		`modules/security/kms.tf:38 kms-usage-actions: statement "Worker" grants ${aws_iam_role.worker.arn} kms:ReEncrypt*; usage grants are limited to kms:Encrypt, kms:Decrypt, kms:GenerateDataKey*`,
+		`modules/security/kms.tf:45 kms-service-principal: statement "Logs" grants logs.amazonaws.com without a kms:ViaService or aws:SourceArn condition`,
+		`modules/security/kms.tf:82 kms-admin-usage: statement without Sid grants *, which uses the key, administrative actions such as kms:CreateGrant`,
+	}, linttest.Messages(findings(t, "testdata"), "kms-"), "Admins does not use the key, CloudTrail and ViaS3 are confined")
+}
@@ -0,0 +1,24 @@
+// Package policies checks the IAM policy documents under modules/ (see
+// tools/iam) for zero trust requirements: separation of duties in KMS key
+// policies, the explicit denies secret policies need, the conditions trust
+// policies need, and the actions, resource ARNs and condition keys of every
+// statement (see iam.Catalog).
+package policies
+
+import (
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Lint checks every policy
+func Lint(policies []*iam.Policy, catalog *iam.Catalog) []lint.Finding {
+	var l lint.Collector
+	for _, p := range policies {
+		lintActions(p, catalog, &l)
+		lintConditions(p, catalog, &l)
+		lintKMS(p, &l)
+		lintSecrets(p, &l)
+		lintTrust(p, &l)
+	}
+	return l.Findings()
+}
@@ -0,0 +1,132 @@
+package policies
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// secretReadAction is the action the guarantees of secret policies are
+// probed with
+const secretReadAction = "secretsmanager:GetSecretValue"
+
+// secretAdminActions delete a secret or change its policy; the principals
+// reading a secret must be denied them
+var secretAdminActions = []string{
+	"secretsmanager:DeleteSecret",
+	"secretsmanager:PutResourcePolicy",
+	"secretsmanager:DeleteResourcePolicy",
+}
+
+// otherEndpoint is a VPC endpoint that is not ours
+const otherEndpoint = "vpce-0000000000000000f"
+
+// secretsEndpoint matches a reference to our Secrets Manager interface
+// endpoint: the resource, or a variable or output carrying its ID such as
+// var.secrets_endpoint_id
+var secretsEndpoint = regexp.MustCompile(`^\$\{(aws_vpc_endpoint\.secretsmanager\.id|.*\.secrets(manager)?_(vpc_)?endpoint_id)\}$`)
+
+// lintSecrets requires every secret policy to deny reads unless they come
+// through our Secrets Manager VPC endpoint, to deny principals of other
+// accounts, and to deny the principals it lets read the secret deleting it
+// or changing its policy. Identity policies of our account may allow what
+// a secret policy merely does not, so each guarantee needs an explicit Deny.
+func lintSecrets(p *iam.Policy, l *lint.Collector) {
+	if !strings.HasPrefix(p.Address, "aws_secretsmanager_secret") {
+		return
+	}
+	readers := readers(p.Statements)
+
+	for _, context := range []map[string][]string{
+		{"aws:SourceVpce": {otherEndpoint}},
+		{"aws:SourceVpce": nil},
+	} {
+		result := iam.Evaluate(p.Statements, iam.Request{Principal: readers[0], Action: secretReadAction, Context: context})
+		if result.Decision != iam.ExplicitDeny {
+			l.Add(lint.SeverityError, "secret-endpoint", p.Address, p.Position,
+				"the secret policy does not deny %s to requests that do not come through our Secrets Manager VPC endpoint; add a Deny statement with a StringNotEquals aws:SourceVpce condition",
+				secretReadAction)
+			break
+		}
+		// the probe cannot tell our endpoint from another one, the
+		// condition has to name it
+		if endpoint := otherEndpointValue(result.Statement); endpoint != "" {
+			l.Add(lint.SeverityError, "secret-endpoint", p.Address, result.Statement.Position,
+				"%s compares aws:SourceVpce with %s, which is not our Secrets Manager VPC endpoint; use aws_vpc_endpoint.secretsmanager.id or the variable carrying it",
+				statement(result.Statement), endpoint)
+			break
+		}
+	}
+
+	if result := iam.Evaluate(p.Statements, iam.Foreign(secretReadAction)); result.Decision != iam.ExplicitDeny {
+		position, detail := p.Position, ""
+		if result.Decision == iam.Allowed {
+			position, detail = result.Statement.Position, ", and "+statement(result.Statement)+" allows them"
+		}
+		l.Add(lint.SeverityError, "secret-foreign-principal", p.Address, position,
+			"the secret policy does not deny principals outside the account%s; add a Deny statement with a StringNotEquals aws:PrincipalAccount condition",
+			detail)
+	}
+
+	for _, reader := range readers {
+		var allowed []string
+		for _, action := range secretAdminActions {
+			request := iam.Request{Principal: reader, Action: action}
+			if reader != "" {
+				request.Context = map[string][]string{"aws:PrincipalArn": {reader}}
+			}
+			if iam.Evaluate(p.Statements, request).Decision != iam.ExplicitDeny {
+				allowed = append(allowed, action)
+			}
+		}
+		if len(allowed) > 0 {
+			who := reader
+			if who == "" {
+				who = "the principals reading the secret"
+			}
+			l.Add(lint.SeverityError, "secret-admin-deny", p.Address, p.Position,
+				"the secret policy does not deny %s %s; add a Deny statement for deletes and policy changes",
+				who, strings.Join(allowed, ", "))
+		}
+	}
+}
+
+// otherEndpointValue returns the first aws:SourceVpce value of a statement
+// that does not refer to our Secrets Manager VPC endpoint, or ""
+func otherEndpointValue(s *iam.Statement) string {
+	for _, c := range s.Conditions {
+		if !strings.EqualFold(c.Key, "aws:SourceVpce") {
+			continue
+		}
+		for _, value := range c.Values {
+			if !secretsEndpoint.MatchString(value) {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// readers returns the AWS principals other than account roots that a
+// policy allows to read the secret, or "" (any principal) when there are
+// none
+func readers(statements []*iam.Statement) []string {
+	var principals []string
+	for _, s := range statements {
+		if !s.Allows() || iam.Evaluate([]*iam.Statement{s}, iam.Request{Action: secretReadAction}).Decision != iam.Allowed {
+			continue
+		}
+		for _, principal := range awsPrincipals(s) {
+			if principal != "*" && !slices.Contains(principals, principal) {
+				principals = append(principals, principal)
+			}
+		}
+	}
+	if len(principals) == 0 {
+		return []string{""}
+	}
+	return principals
+}
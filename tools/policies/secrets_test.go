@@ -0,0 +1,19 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLintSecrets(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{
+		"modules/secrets/main.tf:53 secret-endpoint: the secret policy does not deny secretsmanager:GetSecretValue to requests that do not come through our Secrets Manager VPC endpoint; add a Deny statement with a StringNotEquals aws:SourceVpce condition",
+		"modules/secrets/main.tf:53 secret-admin-deny: the secret policy does not deny the principals reading the secret secretsmanager:PutResourcePolicy, secretsmanager:DeleteResourcePolicy; add a Deny statement for deletes and policy changes",
+		`modules/secrets/main.tf:56 secret-foreign-principal: the secret policy does not deny principals outside the account, and statement "AnyoneRead" allows them; add a Deny statement with a StringNotEquals aws:PrincipalAccount condition`,
+		`modules/secrets/main.tf:96 secret-endpoint: statement "DenyOutsideEndpoint" compares aws:SourceVpce with ${aws_vpc_endpoint.s3.id}, which is not our Secrets Manager VPC endpoint; use aws_vpc_endpoint.secretsmanager.id or the variable carrying it`,
+	}, linttest.Messages(findings(t, "testdata"), "secret-"), "aws_secretsmanager_secret_policy.good has every guarantee, aws:SourceVpc is not our endpoint, and wrong_endpoint denies reads outside the S3 endpoint only")
+}
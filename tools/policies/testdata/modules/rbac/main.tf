@@ -0,0 +1,85 @@
+# ABAC policies with condition keys the actions do and do not support
+
+resource "aws_iam_policy" "abac" {
+  name = "abac"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "SecretsByTag"
+        Effect   = "Allow"
+        Action   = ["secretsmanager:GetSecretValue", "secretsmanager:ListSecrets"]
+        Resource = "*"
+        Condition = {
+          StringEquals = { "aws:ResourceTag/Tier" = "application" }
+        }
+      },
+      {
+        Sid      = "ObjectsByTag"
+        Effect   = "Allow"
+        Action   = "s3:GetObject"
+        Resource = "arn:aws:s3:::data/*"
+        Condition = {
+          StringEqualsIfExists = { "aws:ResourceTag/Tier" = "data" }
+        }
+      },
+      {
+        Sid      = "KMSViaSecrets"
+        Effect   = "Allow"
+        Action   = ["kms:Decrypt", "secretsmanager:GetSecretValue"]
+        Resource = "*"
+        Condition = {
+          StringEquals = { "kms:ViaService" = "secretsmanager.eu-north-1.amazonaws.com" }
+        }
+      },
+      {
+        Sid      = "LogsAnyAction"
+        Effect   = "Allow"
+        Action   = "logs:*"
+        Resource = "*"
+        Condition = {
+          StringEquals = { "aws:ResourceTag/Tier" = "application" }
+        }
+      },
+      {
+        Sid      = "DenyNonTLS"
+        Effect   = "Deny"
+        Action   = "s3:*"
+        Resource = "*"
+        Condition = {
+          Bool = { "aws:SecureTransport" = "no" }
+        }
+      },
+      {
+        Sid      = "DenyOutsideRegions"
+        Effect   = "Deny"
+        Action   = "*"
+        Resource = "*"
+        Condition = {
+          StringNotEquals = { "aws:RequestedRegion" = ["eu-north-1", "${var.region}"] }
+          StringNotLike   = { "aws:SourceVpce" = "vpce-*" }
+          IpAddress       = { "aws:SourceIp" = "10.0.0.0/8" }
+        }
+      },
+      {
+        Sid      = "DenyPublic"
+        Effect   = "Deny"
+        Action   = "s3:*"
+        Resource = "*"
+        Condition = {
+          StringEquals = { "aws:RequestedRegion" = "public" }
+        }
+      },
+      {
+        Sid      = "DenyUntagged"
+        Effect   = "Deny"
+        Action   = "ec2:RunInstances"
+        Resource = "*"
+        Condition = {
+          StringEquals = { "aws:RequestTag/Tier" = "" }
+        }
+      },
+    ]
+  })
+}
@@ -0,0 +1,128 @@
+resource "aws_secretsmanager_secret" "good" {
+  name = "good"
+}
+
+resource "aws_secretsmanager_secret_policy" "good" {
+  secret_arn = aws_secretsmanager_secret.good.arn
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "AppRead"
+        Effect    = "Allow"
+        Principal = { AWS = var.app_role_arn }
+        Action    = ["secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "DenyOutsideEndpoint"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:*"
+        Resource  = "*"
+        Condition = {
+          StringNotEquals = { "aws:SourceVpce" = var.secrets_endpoint_id }
+        }
+      },
+      {
+        Sid       = "DenyOtherAccounts"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:*"
+        Resource  = "*"
+        Condition = {
+          StringNotEquals = { "aws:PrincipalAccount" = data.aws_caller_identity.current.account_id }
+        }
+      },
+      {
+        Sid       = "DenyAppAdmin"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = ["secretsmanager:Delete*", "secretsmanager:PutResourcePolicy"]
+        Resource  = "*"
+        Condition = {
+          ArnEquals = { "aws:PrincipalArn" = var.app_role_arn }
+        }
+      },
+    ]
+  })
+}
+
+resource "aws_secretsmanager_secret" "partial" {
+  name = "partial"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "AnyoneRead"
+        Effect    = "Allow"
+        Principal = { AWS = "*" }
+        Action    = "secretsmanager:GetSecretValue"
+        Resource  = "*"
+      },
+      {
+        Sid       = "DenyOutsideVpc"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:GetSecretValue"
+        Resource  = "*"
+        Condition = {
+          StringNotEquals = { "aws:SourceVpc" = var.vpc_id }
+        }
+      },
+      {
+        Sid       = "NoDeletes"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:DeleteSecret"
+        Resource  = "*"
+      },
+    ]
+  })
+}
+
+resource "aws_secretsmanager_secret_policy" "wrong_endpoint" {
+  secret_arn = aws_secretsmanager_secret.good.arn
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "AppRead"
+        Effect    = "Allow"
+        Principal = { AWS = var.app_role_arn }
+        Action    = "secretsmanager:GetSecretValue"
+        Resource  = "*"
+      },
+      {
+        Sid       = "DenyOutsideEndpoint"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:*"
+        Resource  = "*"
+        Condition = {
+          StringNotEquals = { "aws:SourceVpce" = aws_vpc_endpoint.s3.id }
+        }
+      },
+      {
+        Sid       = "DenyOtherAccounts"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = "secretsmanager:*"
+        Resource  = "*"
+        Condition = {
+          StringNotEquals = { "aws:PrincipalAccount" = data.aws_caller_identity.current.account_id }
+        }
+      },
+      {
+        Sid       = "DenyAppAdmin"
+        Effect    = "Deny"
+        Principal = "*"
+        Action    = ["secretsmanager:Delete*", "secretsmanager:*ResourcePolicy"]
+        Resource  = "*"
+        Condition = {
+          ArnEquals = { "aws:PrincipalArn" = var.app_role_arn }
+        }
+      },
+    ]
+  })
+}
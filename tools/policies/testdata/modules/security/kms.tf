@@ -0,0 +1,95 @@
+resource "aws_kms_key" "main" {
+  description = "main"
+}
+
+resource "aws_kms_key_policy" "main" {
+  key_id = aws_kms_key.main.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Root"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "kms:*"
+        Resource  = "*"
+      },
+      {
+        Sid       = "Admins"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.key_admin.arn }
+        Action    = ["kms:Put*", "kms:Create*", "kms:Describe*", "kms:ScheduleKeyDeletion"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "AppUse"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.app.arn }
+        Action    = ["kms:Encrypt", "kms:Decrypt", "kms:GenerateDataKey*"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "AppManage"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.app.arn }
+        Action    = ["kms:ScheduleKeyDeletion", "kms:Disable*"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "Worker"
+        Effect    = "Allow"
+        Principal = { AWS = [aws_iam_role.worker.arn] }
+        Action    = ["kms:Decrypt", "kms:ReEncrypt*", "kms:PutKeyPolicy"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "Logs"
+        Effect    = "Allow"
+        Principal = { Service = "logs.amazonaws.com" }
+        Action    = ["kms:Encrypt", "kms:Decrypt", "kms:GenerateDataKey*"]
+        Resource  = "*"
+      },
+      {
+        Sid       = "CloudTrail"
+        Effect    = "Allow"
+        Principal = { Service = "cloudtrail.amazonaws.com" }
+        Action    = "kms:GenerateDataKey*"
+        Resource  = "*"
+        Condition = {
+          StringLike = { "aws:SourceArn" = "arn:aws:cloudtrail:*:${data.aws_caller_identity.current.account_id}:trail/*" }
+        }
+      },
+    ]
+  })
+}
+
+data "aws_iam_policy_document" "bucket_key" {
+  statement {
+    sid     = "ViaS3"
+    actions = ["kms:Decrypt", "kms:GenerateDataKey"]
+    principals {
+      type        = "Service"
+      identifiers = ["s3.amazonaws.com"]
+    }
+    resources = ["*"]
+    condition {
+      test     = "StringEquals"
+      variable = "kms:ViaService"
+      values   = ["s3.${var.region}.amazonaws.com"]
+    }
+  }
+
+  statement {
+    actions = ["kms:Decrypt", "kms:CreateGrant"]
+    principals {
+      type        = "*"
+      identifiers = ["*"]
+    }
+    resources = ["*"]
+  }
+}
+
+resource "aws_kms_key" "bucket" {
+  description = "bucket"
+  policy      = data.aws_iam_policy_document.bucket_key.json
+}
@@ -0,0 +1,141 @@
+# Trust policies of service, account, role and third-party principals
+
+data "aws_caller_identity" "current" {}
+
+data "aws_iam_policy_document" "logs_trust" {
+  statement {
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["vpc-flow-logs.amazonaws.com"]
+    }
+    condition {
+      test     = "StringEquals"
+      variable = "aws:SourceAccount"
+      values   = [data.aws_caller_identity.current.account_id]
+    }
+  }
+}
+
+resource "aws_iam_role" "logs" {
+  name               = "logs"
+  assume_role_policy = data.aws_iam_policy_document.logs_trust.json
+}
+
+data "aws_iam_policy_document" "events_trust" {
+  statement {
+    sid     = "Events"
+    actions = ["sts:AssumeRole"]
+    principals {
+      type        = "Service"
+      identifiers = ["events.amazonaws.com", "scheduler.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "events" {
+  name               = "events"
+  assume_role_policy = data.aws_iam_policy_document.events_trust.json
+}
+
+resource "aws_iam_role" "operators" {
+  name = "operators"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Account"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          Bool = { "aws:MultiFactorAuthPresent" = "true" }
+        }
+      },
+      {
+        Sid       = "Pipeline"
+        Effect    = "Allow"
+        Principal = { AWS = aws_iam_role.logs.arn }
+        Action    = ["sts:AssumeRole", "sts:TagSession"]
+      },
+      {
+        Sid       = "NoGuests"
+        Effect    = "Deny"
+        Principal = { AWS = "*" }
+        Action    = "sts:AssumeRole"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role" "vendor" {
+  name = "vendor"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "Vendor"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::111122223333:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          StringEquals = { "sts:ExternalId" = "zero-trust" }
+        }
+      },
+      {
+        Sid       = "GitHub"
+        Effect    = "Allow"
+        Principal = { Federated = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:oidc-provider/token.actions.githubusercontent.com" }
+        Action    = "sts:AssumeRoleWithWebIdentity"
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role" "breakglass" {
+  name = "breakglass"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "RecentMFA"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          NumericLessThan = { "aws:MultiFactorAuthAge" = "3600" }
+        }
+      },
+      {
+        Sid       = "NoMFA"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          Bool = { "aws:MultiFactorAuthPresent" = "false" }
+        }
+      },
+      {
+        Sid       = "AccessKeys"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          BoolIfExists = { "aws:MultiFactorAuthPresent" = "true" }
+        }
+      },
+      {
+        Sid       = "AnyExternalId"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::111122223333:root" }
+        Action    = "sts:AssumeRole"
+        Condition = {
+          StringLike = { "sts:ExternalId" = "*" }
+        }
+      }
+    ]
+  })
+}
@@ -0,0 +1,34 @@
+# Policies with misspelled actions and malformed resource ARNs
+
+data "aws_caller_identity" "current" {}
+
+resource "aws_iam_policy" "typos" {
+  name = "typos"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid    = "Secrets"
+        Effect = "Allow"
+        Action = ["secretmanager:GetSecretValue", "secretsmanager:GetSecretValues", "secretsmanager:Describe*"]
+        Resource = [
+          "arn:aws:secretsmanager:eu-north-1:${data.aws_caller_identity.current.account_id}:secret:app/*",
+          "arn:aws:secretsmanager:eu-north-1:${data.aws_caller_identity.current.account_id}:secrets:app/*",
+        ]
+      },
+      {
+        Sid      = "Keys"
+        Effect   = "Allow"
+        Action   = ["kms:Decrypt", "kms:Rotate*", "cloudtrail:LookupEvents"]
+        Resource = ["arn:aws:kms:*:*:key/*", "arn:aws:kms:*:*:*", "arn:amazon:kms:eu-north-1:111122223333:key/1234", "key/1234"]
+      },
+      {
+        Sid      = "Buckets"
+        Effect   = "Allow"
+        Action   = ["s3:GetObject", "s3:ListBucket", "s3:ListObjects*"]
+        Resource = ["arn:aws:s3:::data", "arn:aws:s3:::data/*", "arn:aws:s3:eu-north-1:111122223333:data", "arn:aws:s4:::data"]
+      },
+    ]
+  })
+}
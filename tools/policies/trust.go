@@ -0,0 +1,74 @@
+package policies
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/iam"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// trustSourceConditions confine a service to assuming a role on behalf of
+// our account or organization
+var trustSourceConditions = []string{"aws:SourceAccount", "aws:SourceArn", "aws:SourceOrgID", "aws:SourceOrgPaths"}
+
+// provesIdentity reports whether a condition requires the users and roles
+// assuming a role to have signed in with MFA, or to know the external id of
+// a third party. The key alone is not enough: Bool aws:MultiFactorAuthPresent
+// "false" holds without MFA, and the IfExists operators hold for long-term
+// access keys, whose requests carry no MFA key at all.
+func provesIdentity(c iam.Condition) bool {
+	switch {
+	case strings.EqualFold(c.Key, "aws:MultiFactorAuthPresent"):
+		return c.Operator == "Bool" && len(c.Values) > 0 && allEqual(c.Values, "true")
+	case strings.EqualFold(c.Key, "aws:MultiFactorAuthAge"):
+		return (c.Operator == "NumericLessThan" || c.Operator == "NumericLessThanEquals") && len(c.Values) > 0
+	case strings.EqualFold(c.Key, "sts:ExternalId"):
+		return c.Operator == "StringEquals" && len(c.Values) > 0 && !slices.Contains(c.Values, "")
+	}
+	return false
+}
+
+func allEqual(values []string, value string) bool {
+	for _, v := range values {
+		if !strings.EqualFold(v, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// lintTrust checks the statements of every trust policy letting principals
+// assume the role. A service principal acts for every account using the
+// service, and without a source condition it can be made to assume the
+// role for another account: the confused deputy problem. Users and roles,
+// including every principal of an account trusted by its root, must prove
+// MFA or an external id.
+func lintTrust(p *iam.Policy, l *lint.Collector) {
+	if p.Kind != iam.KindTrust {
+		return
+	}
+	for _, s := range p.Statements {
+		if !s.Allows() || iam.Evaluate([]*iam.Statement{s}, iam.Request{Action: "sts:AssumeRole"}).Decision != iam.Allowed {
+			continue
+		}
+		if services := s.Principals["Service"]; len(services) > 0 && !hasCondition(s, trustSourceConditions...) {
+			l.Add(lint.SeverityError, "trust-service-source", p.Address, s.Position,
+				"%s lets %s assume the role without an aws:SourceAccount or aws:SourceArn condition, so any account using the service can make it act as the role",
+				statement(s), strings.Join(services, ", "))
+		}
+		principals := append([]string{}, s.Principals["AWS"]...)
+		if _, ok := s.Principals["*"]; ok {
+			principals = append(principals, "*")
+		}
+		proven := false
+		for _, c := range s.Conditions {
+			proven = proven || provesIdentity(c)
+		}
+		if len(principals) > 0 && !proven {
+			l.Add(lint.SeverityError, "trust-principal-condition", p.Address, s.Position,
+				`%s lets %s assume the role without a Bool aws:MultiFactorAuthPresent "true", NumericLessThan aws:MultiFactorAuthAge or StringEquals sts:ExternalId condition`,
+				statement(s), strings.Join(principals, ", "))
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLintTrust(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{
+		"modules/trust/main.tf:26 trust-service-source: statement \"Events\" lets events.amazonaws.com, scheduler.amazonaws.com assume the role without an aws:SourceAccount or aws:SourceArn condition, so any account using the service can make it act as the role",
+		"modules/trust/main.tf:56 trust-principal-condition: statement \"Pipeline\" lets ${aws_iam_role.logs.arn} assume the role without a Bool aws:MultiFactorAuthPresent \"true\", NumericLessThan aws:MultiFactorAuthAge or StringEquals sts:ExternalId condition",
+		"modules/trust/main.tf:112 trust-principal-condition: statement \"NoMFA\" lets arn:aws:iam::${data.aws_caller_identity.current.account_id}:root assume the role without a Bool aws:MultiFactorAuthPresent \"true\", NumericLessThan aws:MultiFactorAuthAge or StringEquals sts:ExternalId condition",
+		"modules/trust/main.tf:121 trust-principal-condition: statement \"AccessKeys\" lets arn:aws:iam::${data.aws_caller_identity.current.account_id}:root assume the role without a Bool aws:MultiFactorAuthPresent \"true\", NumericLessThan aws:MultiFactorAuthAge or StringEquals sts:ExternalId condition",
+		"modules/trust/main.tf:130 trust-principal-condition: statement \"AnyExternalId\" lets arn:aws:iam::111122223333:root assume the role without a Bool aws:MultiFactorAuthPresent \"true\", NumericLessThan aws:MultiFactorAuthAge or StringEquals sts:ExternalId condition",
+	}, linttest.Messages(findings(t, "testdata"), "trust-"), "logs, Account, Vendor and RecentMFA are confined, NoGuests denies, GitHub is federated")
+}
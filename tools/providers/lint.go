@@ -0,0 +1,199 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Lint checks the provider requirements of every module and the lock files
+// of the root modules
+func Lint(c *Config) []lint.Finding {
+	var l lint.Collector
+	constraints := map[string]map[string][]*Requirement{}
+	for _, m := range c.Modules {
+		for _, u := range m.Uses {
+			if r := m.Requirement(u.Name); r == nil {
+				l.Add(lint.SeverityError, "provider-constraint-missing", address(qualify(u.Name)), u.Position,
+					"%s uses %s, but required_providers sets no version for it, so any version is installed", m.Dir, u.Name)
+			}
+		}
+		for _, r := range m.Requirements {
+			if r.Constraint == "" {
+				l.Add(lint.SeverityError, "provider-constraint-missing", address(r.Source), r.Position,
+					"%s sets no version for %s, so any version is installed", m.Dir, display(r.Source))
+				continue
+			}
+			constraint, err := ParseConstraint(r.Constraint)
+			if err != nil {
+				l.Add(lint.SeverityError, "provider-constraint-invalid", address(r.Source), r.Position, "%v", err)
+				continue
+			}
+			if !constraint.Bounded() {
+				l.Add(lint.SeverityError, "provider-constraint-unbounded", address(r.Source), r.Position,
+					"%s constraint %q has no upper bound, so init installs the next major version as soon as it is released", display(r.Source), r.Constraint)
+			}
+			if constraints[r.Source] == nil {
+				constraints[r.Source] = map[string][]*Requirement{}
+			}
+			constraints[r.Source][constraint.String()] = append(constraints[r.Source][constraint.String()], r)
+		}
+	}
+	for _, source := range sortedKeys(constraints) {
+		byConstraint := constraints[source]
+		common := majority(byConstraint)
+		for _, constraint := range sortedKeys(byConstraint) {
+			if constraint == common {
+				continue
+			}
+			for _, r := range byConstraint[constraint] {
+				l.Add(lint.SeverityError, "provider-constraint-inconsistent", address(source), r.Position,
+					"%s constraint %q differs from %q, which %s", display(source), r.Constraint, byConstraint[common][0].Constraint, others(len(byConstraint[common]), "module requires", "modules require"))
+			}
+		}
+	}
+
+	versions := map[string]map[string][]*Locked{}
+	for _, m := range c.Modules {
+		if !m.Root {
+			if m.Lock != nil {
+				l.Add(lint.SeverityWarning, "lockfile-ignored", "terraform", m.Lock.Path+":1",
+					"%s is not a root module, so terraform never reads its lock file; lock the providers in the root modules under envs/", m.Dir)
+			}
+			continue
+		}
+		lintRoot(&l, c, m)
+		if m.Lock == nil {
+			continue
+		}
+		for _, p := range m.Lock.Providers {
+			if versions[p.Source] == nil {
+				versions[p.Source] = map[string][]*Locked{}
+			}
+			versions[p.Source][p.Version] = append(versions[p.Source][p.Version], p)
+		}
+	}
+	for _, source := range sortedKeys(versions) {
+		byVersion := versions[source]
+		common := majority(byVersion)
+		for _, version := range sortedKeys(byVersion) {
+			if version == common {
+				continue
+			}
+			for _, p := range byVersion[version] {
+				l.Add(lint.SeverityError, "lockfile-version-mismatch", address(source), p.Position,
+					"%s is locked at %s, but %s %s", display(source), version, others(len(byVersion[common]), "root module locks", "root modules lock"), common)
+			}
+		}
+	}
+	return l.Findings()
+}
+
+// lintRoot checks the lock file of a root module against the requirements
+// of the module and of the modules it calls
+func lintRoot(l *lint.Collector, c *Config, m *Module) {
+	requirements := c.Requirements(m)
+	if len(requirements) == 0 {
+		return
+	}
+	if m.Lock == nil {
+		l.Add(lint.SeverityError, "lockfile-missing", "terraform", m.Position,
+			"%s has no %s, so every init selects the newest provider versions the constraints allow; commit the one terraform init writes", m.Dir, LockFileName)
+		return
+	}
+	for _, source := range sortedKeys(requirements) {
+		p := m.Lock.Provider(source)
+		if p == nil {
+			l.Add(lint.SeverityError, "lockfile-provider-missing", address(source), m.Lock.Path+":1",
+				"%s requires %s, but its lock file does not lock it", m.Dir, display(source))
+			continue
+		}
+		for _, r := range requirements[source] {
+			constraint, err := ParseConstraint(r.Constraint)
+			if r.Constraint == "" || err != nil {
+				continue
+			}
+			if !constraint.Allows(p.Version) {
+				l.Add(lint.SeverityError, "lockfile-constraint", address(source), p.Position,
+					"%s is locked at %s, which the constraint %q at %s does not allow", display(source), p.Version, r.Constraint, r.Position)
+			}
+		}
+	}
+	for _, p := range m.Lock.Providers {
+		if n := packageHashes(p.Hashes); n < len(Platforms) {
+			hashes := "hashes"
+			if n == 1 {
+				hashes = "hash"
+			}
+			l.Add(lint.SeverityError, "lockfile-hashes", address(p.Source), p.Position,
+				"%s %s has %d h1: %s, fewer than the %d platforms terraform runs on (%s); run terraform providers lock %s",
+				display(p.Source), p.Version, n, hashes, len(Platforms), strings.Join(Platforms, ", "), platformFlags())
+		}
+	}
+}
+
+// packageHashes counts the h1: hashes of a locked provider. terraform init
+// records one for the package of the platform it runs on and
+// `terraform providers lock` one per -platform; the zh: hashes of the
+// registry's checksums only verify downloads from the registry, so an init
+// on another platform fails with a checksum mismatch when the provider comes
+// from a mirror or cache. An h1: hash does not name its platform, so only
+// the count is checked.
+func packageHashes(hashes []string) int {
+	n := 0
+	for _, h := range hashes {
+		if strings.HasPrefix(h, "h1:") {
+			n++
+		}
+	}
+	return n
+}
+
+func platformFlags() string {
+	flags := make([]string, len(Platforms))
+	for i, platform := range Platforms {
+		flags[i] = "-platform=" + platform
+	}
+	return strings.Join(flags, " ")
+}
+
+// address returns the Terraform address of a provider, e.g.
+// provider["registry.terraform.io/hashicorp/aws"]
+func address(source string) string {
+	return `provider["` + source + `"]`
+}
+
+// display returns a source without the default registry, e.g. hashicorp/aws
+func display(source string) string {
+	return strings.TrimPrefix(source, "registry.terraform.io/")
+}
+
+// others returns e.g. "3 other modules require"
+func others(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 other " + singular
+	}
+	return fmt.Sprintf("%d other %s", n, plural)
+}
+
+// majority returns the key with the most values, the smallest on a tie
+func majority[V any](values map[string][]V) string {
+	common := ""
+	for _, key := range sortedKeys(values) {
+		if common == "" || len(values[key]) > len(values[common]) {
+			common = key
+		}
+	}
+	return common
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,294 @@
+// Package providers checks the provider requirements of the modules under
+// modules/ and of the root modules under envs/ against each other and
+// against the dependency lock files (.terraform.lock.hcl) of the root
+// modules: every provider a module uses needs one bounded version
+// constraint, the same in every module, and every root module needs a lock
+// file that locks the same versions as the others, within the constraints,
+// with at least as many h1: hashes as platforms Terraform runs on.
+package providers
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// LockFileName is the dependency lock file terraform init writes to the
+// root module
+const LockFileName = ".terraform.lock.hcl"
+
+// Platforms are the platforms the lock files need hashes for: CI runners
+// and developer machines
+var Platforms = []string{"linux_amd64", "darwin_arm64"}
+
+// Requirement is an entry of a required_providers block
+type Requirement struct {
+	// Name is the local name, e.g. aws
+	Name string
+	// Source is the fully qualified source address, e.g.
+	// registry.terraform.io/hashicorp/aws
+	Source string
+	// Constraint is the version constraint as written, or "" for none
+	Constraint string
+	Position   string
+}
+
+// Use is the first block of a module that uses a provider
+type Use struct {
+	Name     string
+	Position string
+}
+
+// Locked is a provider block of a lock file
+type Locked struct {
+	Source      string
+	Version     string
+	Constraints string
+	Hashes      []string
+	Position    string
+}
+
+// LockFile is a .terraform.lock.hcl
+type LockFile struct {
+	// Path is relative to the root
+	Path      string
+	Providers []*Locked
+}
+
+// Provider returns the locked provider with the given source, or nil
+func (f *LockFile) Provider(source string) *Locked {
+	for _, p := range f.Providers {
+		if p.Source == source {
+			return p
+		}
+	}
+	return nil
+}
+
+// Module is a directory of .tf files
+type Module struct {
+	// Dir is the directory relative to the root, e.g. envs/dev/vpc
+	Dir string
+	// Root is true for the root modules under envs/
+	Root bool
+	// Position is the first block of the module, for findings about the
+	// module as a whole
+	Position     string
+	Requirements []*Requirement
+	// Uses are the providers the resources, data sources and provider
+	// blocks of the module use, by local name, sorted
+	Uses []Use
+	// Calls are the directories of the local modules the module calls
+	Calls []string
+	// Lock is the lock file in the directory, or nil
+	Lock *LockFile
+}
+
+// Requirement returns the requirement for a local name, or nil
+func (m *Module) Requirement(name string) *Requirement {
+	for _, r := range m.Requirements {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Config is the modules under modules/ and envs/ of a root
+type Config struct {
+	Modules []*Module
+}
+
+// Module returns the module of a directory relative to the root, or nil
+func (c *Config) Module(dir string) *Module {
+	for _, m := range c.Modules {
+		if m.Dir == dir {
+			return m
+		}
+	}
+	return nil
+}
+
+// Requirements returns the requirements of a module and of the modules it
+// calls, directly or not, by source
+func (c *Config) Requirements(m *Module) map[string][]*Requirement {
+	requirements := map[string][]*Requirement{}
+	seen := map[string]bool{}
+	var visit func(m *Module)
+	visit = func(m *Module) {
+		if m == nil || seen[m.Dir] {
+			return
+		}
+		seen[m.Dir] = true
+		for _, r := range m.Requirements {
+			requirements[r.Source] = append(requirements[r.Source], r)
+		}
+		for _, u := range m.Uses {
+			if m.Requirement(u.Name) == nil {
+				source := qualify(u.Name)
+				requirements[source] = append(requirements[source], &Requirement{Name: u.Name, Source: source, Position: u.Position})
+			}
+		}
+		for _, dir := range m.Calls {
+			visit(c.Module(dir))
+		}
+	}
+	visit(m)
+	return requirements
+}
+
+// Load reads every module under root/modules and every directory of .tf
+// files under root/envs, with their lock files. Positions and directories
+// are relative to root.
+func Load(root string) (*Config, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(filepath.Join(root, "envs"), func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case os.IsNotExist(err) && path == filepath.Join(root, "envs"):
+			return filepath.SkipDir
+		case err != nil:
+			return err
+		case entry.IsDir() && strings.HasPrefix(entry.Name(), "."):
+			return filepath.SkipDir
+		case entry.IsDir():
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	c := &Config{}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(module.Blocks) == 0 {
+			continue
+		}
+		m := loadModule(root, dir, module)
+		m.Lock, err = loadLockFile(root, filepath.Join(dir, LockFileName))
+		if err != nil {
+			return nil, err
+		}
+		c.Modules = append(c.Modules, m)
+	}
+	return c, nil
+}
+
+// loadModule reads the required_providers blocks, the providers used and
+// the local module calls of the module in dir
+func loadModule(root, dir string, module *tfconfig.Module) *Module {
+	rel := tfconfig.Relative(root, dir)
+	m := &Module{
+		Dir:      rel,
+		Root:     strings.HasPrefix(rel, "envs/"),
+		Position: tfconfig.Position(root, module.Blocks[0].File, module.Blocks[0].Line),
+	}
+	used := map[string]bool{}
+	use := func(name string, block *tfconfig.Block) {
+		if name != "" && !used[name] {
+			used[name] = true
+			m.Uses = append(m.Uses, Use{Name: name, Position: tfconfig.Position(root, block.File, block.Line)})
+		}
+	}
+	for _, block := range module.Blocks {
+		switch block.Type {
+		case "terraform":
+			for _, required := range block.Nested("required_providers") {
+				for name, attr := range required.Body.Attributes {
+					r := &Requirement{Name: name, Source: qualify(name), Position: tfconfig.Position(root, required.File, attr.SrcRange.Start.Line)}
+					value, _ := required.Attr(name)
+					switch value := value.(type) {
+					case string:
+						// the legacy form, aws = ">= 5.0"
+						r.Constraint = value
+					case map[string]interface{}:
+						if source, ok := value["source"].(string); ok {
+							r.Source = qualify(source)
+						}
+						r.Constraint, _ = value["version"].(string)
+					}
+					m.Requirements = append(m.Requirements, r)
+				}
+			}
+		case "provider":
+			use(block.Name(), block)
+		case "resource", "data":
+			if len(block.Labels) > 0 {
+				use(providerOf(block.Labels[0]), block)
+			}
+		case "module":
+			if source := block.String("source"); strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				m.Calls = append(m.Calls, tfconfig.Relative(root, filepath.Join(dir, source)))
+			}
+		}
+	}
+	sort.Slice(m.Requirements, func(i, j int) bool { return m.Requirements[i].Name < m.Requirements[j].Name })
+	sort.Slice(m.Uses, func(i, j int) bool { return m.Uses[i].Name < m.Uses[j].Name })
+	return m
+}
+
+// providerOf returns the local name of the provider of a resource or data
+// source type, e.g. aws for aws_iam_role, or "" for the built-in terraform
+// provider
+func providerOf(resourceType string) string {
+	name, _, _ := strings.Cut(resourceType, "_")
+	if name == "terraform" {
+		return ""
+	}
+	return name
+}
+
+// qualify returns the fully qualified address of a provider source or local
+// name: aws and hashicorp/aws are registry.terraform.io/hashicorp/aws
+func qualify(source string) string {
+	source = strings.ToLower(source)
+	switch strings.Count(source, "/") {
+	case 0:
+		return "registry.terraform.io/hashicorp/" + source
+	case 1:
+		return "registry.terraform.io/" + source
+	}
+	return source
+}
+
+// loadLockFile reads a lock file, or returns nil when there is none
+func loadLockFile(root, path string) (*LockFile, error) {
+	blocks, err := tfconfig.ParseFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &LockFile{Path: tfconfig.Relative(root, path)}
+	for _, block := range blocks {
+		if block.Type != "provider" {
+			continue
+		}
+		hashes, _ := block.Attr("hashes")
+		f.Providers = append(f.Providers, &Locked{
+			Source:      qualify(block.Name()),
+			Version:     block.String("version"),
+			Constraints: block.String("constraints"),
+			Hashes:      tfconfig.Strings(hashes),
+			Position:    tfconfig.Position(root, block.File, block.Line),
+		})
+	}
+	return f, nil
+}
@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata")
+	require.NoError(t, err)
+	require.Len(t, c.Modules, 7)
+	assert.Nil(t, c.Module("envs/dev/empty").Lock)
+	assert.Empty(t, c.Requirements(c.Module("envs/dev/empty")))
+
+	storage := c.Module("modules/storage")
+	assert.False(t, storage.Root)
+	require.Len(t, storage.Requirements, 2)
+	assert.Equal(t, &Requirement{Name: "random", Source: "registry.terraform.io/hashicorp/random", Position: "modules/storage/main.tf:7"}, storage.Requirements[1])
+	assert.Equal(t, []Use{{Name: "aws", Position: "modules/storage/main.tf:17"}, {Name: "random", Position: "modules/storage/main.tf:13"}}, storage.Uses)
+
+	network := c.Module("envs/dev/network")
+	assert.True(t, network.Root)
+	assert.Equal(t, []string{"modules/network"}, network.Calls)
+	require.NotNil(t, network.Lock)
+	assert.Equal(t, "envs/dev/network/.terraform.lock.hcl", network.Lock.Path)
+	aws := network.Lock.Provider("registry.terraform.io/hashicorp/aws")
+	require.NotNil(t, aws)
+	assert.Equal(t, "5.100.0", aws.Version)
+	assert.Len(t, aws.Hashes, 3)
+
+	requirements := c.Requirements(c.Module("envs/dev/dns"))
+	assert.Len(t, requirements["registry.terraform.io/hashicorp/aws"], 2, "the root module's and modules/dns's")
+	assert.Len(t, requirements["registry.terraform.io/hashicorp/random"], 1, "used by modules/dns without a requirement")
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata")
+	require.NoError(t, err)
+	findings := Lint(c)
+	assert.Equal(t, []string{
+		"envs/dev/network/main.tf:5 provider-constraint-missing: envs/dev/network uses aws, but required_providers sets no version for it, so any version is installed",
+		"envs/dev/storage/main.tf:5 provider-constraint-missing: envs/dev/storage uses aws, but required_providers sets no version for it, so any version is installed",
+		"modules/dns/main.tf:14 provider-constraint-missing: modules/dns uses random, but required_providers sets no version for it, so any version is installed",
+		"modules/storage/main.tf:7 provider-constraint-missing: modules/storage sets no version for hashicorp/random, so any version is installed",
+	}, linttest.Messages(findings, "provider-constraint-missing"))
+	assert.Equal(t, []string{
+		`modules/dns/main.tf:3 provider-constraint-unbounded: hashicorp/aws constraint ">= 5.0" has no upper bound, so init installs the next major version as soon as it is released`,
+	}, linttest.Messages(findings, "provider-constraint-unbounded"))
+	assert.Equal(t, []string{
+		`modules/dns/main.tf:3 provider-constraint-inconsistent: hashicorp/aws constraint ">= 5.0" differs from "~> 5.100", which 3 other modules require`,
+	}, linttest.Messages(findings, "provider-constraint-inconsistent"))
+	assert.Equal(t, []string{
+		"envs/dev/storage/main.tf:1 lockfile-missing: envs/dev/storage has no .terraform.lock.hcl, so every init selects the newest provider versions the constraints allow; commit the one terraform init writes",
+	}, linttest.Messages(findings, "lockfile-missing"), "envs/dev/empty uses no provider")
+	assert.Equal(t, []string{
+		"envs/dev/dns/.terraform.lock.hcl:1 lockfile-provider-missing: envs/dev/dns requires hashicorp/random, but its lock file does not lock it",
+	}, linttest.Messages(findings, "lockfile-provider-missing"))
+	assert.Equal(t, []string{
+		`envs/dev/dns/.terraform.lock.hcl:4 lockfile-constraint: hashicorp/aws is locked at 6.26.0, which the constraint "~> 5.100" at envs/dev/dns/main.tf:5 does not allow`,
+	}, linttest.Messages(findings, "lockfile-constraint"), `">= 5.0" in modules/dns allows 6.26.0`)
+	assert.Equal(t, []string{
+		"envs/dev/dns/.terraform.lock.hcl:4 lockfile-version-mismatch: hashicorp/aws is locked at 6.26.0, but 1 other root module locks 5.100.0",
+	}, linttest.Messages(findings, "lockfile-version-mismatch"))
+	assert.Equal(t, []string{
+		"envs/dev/network/.terraform.lock.hcl:14 lockfile-hashes: hashicorp/random 3.7.2 has 1 h1: hash, fewer than the 2 platforms terraform runs on (linux_amd64, darwin_arm64); run terraform providers lock -platform=linux_amd64 -platform=darwin_arm64",
+	}, linttest.Messages(findings, "lockfile-hashes"))
+	assert.Equal(t, []string{
+		"modules/storage/.terraform.lock.hcl:1 lockfile-ignored: modules/storage is not a root module, so terraform never reads its lock file; lock the providers in the root modules under envs/",
+	}, linttest.Messages(findings, "lockfile-ignored"))
+	assert.Equal(t, 11, lint.Count(findings, lint.SeverityError))
+}
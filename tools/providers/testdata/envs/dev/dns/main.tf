@@ -0,0 +1,18 @@
+terraform {
+  backend "s3" {}
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.100"
+    }
+  }
+}
+
+provider "aws" {
+  region = "eu-north-1"
+}
+
+module "dns" {
+  source = "../../../modules/dns"
+}
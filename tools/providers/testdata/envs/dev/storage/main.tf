@@ -0,0 +1,11 @@
+terraform {
+  backend "s3" {}
+}
+
+provider "aws" {
+  region = "eu-north-1"
+}
+
+module "storage" {
+  source = "../../../modules/storage"
+}
@@ -0,0 +1,16 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+  }
+}
+
+resource "aws_route53_zone" "internal" {
+  name = "internal.example.com"
+}
+
+resource "random_id" "suffix" {
+  byte_length = 4
+}
@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a provider version such as 5.100.0, without pre-release or
+// build metadata
+type version []int
+
+func parseVersion(s string) (version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+	v := make(version, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compare compares two versions, treating missing components as 0
+func (v version) compare(other version) int {
+	for i := 0; i < 3; i++ {
+		a, b := v.at(i), other.at(i)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v version) at(i int) int {
+	if i < len(v) {
+		return v[i]
+	}
+	return 0
+}
+
+func (v version) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// clause is one comma-separated part of a version constraint, e.g. ">= 5.0"
+type clause struct {
+	op      string
+	version version
+}
+
+// operators are the version constraint operators, longest first
+var operators = []string{">=", "<=", "!=", "~>", ">", "<", "="}
+
+// Constraint is a parsed version constraint such as ">= 5.0, < 6.0"
+type Constraint []clause
+
+// ParseConstraint parses a Terraform version constraint
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		op := "="
+		for _, o := range operators {
+			if strings.HasPrefix(part, o) {
+				op, part = o, strings.TrimSpace(strings.TrimPrefix(part, o))
+				break
+			}
+		}
+		v, err := parseVersion(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+		}
+		if op == "~>" && len(v) < 2 {
+			return nil, fmt.Errorf("invalid constraint %q: ~> needs a minor version", s)
+		}
+		c = append(c, clause{op: op, version: v})
+	}
+	return c, nil
+}
+
+// Bounded reports whether the constraint has an upper bound, so that a new
+// major version is not installed without a change to the code
+func (c Constraint) Bounded() bool {
+	for _, cl := range c {
+		switch cl.op {
+		case "<", "<=", "~>", "=":
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether v satisfies every clause of the constraint
+func (c Constraint) Allows(v string) bool {
+	parsed, err := parseVersion(v)
+	if err != nil {
+		return false
+	}
+	for _, cl := range c {
+		cmp := parsed.compare(cl.version)
+		var ok bool
+		switch cl.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~>":
+			// ~> 5.1 allows >= 5.1, < 6.0; ~> 5.1.2 allows >= 5.1.2, < 5.2.0
+			upper := make(version, len(cl.version)-1)
+			copy(upper, cl.version)
+			upper[len(upper)-1]++
+			ok = cmp >= 0 && parsed.compare(upper) < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint in canonical form, e.g. ">= 5.0, < 6.0"
+func (c Constraint) String() string {
+	parts := make([]string, len(c))
+	for i, cl := range c {
+		parts[i] = cl.op + " " + cl.version.String()
+	}
+	return strings.Join(parts, ", ")
+}
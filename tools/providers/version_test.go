@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraint string
+		canonical  string
+		bounded    bool
+		allows     []string
+		denies     []string
+	}{
+		{">= 5.0", ">= 5.0", false, []string{"5.0.0", "5.100.0", "6.26.0"}, []string{"4.67.0"}},
+		{"~> 5.100", "~> 5.100", true, []string{"5.100.0", "5.101.3"}, []string{"5.99.0", "6.0.0"}},
+		{"~> 5.100.0", "~> 5.100.0", true, []string{"5.100.0", "5.100.9"}, []string{"5.101.0"}},
+		{">=5.0,<6.0", ">= 5.0, < 6.0", true, []string{"5.100.0"}, []string{"6.0.0"}},
+		{"5.100.0", "= 5.100.0", true, []string{"5.100.0"}, []string{"5.100.1"}},
+		{">= 5.0, != 5.50.0", ">= 5.0, != 5.50.0", false, []string{"5.51.0"}, []string{"5.50.0"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.constraint, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := ParseConstraint(tt.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.canonical, c.String())
+			assert.Equal(t, tt.bounded, c.Bounded())
+			for _, v := range tt.allows {
+				assert.True(t, c.Allows(v), v)
+			}
+			for _, v := range tt.denies {
+				assert.False(t, c.Allows(v), v)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "~> 5", ">= five", "5.0.0.0"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,97 @@
+// Package retryable is the catalog of terraform errors this project hits
+// routinely and that go away on their own, in the regex→reason form used by
+// terratest's Options.RetryableTerraformErrors.
+//
+// terratest's defaults only cover generic network and provider-install
+// failures. Every entry here comes from a captured CI log (see testdata) and
+// must keep matching it.
+package retryable
+
+import (
+	"regexp"
+	"time"
+)
+
+// Entry is a single retryable error
+type Entry struct {
+	// Name identifies the entry in tests and logs
+	Name string
+	// Pattern is matched against terraform's output and error text
+	Pattern string
+	// Reason is logged by terratest when the pattern triggers a retry
+	Reason string
+}
+
+// Catalog lists the project-specific retryable errors
+var Catalog = []Entry{
+	{
+		Name:    "network-firewall-in-progress",
+		Pattern: `InvalidOperationException: .*(in progress|is currently being (created|updated|deleted))`,
+		Reason:  "Network Firewall is still applying a previous change to the firewall, policy or rule group",
+	},
+	{
+		Name:    "network-firewall-update-token",
+		Pattern: `InvalidTokenException: Update token`,
+		Reason:  "Network Firewall rule group or policy was changed concurrently; a retry reads a fresh update token",
+	},
+	{
+		Name:    "acm-pca-state",
+		Pattern: `InvalidStateException: .*[Cc]ertificate [Aa]uthority`,
+		Reason:  "ACM PCA certificate authority is still transitioning to ACTIVE",
+	},
+	{
+		Name:    "acm-request-in-progress",
+		Pattern: `RequestInProgressException`,
+		Reason:  "ACM or ACM PCA is still processing a previous request for this resource",
+	},
+	{
+		Name:    "secret-scheduled-for-deletion",
+		Pattern: `(already scheduled|was marked|is scheduled) for deletion`,
+		Reason:  "Secret with the same name is still being deleted; recovery_window_in_days = 0 deletes it asynchronously, and the name is free once the deletion completes",
+	},
+	{
+		Name:    "iam-instance-profile-propagation",
+		Pattern: `Invalid IAM Instance Profile (name|ARN)|iamInstanceProfile\.(name|arn) is invalid`,
+		Reason:  "IAM instance profile has not propagated to EC2 yet (eventual consistency)",
+	},
+}
+
+// MaxRetries and TimeBetweenRetries are the minimums applied alongside the
+// catalog. Firewall and PCA transitions take minutes, far longer than
+// terratest's default of 3 retries 5 seconds apart.
+const (
+	MaxRetries         = 6
+	TimeBetweenRetries = 30 * time.Second
+)
+
+// Errors returns the catalog as a regex→reason map
+func Errors() map[string]string {
+	errors := make(map[string]string, len(Catalog))
+	for _, entry := range Catalog {
+		errors[entry.Pattern] = entry.Reason
+	}
+	return errors
+}
+
+// Merge returns a new map holding existing plus every catalog entry. Entries
+// already present in existing keep their reason.
+func Merge(existing map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(Catalog))
+	for pattern, reason := range Errors() {
+		merged[pattern] = reason
+	}
+	for pattern, reason := range existing {
+		merged[pattern] = reason
+	}
+	return merged
+}
+
+// Match returns the first catalog entry whose pattern matches log
+func Match(log string) (Entry, bool) {
+	for _, entry := range Catalog {
+		if regexp.MustCompile(entry.Pattern).MatchString(log) {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
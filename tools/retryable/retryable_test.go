@@ -0,0 +1,86 @@
+package retryable
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedLogs maps each captured terraform log in testdata to the catalog
+// entry that must match it; an empty name means the error is not retryable
+var capturedLogs = map[string]string{
+	"network_firewall_in_progress.log":  "network-firewall-in-progress",
+	"network_firewall_deleting.log":     "network-firewall-in-progress",
+	"network_firewall_update_token.log": "network-firewall-update-token",
+	"acm_pca_state.log":                 "acm-pca-state",
+	"acm_request_in_progress.log":       "acm-request-in-progress",
+	"secret_scheduled_for_deletion.log": "secret-scheduled-for-deletion",
+	"iam_instance_profile.log":          "iam-instance-profile-propagation",
+	"kms_access_denied.log":             "",
+	"invalid_cidr.log":                  "",
+}
+
+func TestCatalogPatternsCompile(t *testing.T) {
+	t.Parallel()
+
+	names := map[string]bool{}
+	for _, entry := range Catalog {
+		_, err := regexp.Compile(entry.Pattern)
+		assert.NoError(t, err, entry.Name)
+		assert.NotEmpty(t, entry.Reason, entry.Name)
+		assert.False(t, names[entry.Name], "duplicate entry name %s", entry.Name)
+		names[entry.Name] = true
+	}
+}
+
+func TestCapturedLogsMatchCatalog(t *testing.T) {
+	t.Parallel()
+
+	for fixture, want := range capturedLogs {
+		fixture, want := fixture, want
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			raw, err := os.ReadFile(filepath.Join("testdata", fixture))
+			require.NoError(t, err)
+
+			entry, ok := Match(string(raw))
+			if want == "" {
+				assert.False(t, ok, "%s should not be retried, but matched %s", fixture, entry.Name)
+				return
+			}
+			require.True(t, ok, "%s should be retried", fixture)
+			assert.Equal(t, want, entry.Name)
+		})
+	}
+}
+
+func TestEveryEntryHasCapturedLog(t *testing.T) {
+	t.Parallel()
+
+	covered := map[string]bool{}
+	for _, name := range capturedLogs {
+		covered[name] = true
+	}
+	for _, entry := range Catalog {
+		assert.True(t, covered[entry.Name], "catalog entry %s has no captured log in testdata", entry.Name)
+	}
+}
+
+func TestMergeKeepsExistingErrors(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]string{".*read: connection reset by peer.*": "Failed to reach helm charts repository."}
+	merged := Merge(existing)
+
+	assert.Len(t, merged, len(existing)+len(Catalog))
+	assert.Equal(t, "Failed to reach helm charts repository.", merged[".*read: connection reset by peer.*"])
+	for _, entry := range Catalog {
+		assert.Equal(t, entry.Reason, merged[entry.Pattern])
+	}
+	assert.Len(t, existing, 1, "Merge should not modify its argument")
+}
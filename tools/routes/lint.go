@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+)
+
+// Lint requires isolated tiers to have no path to the internet, and the
+// internet traffic of every other tier except public and the firewall's own
+// to pass through a Network Firewall when the configuration declares one
+func Lint(n *Network) []lint.Finding {
+	var c lint.Collector
+	inspection := map[string]bool{}
+	for _, firewall := range n.Firewalls {
+		for _, tier := range n.locations[firewall] {
+			inspection[tier] = true
+		}
+	}
+
+	for _, tier := range n.Tiers {
+		for _, destination := range Destinations {
+			p := n.Path(tier, destination)
+			if !p.Internet() {
+				continue
+			}
+			first := p.Hops[0]
+			switch {
+			case strings.Contains(tier, "isolated"):
+				c.Add(lint.SeverityError, "internet-path", first.RouteTable, first.Position,
+					"%s subnets must not reach the internet: %s", tier, p)
+			case tier != "public" && !inspection[tier] && destination == Internet && len(n.Firewalls) > 0 && !p.Through(HopFirewall):
+				c.Add(lint.SeverityError, "firewall-bypass", first.RouteTable, first.Position,
+					"internet traffic from %s subnets bypasses %s: %s; route %s to the firewall endpoint",
+					tier, strings.Join(n.Firewalls, ", "), p, Internet)
+			}
+		}
+	}
+	return c.Findings()
+}
@@ -0,0 +1,485 @@
+// Package routes computes where traffic from each subnet tier of the VPC is
+// routed, from the subnets, route tables, NAT gateways, gateway endpoints
+// and Network Firewall declared under modules/, without deploying anything.
+//
+// A tier is an aws_subnet resource, e.g. aws_subnet.private is the tier
+// "private". Modules are deployed separately, so an input variable of one
+// module is resolved to the output of the same name of another module,
+// which is how the root modules under envs/ pass them through remote state.
+package routes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Destinations of a path
+const (
+	Internet = "0.0.0.0/0"
+	VPC      = "vpc"
+	S3       = "s3"
+	DynamoDB = "dynamodb"
+)
+
+// Destinations are the destinations a path is computed for per tier
+var Destinations = []string{Internet, VPC, S3, DynamoDB}
+
+// Hop types
+const (
+	HopLocal           = "local"
+	HopInternetGateway = "internet-gateway"
+	HopNATGateway      = "nat-gateway"
+	HopFirewall        = "firewall-endpoint"
+	HopGatewayEndpoint = "gateway-endpoint"
+	// HopNone means no route matches and the traffic is dropped
+	HopNone = "none"
+)
+
+// Hop is the next hop a route table selects for a destination
+type Hop struct {
+	Type string
+	// Target is the address of the gateway or endpoint, e.g.
+	// aws_nat_gateway.nat_gtw
+	Target string
+	// RouteTable is the address of the table that selected the hop
+	RouteTable string
+	// Position is the position of the route, or of the route table for
+	// local and missing routes
+	Position string
+}
+
+func (h Hop) String() string {
+	if h.Target == "" {
+		return h.Type
+	}
+	return h.Type + " " + h.Target
+}
+
+// Path is the sequence of hops traffic from a tier takes to a destination.
+// NAT gateways and firewall endpoints forward traffic to the route table of
+// the subnet they are in.
+type Path struct {
+	Tier        string
+	Destination string
+	Hops        []Hop
+}
+
+// Last returns the hop the path ends with
+func (p Path) Last() Hop {
+	return p.Hops[len(p.Hops)-1]
+}
+
+// Internet reports whether the path leaves through an internet gateway
+func (p Path) Internet() bool {
+	return p.Last().Type == HopInternetGateway
+}
+
+// Through reports whether the path has a hop of the given type
+func (p Path) Through(hopType string) bool {
+	for _, hop := range p.Hops {
+		if hop.Type == hopType {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns e.g. "private -> 0.0.0.0/0: nat-gateway aws_nat_gateway.nat_gtw -> internet-gateway aws_internet_gateway.igw"
+func (p Path) String() string {
+	hops := make([]string, len(p.Hops))
+	for i, hop := range p.Hops {
+		hops[i] = hop.String()
+	}
+	return fmt.Sprintf("%s -> %s: %s", p.Tier, p.Destination, strings.Join(hops, " -> "))
+}
+
+type route struct {
+	// destination is a CIDR, or the service of a prefix list such as "s3"
+	destination string
+	hop         Hop
+}
+
+type routeTable struct {
+	address  string
+	routes   []route
+	position string
+}
+
+// lookup returns the hop the table selects for a destination. Every table
+// routes the VPC CIDR locally; a prefix list route for a service is more
+// specific than 0.0.0.0/0.
+func (t *routeTable) lookup(destination string) Hop {
+	if destination == VPC {
+		return Hop{Type: HopLocal, RouteTable: t.address, Position: t.position}
+	}
+	for _, r := range t.routes {
+		if r.destination == destination {
+			return r.hop
+		}
+	}
+	if destination != Internet {
+		return t.lookup(Internet)
+	}
+	return Hop{Type: HopNone, RouteTable: t.address, Position: t.position}
+}
+
+// Network is the routing of the VPC
+type Network struct {
+	// Tiers are the subnet tiers, public, private and isolated first
+	Tiers []string
+	// Firewalls are the addresses of the Network Firewalls
+	Firewalls []string
+	// positions are the positions of the tiers' subnets
+	positions map[string]string
+	tables    map[string]*routeTable
+	// associations map tiers to the address of their route table
+	associations map[string]string
+	main         string
+	// locations map NAT gateways and firewalls to the tiers they are in
+	locations map[string][]string
+}
+
+// Position returns the position of a tier's aws_subnet resource
+func (n *Network) Position(tier string) string {
+	return n.positions[tier]
+}
+
+// Path follows the routes of a tier to a destination
+func (n *Network) Path(tier, destination string) Path {
+	p := Path{Tier: tier, Destination: destination}
+	visited := map[string]bool{}
+	for current := tier; ; {
+		visited[current] = true
+		table := n.tables[n.associations[current]]
+		if table == nil {
+			table = n.tables[n.main]
+		}
+		hop := table.lookup(destination)
+		p.Hops = append(p.Hops, hop)
+		if hop.Type != HopNATGateway && hop.Type != HopFirewall {
+			return p
+		}
+
+		next := n.locations[hop.Target]
+		if len(next) == 0 || visited[next[0]] {
+			p.Hops = append(p.Hops, Hop{Type: HopNone, Target: hop.Target, RouteTable: table.address, Position: hop.Position})
+			return p
+		}
+		current = next[0]
+	}
+}
+
+// Paths returns the path of every tier to every destination
+func (n *Network) Paths() []Path {
+	var paths []Path
+	for _, tier := range n.Tiers {
+		for _, destination := range Destinations {
+			paths = append(paths, n.Path(tier, destination))
+		}
+	}
+	return paths
+}
+
+// tierOrder sorts the well-known tiers first
+var tierOrder = map[string]int{"public": 0, "private": 1, "isolated": 2}
+
+// targetAttrs are the route attributes that select a next hop
+var targetAttrs = []string{
+	"gateway_id", "nat_gateway_id", "vpc_endpoint_id", "transit_gateway_id", "vpc_peering_connection_id",
+	"network_interface_id", "egress_only_gateway_id", "local_gateway_id", "carrier_gateway_id", "core_network_arn",
+}
+
+var (
+	resourceRef = regexp.MustCompile(`\b(aws_[a-z0-9_]+)\.([A-Za-z_][A-Za-z0-9_-]*)`)
+	variableRef = regexp.MustCompile(`\bvar\.([A-Za-z_][A-Za-z0-9_-]*)`)
+)
+
+type module struct {
+	config  *tfconfig.Module
+	outputs map[string]*tfconfig.Block
+}
+
+type loader struct {
+	root    string
+	modules []*module
+	network *Network
+}
+
+// Load reads the routing of every module under root/modules. Positions are
+// relative to root.
+func Load(root string) (*Network, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	l := &loader{root: root, network: &Network{
+		positions:    map[string]string{},
+		tables:       map[string]*routeTable{"main": {address: "main"}},
+		associations: map[string]string{},
+		main:         "main",
+		locations:    map[string][]string{},
+	}}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		config, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		m := &module{config: config, outputs: map[string]*tfconfig.Block{}}
+		for _, output := range config.BlocksOfType("output") {
+			m.outputs[output.Name()] = output
+		}
+		l.modules = append(l.modules, m)
+	}
+
+	// route tables first, so that routes and associations declared in other
+	// modules can be added to them
+	for _, m := range l.modules {
+		for _, block := range m.config.BlocksOfType("resource", "aws_route_table") {
+			l.network.tables[block.Address()] = l.routeTable(m, block)
+		}
+		for _, block := range m.config.BlocksOfType("resource", "aws_default_route_table") {
+			l.network.tables[block.Address()] = l.routeTable(m, block)
+			l.network.main = block.Address()
+		}
+	}
+	for _, m := range l.modules {
+		l.load(m)
+	}
+
+	n := l.network
+	sort.Slice(n.Tiers, func(i, j int) bool {
+		a, aKnown := tierOrder[n.Tiers[i]]
+		b, bKnown := tierOrder[n.Tiers[j]]
+		if aKnown != bKnown {
+			return aKnown
+		}
+		if aKnown {
+			return a < b
+		}
+		return n.Tiers[i] < n.Tiers[j]
+	})
+	sort.Strings(n.Firewalls)
+	return n, nil
+}
+
+func (l *loader) load(m *module) {
+	n := l.network
+	for _, block := range m.config.BlocksOfType("resource", "aws_subnet") {
+		n.Tiers = append(n.Tiers, block.Name())
+		n.positions[block.Name()] = l.position(block, block.Line)
+	}
+
+	for _, block := range m.config.BlocksOfType("resource", "aws_route_table_association") {
+		table := l.first(m, block.Source("route_table_id"), "aws_route_table", "aws_default_route_table")
+		for _, subnet := range l.refs(m, block.Source("subnet_id")+" "+block.Source("for_each"), "aws_subnet") {
+			if table != "" {
+				n.associations[tier(subnet)] = table
+			}
+		}
+	}
+	for _, block := range m.config.BlocksOfType("resource", "aws_main_route_table_association") {
+		if table := l.first(m, block.Source("route_table_id"), "aws_route_table"); table != "" {
+			n.main = table
+		}
+	}
+
+	for _, block := range m.config.BlocksOfType("resource", "aws_route") {
+		table := n.tables[l.first(m, block.Source("route_table_id"), "aws_route_table", "aws_default_route_table")]
+		if table == nil {
+			continue
+		}
+		if r, ok := l.route(m, block, table, "destination_cidr_block"); ok {
+			table.routes = append(table.routes, r)
+		}
+	}
+
+	for _, block := range m.config.BlocksOfType("resource", "aws_nat_gateway") {
+		l.locate(m, block.Address(), block.Source("subnet_id"))
+	}
+	for _, block := range m.config.BlocksOfType("resource", "aws_networkfirewall_firewall") {
+		n.Firewalls = append(n.Firewalls, block.Address())
+		l.locate(m, block.Address(), block.Text())
+	}
+
+	for _, block := range m.config.BlocksOfType("resource", "aws_vpc_endpoint") {
+		if !gateway(block) {
+			continue
+		}
+		hop := Hop{Type: HopGatewayEndpoint, Target: block.Address(), Position: l.position(block, block.Line)}
+		for _, address := range l.refs(m, block.Source("route_table_ids"), "aws_route_table", "aws_default_route_table") {
+			l.addEndpointRoute(address, service(block.String("service_name")), hop)
+		}
+	}
+	for _, block := range m.config.BlocksOfType("resource", "aws_vpc_endpoint_route_table_association") {
+		endpoint := l.endpoint(m, block.Source("vpc_endpoint_id"))
+		if !gateway(endpoint) {
+			continue
+		}
+		hop := Hop{Type: HopGatewayEndpoint, Target: endpoint.Address(), Position: l.position(block, block.Line)}
+		l.addEndpointRoute(l.first(m, block.Source("route_table_id"), "aws_route_table", "aws_default_route_table"),
+			service(endpoint.String("service_name")), hop)
+	}
+}
+
+func (l *loader) addEndpointRoute(address, service string, hop Hop) {
+	table := l.network.tables[address]
+	if table == nil || service == "" {
+		return
+	}
+	hop.RouteTable = table.address
+	table.routes = append(table.routes, route{destination: service, hop: hop})
+}
+
+// locate records the tiers of the subnets referenced by source as the
+// location of a NAT gateway or firewall
+func (l *loader) locate(m *module, address, source string) {
+	for _, subnet := range l.refs(m, source, "aws_subnet") {
+		if subnetTier := tier(subnet); !slices.Contains(l.network.locations[address], subnetTier) {
+			l.network.locations[address] = append(l.network.locations[address], subnetTier)
+		}
+	}
+}
+
+func (l *loader) routeTable(m *module, block *tfconfig.Block) *routeTable {
+	table := &routeTable{address: block.Address(), position: l.position(block, block.Line)}
+	for _, nested := range block.Nested("route") {
+		if r, ok := l.route(m, nested, table, "cidr_block"); ok {
+			table.routes = append(table.routes, r)
+		}
+	}
+	return table
+}
+
+// route reads a route block or aws_route resource. cidrAttr is the name of
+// its IPv4 destination attribute.
+func (l *loader) route(m *module, block *tfconfig.Block, table *routeTable, cidrAttr string) (route, bool) {
+	r := route{destination: block.String(cidrAttr)}
+	if r.destination == "" && block.Has("destination_prefix_list_id") {
+		if endpoint := l.endpoint(m, block.Source("destination_prefix_list_id")); endpoint != nil {
+			r.destination = service(endpoint.String("service_name"))
+		}
+	}
+	if r.destination == "" {
+		return r, false
+	}
+
+	r.hop = Hop{RouteTable: table.address, Position: l.position(block, block.Line)}
+	for _, attr := range targetAttrs {
+		if !block.Has(attr) {
+			continue
+		}
+		source := block.Source(attr)
+		r.hop.Type, r.hop.Target = strings.ReplaceAll(strings.TrimSuffix(attr, "_id"), "_", "-"), source
+		switch {
+		case attr == "gateway_id" && block.String(attr) == "local":
+			r.hop.Type, r.hop.Target = HopLocal, ""
+		case attr == "gateway_id" && l.first(m, source, "aws_internet_gateway") != "":
+			r.hop.Type, r.hop.Target = HopInternetGateway, l.first(m, source, "aws_internet_gateway")
+		case attr == "nat_gateway_id" && l.first(m, source, "aws_nat_gateway") != "":
+			r.hop.Type, r.hop.Target = HopNATGateway, l.first(m, source, "aws_nat_gateway")
+		case attr == "vpc_endpoint_id" && l.first(m, source, "aws_networkfirewall_firewall") != "":
+			r.hop.Type, r.hop.Target = HopFirewall, l.first(m, source, "aws_networkfirewall_firewall")
+		case attr == "vpc_endpoint_id" && gateway(l.endpoint(m, source)):
+			r.hop.Type, r.hop.Target = HopGatewayEndpoint, l.first(m, source, "aws_vpc_endpoint")
+		default:
+			if address := l.first(m, source); address != "" {
+				r.hop.Target = address
+			}
+		}
+		break
+	}
+	if r.hop.Type == "" {
+		r.hop.Type = HopNone
+	}
+	return r, true
+}
+
+// endpoint returns the aws_vpc_endpoint referenced by source, or nil
+func (l *loader) endpoint(m *module, source string) *tfconfig.Block {
+	address := l.first(m, source, "aws_vpc_endpoint")
+	for _, other := range l.modules {
+		for _, block := range other.config.BlocksOfType("resource", "aws_vpc_endpoint") {
+			if block.Address() == address {
+				return block
+			}
+		}
+	}
+	return nil
+}
+
+// gateway reports whether an aws_vpc_endpoint is a gateway endpoint, the
+// default vpc_endpoint_type
+func gateway(endpoint *tfconfig.Block) bool {
+	return endpoint != nil && (endpoint.String("vpc_endpoint_type") == "" || endpoint.String("vpc_endpoint_type") == "Gateway")
+}
+
+// refs returns the addresses of the resources of the given types that source
+// references, directly or through input variables resolved to the outputs
+// of other modules
+func (l *loader) refs(m *module, source string, types ...string) []string {
+	return l.resolve(m, source, types, 0)
+}
+
+func (l *loader) resolve(m *module, source string, types []string, depth int) []string {
+	var addresses []string
+	for _, match := range resourceRef.FindAllStringSubmatch(source, -1) {
+		if len(types) == 0 || slices.Contains(types, match[1]) {
+			if ref := match[1] + "." + match[2]; !slices.Contains(addresses, ref) {
+				addresses = append(addresses, ref)
+			}
+		}
+	}
+	if depth > 4 {
+		return addresses
+	}
+	for _, match := range variableRef.FindAllStringSubmatch(source, -1) {
+		for _, other := range l.modules {
+			if other == m {
+				continue
+			}
+			if output, ok := other.outputs[match[1]]; ok {
+				for _, address := range l.resolve(other, output.Source("value"), types, depth+1) {
+					if !slices.Contains(addresses, address) {
+						addresses = append(addresses, address)
+					}
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+// first returns the first address refs returns, or ""
+func (l *loader) first(m *module, source string, types ...string) string {
+	if addresses := l.refs(m, source, types...); len(addresses) > 0 {
+		return addresses[0]
+	}
+	return ""
+}
+
+func (l *loader) position(block *tfconfig.Block, line int) string {
+	return tfconfig.Position(l.root, block.File, line)
+}
+
+// tier returns the tier of an aws_subnet address
+func tier(address string) string {
+	return strings.TrimPrefix(address, "aws_subnet.")
+}
+
+// service returns the service of an endpoint service name, e.g. "s3" for
+// "com.amazonaws.${var.region}.s3"
+func service(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, root string) *Network {
+	t.Helper()
+	n, err := Load(root)
+	require.NoError(t, err)
+	return n
+}
+
+// hops returns the hops of a path as "type target" strings
+func hops(p Path) []string {
+	var all []string
+	for _, hop := range p.Hops {
+		all = append(all, hop.String())
+	}
+	return all
+}
+
+func TestRepositoryRoutes(t *testing.T) {
+	t.Parallel()
+
+	n := load(t, "../..")
+	assert.Equal(t, []string{"public", "private", "isolated"}, n.Tiers)
+	assert.Equal(t, []string{"aws_networkfirewall_firewall.main"}, n.Firewalls)
+
+	for _, tc := range []struct {
+		tier, destination string
+		want              []string
+	}{
+		{"public", Internet, []string{"internet-gateway aws_internet_gateway.igw"}},
+		{"public", S3, []string{"gateway-endpoint aws_vpc_endpoint.s3"}},
+		{"private", VPC, []string{"local"}},
+		{"private", S3, []string{"gateway-endpoint aws_vpc_endpoint.s3"}},
+		{"private", DynamoDB, []string{"gateway-endpoint aws_vpc_endpoint.dynamodb"}},
+		{"isolated", VPC, []string{"local"}},
+	} {
+		assert.Equal(t, tc.want, hops(n.Path(tc.tier, tc.destination)), "%s -> %s", tc.tier, tc.destination)
+	}
+
+	for _, destination := range Destinations {
+		assert.False(t, n.Path("isolated", destination).Internet(), "isolated subnets have no internet path to %s", destination)
+	}
+	assert.Equal(t, "modules/vpc/routes.tf:21", n.Path("private", Internet).Hops[0].Position)
+}
+
+func TestLintFirewallBypass(t *testing.T) {
+	t.Parallel()
+
+	findings := Lint(load(t, "testdata/bypass"))
+	require.Len(t, findings, 1, "%v", findings)
+	assert.Equal(t, "firewall-bypass", findings[0].Check)
+	assert.Equal(t, "aws_route_table.private", findings[0].Address)
+	assert.Equal(t, "modules/vpc/main.tf:36", findings[0].Position)
+	assert.Contains(t, findings[0].Message, "internet traffic from private subnets bypasses aws_networkfirewall_firewall.main")
+}
+
+func TestFirewalledRoutes(t *testing.T) {
+	t.Parallel()
+
+	n := load(t, "testdata")
+	assert.Equal(t, []string{"public", "private", "isolated", "firewall", "isolated_legacy"}, n.Tiers)
+
+	private := n.Path("private", Internet)
+	assert.Equal(t, []string{
+		"nat-gateway aws_nat_gateway.nat",
+		"firewall-endpoint aws_networkfirewall_firewall.main",
+		"internet-gateway aws_internet_gateway.igw",
+	}, hops(private), "private internet traffic passes through the firewall endpoint")
+	assert.True(t, private.Through(HopFirewall))
+	assert.Equal(t, "aws_route_table.public", private.Hops[1].RouteTable)
+	assert.Equal(t, "modules/firewall/main.tf:14", private.Hops[1].Position, "the route is declared in another module")
+
+	assert.Equal(t, []string{"gateway-endpoint aws_vpc_endpoint.s3"}, hops(n.Path("private", S3)))
+	assert.Equal(t, []string{"gateway-endpoint aws_vpc_endpoint.dynamodb"}, hops(n.Path("private", DynamoDB)),
+		"an aws_route to the prefix list of a gateway endpoint")
+	assert.Equal(t, []string{"gateway-endpoint aws_vpc_endpoint.s3"}, hops(n.Path("isolated", S3)),
+		"an aws_vpc_endpoint_route_table_association")
+	assert.Equal(t, []string{"none"}, hops(n.Path("isolated", DynamoDB)), "without a prefix list route, DynamoDB follows 0.0.0.0/0")
+	assert.Equal(t, []string{"none"}, hops(n.Path("isolated", Internet)))
+
+	findings := Lint(n)
+	require.Len(t, findings, 1, "%v", findings)
+	assert.Equal(t, "internet-path", findings[0].Check)
+	assert.Equal(t, "modules/vpc/main.tf:55", findings[0].Position)
+	assert.Contains(t, findings[0].Message, "isolated_legacy subnets must not reach the internet")
+}
+
+func TestRoutingLoop(t *testing.T) {
+	t.Parallel()
+
+	n := &Network{
+		Tiers: []string{"private"},
+		tables: map[string]*routeTable{
+			"main": {address: "main"},
+			"aws_route_table.private": {address: "aws_route_table.private", routes: []route{
+				{destination: Internet, hop: Hop{Type: HopNATGateway, Target: "aws_nat_gateway.nat"}},
+			}},
+		},
+		associations: map[string]string{"private": "aws_route_table.private"},
+		main:         "main",
+		locations:    map[string][]string{"aws_nat_gateway.nat": {"private"}},
+	}
+	assert.Equal(t, []string{"nat-gateway aws_nat_gateway.nat", "none aws_nat_gateway.nat"}, hops(n.Path("private", Internet)),
+		"a NAT gateway in the subnet it serves routes traffic back to itself")
+}
@@ -0,0 +1,28 @@
+resource "aws_vpc_endpoint" "s3" {
+  vpc_id          = var.vpc_id
+  service_name    = "com.amazonaws.${var.region}.s3"
+  route_table_ids = [var.private_rt_id]
+}
+
+resource "aws_vpc_endpoint_route_table_association" "isolated_s3" {
+  vpc_endpoint_id = aws_vpc_endpoint.s3.id
+  route_table_id  = var.isolated_rt_id
+}
+
+resource "aws_vpc_endpoint" "dynamodb" {
+  vpc_id            = var.vpc_id
+  service_name      = "com.amazonaws.${var.region}.dynamodb"
+  vpc_endpoint_type = "Gateway"
+}
+
+resource "aws_route" "private_dynamodb" {
+  route_table_id             = var.private_rt_id
+  destination_prefix_list_id = aws_vpc_endpoint.dynamodb.prefix_list_id
+  vpc_endpoint_id            = aws_vpc_endpoint.dynamodb.id
+}
+
+resource "aws_vpc_endpoint" "sts" {
+  vpc_id            = var.vpc_id
+  service_name      = "com.amazonaws.${var.region}.sts"
+  vpc_endpoint_type = "Interface"
+}
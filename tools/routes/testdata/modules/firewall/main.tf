@@ -0,0 +1,18 @@
+resource "aws_networkfirewall_firewall" "main" {
+  name                = "inspection"
+  firewall_policy_arn = "arn:aws:network-firewall:eu-north-1:123456789012:firewall-policy/inspection"
+  vpc_id              = var.vpc_id
+
+  dynamic "subnet_mapping" {
+    for_each = var.firewall_subnet_ids
+    content {
+      subnet_id = subnet_mapping.value
+    }
+  }
+}
+
+resource "aws_route" "public_egress" {
+  route_table_id         = var.public_rt_id
+  destination_cidr_block = "0.0.0.0/0"
+  vpc_endpoint_id        = tolist(aws_networkfirewall_firewall.main.firewall_status[0].sync_states)[0].attachment[0].endpoint_id
+}
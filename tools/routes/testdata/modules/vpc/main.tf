@@ -0,0 +1,104 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_internet_gateway" "igw" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_subnet" "public" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.0.0/24"
+}
+
+resource "aws_subnet" "firewall" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/28"
+}
+
+resource "aws_subnet" "private" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.2.0/24"
+}
+
+resource "aws_subnet" "isolated" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.3.0/24"
+}
+
+resource "aws_subnet" "isolated_legacy" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.4.0/24"
+}
+
+resource "aws_nat_gateway" "nat" {
+  subnet_id = aws_subnet.public.id
+}
+
+resource "aws_route_table" "firewall" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block = "0.0.0.0/0"
+    gateway_id = aws_internet_gateway.igw.id
+  }
+}
+
+# 0.0.0.0/0 is routed to the firewall endpoint by the firewall module
+resource "aws_route_table" "public" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_route_table" "private" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block     = "0.0.0.0/0"
+    nat_gateway_id = aws_nat_gateway.nat.id
+  }
+}
+
+resource "aws_route_table" "isolated" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_route_table_association" "public" {
+  subnet_id      = aws_subnet.public.id
+  route_table_id = aws_route_table.public.id
+}
+
+resource "aws_route_table_association" "firewall" {
+  subnet_id      = aws_subnet.firewall.id
+  route_table_id = aws_route_table.firewall.id
+}
+
+resource "aws_route_table_association" "private" {
+  subnet_id      = aws_subnet.private.id
+  route_table_id = aws_route_table.private.id
+}
+
+resource "aws_route_table_association" "isolated" {
+  subnet_id      = aws_subnet.isolated.id
+  route_table_id = aws_route_table.isolated.id
+}
+
+resource "aws_route_table_association" "isolated_legacy" {
+  subnet_id      = aws_subnet.isolated_legacy.id
+  route_table_id = aws_route_table.private.id
+}
+
+output "public_rt_id" {
+  value = aws_route_table.public.id
+}
+
+output "private_rt_id" {
+  value = aws_route_table.private.id
+}
+
+output "isolated_rt_id" {
+  value = aws_route_table.isolated.id
+}
+
+output "firewall_subnet_ids" {
+  value = [aws_subnet.firewall.id]
+}
@@ -0,0 +1,270 @@
+// Package stack builds the deployment graph of an environment from its
+// root modules under envs/<env>.
+//
+// Every root module is a directory containing .tf files. A module depends on
+// another when it reads its state through a terraform_remote_state data
+// source (key "<env>/<module>/terraform.tfstate"), and every module with an
+// S3 backend depends on StateModule, which creates the state bucket, and on
+// LockModule, which creates the state lock table, unless LockModule itself
+// depends on it.
+package stack
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// StateModule is the root module that creates the S3 state bucket. It uses a
+// local backend and every S3-backed module depends on it.
+const StateModule = "bootstrap"
+
+// StateBucketVariable is the variable ztctl passes the state bucket in to
+// every root module, with -var, so every root module declares it whether it
+// reads it or not
+const StateBucketVariable = "state_bucket"
+
+// LockModule is the root module that creates the DynamoDB table locking the
+// S3 state. Modules deployed before it run without state locking, so it is
+// deployed right after StateModule and the modules it reads the state of.
+const LockModule = "data_store"
+
+// Module is a root module of an environment
+type Module struct {
+	Name string
+	Dir  string
+	// Backend is the type of the terraform backend block, e.g. "s3" or "local"
+	Backend string
+	// RemoteStates are the modules whose state this module reads
+	RemoteStates []string
+	// DependsOn is RemoteStates plus StateModule and LockModule for S3-backed
+	// modules
+	DependsOn []string
+	// Sources are the directories of the local modules it calls
+	Sources []string
+}
+
+// Stack is the set of root modules of one environment
+type Stack struct {
+	Env     string
+	Dir     string
+	Modules map[string]*Module
+}
+
+// Load reads every root module directly under envDir
+func Load(envDir string) (*Stack, error) {
+	entries, err := os.ReadDir(envDir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stack{Env: filepath.Base(envDir), Dir: envDir, Modules: map[string]*Module{}}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(envDir, entry.Name())
+		config, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(config.Blocks) == 0 {
+			continue
+		}
+		s.Modules[entry.Name()] = loadModule(entry.Name(), dir, config)
+	}
+
+	for _, m := range s.Modules {
+		if m.Backend == "s3" && m.Name != StateModule {
+			if _, ok := s.Modules[StateModule]; ok {
+				m.DependsOn = append(m.DependsOn, StateModule)
+			}
+		}
+		for _, dep := range m.RemoteStates {
+			if _, ok := s.Modules[dep]; !ok {
+				return nil, fmt.Errorf("module %s reads the state of unknown module %s", m.Name, dep)
+			}
+			if !slices.Contains(m.DependsOn, dep) {
+				m.DependsOn = append(m.DependsOn, dep)
+			}
+		}
+	}
+
+	if _, ok := s.Modules[LockModule]; ok {
+		upstream := s.upstream(LockModule)
+		for _, m := range s.Modules {
+			if m.Backend == "s3" && m.Name != StateModule && m.Name != LockModule && !upstream[m.Name] {
+				m.DependsOn = append(m.DependsOn, LockModule)
+			}
+		}
+	}
+	for _, m := range s.Modules {
+		sort.Strings(m.DependsOn)
+	}
+	return s, nil
+}
+
+// upstream returns the modules name depends on, directly or transitively
+func (s *Stack) upstream(name string) map[string]bool {
+	seen := map[string]bool{}
+	queue := append([]string(nil), s.Modules[name].DependsOn...)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		queue = append(queue, s.Modules[dep].DependsOn...)
+	}
+	return seen
+}
+
+func loadModule(name, dir string, config *tfconfig.Module) *Module {
+	m := &Module{Name: name, Dir: dir}
+
+	for _, terraform := range config.BlocksOfType("terraform") {
+		for _, backend := range terraform.Nested("backend") {
+			m.Backend = backend.Name()
+		}
+	}
+
+	for _, remote := range config.BlocksOfType("data", "terraform_remote_state") {
+		settings, _ := remote.Attr("config")
+		values, ok := settings.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if key, ok := values["key"].(string); ok && !tfconfig.IsExpression(key) {
+			if module := ModuleForKey(key); !slices.Contains(m.RemoteStates, module) {
+				m.RemoteStates = append(m.RemoteStates, module)
+			}
+		}
+	}
+	sort.Strings(m.RemoteStates)
+
+	for _, call := range config.BlocksOfType("module") {
+		source := call.String("source")
+		if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+			if called := filepath.Join(dir, source); !slices.Contains(m.Sources, called) {
+				m.Sources = append(m.Sources, called)
+			}
+		}
+	}
+	sort.Strings(m.Sources)
+	return m
+}
+
+// StateKey returns the S3 key a module's state is stored under
+func StateKey(env, module string) string {
+	return path.Join(env, module, "terraform.tfstate")
+}
+
+// ModuleForKey returns the module a state key belongs to, e.g. "vpc" for
+// "dev/vpc/terraform.tfstate"
+func ModuleForKey(key string) string {
+	return path.Base(path.Dir(key))
+}
+
+// Names returns all module names sorted alphabetically
+func (s *Stack) Names() []string {
+	names := make([]string, 0, len(s.Modules))
+	for name := range s.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Order returns the modules in deployment order: every module comes after
+// the modules it depends on, and modules at the same depth are sorted by
+// name so the order is stable
+func (s *Stack) Order() ([]string, error) {
+	remaining := map[string]int{}
+	for name, m := range s.Modules {
+		remaining[name] = len(m.DependsOn)
+	}
+
+	var order []string
+	for len(remaining) > 0 {
+		var ready []string
+		for name, count := range remaining {
+			if count == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			var cycle []string
+			for name := range remaining {
+				cycle = append(cycle, name)
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("dependency cycle between modules: %s", strings.Join(cycle, ", "))
+		}
+
+		sort.Strings(ready)
+		for _, name := range ready {
+			delete(remaining, name)
+			for _, dependent := range s.Dependents(name) {
+				remaining[dependent]--
+			}
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// DestroyOrder returns Order reversed
+func (s *Stack) DestroyOrder() ([]string, error) {
+	order, err := s.Order()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order, nil
+}
+
+// Dependents returns the modules that directly depend on name, sorted
+func (s *Stack) Dependents(name string) []string {
+	var dependents []string
+	for _, m := range s.Modules {
+		for _, dep := range m.DependsOn {
+			if dep == name {
+				dependents = append(dependents, m.Name)
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// Downstream returns names plus every module that depends on them, directly
+// or transitively, sorted
+func (s *Stack) Downstream(names ...string) []string {
+	seen := map[string]bool{}
+	queue := append([]string(nil), names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		queue = append(queue, s.Dependents(name)...)
+	}
+
+	downstream := make([]string, 0, len(seen))
+	for name := range seen {
+		downstream = append(downstream, name)
+	}
+	sort.Strings(downstream)
+	return downstream
+}
@@ -0,0 +1,125 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestLoadDevEnvironment(t *testing.T) {
+	t.Parallel()
+
+	s, err := Load("../../envs/dev")
+	require.NoError(t, err)
+
+	assert.Equal(t, "dev", s.Env)
+	assert.Len(t, s.Modules, 11)
+	assert.Equal(t, "local", s.Modules["bootstrap"].Backend)
+	assert.Equal(t, "s3", s.Modules["vpc"].Backend)
+	assert.Equal(t, []string{"security", "vpc"}, s.Modules["compute"].RemoteStates)
+	assert.Equal(t, []string{"bootstrap", "data_store", "security", "vpc"}, s.Modules["compute"].DependsOn)
+	assert.Equal(t, []string{"bootstrap", "data_store"}, s.Modules["certificates"].DependsOn)
+	assert.Equal(t, []string{"bootstrap", "security"}, s.Modules["data_store"].DependsOn)
+	assert.Equal(t, []string{"bootstrap"}, s.Modules["security"].DependsOn, "data_store reads the state of security, so security runs before the lock table exists")
+	assert.Empty(t, s.Modules["bootstrap"].DependsOn)
+	assert.Equal(t, []string{filepath.Join("..", "..", "modules", "compute")}, s.Modules["compute"].Sources)
+}
+
+func TestDownstream(t *testing.T) {
+	t.Parallel()
+
+	s, err := Load("../../envs/dev")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"certificates"}, s.Downstream("certificates"))
+	assert.Equal(t, []string{"compute", "firewall", "monitoring", "vpc", "vpc-endpoints"}, s.Downstream("vpc"))
+	assert.Equal(t, s.Names(), s.Downstream("bootstrap"), "every S3-backed module depends on the state bucket")
+}
+
+func TestOrderRespectsDependencies(t *testing.T) {
+	t.Parallel()
+
+	s, err := Load("../../envs/dev")
+	require.NoError(t, err)
+
+	order, err := s.Order()
+	require.NoError(t, err)
+	require.Len(t, order, len(s.Modules))
+	assert.Equal(t, "bootstrap", order[0])
+
+	for _, m := range s.Modules {
+		for _, dep := range m.DependsOn {
+			assert.Less(t, indexOf(order, dep), indexOf(order, m.Name), "%s must be deployed before %s", dep, m.Name)
+		}
+	}
+
+	destroy, err := s.DestroyOrder()
+	require.NoError(t, err)
+	assert.Equal(t, "bootstrap", destroy[len(destroy)-1])
+}
+
+func writeModule(t *testing.T, envDir, name, content string) {
+	t.Helper()
+	dir := filepath.Join(envDir, name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0o644))
+}
+
+func remoteState(module string) string {
+	return `
+data "terraform_remote_state" "` + module + `" {
+    backend = "s3"
+    config = {
+        bucket = var.state_bucket
+        key    = "dev/` + module + `/terraform.tfstate"
+        region = "eu-north-1"
+    }
+}
+`
+}
+
+func TestOrderDetectsCycles(t *testing.T) {
+	t.Parallel()
+
+	envDir := filepath.Join(t.TempDir(), "dev")
+	writeModule(t, envDir, "a", remoteState("b"))
+	writeModule(t, envDir, "b", remoteState("a"))
+	writeModule(t, envDir, "c", `terraform {}`)
+
+	s, err := Load(envDir)
+	require.NoError(t, err)
+
+	_, err = s.Order()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle between modules: a, b")
+}
+
+func TestLoadRejectsUnknownRemoteState(t *testing.T) {
+	t.Parallel()
+
+	envDir := filepath.Join(t.TempDir(), "dev")
+	writeModule(t, envDir, "compute", remoteState("network"))
+
+	_, err := Load(envDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown module network")
+}
+
+func TestModuleForKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "vpc-endpoints", ModuleForKey("dev/vpc-endpoints/terraform.tfstate"))
+	assert.Equal(t, "dev/vpc/terraform.tfstate", StateKey("dev", "vpc"))
+}
@@ -0,0 +1,190 @@
+// Package teardown verifies that `terraform destroy` really left nothing
+// behind and retries destroys that fail only because AWS deletes dependent
+// resources asynchronously.
+//
+// A destroy can exit successfully while resources are still tracked (for
+// example after a partial failure that was retried), and a VPC or subnet
+// destroy regularly fails with DependencyViolation while interface endpoint
+// ENIs are still being released. Both cases used to be logged and forgotten.
+package teardown
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/y3gi/zero-trust-aws/tools/tfoutput"
+)
+
+// Resource is a resource still present in a module's state
+type Resource struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+}
+
+// state is the subset of `terraform show -json` needed to list resources
+type state struct {
+	Values *struct {
+		RootModule stateModule `json:"root_module"`
+	} `json:"values"`
+}
+
+type stateModule struct {
+	Resources    []Resource    `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+// Remaining parses `terraform show -json` output and returns the managed
+// resources still tracked in state, sorted by address. Data sources are
+// ignored because they do not correspond to anything that has to be deleted.
+// An empty state yields an empty slice.
+func Remaining(raw []byte) ([]Resource, error) {
+	cleaned := bytes.TrimSpace(tfoutput.Clean(raw))
+	if len(cleaned) == 0 {
+		return nil, &tfoutput.ParseError{Raw: string(raw), Err: fmt.Errorf("no JSON document found")}
+	}
+
+	var s state
+	if err := json.Unmarshal(cleaned, &s); err != nil {
+		return nil, &tfoutput.ParseError{Raw: string(raw), Err: err}
+	}
+	if s.Values == nil {
+		return []Resource{}, nil
+	}
+
+	resources := []Resource{}
+	var walk func(m stateModule)
+	walk = func(m stateModule) {
+		for _, r := range m.Resources {
+			if r.Mode == "managed" {
+				resources = append(resources, r)
+			}
+		}
+		for _, child := range m.ChildModules {
+			walk(child)
+		}
+	}
+	walk(s.Values.RootModule)
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Address < resources[j].Address })
+	return resources, nil
+}
+
+// NotEmptyError is returned when a destroyed module still tracks resources
+type NotEmptyError struct {
+	Module    string
+	Resources []Resource
+}
+
+func (e *NotEmptyError) Error() string {
+	addresses := make([]string, 0, len(e.Resources))
+	for _, r := range e.Resources {
+		addresses = append(addresses, r.Address)
+	}
+	return fmt.Sprintf("module %s still tracks %d resource(s) after destroy:\n  %s",
+		e.Module, len(e.Resources), strings.Join(addresses, "\n  "))
+}
+
+// Verify returns a NotEmptyError listing every managed resource left in the
+// state of module, or nil when the state is empty
+func Verify(module string, raw []byte) error {
+	resources, err := Remaining(raw)
+	if err != nil {
+		return fmt.Errorf("failed to read state of module %s: %w", module, err)
+	}
+	if len(resources) > 0 {
+		return &NotEmptyError{Module: module, Resources: resources}
+	}
+	return nil
+}
+
+// orderingFailures match destroy errors caused by AWS still releasing a
+// dependency (ENIs of interface endpoints, firewall endpoints, NAT gateways)
+// rather than by anything a retry cannot fix
+var orderingFailures = []*regexp.Regexp{
+	regexp.MustCompile(`DependencyViolation`),
+	regexp.MustCompile(`has dependencies and cannot be deleted`),
+	regexp.MustCompile(`InvalidNetworkInterface\.InUse`),
+	regexp.MustCompile(`[Nn]etwork interface .* is currently in use`),
+	regexp.MustCompile(`has some mapped public address\(es\)`),
+	regexp.MustCompile(`resource sg-[0-9a-f]+ has a dependent object`),
+}
+
+// IsOrderingFailure reports whether a destroy error is a known ordering
+// failure that is expected to clear once AWS finishes deleting dependents
+func IsOrderingFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, pattern := range orderingFailures {
+		if pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff bounds the retries of a destroy that hit an ordering failure
+type Backoff struct {
+	// Attempts is the total number of destroy attempts, including the first
+	Attempts int
+	// Initial is the wait before the second attempt; it doubles every retry
+	Initial time.Duration
+	// Max caps a single wait
+	Max time.Duration
+}
+
+// DefaultBackoff allows roughly ten minutes for ENIs to be released, which
+// covers interface endpoints and Network Firewall endpoints in practice
+var DefaultBackoff = Backoff{Attempts: 5, Initial: 30 * time.Second, Max: 4 * time.Minute}
+
+// Delay returns the wait after the given failed attempt (1-based)
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if b.Max > 0 && delay >= b.Max {
+			return b.Max
+		}
+	}
+	if b.Max > 0 && delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+// Retry runs destroy until it succeeds, fails with an error that is not an
+// ordering failure, or the attempts are used up. sleep is time.Sleep outside
+// of tests; onRetry, when set, is told about every retry before sleeping.
+func Retry(b Backoff, sleep func(time.Duration), onRetry func(attempt int, delay time.Duration, err error), destroy func() error) error {
+	attempts := b.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = destroy(); err == nil {
+			return nil
+		}
+		if !IsOrderingFailure(err) || attempt == attempts {
+			break
+		}
+
+		delay := b.Delay(attempt)
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+		sleep(delay)
+	}
+
+	if IsOrderingFailure(err) {
+		return fmt.Errorf("destroy still blocked by dependent resources after %d attempts: %w", attempts, err)
+	}
+	return err
+}
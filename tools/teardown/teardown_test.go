@@ -0,0 +1,141 @@
+package teardown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return raw
+}
+
+func fixtureError(t *testing.T, name string) error {
+	return errors.New("error while running command: exit status 1; " + string(readFixture(t, name)))
+}
+
+func TestRemainingEmptyState(t *testing.T) {
+	t.Parallel()
+
+	for _, fixture := range []string{"empty_state.json", "destroyed_state.txt"} {
+		resources, err := Remaining(readFixture(t, fixture))
+		require.NoError(t, err, fixture)
+		assert.Empty(t, resources, fixture)
+		assert.NoError(t, Verify("vpc", readFixture(t, fixture)), fixture)
+	}
+}
+
+func TestRemainingListsManagedResources(t *testing.T) {
+	t.Parallel()
+
+	resources, err := Remaining(readFixture(t, "leftover_state.json"))
+	require.NoError(t, err)
+
+	require.Len(t, resources, 2, "data sources should not be reported")
+	assert.Equal(t, "module.vpc.aws_subnet.private[0]", resources[0].Address)
+	assert.Equal(t, "module.vpc.aws_vpc.main", resources[1].Address)
+}
+
+func TestVerifyReportsLeftoverResources(t *testing.T) {
+	t.Parallel()
+
+	err := Verify("vpc", readFixture(t, "leftover_state.json"))
+
+	var notEmpty *NotEmptyError
+	require.ErrorAs(t, err, &notEmpty)
+	assert.Equal(t, "vpc", notEmpty.Module)
+	assert.Contains(t, err.Error(), "module.vpc.aws_vpc.main")
+	assert.Contains(t, err.Error(), "module.vpc.aws_subnet.private[0]")
+}
+
+func TestVerifyRejectsUnreadableState(t *testing.T) {
+	t.Parallel()
+
+	err := Verify("vpc", []byte("::debug::Terraform exited with code 1.\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read state of module vpc")
+}
+
+func TestIsOrderingFailure(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsOrderingFailure(fixtureError(t, "subnet_dependency_violation.txt")))
+	assert.True(t, IsOrderingFailure(fixtureError(t, "eni_in_use.txt")))
+	assert.False(t, IsOrderingFailure(fixtureError(t, "kms_access_denied.txt")))
+	assert.False(t, IsOrderingFailure(nil))
+}
+
+func TestBackoffDelay(t *testing.T) {
+	t.Parallel()
+
+	b := Backoff{Attempts: 6, Initial: 30 * time.Second, Max: 2 * time.Minute}
+	assert.Equal(t, 30*time.Second, b.Delay(1))
+	assert.Equal(t, time.Minute, b.Delay(2))
+	assert.Equal(t, 2*time.Minute, b.Delay(3))
+	assert.Equal(t, 2*time.Minute, b.Delay(5), "delay should be capped at Max")
+}
+
+func TestRetrySucceedsAfterOrderingFailures(t *testing.T) {
+	t.Parallel()
+
+	var slept []time.Duration
+	calls := 0
+	err := Retry(Backoff{Attempts: 4, Initial: time.Second, Max: time.Minute},
+		func(d time.Duration) { slept = append(slept, d) },
+		nil,
+		func() error {
+			calls++
+			if calls < 3 {
+				return fixtureError(t, "subnet_dependency_violation.txt")
+			}
+			return nil
+		})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, slept)
+}
+
+func TestRetryGivesUpAfterAttempts(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	var retries []int
+	err := Retry(Backoff{Attempts: 3, Initial: time.Second},
+		func(time.Duration) {},
+		func(attempt int, _ time.Duration, _ error) { retries = append(retries, attempt) },
+		func() error {
+			calls++
+			return fixtureError(t, "eni_in_use.txt")
+		})
+
+	require.Error(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []int{1, 2}, retries)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+}
+
+func TestRetryDoesNotRetryOtherErrors(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	err := Retry(DefaultBackoff,
+		func(time.Duration) { t.Fatal("should not sleep") },
+		nil,
+		func() error {
+			calls++
+			return fixtureError(t, "kms_access_denied.txt")
+		})
+
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, err.Error(), "AccessDeniedException")
+}
@@ -0,0 +1,314 @@
+// Package tfconfig loads the .tf files of a directory into plain Go values so
+// that analyzers can inspect blocks and attributes without evaluating them.
+//
+// Expressions are converted as far as they can be without variables: literal
+// strings, numbers, bools, lists and objects become their Go counterparts and
+// jsonencode(...) is unwrapped to its argument. Anything that needs
+// evaluation (references, interpolations, other function calls) is kept as
+// its source text wrapped in "${...}", e.g. "${var.env}" or
+// "arn:aws:s3:::${var.bucket}/*".
+package tfconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Module is the parsed content of one directory of .tf files
+type Module struct {
+	Dir    string
+	Blocks []*Block
+}
+
+// Block is a top-level or nested HCL block
+type Block struct {
+	Type   string
+	Labels []string
+	File   string
+	Line   int
+	Body   *hclsyntax.Body
+	src    []byte
+	start  int
+}
+
+// LoadDir parses every .tf file directly inside dir, in file name order
+func LoadDir(dir string) (*Module, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	module := &Module{Dir: dir}
+	for _, path := range paths {
+		blocks, err := ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		module.Blocks = append(module.Blocks, blocks...)
+	}
+	return module, nil
+}
+
+// ParseFile parses a single .tf file and returns its top-level blocks
+func ParseFile(path string) ([]*Block, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parses HCL source; path is only used in errors and Block.File
+func Parse(path string, src []byte) ([]*Block, error) {
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, diags.Error())
+	}
+	return wrapBlocks(file.Body.(*hclsyntax.Body).Blocks, path, src), nil
+}
+
+func wrapBlocks(blocks hclsyntax.Blocks, path string, src []byte) []*Block {
+	wrapped := make([]*Block, 0, len(blocks))
+	for _, b := range blocks {
+		wrapped = append(wrapped, &Block{
+			Type:   b.Type,
+			Labels: b.Labels,
+			File:   path,
+			Line:   b.TypeRange.Start.Line,
+			Body:   b.Body,
+			src:    src,
+			start:  b.TypeRange.Start.Byte,
+		})
+	}
+	return wrapped
+}
+
+// BlocksOfType returns the top-level blocks of the given type, e.g. "variable"
+// or "resource", optionally narrowed to those whose first label matches
+func (m *Module) BlocksOfType(blockType string, firstLabel ...string) []*Block {
+	var matched []*Block
+	for _, b := range m.Blocks {
+		if b.Type != blockType {
+			continue
+		}
+		if len(firstLabel) > 0 && (len(b.Labels) == 0 || b.Labels[0] != firstLabel[0]) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+	return matched
+}
+
+// Name returns the last label of the block, e.g. "main" for
+// resource "aws_vpc" "main", or "" for unlabeled blocks
+func (b *Block) Name() string {
+	if len(b.Labels) == 0 {
+		return ""
+	}
+	return b.Labels[len(b.Labels)-1]
+}
+
+// Address returns the block's Terraform-style address, e.g. "aws_vpc.main",
+// "data.aws_iam_policy_document.app" or "variable.env"
+func (b *Block) Address() string {
+	switch b.Type {
+	case "resource":
+		return strings.Join(b.Labels, ".")
+	case "data":
+		return "data." + strings.Join(b.Labels, ".")
+	default:
+		return strings.Join(append([]string{b.Type}, b.Labels...), ".")
+	}
+}
+
+// Position returns "file:line" for use in findings
+func (b *Block) Position() string {
+	return fmt.Sprintf("%s:%d", b.File, b.Line)
+}
+
+// Relative returns path relative to root with forward slashes, the form the
+// files of findings take
+func Relative(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// Position returns "file:line" with the file relative to root
+func Position(root, file string, line int) string {
+	return fmt.Sprintf("%s:%d", Relative(root, file), line)
+}
+
+// Text returns the source of the whole block, from its type to the closing
+// brace
+func (b *Block) Text() string {
+	return string(b.src[b.start:b.Body.SrcRange.End.Byte])
+}
+
+// Nested returns the nested blocks of the given type
+func (b *Block) Nested(blockType string) []*Block {
+	var matched hclsyntax.Blocks
+	for _, nested := range b.Body.Blocks {
+		if nested.Type == blockType {
+			matched = append(matched, nested)
+		}
+	}
+	return wrapBlocks(matched, b.File, b.src)
+}
+
+// Has reports whether the block sets the attribute
+func (b *Block) Has(name string) bool {
+	_, ok := b.Body.Attributes[name]
+	return ok
+}
+
+// Attr returns the converted value of an attribute (see Value)
+func (b *Block) Attr(name string) (interface{}, bool) {
+	attr, ok := b.Body.Attributes[name]
+	if !ok {
+		return nil, false
+	}
+	return Value(attr.Expr, b.src), true
+}
+
+// String returns an attribute as a string; non-string values are returned
+// as their source text
+func (b *Block) String(name string) string {
+	value, ok := b.Attr(name)
+	if !ok {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return b.Source(name)
+}
+
+// Source returns the raw source text of an attribute's expression
+func (b *Block) Source(name string) string {
+	attr, ok := b.Body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	return source(attr.Expr, b.src)
+}
+
+// AttrLine returns the line an attribute starts on, or the block's line
+func (b *Block) AttrLine(name string) int {
+	if attr, ok := b.Body.Attributes[name]; ok {
+		return attr.SrcRange.Start.Line
+	}
+	return b.Line
+}
+
+// Value converts an expression into plain Go values: string, float64, bool,
+// nil, []interface{} and map[string]interface{}. Expressions that need
+// evaluation are returned as "${<source>}".
+func Value(expr hclsyntax.Expression, src []byte) interface{} {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return literal(e.Val)
+	case *hclsyntax.TemplateExpr:
+		var b strings.Builder
+		for _, part := range e.Parts {
+			if lit, ok := part.(*hclsyntax.LiteralValueExpr); ok && lit.Val.Type() == cty.String {
+				b.WriteString(lit.Val.AsString())
+				continue
+			}
+			b.WriteString("${" + source(part, src) + "}")
+		}
+		return b.String()
+	case *hclsyntax.TemplateWrapExpr:
+		return "${" + source(e.Wrapped, src) + "}"
+	case *hclsyntax.TupleConsExpr:
+		values := make([]interface{}, 0, len(e.Exprs))
+		for _, item := range e.Exprs {
+			values = append(values, Value(item, src))
+		}
+		return values
+	case *hclsyntax.ObjectConsExpr:
+		values := make(map[string]interface{}, len(e.Items))
+		for _, item := range e.Items {
+			values[objectKey(item.KeyExpr, src)] = Value(item.ValueExpr, src)
+		}
+		return values
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "jsonencode" && len(e.Args) == 1 {
+			return Value(e.Args[0], src)
+		}
+	case *hclsyntax.ParenthesesExpr:
+		return Value(e.Expression, src)
+	}
+	return "${" + source(expr, src) + "}"
+}
+
+func objectKey(expr hclsyntax.Expression, src []byte) string {
+	if key, ok := expr.(*hclsyntax.ObjectConsKeyExpr); ok {
+		if name := hcl.ExprAsKeyword(key.Wrapped); name != "" && !key.ForceNonLiteral {
+			return name
+		}
+		expr = key.Wrapped
+	}
+	if s, ok := Value(expr, src).(string); ok {
+		return s
+	}
+	return source(expr, src)
+}
+
+func literal(v cty.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	switch v.Type() {
+	case cty.String:
+		return v.AsString()
+	case cty.Bool:
+		return v.True()
+	case cty.Number:
+		f, _ := v.AsBigFloat().Float64()
+		return f
+	}
+	return nil
+}
+
+func source(expr hclsyntax.Expression, src []byte) string {
+	r := expr.Range()
+	if r.End.Byte > len(src) || r.Start.Byte > r.End.Byte {
+		return ""
+	}
+	return string(src[r.Start.Byte:r.End.Byte])
+}
+
+// IsExpression reports whether a converted value is an unevaluated
+// expression such as "${var.env}" rather than a literal
+func IsExpression(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") && strings.Count(s, "${") == 1
+}
+
+// Strings flattens a converted value that may be a single string or a list
+// into a slice of strings; other values are skipped
+func Strings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package tfconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `
+variable "env" {
+  type        = string
+  description = "Environment name"
+}
+
+resource "aws_iam_policy" "app" {
+  name = "${var.env}-app"
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect   = "Allow"
+      Action   = ["s3:GetObject", "s3:PutObject"]
+      Resource = "arn:aws:s3:::${var.bucket}/*"
+      Condition = {
+        Bool = { "aws:SecureTransport" = "true" }
+      }
+    }]
+  })
+  count = 2
+  tags  = merge(var.tags, { Name = "app" })
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`
+
+func TestParseConvertsValues(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := Parse("main.tf", []byte(sample))
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+
+	module := &Module{Blocks: blocks}
+	policy := module.BlocksOfType("resource", "aws_iam_policy")[0]
+	assert.Equal(t, "aws_iam_policy.app", policy.Address())
+	assert.Equal(t, "main.tf:7", policy.Position())
+	assert.Equal(t, "${var.env}-app", policy.String("name"))
+
+	count, _ := policy.Attr("count")
+	assert.Equal(t, 2.0, count)
+
+	document, ok := policy.Attr("policy")
+	require.True(t, ok)
+	statement := document.(map[string]interface{})["Statement"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []string{"s3:GetObject", "s3:PutObject"}, Strings(statement["Action"]))
+	assert.Equal(t, "arn:aws:s3:::${var.bucket}/*", statement["Resource"])
+	assert.Equal(t, "true", statement["Condition"].(map[string]interface{})["Bool"].(map[string]interface{})["aws:SecureTransport"])
+
+	tags, _ := policy.Attr("tags")
+	assert.Equal(t, `${merge(var.tags, { Name = "app" })}`, tags)
+	assert.True(t, IsExpression(tags))
+	assert.False(t, IsExpression(policy.String("name")))
+
+	lifecycle := policy.Nested("lifecycle")
+	require.Len(t, lifecycle, 1)
+	preventDestroy, _ := lifecycle[0].Attr("prevent_destroy")
+	assert.Equal(t, true, preventDestroy)
+
+	assert.Equal(t, "lifecycle {\n    prevent_destroy = true\n  }", lifecycle[0].Text())
+
+	variable := module.BlocksOfType("variable")[0]
+	assert.Equal(t, "variable.env", variable.Address())
+	assert.Equal(t, "variable \"env\" {\n  type        = string\n  description = \"Environment name\"\n}", variable.Text())
+	assert.Equal(t, "string", variable.Source("type"))
+}
+
+func TestParseReportsSyntaxErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse("broken.tf", []byte(`resource "aws_vpc" "main" {`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken.tf")
+}
+
+func TestPosition(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join("repo", "modules")
+	assert.Equal(t, "vpc/main.tf:12", Position(root, filepath.Join(root, "vpc", "main.tf"), 12))
+	assert.Equal(t, "../envs/dev/vpc", Relative(root, filepath.Join("repo", "envs", "dev", "vpc")))
+}
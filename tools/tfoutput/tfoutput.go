@@ -0,0 +1,175 @@
+// Package tfoutput reads `terraform output -json` in CI environments where the
+// runner injects its own lines into terraform's stdout.
+//
+// The hashicorp/setup-terraform wrapper echoes the command it ran and appends
+// ::debug:: workflow commands after terraform exits, which breaks a plain
+// json.Unmarshal. Instead of swallowing that error and returning empty values,
+// this package strips the injected lines, parses the remainder strictly and
+// returns an error carrying the raw text whenever that is not possible.
+package tfoutput
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// annotationPattern matches GitHub Actions workflow commands such as
+// "::debug::...", "::add-mask::..." or "::warning file=main.tf::..."
+var annotationPattern = regexp.MustCompile(`^::[a-zA-Z-]+(\s[^:]*)?::`)
+
+// commandEchoPattern matches the "[command]/path/terraform-bin output -json"
+// line the setup-terraform wrapper prints before running terraform
+var commandEchoPattern = regexp.MustCompile(`^\[command\]`)
+
+// Output is a single entry of `terraform output -json`
+type Output struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Outputs maps output names to their values
+type Outputs map[string]Output
+
+// ParseError is returned when terraform's stdout cannot be turned into outputs.
+// Raw holds the text exactly as terraform (or the runner) produced it.
+type ParseError struct {
+	Raw string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse terraform output: %v\n--- raw output ---\n%s\n--- end raw output ---", e.Err, e.Raw)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Clean removes runner-injected annotation lines from terraform's stdout
+func Clean(raw []byte) []byte {
+	var kept [][]byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if annotationPattern.Match(trimmed) || commandEchoPattern.Match(trimmed) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return bytes.Join(kept, []byte("\n"))
+}
+
+// Parse cleans raw stdout and decodes it as the JSON object produced by
+// `terraform output -json`. Anything other than exactly one JSON object after
+// cleaning is treated as an error.
+func Parse(raw []byte) (Outputs, error) {
+	cleaned := bytes.TrimSpace(Clean(raw))
+	if len(cleaned) == 0 {
+		return nil, &ParseError{Raw: string(raw), Err: fmt.Errorf("no JSON document found")}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(cleaned))
+	decoder.DisallowUnknownFields()
+
+	var outputs Outputs
+	if err := decoder.Decode(&outputs); err != nil {
+		return nil, &ParseError{Raw: string(raw), Err: err}
+	}
+	if outputs == nil {
+		return nil, &ParseError{Raw: string(raw), Err: fmt.Errorf("expected a JSON object, got null")}
+	}
+
+	// Reject trailing content so a second document or leftover noise is never ignored
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, &ParseError{Raw: string(raw), Err: fmt.Errorf("unexpected content after outputs object")}
+	}
+
+	return outputs, nil
+}
+
+// Read runs `terraform output -json` in dir with the variables of env added
+// to the environment, like terratest does with terraform.Options.EnvVars
+// (TF_DATA_DIR, AWS_PROFILE, ...), and parses the result. Only stdout is
+// parsed; stderr is kept separately and attached to the error on failure.
+func Read(binary, dir string, env map[string]string) (Outputs, error) {
+	if binary == "" {
+		binary = "terraform"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, "output", "-json", "-no-color")
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for _, name := range sortedKeys(env) {
+		cmd.Env = append(cmd.Env, name+"="+env[name])
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("terraform output in %s failed: %w\nstderr:\n%s", dir, err, stderr.String())
+	}
+
+	return Parse(stdout.Bytes())
+}
+
+// Names returns the output names in sorted order
+func (o Outputs) Names() []string {
+	return sortedKeys(o)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decode unmarshals the named output into v, rejecting values of the wrong
+// type and null, which encoding/json would silently decode as the zero value
+func (o Outputs) Decode(name string, v interface{}) error {
+	output, ok := o[name]
+	if !ok {
+		return fmt.Errorf("output %q not found (available: %s)", name, strings.Join(o.Names(), ", "))
+	}
+	if value := bytes.TrimSpace(output.Value); len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return fmt.Errorf("output %q is null", name)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(output.Value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("output %q has unexpected value %s: %w", name, output.Value, err)
+	}
+	return nil
+}
+
+// String returns the named output as a string
+func (o Outputs) String(name string) (string, error) {
+	var value string
+	err := o.Decode(name, &value)
+	return value, err
+}
+
+// List returns the named output as a list of strings
+func (o Outputs) List(name string) ([]string, error) {
+	var value []string
+	err := o.Decode(name, &value)
+	return value, err
+}
+
+// Map returns the named output as a map of strings
+func (o Outputs) Map(name string) (map[string]string, error) {
+	var value map[string]string
+	err := o.Decode(name, &value)
+	return value, err
+}
@@ -0,0 +1,142 @@
+package tfoutput
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return content
+}
+
+func TestParseCleanOutput(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := Parse(readFixture(t, "clean.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"cloudtrail_bucket_name", "kms_key_id", "terraform_state_bucket_name"}, outputs.Names())
+
+	bucket, err := outputs.String("terraform_state_bucket_name")
+	require.NoError(t, err)
+	assert.Equal(t, "test-terraform-state-3f9a1c2e", bucket)
+}
+
+func TestParseSetupTerraformWrapperOutput(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := Parse(readFixture(t, "setup_terraform_wrapper.txt"))
+	require.NoError(t, err)
+
+	vpcID, err := outputs.String("vpc_id")
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-0fedcba9876543210", vpcID)
+
+	subnets, err := outputs.List("public_subnet_ids")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-0123456789abcdef0"}, subnets)
+}
+
+func TestParseInterleavedAnnotations(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := Parse(readFixture(t, "interleaved_annotations.txt"))
+	require.NoError(t, err)
+
+	role, err := outputs.String("app_instance_role_name")
+	require.NoError(t, err)
+	assert.Equal(t, "test-ZT-App-Role", role)
+}
+
+func TestParseFailuresKeepRawOutput(t *testing.T) {
+	t.Parallel()
+
+	fixtures := []string{
+		"truncated.txt",
+		"only_annotations.txt",
+		"plain_text_warning.txt",
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture, func(t *testing.T) {
+			t.Parallel()
+
+			raw := readFixture(t, fixture)
+			outputs, err := Parse(raw)
+			require.Error(t, err)
+			assert.Nil(t, outputs)
+
+			var parseErr *ParseError
+			require.True(t, errors.As(err, &parseErr), "error should be a *ParseError")
+			assert.Equal(t, string(raw), parseErr.Raw)
+			assert.Contains(t, err.Error(), string(raw), "error message should carry the raw output")
+		})
+	}
+}
+
+func TestParseRejectsNonObjects(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{"null", "[]", `"vpc-123"`, `{"vpc_id": {"value": "x", "unexpected": 1}}`} {
+		_, err := Parse([]byte(raw))
+		assert.Error(t, err, "input %q should be rejected", raw)
+	}
+}
+
+func TestTypedAccessorsAreStrict(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := Parse(readFixture(t, "setup_terraform_wrapper.txt"))
+	require.NoError(t, err)
+
+	_, err = outputs.String("public_subnet_ids")
+	assert.Error(t, err, "a list output should not decode as a string")
+
+	_, err = outputs.List("vpc_id")
+	assert.Error(t, err, "a string output should not decode as a list")
+
+	_, err = outputs.String("nat_gateway_id")
+	assert.ErrorContains(t, err, `output "nat_gateway_id" not found`)
+
+	outputs, err = Parse([]byte(`{"vpc_id": {"sensitive": false, "type": "string", "value": null},
+		"private_subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": null}}`))
+	require.NoError(t, err)
+	_, err = outputs.String("vpc_id")
+	assert.ErrorContains(t, err, `output "vpc_id" is null`, "null would otherwise decode as \"\"")
+	_, err = outputs.List("private_subnet_ids")
+	assert.ErrorContains(t, err, `output "private_subnet_ids" is null`)
+}
+
+func TestReadPassesEnvVars(t *testing.T) {
+	t.Parallel()
+
+	binary := filepath.Join(t.TempDir(), "terraform")
+	script := "#!/bin/sh\necho '::debug::Terraform exited with code 0.'\n" +
+		`printf '{"data_dir": {"sensitive": false, "type": "string", "value": "%s"}}\n' "$TF_DATA_DIR"` + "\n"
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o755))
+
+	outputs, err := Read(binary, t.TempDir(), map[string]string{"TF_DATA_DIR": ".terraform-e2e"})
+	require.NoError(t, err)
+	dataDir, err := outputs.String("data_dir")
+	require.NoError(t, err)
+	assert.Equal(t, ".terraform-e2e", dataDir)
+}
+
+func TestCleanKeepsTerraformLines(t *testing.T) {
+	t.Parallel()
+
+	raw := []byte("::debug::noise\n{\n  \"a\": {\"sensitive\": false, \"type\": \"string\", \"value\": \"::not-an-annotation::\"}\n}\n")
+	cleaned := Clean(raw)
+
+	assert.NotContains(t, string(cleaned), "::debug::")
+	assert.Contains(t, string(cleaned), "::not-an-annotation::")
+}
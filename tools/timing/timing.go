@@ -0,0 +1,404 @@
+// Package timing records how long each terraform phase takes per module and
+// checks the result against per-module budgets and a stored baseline report.
+//
+// The E2E and integration runners wrap their terraform calls with a Recorder,
+// write the resulting Report as JSON and Markdown at the end of the run, and
+// fail the run when Check returns violations.
+package timing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase is a terraform step that gets timed
+type Phase string
+
+const (
+	PhaseInit    Phase = "init"
+	PhasePlan    Phase = "plan"
+	PhaseApply   Phase = "apply"
+	PhaseOutput  Phase = "output"
+	PhaseDestroy Phase = "destroy"
+)
+
+// Phases lists every phase in the order terraform runs them
+var Phases = []Phase{PhaseInit, PhasePlan, PhaseApply, PhaseOutput, PhaseDestroy}
+
+// Recorder collects phase durations per module. It is safe for concurrent use,
+// so parallel integration tests can share one Recorder.
+type Recorder struct {
+	mu      sync.Mutex
+	suite   string
+	now     func() time.Time
+	modules map[string]map[Phase]time.Duration
+	failed  map[string]bool
+}
+
+// NewRecorder creates an empty Recorder for the named test suite
+func NewRecorder(suite string) *Recorder {
+	return &Recorder{
+		suite:   suite,
+		now:     time.Now,
+		modules: make(map[string]map[Phase]time.Duration),
+		failed:  make(map[string]bool),
+	}
+}
+
+// Add records a duration for a module phase. Repeated phases for the same
+// module (e.g. several plan-only tests against one stack) are summed.
+func (r *Recorder) Add(module string, phase Phase, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.modules[module] == nil {
+		r.modules[module] = make(map[Phase]time.Duration)
+	}
+	r.modules[module][phase] += d
+}
+
+// Time runs fn, records how long it took and returns its error. The duration
+// is recorded even when fn fails so slow failures still show up in the report.
+func (r *Recorder) Time(module string, phase Phase, fn func() error) error {
+	start := r.now()
+	err := fn()
+	r.Add(module, phase, r.now().Sub(start))
+
+	if err != nil {
+		r.mu.Lock()
+		r.failed[module] = true
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// Report snapshots the recorded durations
+func (r *Recorder) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{
+		Suite:       r.suite,
+		GeneratedAt: r.now().UTC(),
+	}
+
+	for module, phases := range r.modules {
+		entry := ModuleTiming{
+			Module: module,
+			Phases: make(map[Phase]float64),
+			Failed: r.failed[module],
+		}
+		for phase, d := range phases {
+			entry.Phases[phase] = round(d.Seconds())
+			entry.TotalSeconds += d.Seconds()
+		}
+		entry.TotalSeconds = round(entry.TotalSeconds)
+		report.Modules = append(report.Modules, entry)
+	}
+
+	sort.Slice(report.Modules, func(i, j int) bool {
+		return report.Modules[i].Module < report.Modules[j].Module
+	})
+	return report
+}
+
+// Report is the JSON timing report written after a run
+type Report struct {
+	Suite       string         `json:"suite"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Modules     []ModuleTiming `json:"modules"`
+}
+
+// ModuleTiming holds the phase durations of one module, in seconds
+type ModuleTiming struct {
+	Module       string            `json:"module"`
+	Phases       map[Phase]float64 `json:"phases"`
+	TotalSeconds float64           `json:"total_seconds"`
+	Failed       bool              `json:"failed,omitempty"`
+}
+
+// Module returns the timing entry for a module, if it was recorded
+func (r Report) Module(name string) (ModuleTiming, bool) {
+	for _, m := range r.Modules {
+		if m.Module == name {
+			return m, true
+		}
+	}
+	return ModuleTiming{}, false
+}
+
+// Config holds the limits a run is checked against
+type Config struct {
+	// RegressionPercent fails a module whose total grows by more than this
+	// percentage over the baseline. Zero disables the regression check.
+	RegressionPercent float64 `json:"regression_percent"`
+
+	// MinRegressionSeconds ignores regressions smaller than this many seconds,
+	// so a 4s → 6s plan doesn't fail the run as a 50% regression.
+	MinRegressionSeconds float64 `json:"min_regression_seconds"`
+
+	// BudgetSeconds is the maximum total duration per module
+	BudgetSeconds map[string]float64 `json:"budget_seconds"`
+}
+
+// Violation describes a module that went over budget or regressed
+type Violation struct {
+	Module  string
+	Kind    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s [%s]: %s", v.Module, v.Kind, v.Message)
+}
+
+// Check compares a report with the configured budgets and, when baseline is
+// non-nil, with a previous report. Modules missing from either side are only
+// checked against what is available.
+func Check(report Report, config Config, baseline *Report) []Violation {
+	var violations []Violation
+
+	for _, m := range report.Modules {
+		if budget, ok := config.BudgetSeconds[m.Module]; ok && budget > 0 && m.TotalSeconds > budget {
+			violations = append(violations, Violation{
+				Module:  m.Module,
+				Kind:    "budget",
+				Message: fmt.Sprintf("took %s, budget is %s", formatSeconds(m.TotalSeconds), formatSeconds(budget)),
+			})
+		}
+
+		if baseline == nil || config.RegressionPercent <= 0 {
+			continue
+		}
+		previous, ok := baseline.Module(m.Module)
+		if !ok || previous.TotalSeconds <= 0 {
+			continue
+		}
+
+		delta := m.TotalSeconds - previous.TotalSeconds
+		percent := delta / previous.TotalSeconds * 100
+		if percent > config.RegressionPercent && delta >= config.MinRegressionSeconds {
+			violations = append(violations, Violation{
+				Module: m.Module,
+				Kind:   "regression",
+				Message: fmt.Sprintf("took %s vs %s baseline (+%.0f%%, limit %.0f%%)",
+					formatSeconds(m.TotalSeconds), formatSeconds(previous.TotalSeconds), percent, config.RegressionPercent),
+			})
+		}
+	}
+
+	return violations
+}
+
+// Markdown renders the report as a summary table, marking modules that have
+// violations. baseline may be nil.
+func Markdown(report Report, config Config, baseline *Report, violations []Violation) string {
+	flagged := make(map[string][]string)
+	for _, v := range violations {
+		flagged[v.Module] = append(flagged[v.Module], v.Kind)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Terraform timing: %s\n\n", report.Suite)
+	b.WriteString("| Module |")
+	for _, phase := range Phases {
+		fmt.Fprintf(&b, " %s |", phase)
+	}
+	b.WriteString(" total | budget | baseline | status |\n|---|")
+	for range Phases {
+		b.WriteString("---:|")
+	}
+	b.WriteString("---:|---:|---:|---|\n")
+
+	for _, m := range report.Modules {
+		fmt.Fprintf(&b, "| %s |", m.Module)
+		for _, phase := range Phases {
+			if seconds, ok := m.Phases[phase]; ok {
+				fmt.Fprintf(&b, " %s |", formatSeconds(seconds))
+			} else {
+				b.WriteString(" - |")
+			}
+		}
+		fmt.Fprintf(&b, " %s |", formatSeconds(m.TotalSeconds))
+
+		if budget, ok := config.BudgetSeconds[m.Module]; ok && budget > 0 {
+			fmt.Fprintf(&b, " %s |", formatSeconds(budget))
+		} else {
+			b.WriteString(" - |")
+		}
+
+		if previous, ok := baselineModule(baseline, m.Module); ok {
+			fmt.Fprintf(&b, " %s |", formatSeconds(previous.TotalSeconds))
+		} else {
+			b.WriteString(" - |")
+		}
+
+		switch {
+		case len(flagged[m.Module]) > 0:
+			fmt.Fprintf(&b, " ❌ %s |\n", strings.Join(flagged[m.Module], ", "))
+		case m.Failed:
+			b.WriteString(" ⚠️ terraform error |\n")
+		default:
+			b.WriteString(" ✅ |\n")
+		}
+	}
+
+	if len(violations) > 0 {
+		b.WriteString("\n### Violations\n\n")
+		for _, v := range violations {
+			fmt.Fprintf(&b, "- %s\n", v)
+		}
+	}
+
+	return b.String()
+}
+
+// LoadConfig reads a Config from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("invalid timing config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// LoadReport reads a Report from a JSON file
+func LoadReport(path string) (Report, error) {
+	var report Report
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return report, fmt.Errorf("invalid timing report %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// WriteJSON writes the report to path as indented JSON
+func (r Report) WriteJSON(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+func baselineModule(baseline *Report, module string) (ModuleTiming, bool) {
+	if baseline == nil {
+		return ModuleTiming{}, false
+	}
+	return baseline.Module(module)
+}
+
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+func round(seconds float64) float64 {
+	return float64(int64(seconds*100+0.5)) / 100
+}
+
+// Settings locate the files a test runner reads and writes at the end of a run
+type Settings struct {
+	// Name is the report file name without extension. Separate `go test -run`
+	// invocations of one suite should use separate names so they don't
+	// overwrite each other's reports or share a baseline.
+	Name string
+	// ReportDir receives <Name>.json and <Name>.md
+	ReportDir string
+	// ConfigPath holds the budgets; a missing file means no limits
+	ConfigPath string
+	// BaselineDir holds previous reports as <Name>.json; a missing file skips
+	// the regression check
+	BaselineDir string
+}
+
+// SettingsFromEnv returns the given defaults overridden by TIMING_REPORT_NAME,
+// TIMING_REPORT_DIR, TIMING_CONFIG and TIMING_BASELINE_DIR when those are set
+func SettingsFromEnv(defaults Settings) Settings {
+	if name := os.Getenv("TIMING_REPORT_NAME"); name != "" {
+		defaults.Name = name
+	}
+	if dir := os.Getenv("TIMING_REPORT_DIR"); dir != "" {
+		defaults.ReportDir = dir
+	}
+	if path := os.Getenv("TIMING_CONFIG"); path != "" {
+		defaults.ConfigPath = path
+	}
+	if dir := os.Getenv("TIMING_BASELINE_DIR"); dir != "" {
+		defaults.BaselineDir = dir
+	}
+	return defaults
+}
+
+// Finish writes the JSON and Markdown reports, appends the Markdown to the
+// GitHub Actions step summary when available, and returns the violations.
+// Nothing is written when no terraform phase was recorded.
+func Finish(r *Recorder, settings Settings) ([]Violation, error) {
+	report := r.Report()
+	if len(report.Modules) == 0 {
+		return nil, nil
+	}
+
+	var config Config
+	if settings.ConfigPath != "" {
+		loaded, err := LoadConfig(settings.ConfigPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		config = loaded
+	}
+
+	name := settings.Name
+	if name == "" {
+		name = report.Suite
+	}
+
+	var baseline *Report
+	if settings.BaselineDir != "" {
+		loaded, err := LoadReport(filepath.Join(settings.BaselineDir, name+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			baseline = &loaded
+		}
+	}
+
+	violations := Check(report, config, baseline)
+	summary := Markdown(report, config, baseline, violations)
+
+	if err := os.MkdirAll(settings.ReportDir, 0o755); err != nil {
+		return violations, err
+	}
+	if err := report.WriteJSON(filepath.Join(settings.ReportDir, name+".json")); err != nil {
+		return violations, err
+	}
+	if err := os.WriteFile(filepath.Join(settings.ReportDir, name+".md"), []byte(summary), 0o644); err != nil {
+		return violations, err
+	}
+
+	if stepSummary := os.Getenv("GITHUB_STEP_SUMMARY"); stepSummary != "" {
+		f, err := os.OpenFile(stepSummary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return violations, err
+		}
+		defer f.Close()
+		if _, err := f.WriteString(summary + "\n"); err != nil {
+			return violations, err
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,178 @@
+package timing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances by step every time it is read
+func fakeClock(step time.Duration) func() time.Time {
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		current = current.Add(step)
+		return current
+	}
+}
+
+func sampleReport() Report {
+	r := NewRecorder("e2e")
+	r.Add("vpc", PhaseInit, 10*time.Second)
+	r.Add("vpc", PhaseApply, 170*time.Second)
+	r.Add("firewall", PhaseInit, 12*time.Second)
+	r.Add("firewall", PhaseApply, 588*time.Second)
+	r.Add("firewall", PhaseDestroy, 600*time.Second)
+	return r.Report()
+}
+
+func TestRecorderSumsRepeatedPhases(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder("integration")
+	r.Add("compute", PhasePlan, 20*time.Second)
+	r.Add("compute", PhasePlan, 25*time.Second)
+	r.Add("compute", PhaseInit, 5*time.Second)
+
+	report := r.Report()
+	require.Len(t, report.Modules, 1)
+
+	compute := report.Modules[0]
+	assert.Equal(t, "compute", compute.Module)
+	assert.Equal(t, 45.0, compute.Phases[PhasePlan])
+	assert.Equal(t, 50.0, compute.TotalSeconds)
+	assert.False(t, compute.Failed)
+}
+
+func TestRecorderTimeRecordsFailures(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder("e2e")
+	r.now = fakeClock(3 * time.Second)
+
+	err := r.Time("secrets", PhaseApply, func() error { return errors.New("apply failed") })
+	require.Error(t, err)
+
+	secrets, ok := r.Report().Module("secrets")
+	require.True(t, ok)
+	assert.Equal(t, 3.0, secrets.Phases[PhaseApply])
+	assert.True(t, secrets.Failed, "a failed phase should mark the module as failed")
+}
+
+func TestCheckBudget(t *testing.T) {
+	t.Parallel()
+
+	config := Config{BudgetSeconds: map[string]float64{"firewall": 900, "vpc": 600}}
+	violations := Check(sampleReport(), config, nil)
+
+	require.Len(t, violations, 1)
+	assert.Equal(t, "firewall", violations[0].Module)
+	assert.Equal(t, "budget", violations[0].Kind)
+}
+
+func TestCheckRegression(t *testing.T) {
+	t.Parallel()
+
+	baseline := Report{Modules: []ModuleTiming{
+		{Module: "vpc", TotalSeconds: 120},
+		{Module: "firewall", TotalSeconds: 1150},
+	}}
+	config := Config{RegressionPercent: 25, MinRegressionSeconds: 30}
+
+	violations := Check(sampleReport(), config, &baseline)
+
+	// vpc: 180s vs 120s is +50%; firewall: 1200s vs 1150s is only +4%
+	require.Len(t, violations, 1)
+	assert.Equal(t, "vpc", violations[0].Module)
+	assert.Equal(t, "regression", violations[0].Kind)
+}
+
+func TestCheckRegressionIgnoresNoise(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder("integration")
+	r.Add("rbac-authorization", PhasePlan, 8*time.Second)
+	baseline := Report{Modules: []ModuleTiming{{Module: "rbac-authorization", TotalSeconds: 4}}}
+
+	violations := Check(r.Report(), Config{RegressionPercent: 25, MinRegressionSeconds: 30}, &baseline)
+	assert.Empty(t, violations, "a 4s regression is below the noise floor")
+}
+
+func TestMarkdownMarksViolations(t *testing.T) {
+	t.Parallel()
+
+	config := Config{BudgetSeconds: map[string]float64{"firewall": 900}}
+	report := sampleReport()
+	violations := Check(report, config, nil)
+
+	summary := Markdown(report, config, nil, violations)
+	assert.Contains(t, summary, "## Terraform timing: e2e")
+	assert.Contains(t, summary, "| firewall | 12s | - | 9m48s | - | 10m0s | 20m0s | 15m0s | - | ❌ budget |")
+	assert.Contains(t, summary, "| vpc | 10s | - | 2m50s | - | - | 3m0s | - | - | ✅ |")
+	assert.Contains(t, summary, "### Violations")
+}
+
+func TestFinishWritesReports(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "budgets.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"budget_seconds": {"vpc": 60}}`), 0o644))
+
+	summaryPath := filepath.Join(dir, "step-summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryPath)
+
+	r := NewRecorder("e2e")
+	r.Add("vpc", PhaseApply, 90*time.Second)
+
+	violations, err := Finish(r, Settings{
+		Name:        "critical-path",
+		ReportDir:   filepath.Join(dir, "reports"),
+		ConfigPath:  configPath,
+		BaselineDir: filepath.Join(dir, "missing-baselines"),
+	})
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+
+	written, err := LoadReport(filepath.Join(dir, "reports", "critical-path.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "e2e", written.Suite)
+	assert.FileExists(t, filepath.Join(dir, "reports", "critical-path.md"))
+
+	summary, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(summary), "| vpc |")
+}
+
+func TestFinishComparesAgainstBaseline(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	baselineDir := filepath.Join(dir, "baselines")
+	require.NoError(t, os.MkdirAll(baselineDir, 0o755))
+	require.NoError(t, Report{Suite: "e2e", Modules: []ModuleTiming{{Module: "vpc", TotalSeconds: 100}}}.
+		WriteJSON(filepath.Join(baselineDir, "e2e.json")))
+
+	configPath := filepath.Join(dir, "budgets.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"regression_percent": 20}`), 0o644))
+
+	r := NewRecorder("e2e")
+	r.Add("vpc", PhaseApply, 150*time.Second)
+
+	violations, err := Finish(r, Settings{ReportDir: filepath.Join(dir, "reports"), ConfigPath: configPath, BaselineDir: baselineDir})
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "regression", violations[0].Kind)
+	assert.FileExists(t, filepath.Join(dir, "reports", "e2e.json"), "the suite name is the default report name")
+}
+
+func TestFinishWithoutMeasurements(t *testing.T) {
+	dir := t.TempDir()
+
+	violations, err := Finish(NewRecorder("e2e"), Settings{ReportDir: filepath.Join(dir, "reports")})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.NoDirExists(t, filepath.Join(dir, "reports"), "nothing should be written when no phase ran")
+}
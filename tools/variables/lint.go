@@ -0,0 +1,82 @@
+package variables
+
+import (
+	"strings"
+
+	"github.com/y3gi/zero-trust-aws/tools/lint"
+	"github.com/y3gi/zero-trust-aws/tools/stack"
+)
+
+// holds returns what a variable holds judging by its name and type, e.g.
+// "a CIDR" for bastion_allowed_cidr of type string or "a list of IDs" for
+// private_subnet_ids of type list(string), or "" when its values need no
+// validation. Numbers, bools and objects hold no ID or CIDR whatever their
+// name, e.g. cidr_count; a variable without a type may hold anything.
+func holds(name, typ string) string {
+	var kind, kinds string
+	switch {
+	case strings.Contains(name, "cidr"):
+		kind, kinds = "a CIDR", "CIDRs"
+	case strings.HasSuffix(name, "_arn") || strings.HasSuffix(name, "_arns"):
+		kind, kinds = "an ARN", "ARNs"
+	case strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "_ids"):
+		kind, kinds = "an ID", "IDs"
+	case name == "env" || name == "environment":
+		kind, kinds = "an environment name", "environment names"
+	default:
+		return ""
+	}
+	switch typ := strings.Join(strings.Fields(typ), ""); typ {
+	case "", "any", "string":
+		return kind
+	case "list(string)", "set(string)", "map(string)":
+		collection, _, _ := strings.Cut(typ, "(")
+		return "a " + collection + " of " + kinds
+	}
+	return ""
+}
+
+// Lint checks the variables of every module and the arguments of every
+// module call
+func Lint(c *Config) []lint.Finding {
+	var l lint.Collector
+	for _, m := range c.Modules {
+		for _, v := range m.Variables {
+			address := "variable." + v.Name
+			if v.Type == "" {
+				l.Add(lint.SeverityError, "variable-type", address, v.Position,
+					"%s has no type, so any value is accepted", v.Name)
+			}
+			if strings.TrimSpace(v.Description) == "" {
+				l.Add(lint.SeverityError, "variable-description", address, v.Position,
+					"%s has no description", v.Name)
+			}
+			if kind := holds(v.Name, v.Type); kind != "" && v.Validations == 0 {
+				l.Add(lint.SeverityError, "variable-validation", address, v.Position,
+					"%s holds %s but has no validation block, so malformed values and placeholders reach the provider", v.Name, kind)
+			}
+			if !m.References[v.Name] && !(m.Root && v.Name == stack.StateBucketVariable) {
+				l.Add(lint.SeverityWarning, "variable-unused", address, v.Position,
+					"%s is declared in %s but never read", v.Name, m.Dir)
+			}
+		}
+
+		for _, call := range m.Calls {
+			called := c.Module(call.Source)
+			if called == nil {
+				continue
+			}
+			declared := map[string]bool{}
+			for _, v := range called.Variables {
+				declared[v.Name] = true
+			}
+			for _, arg := range call.Arguments {
+				if !declared[arg.Name] {
+					l.Add(lint.SeverityError, "module-argument-unknown", "module."+call.Name, arg.Position,
+						"passes %s, which %s does not declare", arg.Name, call.Source)
+				}
+			}
+		}
+	}
+	return l.Findings()
+}
@@ -0,0 +1,20 @@
+variable "state_bucket" {
+  description = "S3 bucket for terraform state"
+  type        = string
+  default     = ""
+}
+
+module "network" {
+  source = "../../../modules/network"
+
+  env         = "dev"
+  vpc_cidr    = "10.0.0.0/16"
+  vpc_id      = "vpc-error"
+  kms_key_arn = "arn:aws:kms:eu-north-1:000000000000:key/error"
+  subnet_ids  = []
+}
+
+variable "region" {
+  description = "Region of the VPC"
+  type        = string
+}
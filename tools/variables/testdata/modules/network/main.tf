@@ -0,0 +1,5 @@
+resource "aws_flow_log" "vpc" {
+  vpc_id          = var.vpc_id
+  log_destination = "arn:aws:logs:eu-north-1:${var.env}:log-group:${var.kms_key_arn}"
+  tags            = merge(var.tags, { Cidr = var.vpc_cidr, Allowed = join(",", var.allowed_cidrs), Subnets = var.cidr_count })
+}
@@ -0,0 +1,55 @@
+# Variables with and without types, descriptions and validation blocks
+
+variable "env" {
+  description = "Environment name"
+  type        = string
+
+  validation {
+    condition     = contains(["dev", "test", "prod"], var.env)
+    error_message = "env must be dev, test or prod."
+  }
+}
+
+variable "vpc_cidr" {
+  description = "CIDR block of the VPC"
+  type        = string
+
+  validation {
+    condition     = can(cidrnetmask(var.vpc_cidr))
+    error_message = "vpc_cidr must be a CIDR block."
+  }
+}
+
+variable "vpc_id" {
+  type = string
+}
+
+variable "kms_key_arn" {
+  description = "ARN of the key encrypting the logs"
+}
+
+variable "tags" {
+  description = ""
+  type        = map(string)
+  default     = {}
+}
+
+variable "region" {
+  description = "Region of the VPC"
+  type        = string
+
+  validation {
+    condition     = length(var.region) > 0
+    error_message = "region must be set."
+  }
+}
+
+variable "allowed_cidrs" {
+  description = "CIDR blocks allowed to reach the VPC"
+  type        = list(string)
+}
+
+variable "cidr_count" {
+  description = "Number of subnets carved from vpc_cidr"
+  type        = number
+}
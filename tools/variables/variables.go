@@ -0,0 +1,161 @@
+// Package variables checks the input variables of the modules under modules/
+// and of the root modules under envs/: every variable needs a type and a
+// description, variables holding CIDRs, ARNs, IDs or environment names need
+// a validation block so that placeholders such as "vpc-error" fail the plan,
+// and every variable must be used. Module calls under envs/ must only pass
+// variables the called module declares.
+package variables
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/y3gi/zero-trust-aws/tools/tfconfig"
+)
+
+// Variable is a variable block
+type Variable struct {
+	Name        string
+	Type        string
+	Description string
+	Validations int
+	Position    string
+}
+
+// Argument is an argument of a module call
+type Argument struct {
+	Name     string
+	Position string
+}
+
+// Call is a module block calling a local module
+type Call struct {
+	Name string
+	// Source is the directory of the called module, relative to the root
+	Source    string
+	Arguments []Argument
+	Position  string
+}
+
+// Module is a directory of .tf files
+type Module struct {
+	// Dir is the directory relative to the root, e.g. modules/vpc
+	Dir string
+	// Root is true for the root modules under envs/
+	Root      bool
+	Variables []*Variable
+	// References are the names of the variables the module reads as var.<name>
+	References map[string]bool
+	Calls      []*Call
+}
+
+// Config is the modules under modules/ and envs/ of a root
+type Config struct {
+	Modules []*Module
+}
+
+// Module returns the module of a directory relative to the root, or nil
+func (c *Config) Module(dir string) *Module {
+	for _, m := range c.Modules {
+		if m.Dir == dir {
+			return m
+		}
+	}
+	return nil
+}
+
+// metaArguments are the arguments of a module block that are not variables
+var metaArguments = []string{"source", "version", "providers", "count", "for_each", "depends_on"}
+
+// Load reads every module under root/modules and every directory of .tf
+// files under root/envs. Positions and directories are relative to root.
+func Load(root string) (*Config, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "modules", "*"))
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(filepath.Join(root, "envs"), func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case os.IsNotExist(err) && path == filepath.Join(root, "envs"):
+			return filepath.SkipDir
+		case err != nil:
+			return err
+		case entry.IsDir() && strings.HasPrefix(entry.Name(), "."):
+			return filepath.SkipDir
+		case entry.IsDir():
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	c := &Config{}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		module, err := tfconfig.LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(module.Blocks) == 0 {
+			continue
+		}
+		c.Modules = append(c.Modules, loadModule(root, dir, module))
+	}
+	return c, nil
+}
+
+// loadModule reads the variables, variable references and local module calls
+// of the module in dir
+func loadModule(root, dir string, module *tfconfig.Module) *Module {
+	rel := tfconfig.Relative(root, dir)
+	m := &Module{Dir: rel, Root: strings.HasPrefix(rel, "envs/"), References: map[string]bool{}}
+	for _, block := range module.Blocks {
+		switch block.Type {
+		case "variable":
+			m.Variables = append(m.Variables, &Variable{
+				Name:        block.Name(),
+				Type:        block.Source("type"),
+				Description: block.String("description"),
+				Validations: len(block.Nested("validation")),
+				Position:    tfconfig.Position(root, block.File, block.Line),
+			})
+			// a validation reading the variable does not use it
+			continue
+		case "module":
+			if source := block.String("source"); strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				call := &Call{
+					Name:     block.Name(),
+					Source:   tfconfig.Relative(root, filepath.Join(dir, source)),
+					Position: tfconfig.Position(root, block.File, block.Line),
+				}
+				for name := range block.Body.Attributes {
+					if !slices.Contains(metaArguments, name) {
+						call.Arguments = append(call.Arguments, Argument{Name: name, Position: tfconfig.Position(root, block.File, block.AttrLine(name))})
+					}
+				}
+				sort.Slice(call.Arguments, func(i, j int) bool { return call.Arguments[i].Name < call.Arguments[j].Name })
+				m.Calls = append(m.Calls, call)
+			}
+		}
+		hclsyntax.VisitAll(block.Body, func(node hclsyntax.Node) hcl.Diagnostics {
+			if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && expr.Traversal.RootName() == "var" && len(expr.Traversal) > 1 {
+				if attr, ok := expr.Traversal[1].(hcl.TraverseAttr); ok {
+					m.References[attr.Name] = true
+				}
+			}
+			return nil
+		})
+	}
+	return m
+}
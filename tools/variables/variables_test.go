@@ -0,0 +1,79 @@
+package variables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/y3gi/zero-trust-aws/tools/lint/linttest"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata")
+	require.NoError(t, err)
+	require.Len(t, c.Modules, 2)
+	call := c.Module("envs/dev/network").Calls[0]
+	assert.Equal(t, "modules/network", call.Source)
+	var arguments []string
+	for _, arg := range call.Arguments {
+		arguments = append(arguments, arg.Name)
+	}
+	assert.Equal(t, []string{"env", "kms_key_arn", "subnet_ids", "vpc_cidr", "vpc_id"}, arguments)
+
+	network := c.Module("modules/network")
+	assert.Equal(t, map[string]bool{"env": true, "vpc_cidr": true, "vpc_id": true, "kms_key_arn": true, "tags": true, "allowed_cidrs": true, "cidr_count": true}, network.References,
+		"the validation of region reads it, but nothing else does")
+	assert.Equal(t, 1, network.Variables[0].Validations)
+	assert.Equal(t, "map(string)", network.Variables[4].Type)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	c, err := Load("testdata")
+	require.NoError(t, err)
+	findings := Lint(c)
+	assert.Equal(t, []string{
+		"modules/network/variables.tf:27 variable-type: kms_key_arn has no type, so any value is accepted",
+	}, linttest.Messages(findings, "variable-type"))
+	assert.Equal(t, []string{
+		"modules/network/variables.tf:23 variable-description: vpc_id has no description",
+		"modules/network/variables.tf:31 variable-description: tags has no description",
+	}, linttest.Messages(findings, "variable-description"))
+	assert.Equal(t, []string{
+		"modules/network/variables.tf:23 variable-validation: vpc_id holds an ID but has no validation block, so malformed values and placeholders reach the provider",
+		"modules/network/variables.tf:27 variable-validation: kms_key_arn holds an ARN but has no validation block, so malformed values and placeholders reach the provider",
+		"modules/network/variables.tf:47 variable-validation: allowed_cidrs holds a list of CIDRs but has no validation block, so malformed values and placeholders reach the provider",
+	}, linttest.Messages(findings, "variable-validation"), "env and vpc_cidr are validated, tags, region and the number cidr_count hold nothing to validate")
+	assert.Equal(t, []string{
+		"envs/dev/network/main.tf:17 variable-unused: region is declared in envs/dev/network but never read",
+		"modules/network/variables.tf:37 variable-unused: region is declared in modules/network but never read",
+	}, linttest.Messages(findings, "variable-unused"), "ztctl passes state_bucket to every root module, so they declare it whether they read it or not")
+	assert.Equal(t, []string{
+		"envs/dev/network/main.tf:14 module-argument-unknown: passes subnet_ids, which modules/network does not declare",
+	}, linttest.Messages(findings, "module-argument-unknown"))
+}
+
+func TestHolds(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, typ, want string
+	}{
+		{"bastion_allowed_cidr", "string", "a CIDR"},
+		{"cidr_blocks", "list(string)", "a list of CIDRs"},
+		{"kms_key_arn", "", "an ARN"},
+		{"private_subnet_ids", "set( string )", "a set of IDs"},
+		{"subnet_ids", "map(string)", "a map of IDs"},
+		{"env", "string", "an environment name"},
+		{"cidr_count", "number", ""},
+		{"enable_vpc_id", "bool", ""},
+		{"instance_type", "string", ""},
+		{"identity", "string", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, holds(tt.name, tt.typ), tt.name)
+	}
+}